@@ -0,0 +1,104 @@
+package gyaml
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Anchors returns the values anchored in a YAML document, by the names of
+// their anchors. For a document such as
+//
+//	defaults: &defaults
+//	  timeout: 30
+//	service:
+//	  <<: *defaults
+//
+// Anchors(doc)["defaults"] is the object holding timeout, with its source
+// text and position like any value from Get. When a name is anchored more
+// than once, the last value is kept. It returns nil when the document
+// cannot be parsed, and an empty map when it has no anchors. Only the
+// first document of a stream is read.
+func Anchors(yamlStr string) map[string]Result {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil || doc.decoded == nil {
+		return nil
+	}
+	anchors := map[string]Result{}
+	if doc.decoded.node == nil {
+		return anchors
+	}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Anchor != "" {
+			r := doc.decoded.anchored(n)
+			r.path = "&" + Escape(n.Anchor)
+			anchors[n.Anchor] = r
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(doc.decoded.node)
+	return anchors
+}
+
+// anchorPath splits a path starting with an anchor, such as
+// "&defaults.timeout", into the name of the anchor and the rest of the
+// path, which follows a "." or a "|".
+func anchorPath(path string) (name, rest string, ok bool) {
+	if len(path) < 2 || path[0] != '&' {
+		return "", "", false
+	}
+	i := 1
+	for i < len(path) && path[i] != '.' && path[i] != '|' {
+		if path[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i > len(path) {
+		i = len(path)
+	}
+	if i < len(path) {
+		rest = path[i+1:]
+	}
+	return unescapeKey(path[1:i]), rest, i > 1
+}
+
+// findAnchor returns the last node under node anchored as name, or nil.
+func findAnchor(node *yaml.Node, name string) *yaml.Node {
+	var found *yaml.Node
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Anchor == name {
+			found = n
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(node)
+	return found
+}
+
+// anchored returns the Result of the value of node, a node of d carrying
+// an anchor.
+func (d *decodedValue) anchored(node *yaml.Node) Result {
+	value, err := decodeNode(node)
+	if err != nil {
+		return Result{Type: Null}
+	}
+	return d.at(value, node)
+}
+
+// anchor returns the value anchored as name within the value of d, or
+// Null when there is none.
+func (d *decodedValue) anchor(name string) Result {
+	if d.node == nil {
+		return Result{Type: Null}
+	}
+	node := findAnchor(d.node, name)
+	if node == nil {
+		return Result{Type: Null}
+	}
+	return d.anchored(node)
+}
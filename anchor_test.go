@@ -0,0 +1,85 @@
+package gyaml
+
+import (
+	"fmt"
+	"testing"
+)
+
+const anchorYAML = `defaults: &defaults
+  timeout: 30  # seconds
+  tags: [a, b]
+name: &name web
+service:
+  <<: *defaults
+  name: *name
+again: &name api
+"&plain": key
+`
+
+func TestAnchorPaths(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"&defaults.timeout", "30"},
+		{"&defaults.tags.1", "b"},
+		{"&defaults.tags.#", "2"},
+		{"&defaults|@keys", "[timeout tags]"},
+		{"&defaults", "map[tags:[a b] timeout:30]"},
+		// The last value anchored with a name wins
+		{"&name", "api"},
+		{"&name.x", ""},
+		{"&missing", ""},
+		{`\&plain`, "key"},
+		{"service.timeout", "30"},
+	}
+	doc, err := ParseDocument(anchorYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		results := []Result{
+			Get(anchorYAML, test.path), Parse(anchorYAML).Get(test.path), GetWith(anchorYAML, test.path),
+			GetMany(anchorYAML, "name", test.path)[1], doc.Get(test.path),
+		}
+		for i, r := range results {
+			got := r.String()
+			if r.Type == YAML {
+				got = fmt.Sprint(r.Value())
+			}
+			if got != test.expected {
+				t.Errorf("%d: Get(%q) = %q, expected %q", i, test.path, got, test.expected)
+			}
+		}
+	}
+
+	timeout := Get(anchorYAML, "&defaults.timeout")
+	if anchorYAML[timeout.Index:timeout.End] != "30" || timeout.path != "&defaults.timeout" {
+		t.Errorf("Unexpected position %d-%d or path %q", timeout.Index, timeout.End, timeout.path)
+	}
+	if raw := Get(anchorYAML, "&defaults").Raw; raw != "&defaults\ntimeout: 30  # seconds\ntags: [a, b]\n" {
+		t.Errorf("Expected the source text, got %q", raw)
+	}
+	if r, err := GetE(anchorYAML, "&defaults.tags.5", WithStrictBounds()); r.Exists() || err == nil {
+		t.Errorf("Expected the options to apply after the anchor, got %v", err)
+	}
+}
+
+func TestAnchors(t *testing.T) {
+	anchors := Anchors(anchorYAML)
+	if len(anchors) != 2 {
+		t.Fatalf("Expected 2 anchors, got %v", anchors)
+	}
+	if got := anchors["defaults"].Get("timeout").Int(); got != 30 {
+		t.Errorf("Expected the anchored object, got %d", got)
+	}
+	if got := anchors["name"]; got.String() != "api" || got.path != "&name" {
+		t.Errorf("Expected the last value anchored as name, got %s", got.GoString())
+	}
+	if anchors := Anchors("a: 1"); anchors == nil || len(anchors) != 0 {
+		t.Errorf("Expected no anchors, got %v", anchors)
+	}
+	if anchors := Anchors("a: ["); anchors != nil {
+		t.Errorf("Expected nil for invalid YAML, got %v", anchors)
+	}
+}
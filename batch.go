@@ -0,0 +1,190 @@
+package gyaml
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ForEachPathOf resolves every path in paths against the YAML document and
+// calls fn with the index of the path, the path and its Result, as each
+// path resolves. Returning false from fn stops the remaining work.
+//
+// The document is parsed once, and the whole stream once more when paths
+// starting with @N pick other documents. The paths are arranged in a trie
+// of their key components so that a prefix shared by many paths is
+// resolved a single time. Callbacks for paths sharing a prefix are therefore made
+// together: at every level, groups are ordered by the first path that
+// belongs to them. Each Result is the same as Get(yamlStr, path) would
+// return.
+func ForEachPathOf(yamlStr string, paths []string, fn func(i int, path string, r Result) bool) {
+	forEachPathOf(yamlStr, nil, paths, fn)
+}
+
+// forEachPathOf resolves the paths for ForEachPathOf. data holds the text
+// of the document as bytes when the caller has them, and is nil otherwise.
+func forEachPathOf(yamlStr string, data []byte, paths []string, fn func(i int, path string, r Result) bool) {
+	doc, _ := parseSource(yamlStr, data, Options{})
+	root := newPathTrie(paths)
+	if doc.decoded == nil {
+		// Invalid YAML: nothing resolves
+		root.walk(nil, nil, nil, false, func(i int, r Result) bool {
+			return fn(i, paths[i], Result{Type: Null})
+		})
+		return
+	}
+	// The other documents of a stream are parsed for the first path
+	// that needs them
+	var stream []Result
+	var streamErr error
+	parsed := false
+	root.walk(doc.decoded, doc.decoded.v, doc.decoded.node, true, func(i int, r Result) bool {
+		if paths[i] == "" {
+			r = doc
+		} else if n, rest, ok := documentPath(paths[i]); ok {
+			if !parsed {
+				stream, streamErr = streamDocuments(yamlStr, Options{})
+				parsed = true
+			}
+			if r = nthDocument(stream, n); streamErr == nil && rest != "" {
+				r = r.Get(rest)
+			}
+		} else if _, _, ok := anchorPath(paths[i]); ok {
+			r = doc.Get(paths[i])
+		}
+		r.path = paths[i]
+		return fn(i, paths[i], r)
+	})
+}
+
+// GetMany searches the YAML for each of the paths, returning their
+// Results in the same order. The document is parsed once and the paths are
+// resolved as by ForEachPathOf, so each Result is the same as Get would
+// return for its path.
+func GetMany(yamlStr string, paths ...string) []Result {
+	return getMany(yamlStr, nil, paths)
+}
+
+// GetManyBytes searches YAML bytes for each of the paths like GetMany.
+// The bytes are copied, so the Results do not refer to yamlBytes.
+func GetManyBytes(yamlBytes []byte, paths ...string) []Result {
+	return getMany(string(yamlBytes), yamlBytes, paths)
+}
+
+// getMany resolves the paths for GetMany and GetManyBytes.
+func getMany(yamlStr string, data []byte, paths []string) []Result {
+	results := make([]Result, len(paths))
+	forEachPathOf(yamlStr, data, paths, func(i int, _ string, r Result) bool {
+		results[i] = r
+		return true
+	})
+	return results
+}
+
+// pathTrie is a node in a trie of path components. Each node stands for
+// the prefix spelled by the components leading to it.
+type pathTrie struct {
+	// items holds the children and the paths ending at this node, in the
+	// order of the first path that refers to them
+	items    []trieItem
+	children map[string]*pathTrie
+}
+
+// trieItem is either a child node, reached through key, or a path that
+// ends at this node. A path ending at a node may carry a rest, the part
+// of the path from its first operator onwards.
+type trieItem struct {
+	key   string
+	child *pathTrie
+	index int
+	rest  string
+}
+
+// newPathTrie builds the trie for paths. Only the leading run of plain key
+// and index components of each path is shared; everything from the first
+// operator onwards is resolved per path.
+func newPathTrie(paths []string) *pathTrie {
+	root := &pathTrie{}
+	for i, path := range paths {
+		node := root
+		rest := path
+		for rest != "" {
+			head, tail := leadingKey(rest)
+			if head == "" {
+				break
+			}
+			child := node.children[head]
+			if child == nil {
+				if node.children == nil {
+					node.children = make(map[string]*pathTrie)
+				}
+				child = &pathTrie{}
+				node.children[head] = child
+				node.items = append(node.items, trieItem{key: head, child: child})
+			}
+			node, rest = child, tail
+		}
+		node.items = append(node.items, trieItem{index: i, rest: rest})
+	}
+	return root
+}
+
+// walk resolves the trie against a value and its node within the document
+// doc. found is false when the prefix of this node does not resolve. It
+// returns false when fn asked to stop.
+func (p *pathTrie) walk(doc *decodedValue, value interface{}, node *yaml.Node, found bool, fn func(i int, r Result) bool) bool {
+	// Paths ending here with the same rest share their Result
+	var resolved map[string]Result
+	for _, item := range p.items {
+		if item.child != nil {
+			childValue, childNode, childFound := value, node, found
+			if found {
+				childValue, childFound = stepKey(value, item.key)
+				childNode = nil
+				if childFound && node != nil && isSimplePath(item.key) {
+					childNode, _ = lookupNode(node, item.key)
+				}
+			}
+			if !item.child.walk(doc, childValue, childNode, childFound, fn) {
+				return false
+			}
+			continue
+		}
+
+		r, ok := resolved[item.rest]
+		if !ok {
+			switch {
+			case !found:
+				r = Result{Type: Null}
+			case item.rest != "":
+				r = doc.keepFound((&resolver{tree: doc}).getByPath(value, item.rest))
+			default:
+				r = doc.at(value, node)
+			}
+			if resolved == nil {
+				resolved = make(map[string]Result)
+			}
+			resolved[item.rest] = r
+		}
+		if !fn(item.index, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// leadingKey splits off the first component of path when it is a plain
+// key or index that can be resolved on its own, returning the component
+// and the rest of the path. It returns an empty component for paths that
+// start with anything else, such as an operator or a multipath.
+func leadingKey(path string) (string, string) {
+	head := pathParts(path)[0]
+	if head.text == "" || strings.IndexByte("#@&{[", head.text[0]) >= 0 {
+		return "", path
+	}
+	rest := path[head.end:]
+	if rest != "" && rest[0] == '.' {
+		rest = rest[1:]
+	}
+	return head.text, rest
+}
@@ -0,0 +1,223 @@
+package gyaml
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestForEachPathOf(t *testing.T) {
+	paths := []string{
+		"name.first",
+		"age",
+		"name.last",
+		"children.#",
+		"friends.#.first",
+		`friends.#(last="Craig").age`,
+		"children.1",
+		"missing.path",
+		"",
+		"#",
+		"name",
+	}
+
+	seen := make(map[int]bool)
+	ForEachPathOf(testYAML, paths, func(i int, path string, r Result) bool {
+		if seen[i] {
+			t.Errorf("Path %d reported twice", i)
+		}
+		seen[i] = true
+		if path != paths[i] {
+			t.Errorf("Path %d: expected %q, got %q", i, paths[i], path)
+		}
+		if want := Get(testYAML, path); !sameResult(r, want) {
+			t.Errorf("Path %q: got %+v, expected %+v", path, r, want)
+		}
+		return true
+	})
+	if len(seen) != len(paths) {
+		t.Errorf("Expected %d callbacks, got %d", len(paths), len(seen))
+	}
+}
+
+func TestForEachPathOfGroupsByLeadingKey(t *testing.T) {
+	paths := []string{"name.first", "age", "name.last", "name"}
+	var order []int
+	ForEachPathOf(testYAML, paths, func(i int, path string, r Result) bool {
+		order = append(order, i)
+		return true
+	})
+	if len(order) != 4 || order[0] != 0 || order[1] != 2 || order[2] != 3 || order[3] != 1 {
+		t.Errorf("Expected callbacks grouped by leading key [0 2 3 1], got %v", order)
+	}
+}
+
+func TestForEachPathOfEarlyExit(t *testing.T) {
+	calls := 0
+	ForEachPathOf(testYAML, []string{"age", "name.first", "name.last"}, func(i int, path string, r Result) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected 1 callback after early exit, got %d", calls)
+	}
+}
+
+func TestForEachPathOfInvalidYAML(t *testing.T) {
+	calls := 0
+	ForEachPathOf("a: [1, 2", []string{"a", "b.c"}, func(i int, path string, r Result) bool {
+		calls++
+		if r.Exists() {
+			t.Errorf("Path %q should not exist in invalid YAML", path)
+		}
+		return true
+	})
+	if calls != 2 {
+		t.Errorf("Expected 2 callbacks, got %d", calls)
+	}
+}
+
+func TestLeadingKey(t *testing.T) {
+	tests := []struct {
+		path, head, rest string
+	}{
+		{"a.b.c", "a", "b.c"},
+		{"a", "a", ""},
+		{`a\.b.c`, `a\.b`, "c"},
+		{"#.name", "", "#.name"},
+		{".a", "", ".a"},
+		{`items.#(name="x.y")`, "items", `#(name="x.y")`},
+		{"{a,b.c}.d", "", "{a,b.c}.d"},
+		{"a.[b,c]", "a", "[b,c]"},
+	}
+	for _, test := range tests {
+		head, rest := leadingKey(test.path)
+		if head != test.head || rest != test.rest {
+			t.Errorf("leadingKey(%q) = %q, %q; expected %q, %q", test.path, head, rest, test.head, test.rest)
+		}
+	}
+}
+
+// collectPaths returns the escaped path of every value in doc, in
+// document order.
+func collectPaths(doc string) []string {
+	var paths []string
+	var visit func(prefix string, r Result)
+	visit = func(prefix string, r Result) {
+		r.ForEach(func(key, value Result) bool {
+			component := strconv.Itoa(key.Index)
+			if key.Type == String {
+				component = Escape(key.Str)
+			}
+			path := component
+			if prefix != "" {
+				path = prefix + "." + component
+			}
+			paths = append(paths, path)
+			visit(path, value)
+			return true
+		})
+	}
+	visit("", Parse(doc))
+	return paths
+}
+
+// bulkPaths returns n paths over complexYAML: every addressable path,
+// plus operator paths, repeated until there are n of them.
+func bulkPaths(n int) []string {
+	base := append(collectPaths(complexYAML),
+		"application.database.replicas.#.name",
+		"application.database.replicas.#",
+		`application.database.replicas.#(name="replica-2").connection.host`,
+		"application.services.user_service.endpoints.#.path",
+		"application.missing.key",
+	)
+	paths := make([]string, 0, n)
+	for len(paths) < n {
+		paths = append(paths, base...)
+	}
+	return paths[:n]
+}
+
+func TestForEachPathOfMatchesGet(t *testing.T) {
+	paths := bulkPaths(500)
+	calls := 0
+	ForEachPathOf(complexYAML, paths, func(i int, path string, r Result) bool {
+		calls++
+		if want := Get(complexYAML, path); !sameResult(r, want) {
+			t.Errorf("Path %q: got %+v, expected %+v", path, r, want)
+		}
+		return true
+	})
+	if calls != len(paths) {
+		t.Errorf("Expected %d callbacks, got %d", len(paths), calls)
+	}
+}
+
+func TestForEachPathOfStream(t *testing.T) {
+	const stream = "name: a\n---\nname: b\nlist: [1, 2]\n---\n- x\n"
+	paths := []string{"@1.name", "name", "@1", "@2.0", "@1|list.#", "@3", "@1"}
+	results := GetMany(stream, paths...)
+	for i, r := range results {
+		if want := Get(stream, paths[i]); !sameResult(r, want) {
+			t.Errorf("Path %q: got %+v, expected %+v", paths[i], r, want)
+		}
+	}
+	// The stream is parsed once for all the paths into it
+	if results[2].decoded == nil || results[2].decoded != results[6].decoded {
+		t.Errorf("Expected the paths to share the documents of the stream")
+	}
+}
+
+func TestForEachPathOfEarlyExitInsideTrie(t *testing.T) {
+	paths := []string{"a.b.c", "a.b.d", "a.e", "f"}
+	var got []string
+	ForEachPathOf("a: {b: {c: 1, d: 2}, e: 3}\nf: 4\n", paths, func(i int, path string, r Result) bool {
+		got = append(got, path)
+		return path != "a.b.d"
+	})
+	if len(got) != 2 || got[1] != "a.b.d" {
+		t.Errorf("Expected iteration to stop at a.b.d, got %v", got)
+	}
+}
+
+func TestForEachPathOfNodeOrder(t *testing.T) {
+	ForEachPathOf(orderedYAML, []string{"sections", "sections.zeta"}, func(i int, path string, r Result) bool {
+		if i == 0 {
+			keys := r.Map()
+			if len(keys) != 5 {
+				t.Errorf("Expected 5 keys, got %d", len(keys))
+			}
+			first := ""
+			r.ForEach(func(key, value Result) bool {
+				first = key.String()
+				return false
+			})
+			if first != "zeta" {
+				t.Errorf("Expected document order to be kept, first key %q", first)
+			}
+		}
+		return true
+	})
+}
+
+func TestGetMany(t *testing.T) {
+	paths := append(bulkPaths(100), "", "missing.key", "application.name")
+	results := GetMany(complexYAML, paths...)
+	if len(results) != len(paths) {
+		t.Fatalf("Expected %d results, got %d", len(paths), len(results))
+	}
+	for i, path := range paths {
+		if want := Get(complexYAML, path); !sameResult(results[i], want) {
+			t.Errorf("Path %q: got %+v, expected %+v", path, results[i], want)
+		}
+	}
+	if got := GetManyBytes([]byte(testYAML), "name.first", "age"); len(got) != 2 || got[0].String() != "Tom" || got[1].Int() != 37 {
+		t.Errorf("Unexpected results %v", got)
+	}
+	if got := GetMany("a: [1, 2", "a", "b"); len(got) != 2 || got[0].Exists() || got[1].Exists() {
+		t.Errorf("Expected no values from invalid YAML, got %v", got)
+	}
+	if got := GetMany(testYAML); len(got) != 0 {
+		t.Errorf("Expected no results without paths, got %v", got)
+	}
+}
@@ -2,6 +2,8 @@ package gyaml
 
 import (
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 const benchmarkYAML = `
@@ -53,6 +55,14 @@ func BenchmarkGet(b *testing.B) {
 	}
 }
 
+func BenchmarkGetCached(b *testing.B) {
+	EnableCache(8)
+	defer EnableCache(0)
+	for i := 0; i < b.N; i++ {
+		Get(benchmarkYAML, "users.0.name")
+	}
+}
+
 func BenchmarkGetNested(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		Get(benchmarkYAML, "users.0.profile.settings.theme")
@@ -122,3 +132,124 @@ func BenchmarkGetMultiple(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkGetNestedGeneral(b *testing.B) {
+	// Same path as BenchmarkGetNested, forced through the general engine
+	for i := 0; i < b.N; i++ {
+		var root interface{}
+		yaml.Unmarshal([]byte(benchmarkYAML), &root)
+		getByPath(root, "users.0.profile.settings.theme")
+	}
+}
+
+func BenchmarkGetThenArray(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Get(benchmarkYAML, "users").Array()
+	}
+}
+
+func BenchmarkArrayCached(b *testing.B) {
+	result := Get(benchmarkYAML, "users")
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		result.Array()
+	}
+}
+
+func BenchmarkArrayFromRaw(b *testing.B) {
+	result := Result{Type: YAML, Raw: Get(benchmarkYAML, "users").Raw}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		result.Array()
+	}
+}
+
+func BenchmarkBulkPathsNaive(b *testing.B) {
+	paths := bulkPaths(500)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		doc := Parse(complexYAML)
+		for _, path := range paths {
+			doc.Get(path)
+		}
+	}
+}
+
+func BenchmarkBulkPathsTrie(b *testing.B) {
+	paths := bulkPaths(500)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ForEachPathOf(complexYAML, paths, func(i int, path string, r Result) bool {
+			return true
+		})
+	}
+}
+
+func BenchmarkExistsContainer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Exists(benchmarkYAML, "users.1.profile")
+	}
+}
+
+func BenchmarkExistsMissing(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Exists(benchmarkYAML, "users.1.nickname")
+	}
+}
+
+func BenchmarkGetExistsContainer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Get(benchmarkYAML, "users.1.profile").Exists()
+	}
+}
+
+func BenchmarkGetChained(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Get(complexYAML, "application").Get("services").Get("auth_service").Get("jwt.expiry")
+	}
+}
+
+func BenchmarkGetRefChained(b *testing.B) {
+	// Same chain as BenchmarkGetChained, without writing out the values in
+	// between
+	for i := 0; i < b.N; i++ {
+		GetRef(complexYAML, "application").Get("services").Get("auth_service").Get("jwt.expiry")
+	}
+}
+
+func BenchmarkGetTraced(b *testing.B) {
+	// BenchmarkGet runs with no trace function; this one measures the cost
+	// of having one
+	SetTraceFunc(func(TraceEvent) {})
+	defer SetTraceFunc(nil)
+	for i := 0; i < b.N; i++ {
+		Get(benchmarkYAML, "users.0.name")
+	}
+}
+
+func BenchmarkDocumentGetMultiple(b *testing.B) {
+	// Same paths as BenchmarkGetMultiple, parsing the document once
+	paths := []string{
+		"users.0.name",
+		"users.1.email",
+		"users.2.profile.age",
+		"config.database.host",
+		"config.server.port",
+	}
+	doc, err := ParseDocument(benchmarkYAML)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc.GetMany(paths...)
+	}
+}
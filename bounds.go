@@ -0,0 +1,66 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIndexOutOfRange is reported by GetE and GetWithOptions, with
+// StrictBounds, when an index does not address an element of its array.
+// The Result is Null, as without StrictBounds.
+var ErrIndexOutOfRange = errors.New("gyaml: index out of range")
+
+// Every path component applied to an array goes through indexOf, so that
+// all ways of reaching an element agree on the bounds:
+//
+//	component         lenient (default)   StrictBounds
+//	0 <= i < len      the element         the element
+//	i >= len          Null                Null, ErrIndexOutOfRange
+//	i < 0             Null                Null, ErrIndexOutOfRange
+//	not an integer    Null                Null
+//
+// A component that is not an integer is a key, which arrays do not have,
+// so it is never a bounds error. Within a projection such as
+// "rows.#.3", each element reached counts on its own: lenient paths skip
+// the elements that are too short, and strict ones report the first.
+
+// indexOf resolves part as an index into an array of n elements. isIndex
+// is false when part is not an integer, and inRange is false when it is
+// one that addresses no element.
+func indexOf(part string, n int) (i int, isIndex, inRange bool) {
+	if part == "" {
+		return 0, false, false
+	}
+	i, isIndex = parseIndex(part)
+	if !isIndex {
+		// Integers too large for an int address no element either
+		return 0, isDigits(part), false
+	}
+	return i, true, i >= 0 && i < n
+}
+
+// index resolves part as an index into an array of n elements, recording
+// an index out of range as an error with StrictBounds.
+func (r *resolver) index(part string, n int) (int, bool) {
+	i, isIndex, inRange := indexOf(part, n)
+	if isIndex && !inRange && r.opts.StrictBounds {
+		r.fail(fmt.Errorf("%w: %s in an array of %d", ErrIndexOutOfRange, part, n))
+	}
+	return i, inRange
+}
+
+// isDigits reports whether s is an optionally signed run of digits.
+func isDigits(s string) bool {
+	if s[0] == '+' || s[0] == '-' {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package gyaml
+
+import (
+	"errors"
+	"testing"
+)
+
+const boundsYAML = `
+list: [a, b, c]
+rows:
+  - [1, 2, 3]
+  - [4]
+empty: []
+`
+
+// TestBoundsPolicy checks every way of indexing an array against the
+// policy documented in bounds.go.
+func TestBoundsPolicy(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		// outOfRange is whether strict bounds report the path
+		outOfRange bool
+	}{
+		{"list.0", "a", false},
+		{"list.2", "c", false},
+		{"list.+1", "b", false},
+		{"list.3", "", true},
+		{"list.-1", "", true},
+		{"list.99999999999999999999", "", true},
+		{"list.x", "", false},
+		{"empty.0", "", true},
+		{"rows.1.0", "4", false},
+		{"rows.1.1", "", true},
+		{"rows.#.2", "[3]", true},
+		{"rows.#.0", "[1, 4]", false},
+	}
+	doc := Parse(boundsYAML)
+	for _, tt := range tests {
+		lenient := []Result{
+			Get(boundsYAML, tt.path),
+			doc.Get(tt.path),
+			ParseWith(boundsYAML, WithStrictBounds()).Get(tt.path),
+		}
+		for i, r := range lenient {
+			if got := flowString(r); tt.want == "" && r.Exists() || tt.want != "" && got != tt.want && r.String() != tt.want {
+				t.Errorf("%s (%d): expected %q, got %s", tt.path, i, tt.want, got)
+			}
+		}
+		if got := Has(boundsYAML, tt.path); got != (tt.want != "") {
+			t.Errorf("Has(%s) = %v", tt.path, got)
+		}
+		ForEachPathOf(boundsYAML, []string{tt.path}, func(_ int, _ string, r Result) bool {
+			if r.Exists() != (tt.want != "") {
+				t.Errorf("ForEachPathOf(%s): unexpected %s", tt.path, r.GoString())
+			}
+			return true
+		})
+
+		if _, err := GetE(boundsYAML, tt.path); err != nil {
+			t.Errorf("%s: unexpected lenient error %v", tt.path, err)
+		}
+		r, err := GetE(boundsYAML, tt.path, WithStrictBounds())
+		if got := errors.Is(err, ErrIndexOutOfRange); got != tt.outOfRange {
+			t.Errorf("%s: expected out of range %v, got error %v", tt.path, tt.outOfRange, err)
+		}
+		if r.Exists() != (tt.want != "") {
+			t.Errorf("%s: strict bounds changed the result to %s", tt.path, r.GoString())
+		}
+	}
+}
+
+func TestBoundsErrorMessage(t *testing.T) {
+	_, err := GetE(boundsYAML, "rows.1.5", WithStrictBounds())
+	if err == nil || err.Error() != "gyaml: index out of range: 5 in an array of 1" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
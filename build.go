@@ -0,0 +1,321 @@
+package gyaml
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object returns an empty object, to be filled with SetKey and Merge.
+//
+// The builders return new Results and never modify the one they are
+// called on, so a Result can be shared and extended safely. The Raw of a
+// built value lists keys in sorted order, like other values gyaml builds.
+//
+//	web := gyaml.Object().
+//		SetKey("name", "web1").
+//		SetKey("roles", gyaml.ArrayOf("web", "api"))
+func Object() Result {
+	return makeResult(map[string]interface{}{})
+}
+
+// ArrayOf returns an array of the given values. A value can be a Result,
+// or any Go value that encodes to YAML.
+func ArrayOf(values ...interface{}) Result {
+	arr := make([]interface{}, len(values))
+	for i, v := range values {
+		arr[i] = buildValue(v)
+	}
+	return makeResult(arr)
+}
+
+// SetKey returns a copy of an object with key set to value, which can be a
+// Result or any Go value that encodes to YAML. It returns a Null result
+// when t is not an object.
+func (t Result) SetKey(key string, value interface{}) Result {
+	if obj, ok := t.Value().(map[string]interface{}); ok {
+		out := make(map[string]interface{}, len(obj)+1)
+		for k, v := range obj {
+			out[k] = v
+		}
+		out[key] = buildValue(value)
+		return makeResult(out)
+	}
+	out, ok := anyKeyMap(t.Value())
+	if !ok {
+		return Result{Type: Null}
+	}
+	out[key] = buildValue(value)
+	return makeResult(out)
+}
+
+// Append returns a copy of an array with elem added at the end. It returns
+// a Null result when t is not an array.
+func (t Result) Append(elem interface{}) Result {
+	arr, ok := t.Value().([]interface{})
+	if !ok {
+		return Result{Type: Null}
+	}
+	out := make([]interface{}, len(arr), len(arr)+1)
+	copy(out, arr)
+	return makeResult(append(out, buildValue(elem)))
+}
+
+// Merge returns a copy of an object with the keys of other added. Keys
+// present in both take the value from other; nested objects are replaced,
+// not merged. It returns a Null result unless both are objects.
+func (t Result) Merge(other Result) Result {
+	a, aok := t.Value().(map[string]interface{})
+	b, bok := other.Value().(map[string]interface{})
+	if aok && bok {
+		out := make(map[string]interface{}, len(a)+len(b))
+		for k, v := range a {
+			out[k] = v
+		}
+		for k, v := range b {
+			out[k] = v
+		}
+		return makeResult(out)
+	}
+	anyA, ok := anyKeyMap(t.Value())
+	if !ok {
+		return Result{Type: Null}
+	}
+	anyB, ok := anyKeyMap(other.Value())
+	if !ok {
+		return Result{Type: Null}
+	}
+	for k, v := range anyB {
+		anyA[k] = v
+	}
+	return makeResult(anyA)
+}
+
+// maxUnflattenIndex bounds the indices Unflatten reads as positions in an
+// array, so that a stray large number does not allocate a huge array.
+const maxUnflattenIndex = 1<<16 - 1
+
+// Unflatten builds a YAML document from values keyed by their path, the
+// inverse of Flatten, to turn overrides such as environment variables
+// back into a configuration file:
+//
+//	gyaml.Unflatten(map[string]interface{}{
+//		"database.host":  "db1",
+//		"database.port":  5432,
+//		"servers.0.name": "web",
+//	})
+//	// database:
+//	//     host: db1
+//	//     port: 5432
+//	// servers:
+//	//     - name: web
+//
+// Paths are split at each dot not escaped with a backslash. Segments of
+// plain digits up to 65535 become positions in an array, as long as all
+// the segments at that level are; the elements no path reaches are null.
+// A value can be a Result or any Go value that encodes to YAML. When a
+// path also leads to values below it, as "a" and "a.b" do, the values
+// below it are kept, and the empty path sets the whole document. Keys
+// are written in sorted order, and an empty map gives an empty object.
+func Unflatten(flat map[string]interface{}) string {
+	root := &flatNode{value: map[string]interface{}{}}
+	for path, value := range flat {
+		n := root
+		if path != "" {
+			for _, seg := range splitFlatPath(path) {
+				if n.children == nil {
+					n.children = map[string]*flatNode{}
+				}
+				child, ok := n.children[seg]
+				if !ok {
+					child = &flatNode{}
+					n.children[seg] = child
+				}
+				n = child
+			}
+		}
+		n.value = buildValue(value)
+	}
+	raw, err := marshalYAML(root.build())
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// flatNode is a path segment of the values given to Unflatten.
+type flatNode struct {
+	value    interface{}
+	children map[string]*flatNode
+}
+
+// build returns the value of n, an object or array when it has children.
+func (n *flatNode) build() interface{} {
+	if len(n.children) == 0 {
+		return n.value
+	}
+	size := 0
+	for seg := range n.children {
+		i, ok := flatIndex(seg)
+		if !ok {
+			size = -1
+			break
+		}
+		if i >= size {
+			size = i + 1
+		}
+	}
+	if size >= 0 {
+		arr := make([]interface{}, size)
+		for seg, child := range n.children {
+			i, _ := flatIndex(seg)
+			arr[i] = child.build()
+		}
+		return arr
+	}
+	obj := make(map[string]interface{}, len(n.children))
+	for seg, child := range n.children {
+		obj[unescapeKey(seg)] = child.build()
+	}
+	return obj
+}
+
+// splitFlatPath splits a path given to Unflatten at the dots not escaped
+// with a backslash. The segments keep their escapes, so that an escaped
+// number such as \0 is a key rather than an index.
+func splitFlatPath(path string) []string {
+	var segs []string
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '.':
+			segs = append(segs, path[start:i])
+			start = i + 1
+		}
+	}
+	return append(segs, path[start:])
+}
+
+// flatIndex returns the array position a segment of plain digits stands
+// for, and false for other segments and for positions above
+// maxUnflattenIndex.
+func flatIndex(seg string) (int, bool) {
+	if seg == "" || len(seg) > 1 && seg[0] == '0' || strings.Trim(seg, "0123456789") != "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(seg)
+	if err != nil || i > maxUnflattenIndex {
+		return 0, false
+	}
+	return i, true
+}
+
+// anyKeyMap returns a copy of an object as a map with keys of any type.
+func anyKeyMap(v interface{}) (map[interface{}]interface{}, bool) {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		out := make(map[interface{}]interface{}, len(obj))
+		for k, v := range obj {
+			out[k] = v
+		}
+		return out, true
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{}, len(obj))
+		for k, v := range obj {
+			out[k] = v
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// buildValue converts a value given to a builder into the form of a
+// decoded YAML value. Arrays and objects are copied only where they hold
+// values of another form.
+func buildValue(v interface{}) interface{} {
+	out, _ := convertValue(v)
+	return out
+}
+
+// convertValue converts v like buildValue, and reports whether the result
+// differs from v.
+func convertValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case Result:
+		return v.nativeValue(), true
+	case nil, bool, string, int, int64, uint64, float64, time.Time:
+		return v, false
+	case []interface{}:
+		var out []interface{}
+		for i, elem := range v {
+			c, changed := convertValue(elem)
+			if changed && out == nil {
+				out = append([]interface{}(nil), v...)
+			}
+			if out != nil {
+				out[i] = c
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case map[string]interface{}:
+		var out map[string]interface{}
+		for k, elem := range v {
+			c, changed := convertValue(elem)
+			if changed && out == nil {
+				out = make(map[string]interface{}, len(v))
+				for k, elem := range v {
+					out[k] = elem
+				}
+			}
+			if out != nil {
+				out[k] = c
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case map[interface{}]interface{}:
+		var out map[interface{}]interface{}
+		for k, elem := range v {
+			c, changed := convertValue(elem)
+			if changed && out == nil {
+				out, _ = anyKeyMap(v)
+			}
+			if out != nil {
+				out[k] = c
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	}
+	return encodeValue(v), true
+}
+
+// encodeValue converts any other Go value by encoding it to YAML and
+// decoding it back. It returns nil for values that do not encode, such
+// as functions and channels, for which yaml.v3 panics.
+func encodeValue(v interface{}) (out interface{}) {
+	defer func() {
+		if recover() != nil {
+			out = nil
+		}
+	}()
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil
+	}
+	if err := node.Decode(&out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,263 @@
+package gyaml
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildObject(t *testing.T) {
+	r := Object()
+	r = r.SetKey("name", "web1")
+	r = r.SetKey("roles", ArrayOf("web", "api"))
+	r = r.SetKey("port", 8080)
+
+	if _, ok := r.Value().(map[string]interface{}); !ok {
+		t.Fatalf("built value is not an object: %#v", r)
+	}
+	want := "name: web1\nport: 8080\nroles:\n    - web\n    - api\n"
+	if r.Raw != want {
+		t.Fatalf("Raw = %q, want %q", r.Raw, want)
+	}
+
+	// The Raw parses back to the same value
+	if got := Get(r.Raw, "roles.1").String(); got != "api" {
+		t.Errorf("roles.1 = %q, want api", got)
+	}
+	if got := Get(r.Raw, "port").Int(); got != 8080 {
+		t.Errorf("port = %d, want 8080", got)
+	}
+	if got := r.Get("roles.#").Int(); got != 2 {
+		t.Errorf("roles.# = %d, want 2", got)
+	}
+}
+
+func TestBuildImmutable(t *testing.T) {
+	base := Object().SetKey("a", 1)
+	next := base.SetKey("b", 2)
+	if base.Get("b").Exists() {
+		t.Errorf("SetKey modified the original: %s", base.Raw)
+	}
+	if !next.Get("a").Exists() || !next.Get("b").Exists() {
+		t.Errorf("SetKey lost keys: %s", next.Raw)
+	}
+
+	arr := ArrayOf(1, 2)
+	longer := arr.Append(3)
+	if arr.Len() != 2 || longer.Len() != 3 {
+		t.Errorf("Append: original has %d elements, copy has %d", arr.Len(), longer.Len())
+	}
+
+	doc := Parse("a: 1\nb: [x]\n")
+	doc.SetKey("a", 2)
+	if got := doc.Get("a").Int(); got != 1 {
+		t.Errorf("SetKey modified a parsed document: a = %d", got)
+	}
+}
+
+func TestBuildAppend(t *testing.T) {
+	r := ArrayOf().Append("x").Append(ArrayOf(1, 2)).Append(Parse("k: v"))
+	want := "- x\n- - 1\n  - 2\n- k: v\n"
+	if r.Raw != want {
+		t.Fatalf("Raw = %q, want %q", r.Raw, want)
+	}
+	if got := Parse("a: 1").Append(2); got.Exists() {
+		t.Errorf("Append on an object = %s, want Null", got.Raw)
+	}
+}
+
+func TestBuildMerge(t *testing.T) {
+	base := Parse("name: web1\nport: 80\ntags: {a: 1}\n")
+	over := Object().SetKey("port", 8080).SetKey("tags", Object().SetKey("b", 2))
+	r := base.Merge(over)
+
+	if got := r.Get("port").Int(); got != 8080 {
+		t.Errorf("port = %d, want 8080 from other", got)
+	}
+	if got := r.Get("name").String(); got != "web1" {
+		t.Errorf("name = %q, want web1", got)
+	}
+	// Nested objects are replaced rather than merged
+	if r.Get("tags.a").Exists() || r.Get("tags.b").Int() != 2 {
+		t.Errorf("tags = %s, want {b: 2}", r.Get("tags").Raw)
+	}
+	if got := base.Get("port").Int(); got != 80 {
+		t.Errorf("Merge modified the original: port = %d", got)
+	}
+
+	// Objects with keys that are not strings keep them
+	mixed := Parse("1: one\ntrue: yes\n").Merge(Object().SetKey("name", "x"))
+	if mixed.Len() != 3 || mixed.Get("name").String() != "x" {
+		t.Errorf("merged mixed keys = %s", mixed.Raw)
+	}
+}
+
+func TestBuildWrongTypes(t *testing.T) {
+	for _, r := range []Result{
+		ArrayOf(1).SetKey("a", 1),
+		Parse("5").SetKey("a", 1),
+		Object().Merge(ArrayOf(1)),
+		ArrayOf(1).Merge(Object()),
+		Result{}.Append(1),
+	} {
+		if r.Type != Null {
+			t.Errorf("got %v %q, want Null", r.Type, r.Raw)
+		}
+	}
+}
+
+func TestBuildGoValues(t *testing.T) {
+	type server struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	r := Object().
+		SetKey("servers", []server{{"a", 1}, {"b", 2}}).
+		SetKey("weights", []float64{0.5, 1.5}).
+		SetKey("labels", map[string]string{"app": "web"}).
+		SetKey("none", nil)
+
+	if got := r.Get("servers.1.host").String(); got != "b" {
+		t.Errorf("servers.1.host = %q, want b", got)
+	}
+	if got := r.Get("servers.#.port").Raw; got != "- 1\n- 2\n" {
+		t.Errorf("servers.#.port = %q", got)
+	}
+	if got := r.Get("weights.1").Float(); got != 1.5 {
+		t.Errorf("weights.1 = %v, want 1.5", got)
+	}
+	if got := r.Get("labels.app").String(); got != "web" {
+		t.Errorf("labels.app = %q, want web", got)
+	}
+	if _, ok := r.Value().(map[string]interface{})["none"]; !ok || r.Len() != 4 {
+		t.Errorf("nil value was dropped: %s", r.Raw)
+	}
+
+	// Number Results keep their integer type
+	if _, ok := ArrayOf(Get("n: 3", "n")).Value().([]interface{})[0].(int); !ok {
+		t.Errorf("integer Result was not kept as int")
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	got := Unflatten(map[string]interface{}{
+		"database.host":    "db1",
+		"database.port":    5432,
+		"servers.0.name":   "web",
+		"servers.2":        ArrayOf("x"),
+		`log\.level`:       "debug",
+		`codes.\0`:         "zero",
+		"codes.1":          "one",
+		"enabled":          true,
+		"override":         1,
+		"override.nested":  2,
+		"big.99999999":     "key",
+		"padded.01":        "key",
+		"padded.1":         "index",
+		"database.options": Object(),
+	})
+	want := `big:
+    "99999999": key
+codes:
+    "0": zero
+    "1": one
+database:
+    host: db1
+    options: {}
+    port: 5432
+enabled: true
+log.level: debug
+override:
+    nested: 2
+padded:
+    "01": key
+    "1": index
+servers:
+    - name: web
+    - null
+    - - x
+`
+	if got != want {
+		t.Errorf("Unflatten = %q, want %q", got, want)
+	}
+
+	// Flatten and Unflatten are inverses
+	doc := "a:\n    b.c: [1, null, {d: x}]\n    e: {}\n"
+	flat := map[string]interface{}{}
+	for path, r := range Flatten(doc) {
+		flat[path] = r
+	}
+	if back := Unflatten(flat); !Parse(back).Equal(Parse(doc)) {
+		t.Errorf("Unflatten(Flatten(%q)) = %q", doc, back)
+	}
+
+	if got := Unflatten(nil); got != "{}\n" {
+		t.Errorf("Unflatten(nil) = %q", got)
+	}
+	if got := Unflatten(map[string]interface{}{"": "scalar"}); got != "scalar\n" {
+		t.Errorf("Unflatten of the empty path = %q", got)
+	}
+}
+
+func TestGetValueAt(t *testing.T) {
+	var fromJSON map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"servers": [{"name": "a", "port": 80}, {"name": "b", "port": 8080}], "tags": {"env": "prod"}}`), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	doc := "servers: [{name: a, port: 80}, {name: b, port: 8080}]\ntags: {env: prod}\n"
+	for _, path := range []string{"servers.1.name", "servers.#", "servers.#.port", "servers.#(port>1024).name", "tags", "tags.env", "servers.5", "missing", ""} {
+		want, got := Get(doc, path), GetValueAt(fromJSON, path)
+		if want.Type != got.Type || !want.Equal(got) || got.path != path {
+			t.Errorf("%s: got %s, want %s", path, got.GoString(), want.GoString())
+		}
+	}
+
+	type port struct {
+		Number   int    `yaml:"number"`
+		Protocol string `yaml:"protocol,omitempty"`
+	}
+	type service struct {
+		Name  string
+		Ports []port `yaml:"ports"`
+	}
+	hand := map[string]interface{}{
+		"services": []service{{Name: "web", Ports: []port{{80, "tcp"}, {443, ""}}}},
+		"weights":  []float64{0.5, 1.5},
+		"labels":   map[string]string{"app": "web"},
+		"ptr":      &port{Number: 22},
+		"mixed":    map[interface{}]interface{}{1: "one", "two": []int{2}},
+	}
+	tests := map[string]string{
+		"services.0.name":                         "web",
+		"services.0.ports.1.number":               "443",
+		"services.0.ports.#":                      "2",
+		"services.0.ports.1.protocol":             "",
+		`services.#(name="web").ports.0.protocol`: "tcp",
+		"weights.1":                               "1.5",
+		"labels.app":                              "web",
+		"ptr.number":                              "22",
+		"mixed.1":                                 "one",
+		"mixed.two.0":                             "2",
+		"services.0.Name":                         "",
+	}
+	for path, want := range tests {
+		if got := GetValueAt(hand, path); got.String() != want || got.Exists() != (want != "") {
+			t.Errorf("%s: got %s, want %q", path, got.GoString(), want)
+		}
+	}
+	if _, ok := hand["weights"].([]float64); !ok {
+		t.Errorf("GetValueAt modified its argument")
+	}
+
+	// Values that do not encode to YAML have no paths
+	for _, v := range []interface{}{func() {}, make(chan int), nil} {
+		if r := GetValueAt(v, ""); r.Exists() {
+			t.Errorf("%T: expected Null, got %s", v, r.GoString())
+		}
+	}
+	if r := GetValueAt(map[string]interface{}{"f": func() {}, "a": 1}, "f"); r.Exists() {
+		t.Errorf("Expected Null for a function, got %s", r.GoString())
+	}
+	if r := GetValueAt(42, ""); r.Int() != 42 {
+		t.Errorf("Expected a scalar root, got %s", r.GoString())
+	}
+}
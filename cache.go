@@ -0,0 +1,99 @@
+package gyaml
+
+import (
+	"container/list"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseCache holds the node trees of recently parsed documents, once
+// EnableCache has given it a capacity.
+type parseCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+// cacheEntry is the node tree of a document, or the error it failed with.
+type cacheEntry struct {
+	src  string
+	node *yaml.Node
+	err  error
+}
+
+// cache is the cache set up by EnableCache.
+var cache parseCache
+
+// EnableCache keeps the parsed form of the size most recently used
+// documents, so that calls to Get, Parse and their variants on a document
+// seen before skip parsing it again:
+//
+//	gyaml.EnableCache(64)
+//	for _, path := range paths {
+//		gyaml.Get(config, path) // config is parsed once
+//	}
+//
+// Documents are matched by their text, and the least recently used one is
+// dropped once size are held. The results are the same as without the
+// cache, including the errors of documents that cannot be parsed. A size
+// of 0 or less disables the cache and drops what it holds, which is the
+// default. EnableCache is safe for concurrent use with Get; calling it
+// again changes the capacity and keeps the most recently used documents
+// that fit.
+func EnableCache(size int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if size <= 0 {
+		cache.size, cache.order, cache.entries = 0, nil, nil
+		return
+	}
+	if cache.order == nil {
+		cache.order, cache.entries = list.New(), map[string]*list.Element{}
+	}
+	cache.size = size
+	cache.trim()
+}
+
+// parseCached parses a document like parseNode, through the cache when it
+// is enabled. The tree returned may be shared with other calls, so it must
+// not be modified. data holds the text as bytes when the caller has them,
+// and is nil otherwise.
+func parseCached(yamlStr string, data []byte) (*yaml.Node, error) {
+	cache.mu.Lock()
+	if cache.size == 0 {
+		cache.mu.Unlock()
+		return parseNode(textBytes(yamlStr, data))
+	}
+	if elem, ok := cache.entries[yamlStr]; ok {
+		cache.order.MoveToFront(elem)
+		entry := elem.Value.(*cacheEntry)
+		cache.mu.Unlock()
+		return entry.node, entry.err
+	}
+	cache.mu.Unlock()
+
+	// Parse outside the lock, so that other documents are not held up
+	node, err := parseNode(textBytes(yamlStr, data))
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.size > 0 {
+		if _, ok := cache.entries[yamlStr]; !ok {
+			cache.entries[yamlStr] = cache.order.PushFront(&cacheEntry{src: yamlStr, node: node, err: err})
+			cache.trim()
+		}
+	}
+	return node, err
+}
+
+// trim drops the least recently used documents beyond the capacity of c,
+// under c.mu.
+func (c *parseCache) trim() {
+	for c.order.Len() > c.size {
+		elem := c.order.Back()
+		c.order.Remove(elem)
+		delete(c.entries, elem.Value.(*cacheEntry).src)
+	}
+}
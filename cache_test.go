@@ -0,0 +1,111 @@
+package gyaml
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func cachedDocs() []string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	var docs []string
+	if cache.order == nil {
+		return docs
+	}
+	for elem := cache.order.Front(); elem != nil; elem = elem.Next() {
+		docs = append(docs, elem.Value.(*cacheEntry).src)
+	}
+	return docs
+}
+
+func TestCache(t *testing.T) {
+	EnableCache(0)
+	EnableCache(2)
+	defer EnableCache(0)
+
+	paths := []string{"name.first", "age", "children.#", "friends.#(last=\"Murphy\").first", ""}
+	var uncached []Result
+	for _, path := range paths {
+		uncached = append(uncached, Get(testYAML, path))
+	}
+	for i, path := range paths {
+		if r := Get(testYAML, path); r.Raw != uncached[i].Raw || r.Index != uncached[i].Index || r.String() != uncached[i].String() {
+			t.Errorf("Get(%q) = %v with the cache, expected %v", path, r, uncached[i])
+		}
+	}
+	if got := cachedDocs(); len(got) != 1 || got[0] != testYAML {
+		t.Errorf("Expected the document to be cached once, got %d documents", len(got))
+	}
+
+	// The least recently used document is dropped
+	Get("a: 1", "a")
+	Get(testYAML, "age")
+	Get("b: 2", "b")
+	if got := cachedDocs(); fmt.Sprint(got) != fmt.Sprint([]string{"b: 2", testYAML}) {
+		t.Errorf("Unexpected cached documents %q", got)
+	}
+
+	// Errors are cached too
+	for i := 0; i < 2; i++ {
+		if _, err := GetE("a: [1", "a"); err == nil {
+			t.Errorf("Expected an error for an invalid document")
+		}
+	}
+
+	EnableCache(1)
+	if got := cachedDocs(); len(got) != 1 {
+		t.Errorf("Expected 1 document after shrinking the cache, got %d", len(got))
+	}
+	EnableCache(0)
+	if got := cachedDocs(); len(got) != 0 {
+		t.Errorf("Expected the cache to be dropped, got %d documents", len(got))
+	}
+	Get(testYAML, "age")
+	if got := cachedDocs(); len(got) != 0 {
+		t.Errorf("Expected nothing cached while disabled, got %d documents", len(got))
+	}
+}
+
+func TestCacheKeepMergeKeys(t *testing.T) {
+	EnableCache(4)
+	defer EnableCache(0)
+
+	doc := "base: &b {x: 1}\nitem:\n  <<: *b\n"
+	if got := Get(doc, "item.x").Int(); got != 1 {
+		t.Fatalf("Expected the merged key, got %d", got)
+	}
+	if got := ParseWith(doc, WithKeepMergeKeys()).Get(`item.<<.x`).Int(); got != 1 {
+		t.Errorf("Expected the merge key kept, got %d", got)
+	}
+	// Keeping merge keys must not change the cached tree
+	if got := Parse(doc).Get("item.x").Int(); got != 1 {
+		t.Errorf("Expected the merged key after WithKeepMergeKeys, got %d", got)
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	EnableCache(3)
+	defer EnableCache(0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				n := (g + i) % 5
+				doc := fmt.Sprintf("n: %d\nlist: [a, b]\n", n)
+				if got := Get(doc, "n").Int(); got != int64(n) {
+					t.Errorf("Get(n) = %d, expected %d", got, n)
+					return
+				}
+				if !Has(doc, "list.1") {
+					t.Errorf("Expected list.1 to exist")
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
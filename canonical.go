@@ -0,0 +1,47 @@
+package gyaml
+
+// Canonical re-emits the first document of a YAML stream in a canonical
+// form, as Parse(yamlStr).Canonical() does, so that documents holding the
+// same values give the same text for diffing and hashing. The error is
+// that of parsing, when the YAML is not valid; an empty document gives
+// "null\n".
+func Canonical(yamlStr string) (string, error) {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil {
+		return "", newSyntaxError(yamlStr, err)
+	}
+	return doc.Canonical(), nil
+}
+
+// Canonical returns the value as YAML in a canonical form: keys sorted at
+// every level, with keys that are not strings ordered by their YAML form,
+// four spaces of indentation, and block style throughout. Scalars are
+// written the same way whatever their form in the document, so 0x1F
+// becomes 31, ~ becomes null and 1.50 becomes 1.5, and strings are only
+// quoted when they would read back as another type. Comments, anchors
+// and tags are dropped, aliases and merge keys are expanded, and
+// timestamps within arrays and objects are written in RFC 3339; a
+// timestamp on its own is a String, and written as one. A Result that
+// does not exist gives "null\n".
+func (t Result) Canonical() string {
+	v := t.nativeValue()
+	switch {
+	case t.Type == YAML:
+		d, ok := t.decode()
+		if !ok {
+			return ""
+		}
+		v = d.v
+	case t.Type == Number && t.Raw != "":
+		// Numbers are read from their text, so that 0x1F stays an integer
+		var n interface{}
+		if err := unmarshal([]byte(t.Raw), &n); err == nil {
+			v = n
+		}
+	}
+	out, err := marshalYAML(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
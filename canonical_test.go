@@ -0,0 +1,105 @@
+package gyaml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	doc := `# Service
+name: web
+ports: [0x50, 443]
+limits: {cpu: 1.50, memory: ~}
+defaults: &d
+  retries: 3
+  mode: 'yes'
+service:
+  <<: *d
+  zone: b
+1: one
+`
+	want := `1: one
+defaults:
+    mode: "yes"
+    retries: 3
+limits:
+    cpu: 1.5
+    memory: null
+name: web
+ports:
+    - 80
+    - 443
+service:
+    mode: "yes"
+    retries: 3
+    zone: b
+`
+	got, err := Canonical(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Canonical =\n%s\nwant\n%s", got, want)
+	}
+
+	// The same values written differently give the same text
+	same := `
+service: {zone: b, retries: 0x3, mode: "yes"}
+ports:
+  - 80
+  - 4_43
+1: "one"
+name: "web"
+limits:
+  memory: null
+  cpu: 15e-1
+defaults: {mode: "yes", retries: +3}
+`
+	if got, err := Canonical(same); err != nil || got != want {
+		t.Errorf("Canonical of an equivalent document =\n%s\nwant\n%s", got, want)
+	}
+	if other, _ := Canonical("name: api"); other == want {
+		t.Errorf("Expected different documents to differ")
+	}
+
+	if got, err := Canonical(""); err != nil || got != "null\n" {
+		t.Errorf("Canonical of an empty document = %q, %v", got, err)
+	}
+	var serr *SyntaxError
+	if _, err := Canonical("a: [1"); !errors.As(err, &serr) {
+		t.Errorf("Expected a SyntaxError, got %v", err)
+	}
+}
+
+func TestResultCanonical(t *testing.T) {
+	doc := `
+a: {z: 1, y: [b, a]}
+hex: 0x1F
+ratio: 1.50
+word: 'no'
+flag: yes
+date: 2024-01-02
+`
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a", "\"y\":\n    - b\n    - a\nz: 1\n"},
+		{"a.y.@reverse", "- a\n- b\n"},
+		{"hex", "31\n"},
+		{"ratio", "1.5\n"},
+		{"word", "\"no\"\n"},
+		{"flag", "\"yes\"\n"},
+		{"a.y.#", "2\n"},
+		{"date", "\"2024-01-02\"\n"},
+		{"missing", "null\n"},
+	}
+	for _, tt := range tests {
+		if got := Get(doc, tt.path).Canonical(); got != tt.want {
+			t.Errorf("Get(%q).Canonical() = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if got := Parse(doc).Get("{a.z,hex}").Canonical(); got != "hex: 31\nz: 1\n" {
+		t.Errorf("Canonical of a multipath = %q", got)
+	}
+}
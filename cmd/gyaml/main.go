@@ -0,0 +1,245 @@
+// Command gyaml evaluates a path against a YAML document and prints the
+// result, with the same syntax and semantics as gyaml.Get, for scripts and
+// for trying out paths:
+//
+//	gyaml 'servers.#(region="eu-west-1")#.name' config.yaml
+//	kubectl get pods -o yaml | gyaml 'items.#.metadata.name'
+//
+// The document is read from the file named after the path, or from the
+// standard input when there is none or it is "-". Scalars are printed as
+// Result.String gives them, and arrays and objects as YAML, unless -o
+// names another output format:
+//
+//	yaml
+//		arrays and objects as YAML, the default
+//	json
+//		the result as JSON, as Result.JSON gives it
+//	raw
+//		each element of an array on a line of its own, for shell
+//		pipelines, with strings as they are and arrays and objects as
+//		JSON on one line
+//	tsv
+//		each element of an array on a line of its own, with the values of
+//		the paths given by -columns separated by tabs, as in
+//		-o tsv -columns name,ip. Tabs, line breaks and backslashes within
+//		values are written as \t, \n, \r and \\.
+//
+// Usage:
+//
+//	gyaml [flags] path [file]
+//
+// The flags are:
+//
+//	-o format
+//		the output format: yaml, json, raw or tsv
+//	-json
+//		the same as -o json
+//	-columns paths
+//		the paths of the columns of -o tsv, separated by commas. Without
+//		them, the keys of the first object are the columns, and elements
+//		that are not objects make up a single column.
+//	-header
+//		begin -o tsv with a line naming the columns
+//	-q
+//		print nothing, and only report through the exit status
+//	-strict
+//		report queries that compare values of the wrong type, and
+//		indices outside their array, as gyaml.WithStrictTypes and
+//		gyaml.WithStrictBounds do
+//
+// The exit status is 0 when the path has a value, 1 when it has none, as
+// for a missing key or a null, and 2 when the arguments or the document
+// cannot be read, or a strict check fails.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yongPhone/gyaml"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments and streams, and returns
+// its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gyaml", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("o", "yaml", "the output `format`: yaml, json, raw or tsv")
+	asJSON := flags.Bool("json", false, "the same as -o json")
+	columns := flags.String("columns", "", "the `paths` of the columns of -o tsv, separated by commas")
+	header := flags.Bool("header", false, "begin -o tsv with a line naming the columns")
+	quiet := flags.Bool("q", false, "print nothing, and only report through the exit status")
+	strict := flags.Bool("strict", false, "report type mismatches in queries and indices out of range")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gyaml [flags] path [file]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+	if *asJSON {
+		*format = "json"
+	}
+	write, ok := formats[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "gyaml: unknown output format %q\n", *format)
+		return 2
+	}
+
+	var doc []byte
+	var err error
+	if name := flags.Arg(1); name == "" || name == "-" {
+		doc, err = io.ReadAll(stdin)
+	} else {
+		doc, err = os.ReadFile(name)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "gyaml:", err)
+		return 2
+	}
+
+	var opts []gyaml.Option
+	if *strict {
+		opts = append(opts, gyaml.WithStrictTypes(), gyaml.WithStrictBounds())
+	}
+	result, err := gyaml.GetE(string(doc), path, opts...)
+	switch {
+	case errors.Is(err, gyaml.ErrAmbiguousKey):
+		// The value is still valid
+		fmt.Fprintln(stderr, err)
+	case err != nil:
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if !result.Exists() {
+		return 1
+	}
+	if *quiet {
+		return 0
+	}
+	var cols []string
+	if *columns != "" {
+		cols = strings.Split(*columns, ",")
+	}
+	w := bufio.NewWriter(stdout)
+	write(w, result, output{columns: cols, header: *header})
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(stderr, "gyaml:", err)
+		return 2
+	}
+	return 0
+}
+
+// output holds the settings of the output formats.
+type output struct {
+	// columns are the paths of the columns of tsv
+	columns []string
+	// header begins tsv with the names of the columns
+	header bool
+}
+
+// formats writes a result in each output format, by the name -o gives it.
+var formats = map[string]func(w *bufio.Writer, r gyaml.Result, out output){
+	"yaml": writeYAML,
+	"json": writeJSON,
+	"raw":  writeRaw,
+	"tsv":  writeTSV,
+}
+
+// writeYAML writes scalars as String gives them, and arrays and objects
+// as YAML.
+func writeYAML(w *bufio.Writer, r gyaml.Result, _ output) {
+	w.WriteString(strings.TrimSuffix(r.String(), "\n"))
+	w.WriteByte('\n')
+}
+
+// writeJSON writes the result as JSON.
+func writeJSON(w *bufio.Writer, r gyaml.Result, _ output) {
+	w.WriteString(r.JSON())
+	w.WriteByte('\n')
+}
+
+// writeRaw writes each element of an array, or the result itself, on a
+// line of its own.
+func writeRaw(w *bufio.Writer, r gyaml.Result, _ output) {
+	for _, elem := range elements(r) {
+		w.WriteString(rawText(elem))
+		w.WriteByte('\n')
+	}
+}
+
+// writeTSV writes each element of an array, or the result itself, on a
+// line of its own, with the values of the columns separated by tabs.
+func writeTSV(w *bufio.Writer, r gyaml.Result, out output) {
+	rows := elements(r)
+	columns, names := out.columns, out.columns
+	if columns == nil && len(rows) > 0 && rows[0].IsObject() {
+		names = rows[0].Keys()
+		for _, key := range names {
+			columns = append(columns, gyaml.Escape(key))
+		}
+	}
+	if out.header && columns != nil {
+		writeRow(w, names)
+	}
+	for _, row := range rows {
+		if columns == nil {
+			writeRow(w, []string{rawText(row)})
+			continue
+		}
+		cells := make([]string, len(columns))
+		for i, col := range columns {
+			cells[i] = rawText(row.Get(col))
+		}
+		writeRow(w, cells)
+	}
+}
+
+// writeRow writes a line of tab-separated cells.
+func writeRow(w *bufio.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			w.WriteByte('\t')
+		}
+		w.WriteString(tsvEscaper.Replace(cell))
+	}
+	w.WriteByte('\n')
+}
+
+// tsvEscaper writes the characters that would break a line of TSV as
+// escapes.
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// elements returns the elements of an array, or the result itself.
+func elements(r gyaml.Result) []gyaml.Result {
+	if r.IsArray() {
+		return r.Array()
+	}
+	return []gyaml.Result{r}
+}
+
+// rawText returns a scalar as String gives it, an array or object as JSON
+// on one line, and "" for a value that does not exist.
+func rawText(r gyaml.Result) string {
+	switch {
+	case r.IsArray() || r.IsObject():
+		return r.JSON()
+	case !r.Exists():
+		return ""
+	}
+	return r.String()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const doc = `
+servers:
+  - name: web
+    region: eu-west-1
+    port: 8080
+  - name: db
+    region: us-east-1
+    port: 5432
+owner: null
+`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args   []string
+		status int
+		stdout string
+	}{
+		{[]string{"servers.0.name"}, 0, "web\n"},
+		{[]string{"servers.#"}, 0, "2\n"},
+		{[]string{`servers.#(region="eu-west-1")#.name`}, 0, "- web\n"},
+		{[]string{"servers.1"}, 0, "name: db\nregion: us-east-1\nport: 5432\n"},
+		{[]string{"-json", "servers.1"}, 0, `{"name":"db","region":"us-east-1","port":5432}` + "\n"},
+		{[]string{"-json", "servers.#.port"}, 0, "[8080,5432]\n"},
+		{[]string{"-q", "servers.0"}, 0, ""},
+		{[]string{"servers.0.missing"}, 1, ""},
+		{[]string{"owner"}, 1, ""},
+		{[]string{"-q", "missing"}, 1, ""},
+		{[]string{"servers.#(port>1000).name", "-"}, 0, "web\n"},
+		{[]string{"-strict", `servers.#(name>1)`}, 2, ""},
+		{[]string{"-strict", "servers.5"}, 2, ""},
+		{[]string{}, 2, ""},
+		{[]string{"a", "b", "c"}, 2, ""},
+		{[]string{"-nosuch", "a"}, 2, ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(tt.args, strings.NewReader(doc), &stdout, &stderr)
+		if status != tt.status || stdout.String() != tt.stdout {
+			t.Errorf("%q: got %d %q, want %d %q", tt.args, status, stdout.String(), tt.status, tt.stdout)
+		}
+		if (status == 2) != (stderr.Len() > 0) {
+			t.Errorf("%q: unexpected stderr %q", tt.args, stderr.String())
+		}
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(name, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"servers.1.name", name}, strings.NewReader(""), &stdout, &stderr); status != 0 || stdout.String() != "db\n" {
+		t.Errorf("Unexpected %d %q %q", status, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	status := run([]string{"a", filepath.Join(t.TempDir(), "missing.yaml")}, nil, &stdout, &stderr)
+	if status != 2 || !strings.Contains(stderr.String(), "missing.yaml") {
+		t.Errorf("Expected an error for a missing file, got %d %q", status, stderr.String())
+	}
+
+	stderr.Reset()
+	status = run([]string{"a"}, strings.NewReader("a: [1"), &stdout, &stderr)
+	if status != 2 || !strings.Contains(stderr.String(), "line 1") {
+		t.Errorf("Expected a syntax error, got %d %q", status, stderr.String())
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	const hosts = `
+hosts:
+  - name: web
+    ip: 10.0.0.1
+    tags: [a, b]
+    meta: {zone: eu}
+  - name: "db\tmain"
+    ip: 10.0.0.2
+    note: "line 1\nline 2"
+  - name: cache
+"a.b": {c: 1}
+`
+	tests := []struct {
+		args   []string
+		stdout string
+	}{
+		{[]string{"-o", "yaml", "hosts.#.name"}, "- web\n- \"db\\tmain\"\n- cache\n"},
+		{[]string{"-o", "json", "hosts.#.name"}, `["web","db\tmain","cache"]` + "\n"},
+		{[]string{"-o", "raw", "hosts.#.ip"}, "10.0.0.1\n10.0.0.2\n"},
+		{[]string{"-o", "raw", "hosts.0.tags"}, "a\nb\n"},
+		{[]string{"-o", "raw", "hosts.0"}, `{"name":"web","ip":"10.0.0.1","tags":["a","b"],"meta":{"zone":"eu"}}` + "\n"},
+		{[]string{"-o", "raw", "hosts.#.meta"}, `{"zone":"eu"}` + "\n"},
+		{[]string{"-o", "raw", "hosts.1.note"}, "line 1\nline 2\n"},
+		{[]string{"-o", "raw", "hosts.0.name"}, "web\n"},
+		{[]string{"-o", "tsv", "-columns", "name,ip", "hosts"}, "web\t10.0.0.1\ndb\\tmain\t10.0.0.2\ncache\t\n"},
+		{[]string{"-o", "tsv", "-columns", "name,meta.zone,tags", "-header", "hosts"}, "name\tmeta.zone\ttags\nweb\teu\t[\"a\",\"b\"]\ndb\\tmain\t\t\ncache\t\t\n"},
+		{[]string{"-o", "tsv", "-header", "hosts.#(name!=web)#"}, "name\tip\tnote\ndb\\tmain\t10.0.0.2\tline 1\\nline 2\ncache\t\t\n"},
+		{[]string{"-o", "tsv", "-header", "{\"x\":a\\.b}"}, "x\n{\"c\":1}\n"},
+		{[]string{"-o", "tsv", "-header", "@this|@keys"}, "hosts\na.b\n"},
+		{[]string{"-o", "tsv", "-header", "hosts.0|@keys|@reverse|0"}, "meta\n"},
+		{[]string{"-o", "tsv", "-header", "-columns", "c", "a\\.b"}, "c\n1\n"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(tt.args, strings.NewReader(hosts), &stdout, &stderr)
+		if status != 0 || stdout.String() != tt.stdout {
+			t.Errorf("%q: got %d %q, want %q (%s)", tt.args, status, stdout.String(), tt.stdout, stderr.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-o", "xml", "hosts"}, strings.NewReader(hosts), &stdout, &stderr); status != 2 || !strings.Contains(stderr.String(), "xml") {
+		t.Errorf("Expected an error for an unknown format, got %d %q", status, stderr.String())
+	}
+}
@@ -0,0 +1,103 @@
+package gyaml
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrStop can be returned by the function passed to Collect to stop
+// before the remaining elements. It is not reported as a failure.
+var ErrStop = errors.New("gyaml: stop")
+
+// ElementError is a failure of the function passed to Collect, for one
+// element.
+type ElementError struct {
+	// Path leads to the element from the top of the document when the
+	// path of the collected Result is known, as for Get, and from the
+	// collected Result otherwise
+	Path string
+	// Index is the position of the element, counting from 0
+	Index int
+	// Err is the error returned for the element
+	Err error
+}
+
+func (e *ElementError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error returned for the element.
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// CollectError holds the failures of the function passed to Collect, in
+// the order of the elements.
+type CollectError []*ElementError
+
+func (e CollectError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of the failures matches target, so that
+// errors.Is sees through a CollectError.
+func (e CollectError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first failure that matches target, so that errors.As sees
+// through a CollectError.
+func (e CollectError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect calls fn for each element of an array, or each value of an
+// object, in the order of ForEach, and gathers the errors fn returns. It
+// returns nil when fn succeeded for every element, and otherwise a
+// CollectError listing each failure with the path and index of its
+// element:
+//
+//	err := gyaml.Get(yaml, "services").Collect(func(s gyaml.Result) error {
+//		_, err := s.Get("port").IntE()
+//		return err
+//	})
+//	// services.1: gyaml: cannot use string "http" as integer
+//
+// Elements are visited one at a time without building the whole array.
+// Returning ErrStop stops before the remaining elements without being
+// reported. Errors from a context, context.Canceled and
+// context.DeadlineExceeded, are reported and stop the iteration too.
+// Results that are neither arrays nor objects have no elements.
+func (t Result) Collect(fn func(r Result) error) error {
+	var errs CollectError
+	t.ForEach(func(key, value Result) bool {
+		err := fn(value)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrStop) {
+			return false
+		}
+		errs = append(errs, &ElementError{Path: value.path, Index: key.Index, Err: err})
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	})
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
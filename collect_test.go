@@ -0,0 +1,149 @@
+package gyaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const collectYAML = `
+services:
+  - name: web
+    port: 80
+  - name: api
+    port: http
+  - name: db
+    port: 5432
+  - name: cache
+    port: none
+`
+
+func TestCollect(t *testing.T) {
+	var names []string
+	err := Get(collectYAML, "services").Collect(func(s Result) error {
+		names = append(names, s.Get("name").String())
+		_, err := s.Get("port").IntE()
+		return err
+	})
+	if len(names) != 4 {
+		t.Errorf("Expected every element to be visited, got %q", names)
+	}
+	var errs CollectError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected two failures, got %v", err)
+	}
+	if errs[0].Path != "services.1" || errs[0].Index != 1 || errs[1].Path != "services.3" || errs[1].Index != 3 {
+		t.Errorf("Unexpected failures %q", err)
+	}
+	want := `services.1: gyaml: cannot use string "http" as integer` + "\n" +
+		`services.3: gyaml: cannot use string "none" as integer`
+	if err.Error() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, err)
+	}
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Value.String() != "http" {
+		t.Errorf("Expected errors.As to find the first TypeError, got %v", typeErr)
+	}
+
+	// Paths are relative to Results whose own path is unknown
+	err = ArrayOf(1, "x").Collect(func(r Result) error {
+		if r.Type != Number {
+			return fmt.Errorf("not a number")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "1: not a number" {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// Objects report their keys
+	err = Get("limits: {cpu: 2, memory: lots}", "limits").Collect(func(r Result) error {
+		_, err := r.FloatE()
+		return err
+	})
+	if errs, ok := err.(CollectError); !ok || len(errs) != 1 || errs[0].Path != "limits.memory" || errs[0].Index != 1 {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if err := Get(collectYAML, "services.0.name").Collect(func(Result) error { return errors.New("called") }); err != nil {
+		t.Errorf("Expected scalars to have no elements, got %v", err)
+	}
+}
+
+func TestCollectQueryPaths(t *testing.T) {
+	for _, tt := range []struct {
+		path  string
+		paths []string
+	}{
+		{"services.#(name!=web)#", []string{"services.#(name!=web)#|0", "services.#(name!=web)#|2"}},
+		{"services.#(name%\"*a*\")#", []string{"services.#(name%\"*a*\")#|0", "services.#(name%\"*a*\")#|1"}},
+		{"services.#.port", []string{"services.#.port|1", "services.#.port|3"}},
+		{"services|@reverse", []string{"services|@reverse|0", "services|@reverse|2"}},
+		{"[services.1,services.3]", []string{"[services.1,services.3]|0", "[services.1,services.3]|1"}},
+	} {
+		err := Get(collectYAML, tt.path).Collect(func(r Result) error {
+			if r.Type == YAML {
+				r = r.Get("port")
+			}
+			_, err := r.IntE()
+			return err
+		})
+		var errs CollectError
+		errors.As(err, &errs)
+		if len(errs) != len(tt.paths) {
+			t.Errorf("%s: expected %d failures, got %v", tt.path, len(tt.paths), err)
+			continue
+		}
+		for i, e := range errs {
+			if e.Path != tt.paths[i] {
+				t.Errorf("%s: expected the path %s, got %s", tt.path, tt.paths[i], e.Path)
+			}
+			// The path leads back to the element
+			elem := Get(collectYAML, e.Path)
+			if elem.Type == YAML {
+				elem = elem.Get("port")
+			}
+			if _, err := elem.IntE(); err == nil || err.Error() != e.Err.Error() {
+				t.Errorf("%s: Get(%s) is not the element, got %s", tt.path, e.Path, elem.Raw)
+			}
+		}
+	}
+
+	// The first match of a query is a value of the document
+	if got := Get(collectYAML, "services.#(name=api)").Get("port").path; got != "services.#(name=api).port" {
+		t.Errorf("Unexpected path %s", got)
+	}
+}
+
+func TestCollectStop(t *testing.T) {
+	services := Get(collectYAML, "services")
+	visited := 0
+	err := services.Collect(func(s Result) error {
+		visited++
+		if s.Get("name").String() == "api" {
+			return fmt.Errorf("enough: %w", ErrStop)
+		}
+		return nil
+	})
+	if err != nil || visited != 2 {
+		t.Errorf("Expected to stop silently after 2 elements, got %v after %d", err, visited)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	visited = 0
+	err = services.Collect(func(s Result) error {
+		visited++
+		if visited == 1 {
+			return errors.New("bad")
+		}
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) || visited != 2 {
+		t.Errorf("Expected to stop on cancellation after 2 elements, got %v after %d", err, visited)
+	}
+	if errs := err.(CollectError); len(errs) != 2 || errs[1].Path != "services.1" {
+		t.Errorf("Expected the cancellation to be reported, got %q", err)
+	}
+}
@@ -0,0 +1,136 @@
+package gyaml
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comment holds the comments attached to a value in a YAML document, with
+// the "#" that starts each line and the space after it removed. Comments
+// of several lines keep their line breaks.
+type Comment struct {
+	// Head is the comment on the lines just above the value, or above its
+	// key in an object
+	Head string
+	// Line is the comment at the end of the line of the value, or of its
+	// key when the value starts on the next line
+	Line string
+	// Foot is the comment on the lines just below the value, before a
+	// blank line or a line indented less
+	Foot string
+}
+
+// GetComment returns the comments attached to the value at path, so that
+// the documentation written next to a setting can be read with it. For a
+// document such as
+//
+//	server:
+//	  # Port to listen on
+//	  port: 8080  # default
+//
+// GetComment(doc, "server.port") returns Head "Port to listen on" and
+// Line "default". The comments of an entry of an object are those of its
+// key and of its value together. An empty path returns the comments of the
+// whole document, such as a comment at its top followed by a blank line.
+//
+// The path is a chain of plain keys and indices, as for SetRaw. Aliases
+// are followed, so the comments found are those where the value is
+// written. It returns a zero Comment when the document cannot be parsed
+// or the path does not exist.
+func GetComment(yamlStr, path string) Comment {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlStr), &doc); err != nil || len(doc.Content) == 0 {
+		return Comment{}
+	}
+	if path == "" {
+		root := doc.Content[0]
+		return Comment{
+			Head: commentText(firstComment(doc.HeadComment, root.HeadComment)),
+			Line: commentText(root.LineComment),
+			Foot: commentText(firstComment(root.FootComment, doc.FootComment)),
+		}
+	}
+	segments, err := SplitPath(path)
+	if err != nil {
+		return Comment{}
+	}
+	var key *yaml.Node
+	node := doc.Content[0]
+	for _, seg := range segments {
+		if seg.Kind != SegmentKey && seg.Kind != SegmentIndex {
+			return Comment{}
+		}
+		node = resolveAlias(node)
+		switch node.Kind {
+		case yaml.SequenceNode:
+			idx, _, inRange := indexOf(seg.Text, len(node.Content))
+			if !inRange {
+				return Comment{}
+			}
+			key, node = nil, node.Content[idx]
+		case yaml.MappingNode:
+			key, node = entryNodes(node, unescapeKey(seg.Text))
+			if node == nil {
+				return Comment{}
+			}
+		default:
+			return Comment{}
+		}
+	}
+	node = resolveAlias(node)
+	if key == nil {
+		return Comment{Head: commentText(node.HeadComment), Line: commentText(node.LineComment), Foot: commentText(node.FootComment)}
+	}
+	return Comment{
+		Head: commentText(firstComment(key.HeadComment, node.HeadComment)),
+		Line: commentText(firstComment(node.LineComment, key.LineComment)),
+		Foot: commentText(firstComment(node.FootComment, key.FootComment)),
+	}
+}
+
+// entryNodes returns the key and value nodes of the entry named name in a
+// mapping node, or nils when there is none. Entries written in the
+// mapping take precedence over those brought in by merge keys.
+func entryNodes(node *yaml.Node, name string) (*yaml.Node, *yaml.Node) {
+	var k, v *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if c := node.Content[i]; c.Kind == yaml.ScalarNode && c.Value == name && !isMergeKey(c) {
+			k, v = c, node.Content[i+1]
+		}
+	}
+	if k != nil {
+		return k, v
+	}
+	visitEntries(node, func(key, value *yaml.Node) {
+		if key.Kind == yaml.ScalarNode && key.Value == name {
+			k, v = key, value
+		}
+	})
+	return k, v
+}
+
+// firstComment returns the first of comments that is not empty.
+func firstComment(comments ...string) string {
+	for _, c := range comments {
+		if c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+// commentText removes the "#" that starts each line of a comment, and the
+// space that follows it.
+func commentText(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "#")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
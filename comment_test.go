@@ -0,0 +1,60 @@
+package gyaml
+
+import "testing"
+
+const commentYAML = `# Service configuration
+
+# Server settings
+server:   # the server
+  # Port to listen on
+  port: 8080  # default
+  # Hosts to serve,
+  # in order of preference
+  hosts:
+    # first choice
+    - a   # primary
+    - b
+  # end of hosts
+
+defaults: &d
+  # Seconds to wait
+  timeout: 30
+service:
+  <<: *d
+  zone: b  # availability zone
+alias: *d
+name: x  # name
+# about name
+
+# end of file
+`
+
+func TestGetComment(t *testing.T) {
+	tests := []struct {
+		path string
+		want Comment
+	}{
+		{"", Comment{Head: "Service configuration", Foot: "end of file"}},
+		{"server", Comment{Head: "Server settings", Line: "the server"}},
+		{"server.port", Comment{Head: "Port to listen on", Line: "default"}},
+		{"server.hosts", Comment{Head: "Hosts to serve,\nin order of preference", Foot: "end of hosts"}},
+		{"server.hosts.0", Comment{Head: "first choice", Line: "primary"}},
+		{"server.hosts.1", Comment{}},
+		{"service.zone", Comment{Line: "availability zone"}},
+		{"service.timeout", Comment{Head: "Seconds to wait"}},
+		{"alias.timeout", Comment{Head: "Seconds to wait"}},
+		{"name", Comment{Line: "name", Foot: "about name"}},
+		{"missing", Comment{}},
+		{"server.hosts.5", Comment{}},
+		{"server.port.x", Comment{}},
+		{"server.hosts.#", Comment{}},
+	}
+	for _, tt := range tests {
+		if got := GetComment(commentYAML, tt.path); got != tt.want {
+			t.Errorf("GetComment(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+	if got := GetComment("a: [1", "a"); got != (Comment{}) {
+		t.Errorf("Expected no comment for an invalid document, got %+v", got)
+	}
+}
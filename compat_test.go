@@ -0,0 +1,141 @@
+package gyaml
+
+import (
+	"testing"
+	"time"
+)
+
+const compatYAML = `
+defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  <<: *defaults
+  timeout: 60
+backup: *defaults
+nothing: null
+tilde: ~
+empty:
+created: 2024-01-15T10:30:00Z
+day: 2024-01-15
+quoted: "2024-01-15T10:30:00.5+02:00"
+enabled: yes
+`
+
+func TestCompatTime(t *testing.T) {
+	created := Get(compatYAML, "created")
+	if created.Type != String || created.String() != "2024-01-15T10:30:00Z" {
+		t.Errorf("Expected the timestamp as written, got %v %q", created.Type, created.String())
+	}
+	if !created.Time().Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v", created.Time())
+	}
+	if day := Get(compatYAML, "day"); day.String() != "2024-01-15" || !day.Time().Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %q %v", day.String(), day.Time())
+	}
+	if q := Get(compatYAML, "quoted").Time(); q.UnixNano() != time.Date(2024, 1, 15, 8, 30, 0, 5e8, time.UTC).UnixNano() {
+		t.Errorf("Unexpected time %v", q)
+	}
+	if got := Parse(compatYAML).Map()["created"]; got.Type != String || !got.Time().Equal(created.Time()) {
+		t.Errorf("Unexpected timestamp from Map: %v %q", got.Type, got.String())
+	}
+	if !Get(compatYAML, "enabled").Time().IsZero() {
+		t.Error("Expected the zero time for a value that is not a timestamp")
+	}
+}
+
+func TestCompatLess(t *testing.T) {
+	a, b := Result{Type: String, Str: "apple"}, Result{Type: String, Str: "Banana"}
+	if !a.Less(b, false) || a.Less(b, true) {
+		t.Error("Unexpected string ordering")
+	}
+	if !Get(compatYAML, "defaults.retries").Less(Get(compatYAML, "defaults.timeout"), true) {
+		t.Error("Expected 3 < 30")
+	}
+	if !(Result{Type: Null}).Less(a, true) || a.Less(Result{Type: Number, Num: 1}, true) {
+		t.Error("Expected values of different types to be ordered by type")
+	}
+}
+
+// The tests below pin down where gyaml deliberately differs from gjson.
+
+func TestCompatNullDoesNotExist(t *testing.T) {
+	// gjson reports a literal null as existing
+	for _, path := range []string{"nothing", "tilde", "empty"} {
+		r := Get(compatYAML, path)
+		if r.Exists() || r.Type != Null {
+			t.Errorf("Expected %s to be a null that does not exist, got %v", path, r.Type)
+		}
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	doc := Parse(compatYAML)
+	ref := GetRef(compatYAML, "")
+	for _, path := range []string{"nothing", "tilde", "empty"} {
+		for _, r := range []Result{
+			Get(compatYAML, path),
+			doc.Get(path),
+			ref.Get(path),
+			GetMany(compatYAML, path)[0],
+			Get(compatYAML, "@this|"+path),
+		} {
+			if !r.IsNull() || r.Exists() {
+				t.Errorf("%s: expected an explicit null, got %#v", path, r)
+			}
+		}
+	}
+	for _, path := range []string{"missing", "nothing.deeper", "service.missing", "service.timeout", "backup.9"} {
+		if r := Get(compatYAML, path); r.IsNull() {
+			t.Errorf("%s: expected no explicit null, got %#v", path, r)
+		}
+	}
+	if r, err := GetE(compatYAML, "tilde"); err != nil || !r.IsNull() {
+		t.Errorf("Expected GetE to report the null, got %#v (%v)", r, err)
+	}
+
+	// Elements and entries holding null are explicit nulls too
+	list := Get("list: [1, null, ~]", "list").Array()
+	if list[0].IsNull() || !list[1].IsNull() || !list[2].IsNull() {
+		t.Errorf("Unexpected elements %v", list)
+	}
+	nulls := 0
+	Get(compatYAML, "").ForEach(func(_, value Result) bool {
+		if value.IsNull() {
+			nulls++
+		}
+		return true
+	})
+	if nulls != 3 {
+		t.Errorf("Expected 3 nulls, got %d", nulls)
+	}
+	if Get("", "a").IsNull() || Get("a: [", "a").IsNull() {
+		t.Error("Expected empty and invalid documents to hold no null")
+	}
+}
+
+func TestCompatAnchorsResolve(t *testing.T) {
+	// JSON has no anchors: aliases read as copies of the anchored value,
+	// and merge keys bring in the merged keys
+	if Get(compatYAML, "backup.retries").Int() != 3 {
+		t.Error("Expected the alias to resolve to the anchored mapping")
+	}
+	if Get(compatYAML, "service.retries").Int() != 3 || Get(compatYAML, "service.timeout").Int() != 60 {
+		t.Error("Expected merge keys to be expanded, with local keys taking precedence")
+	}
+	if Get(compatYAML, "service.<<").Exists() {
+		t.Error("Expected the merge key itself to be hidden")
+	}
+}
+
+func TestCompatTypes(t *testing.T) {
+	// The JSON type is named YAML and covers objects and arrays
+	if Get(compatYAML, "defaults").Type != YAML {
+		t.Error("Expected objects to have the YAML type")
+	}
+	// YAML 1.1 booleans are strings, which Bool reads as booleans
+	enabled := Get(compatYAML, "enabled")
+	if enabled.Type != String || !enabled.Bool() {
+		t.Errorf("Expected a string that reads as true, got %v", enabled.Type)
+	}
+}
@@ -0,0 +1,153 @@
+package gyaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The conformance suite checks path semantics against tables of cases in
+// testdata/conformance. Each file holds named documents and the cases run
+// against them:
+//
+//	documents:
+//	  readme: |
+//	    name: {first: Tom, last: Anderson}
+//	cases:
+//	  - {doc: readme, path: name.last, type: String, str: Anderson}
+//	  - {doc: readme, path: name, value: {first: Tom, last: Anderson}}
+//
+// A case expects a type, and optionally the String() of the result (str)
+// or, for arrays and objects, its value. Where gyaml differs from gjson,
+// by design or for a feature it lacks, the case records what gjson returns under gjson, along with
+// the reason under known_difference; the suite fails if the two agree, so
+// that the entry is turned into a plain case once gyaml catches up.
+//
+// Files listed in GYAML_CONFORMANCE, separated like PATH, are run as well,
+// so new cases can be tried out before they are added to testdata.
+
+// conformanceFile is one file of the conformance suite.
+type conformanceFile struct {
+	Documents map[string]string `yaml:"documents"`
+	Cases     []conformanceCase `yaml:"cases"`
+}
+
+// conformanceCase is a path resolved against a document, and the result
+// expected of it.
+type conformanceCase struct {
+	Doc                 string `yaml:"doc"`
+	Path                string `yaml:"path"`
+	conformanceExpected `yaml:",inline"`
+	Gjson               *conformanceExpected `yaml:"gjson"`
+	KnownDifference     string               `yaml:"known_difference"`
+}
+
+// conformanceExpected describes a result. Type defaults to YAML when a
+// value is given. It is kept as a node, as "Null" would otherwise be read
+// as a null.
+type conformanceExpected struct {
+	Type  yaml.Node `yaml:"type"`
+	Str   *string   `yaml:"str"`
+	Value yaml.Node `yaml:"value"`
+}
+
+var conformanceTypes = map[string]Type{
+	"Null": Null, "False": False, "Number": Number,
+	"String": String, "True": True, "YAML": YAML,
+}
+
+// mismatch describes how r differs from the expectation, or returns ""
+// when it matches.
+func (e *conformanceExpected) mismatch(r Result) (string, error) {
+	name := e.Type.Value
+	if e.Type.IsZero() && !e.Value.IsZero() {
+		name = "YAML"
+	}
+	want, ok := conformanceTypes[name]
+	if !ok {
+		return "", fmt.Errorf("unknown type %q", name)
+	}
+	if r.Type != want {
+		return fmt.Sprintf("type %s, want %s (%q)", r.Type, name, r.Raw), nil
+	}
+	if e.Str != nil && r.String() != *e.Str {
+		return fmt.Sprintf("String() = %q, want %q", r.String(), *e.Str), nil
+	}
+	if !e.Value.IsZero() {
+		var value interface{}
+		if err := e.Value.Decode(&value); err != nil {
+			return "", err
+		}
+		// Compare the encoded forms, which are the same for equal values
+		// whatever their Go types
+		if got, want := makeResult(r.Value()).Raw, makeResult(value).Raw; got != want {
+			return fmt.Sprintf("value\n%s\nwant\n%s", got, want), nil
+		}
+	}
+	return "", nil
+}
+
+// conformanceFiles returns the files of the suite.
+func conformanceFiles(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range filepath.SplitList(os.Getenv("GYAML_CONFORMANCE")) {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func TestConformance(t *testing.T) {
+	total := 0
+	for _, file := range conformanceFiles(t) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var suite conformanceFile
+		if err := yaml.Unmarshal(data, &suite); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		t.Run(name, func(t *testing.T) {
+			for i, c := range suite.Cases {
+				doc, ok := suite.Documents[c.Doc]
+				if !ok {
+					t.Errorf("case %d: unknown document %q", i, c.Doc)
+					continue
+				}
+				r := Get(doc, c.Path)
+				msg, err := c.mismatch(r)
+				if err != nil {
+					t.Errorf("case %d (%s): %v", i, c.Path, err)
+					continue
+				}
+				if msg != "" {
+					t.Errorf("%s: %s", c.Path, msg)
+				}
+				if (c.Gjson == nil) != (c.KnownDifference == "") {
+					t.Errorf("%s: a known difference needs both gjson and known_difference", c.Path)
+					continue
+				}
+				if c.Gjson != nil {
+					if msg, err := c.Gjson.mismatch(r); err == nil && msg == "" {
+						t.Errorf("%s: now agrees with gjson, drop the known difference %q", c.Path, c.KnownDifference)
+					}
+				}
+			}
+		})
+		total += len(suite.Cases)
+	}
+	if total < 150 {
+		t.Errorf("Expected at least 150 conformance cases, found %d", total)
+	}
+}
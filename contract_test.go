@@ -0,0 +1,137 @@
+package gyaml
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// contractDocs are the documents of the test suite.
+var contractDocs = []string{
+	testYAML, complexYAML, edgeCaseYAML, benchmarkYAML, orderedYAML,
+	hygieneYAML, spanYAML, streamYAML, collectionTagsYAML,
+}
+
+// touch calls the accessors of a result and of the values within it.
+func touch(r Result, depth int) string {
+	var sb strings.Builder
+	sb.WriteString(r.String())
+	sb.WriteString(strconv.FormatInt(r.Int(), 10))
+	sb.WriteString(strconv.FormatBool(r.Bool()))
+	sb.WriteString(strconv.Itoa(r.Len() + len(r.Elements()) + len(r.Map())))
+	if depth > 0 {
+		r.ForEach(func(key, value Result) bool {
+			sb.WriteString(key.String())
+			sb.WriteString(touch(value, depth-1))
+			return true
+		})
+		for _, elem := range r.Array() {
+			sb.WriteString(touch(elem.Get("0"), depth-1))
+		}
+	}
+	return sb.String()
+}
+
+func TestGetBytesDoesNotModifyOrAliasInput(t *testing.T) {
+	paths := suitePaths(t)
+	paths[""] = true
+	for _, doc := range contractDocs {
+		for path := range paths {
+			buf := []byte(doc)
+			sum := sha256.Sum256(buf)
+
+			r := GetBytes(buf, path)
+			raw, str := r.Raw, r.Str
+			seen := touch(r, 2)
+			if sha256.Sum256(buf) != sum {
+				t.Fatalf("GetBytes(%q) modified its input", path)
+			}
+
+			// Reusing the buffer must not change the result
+			for i := range buf {
+				buf[i] = 'x'
+			}
+			if r.Raw != raw || r.Str != str || touch(r, 2) != seen {
+				t.Fatalf("Result of GetBytes(%q) changed when the input was reused", path)
+			}
+		}
+	}
+}
+
+func TestParseBytesDoesNotAliasInput(t *testing.T) {
+	for _, doc := range contractDocs {
+		buf := []byte(doc)
+		r := ParseBytes(buf)
+		many := GetManyBytes(buf, "0", "a")
+		raw, seen := r.Raw, touch(r, 2)
+		manySeen := touch(many[0], 2) + touch(many[1], 2)
+		for i := range buf {
+			buf[i] = 'x'
+		}
+		if r.Raw != raw || touch(r, 2) != seen {
+			t.Fatalf("Result of ParseBytes(%q) changed when the input was reused", doc)
+		}
+		if touch(many[0], 2)+touch(many[1], 2) != manySeen {
+			t.Fatalf("Results of GetManyBytes(%q) changed when the input was reused", doc)
+		}
+	}
+}
+
+func TestPathListIsNotModified(t *testing.T) {
+	var paths []string
+	for path := range suitePaths(t) {
+		paths = append(paths, path)
+	}
+	orig := append([]string(nil), paths...)
+	for _, doc := range contractDocs {
+		ForEachPathOf(doc, paths, func(i int, path string, r Result) bool {
+			touch(r, 1)
+			return true
+		})
+	}
+	for i := range paths {
+		if paths[i] != orig[i] {
+			t.Fatalf("ForEachPathOf modified path %d: %q became %q", i, orig[i], paths[i])
+		}
+	}
+}
+
+// TestNoUnsafeMemorySharing is a vet-style check of the package sources:
+// converting between strings and byte slices without copying would let
+// Results alias the caller's buffers, so it is not allowed.
+func TestNoUnsafeMemorySharing(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range f.Imports {
+			if imp.Path.Value == `"unsafe"` {
+				t.Errorf("%s imports unsafe", file)
+			}
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, header := range []string{"reflect.SliceHeader", "reflect.StringHeader"} {
+			if bytes.Contains(src, []byte(header)) {
+				t.Errorf("%s uses %s", file, header)
+			}
+		}
+	}
+}
@@ -786,10 +786,14 @@ non_array_value: "not_an_array"
 		t.Error("Empty query should return non-existent result")
 	}
 
-	// Test query without comparison operator
+	// A query without a comparison operator tests the presence of the key
 	result = Get(yaml, `complex_data.#(name)`)
+	if result.Get("name").String() != "Alice" {
+		t.Error("Query without operator should match the first element with the key")
+	}
+	result = Get(yaml, `complex_data.#(!name)`)
 	if result.Exists() {
-		t.Error("Query without operator should return non-existent result")
+		t.Error("Query for a missing key should return non-existent result")
 	}
 }
 
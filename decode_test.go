@@ -0,0 +1,126 @@
+package gyaml
+
+import (
+	"testing"
+)
+
+// countUnmarshal replaces the Raw decoder for the duration of a test and
+// returns a pointer to the number of times it was called.
+func countUnmarshal(t *testing.T) *int {
+	t.Helper()
+	calls := 0
+	orig := unmarshal
+	unmarshal = func(in []byte, out interface{}) error {
+		calls++
+		return orig(in, out)
+	}
+	t.Cleanup(func() { unmarshal = orig })
+	return &calls
+}
+
+func TestGetThenArrayUsesDecodedTree(t *testing.T) {
+	calls := countUnmarshal(t)
+
+	users := Get(benchmarkYAML, "users")
+	arr := users.Array()
+	if len(arr) != 3 {
+		t.Fatalf("Expected 3 users, got %d", len(arr))
+	}
+	if arr[1].Get("name").String() != "Bob Smith" {
+		t.Errorf("Expected 'Bob Smith', got '%s'", arr[1].Get("name").String())
+	}
+	profile := arr[0].Get("profile").Map()
+	if profile["city"].String() != "New York" {
+		t.Errorf("Expected 'New York', got '%s'", profile["city"].String())
+	}
+	users.ForEach(func(key, value Result) bool { return true })
+	if users.Value() == nil {
+		t.Error("Value should return the decoded tree")
+	}
+
+	if *calls != 0 {
+		t.Errorf("Expected no Raw unmarshal in the Get->Array flow, got %d", *calls)
+	}
+}
+
+func TestQueryResultUsesDecodedTree(t *testing.T) {
+	calls := countUnmarshal(t)
+
+	result := Get(testYAML, `friends.#(last="Craig")`)
+	if result.Get("hobbies").Array()[0].String() != "fishing" {
+		t.Error("Expected 'fishing' from query result")
+	}
+	if Get(testYAML, "friends.#.hobbies").Array()[2].Array()[1].String() != "gardening" {
+		t.Error("Expected 'gardening' from array operation result")
+	}
+	if *calls != 0 {
+		t.Errorf("Expected no Raw unmarshal, got %d", *calls)
+	}
+}
+
+func TestParseCachesRoot(t *testing.T) {
+	calls := countUnmarshal(t)
+
+	doc := Parse(testYAML)
+	if doc.Get("name.last").String() != "Anderson" {
+		t.Error("Expected 'Anderson'")
+	}
+	if len(doc.Get("children").Array()) != 3 {
+		t.Error("Expected 3 children")
+	}
+	if *calls != 0 {
+		t.Errorf("Expected no Raw unmarshal after Parse, got %d", *calls)
+	}
+}
+
+func TestLiteralResultFallsBackToRaw(t *testing.T) {
+	calls := countUnmarshal(t)
+
+	literal := Result{Type: YAML, Raw: "- a\n- b\n"}
+	arr := literal.Array()
+	if len(arr) != 2 || arr[1].String() != "b" {
+		t.Errorf("Expected [a b], got %v", arr)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected Raw to be parsed once, got %d", *calls)
+	}
+
+	obj := Result{Type: YAML, Raw: "a: 1\n"}
+	if obj.Map()["a"].Int() != 1 {
+		t.Error("Expected a=1 from literal map")
+	}
+	if obj.Get("a").Int() != 1 {
+		t.Error("Expected a=1 from literal Get")
+	}
+}
+
+func TestEntryPointsUseDecodedTree(t *testing.T) {
+	stream := "a: {x: 1}\n---\nb: [1, 2]\n"
+	results := map[string]func() Result{
+		"ParseBytes":  func() Result { return ParseBytes([]byte(testYAML)).Get("friends") },
+		"GetBytes":    func() Result { return GetBytes([]byte(testYAML), "friends") },
+		"GetMany":     func() Result { return GetMany(testYAML, "friends", "children")[0] },
+		"GetRef":      func() Result { return GetRef(testYAML, "friends") },
+		"ParseWith":   func() Result { return ParseWith(testYAML, WithKeepMergeKeys()).Get("friends") },
+		"ParseStream": func() Result { return ParseStream(stream).Get("@1.b") },
+		"Documents":   func() Result { return Documents(stream)[1].Get("b") },
+		"Anchors":     func() Result { return Anchors("d: &d [{x: 1}]\ne: *d\n")["d"] },
+		"modifier":    func() Result { return Get(testYAML, "children|@reverse") },
+		"multipath":   func() Result { return Get(testYAML, "[children,friends]") },
+	}
+	for name, result := range results {
+		calls := countUnmarshal(t)
+		r := result()
+		r.Array()
+		r.Map()
+		r.Value()
+		r.ForEach(func(_, value Result) bool {
+			value.Array()
+			value.Get("0")
+			return true
+		})
+		if *calls != 0 {
+			t.Errorf("%s: expected no Raw unmarshal, got %d", name, *calls)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package gyaml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Difference is a place where two values differ.
+type Difference struct {
+	// Path leads to the value from the top of the values compared, with
+	// keys escaped so that it can be passed to Get. It is empty when the
+	// values differ as a whole.
+	Path string
+	// A and B are the values at Path. A value missing on one side is Null.
+	A, B Result
+}
+
+// String returns the difference as "path: a != b", with the values in
+// flow style.
+func (d Difference) String() string {
+	path := d.Path
+	if path == "" {
+		path = "(top)"
+	}
+	return fmt.Sprintf("%s: %s != %s", path, flowString(d.A), flowString(d.B))
+}
+
+// flowString returns a value on a single line, for messages.
+func flowString(t Result) string {
+	switch t.Type {
+	case Null:
+		return "null"
+	case String:
+		return strconv.Quote(t.Str)
+	case YAML:
+		node, err := encodeNode(t.Value())
+		if err != nil {
+			return t.Raw
+		}
+		setFlowStyle(node)
+		raw, err := yaml.Marshal(node)
+		if err != nil {
+			return t.Raw
+		}
+		return shorten(strings.TrimSpace(string(raw)), 200)
+	}
+	return t.String()
+}
+
+// setFlowStyle switches the containers of a node tree to flow style.
+func setFlowStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style |= yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+// Diff compares two values and returns where they differ, ordered by
+// path. Objects are compared key by key regardless of key order, arrays
+// element by element, and numbers by value, so that 1 and 1.0 are equal.
+// As a YAML null does not exist, a key holding null matches a missing
+// key.
+func Diff(a, b Result) []Difference {
+	var diffs []Difference
+	diffValues("", a.nativeValue(), b.nativeValue(), &diffs)
+	return diffs
+}
+
+// Equal reports whether two values are the same, as compared by Diff.
+func (t Result) Equal(other Result) bool {
+	return len(Diff(t, other)) == 0
+}
+
+// diffValues appends the differences between a and b, found at path, to
+// diffs.
+func diffValues(path string, a, b interface{}, diffs *[]Difference) {
+	am, aIsMap := diffEntries(a)
+	bm, bIsMap := diffEntries(b)
+	if aIsMap && bIsMap {
+		keys := make([]string, 0, len(am)+len(bm))
+		for k := range am {
+			keys = append(keys, k)
+		}
+		for k := range bm {
+			if _, ok := am[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffValues(joinPath(path, Escape(k)), am[k], bm[k], diffs)
+		}
+		return
+	}
+	aa, aIsArray := a.([]interface{})
+	ba, bIsArray := b.([]interface{})
+	if aIsArray && bIsArray {
+		for i := 0; i < len(aa) || i < len(ba); i++ {
+			var av, bv interface{}
+			if i < len(aa) {
+				av = aa[i]
+			}
+			if i < len(ba) {
+				bv = ba[i]
+			}
+			diffValues(joinPath(path, strconv.Itoa(i)), av, bv, diffs)
+		}
+		return
+	}
+	if !aIsMap && !bIsMap && !aIsArray && !bIsArray && scalarsEqual(a, b) {
+		return
+	}
+	*diffs = append(*diffs, Difference{Path: path, A: makeResult(a), B: makeResult(b)})
+}
+
+// diffEntries returns the entries of an object keyed by their written
+// form. String keys take precedence, as in Map.
+func diffEntries(v interface{}) (map[string]interface{}, bool) {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		return obj, true
+	case map[interface{}]interface{}:
+		entries := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			if _, isString := k.(string); !isString {
+				entries[canonicalKey(k)] = v
+			}
+		}
+		for k, v := range obj {
+			if s, isString := k.(string); isString {
+				entries[s] = v
+			}
+		}
+		return entries, true
+	}
+	return nil, false
+}
+
+// scalarsEqual reports whether two scalar values are the same. Numbers
+// are compared by value, and integers exactly. Timestamps match the
+// strings that read as the same time, as Results hold them as strings.
+func scalarsEqual(a, b interface{}) bool {
+	if isNumeric(a) && isNumeric(b) {
+		fa, fb := toFloat(a), toFloat(b)
+		if math.IsNaN(fa) && math.IsNaN(fb) {
+			return true
+		}
+		if isInteger(a) && isInteger(b) {
+			return fmt.Sprint(a) == fmt.Sprint(b)
+		}
+		return fa == fb
+	}
+	ta, aIsTime := a.(time.Time)
+	tb, bIsTime := b.(time.Time)
+	switch {
+	case aIsTime && bIsTime:
+		return ta.Equal(tb)
+	case aIsTime:
+		return sameTime(ta, b)
+	case bIsTime:
+		return sameTime(tb, a)
+	}
+	return a == b
+}
+
+// sameTime reports whether v is a string that reads as tm.
+func sameTime(tm time.Time, v interface{}) bool {
+	s, ok := v.(string)
+	return ok && s != "" && tm.Equal(Result{Type: String, Str: s}.Time())
+}
+
+// toFloat returns a numeric value as a float64.
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case float32:
+		return widenFloat32(v)
+	case float64:
+		return v
+	}
+	f, _ := strconv.ParseFloat(fmt.Sprint(v), 64)
+	return f
+}
+
+// isInteger reports whether a numeric value has an integer type.
+func isInteger(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package gyaml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := Parse(`
+name: web
+port: 80
+ratio: 1
+tags: [a, b]
+db: {host: x, user: ~}
+when: 2001-12-14
+`)
+	b := Parse(`
+db: {host: y}
+name: web
+port: 8080
+ratio: 1.0
+tags: [a, b, c]
+extra: true
+when: "2001-12-14"
+`)
+	var got []string
+	for _, d := range Diff(a, b) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`db.host: "x" != "y"`,
+		`extra: null != true`,
+		`port: 80 != 8080`,
+		`tags.2: null != "c"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if !a.Equal(a) || a.Equal(b) {
+		t.Error("Equal disagrees with Diff")
+	}
+	if !Parse("{b: 1, a: [1, 2]}").Equal(Get("x: {a: [1, 2.0], b: 1}", "x")) {
+		t.Error("Expected key order and number form not to matter")
+	}
+	if !Parse("12345678901234567890").Equal(Parse("12345678901234567890")) ||
+		Parse("12345678901234567890").Equal(Parse("12345678901234567891")) {
+		t.Error("Expected large integers to compare exactly")
+	}
+	if !Parse(".nan").Equal(Parse(".nan")) {
+		t.Error("Expected NaN to equal NaN")
+	}
+}
+
+func TestDiffShapes(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want string
+	}{
+		{"[1, 2]", "{a: 1}", "(top): [1, 2] != {a: 1}"},
+		{"a: [1]", "a: 1", "a: [1] != 1"},
+		{"a: {b: 1}", "a: ~", "a: {b: 1} != null"},
+		{"{1: x}", `{"1": y}`, `\1: "x" != "y"`},
+		{"'true'", "true", `(top): "true" != true`},
+		{"a.b: 1", "a.b: 2", `a\.b: 1 != 2`},
+	} {
+		diffs := Diff(Parse(c.a), Parse(c.b))
+		if len(diffs) != 1 || diffs[0].String() != c.want {
+			t.Errorf("Diff(%s, %s) = %v, want %s", c.a, c.b, diffs, c.want)
+		}
+	}
+	// Paths of differences resolve in the values compared
+	a := Parse("a.b: [{c: 1}]")
+	d := Diff(a, Parse("a.b: [{c: 2}]"))
+	if len(d) != 1 || a.Get(d[0].Path).Int() != 1 {
+		t.Errorf("Expected the path of %v to resolve", d)
+	}
+}
+
+func TestGoString(t *testing.T) {
+	doc := "name: {first: Tom}\nage: 37\nbio: " + strings.Repeat("x", 100) + "\nlist: [a, b]\n"
+	for _, c := range []struct {
+		r    Result
+		want string
+	}{
+		{Get(doc, "name.first"), `gyaml.Result{Type: String, Path: "name.first", Value: "Tom"}`},
+		{Get(doc, "age"), `gyaml.Result{Type: Number, Path: "age", Value: 37}`},
+		{Get(doc, "missing"), `gyaml.Result{Type: Null, Path: "missing"}`},
+		{Get(doc, "name"), `gyaml.Result{Type: YAML, Path: "name", Value: "{first: Tom}\n"}`},
+		{Get(doc, "bio"), `gyaml.Result{Type: String, Path: "bio", Value: "` + strings.Repeat("x", 60) + `..."}`},
+		{Get(doc, "name").Get("first"), `gyaml.Result{Type: String, Path: "name.first", Value: "Tom"}`},
+		{Get(doc, "list").Array()[1], `gyaml.Result{Type: String, Path: "list.1", Value: "b"}`},
+		{Parse("a.b: true").Map()["a.b"], `gyaml.Result{Type: True, Path: "a\\.b"}`},
+		{makeResult(1.5), `gyaml.Result{Type: Number, Value: 1.5}`},
+	} {
+		if got := fmt.Sprintf("%#v", c.r); got != c.want {
+			t.Errorf("got  %s\nwant %s", got, c.want)
+		}
+	}
+
+	var paths []string
+	Get(doc, "name").ForEach(func(key, value Result) bool {
+		paths = append(paths, value.path)
+		return true
+	})
+	if len(paths) != 1 || paths[0] != "name.first" {
+		t.Errorf("ForEach paths = %v", paths)
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	names := map[Type]string{Null: "Null", False: "False", Number: "Number", String: "String", True: "True", YAML: "YAML"}
+	for typ, name := range names {
+		if typ.String() != name {
+			t.Errorf("Type(%d).String() = %q, want %q", int(typ), typ.String(), name)
+		}
+	}
+	if got := fmt.Sprintf("got %v", Type(9)); got != "got Type(9)" {
+		t.Errorf("Unexpected %q for an unknown type", got)
+	}
+	if got := (&TypeError{Value: Result{Type: Type(9)}, Want: "string"}).Error(); got != "gyaml: cannot use Type(9) as string" {
+		t.Errorf("Unexpected %q", got)
+	}
+}
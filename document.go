@@ -0,0 +1,107 @@
+package gyaml
+
+import "sync"
+
+// Document is a YAML document parsed once, to read many paths from it
+// without decoding the text again for each one. A Document is never
+// changed after ParseDocument returns it, so it is safe for concurrent
+// use.
+type Document struct {
+	root Result
+	opts Options
+
+	// The documents of the stream, for paths starting with @N, are parsed
+	// the first time one of them is read
+	once      sync.Once
+	stream    []Result
+	streamErr error
+}
+
+// ParseDocument parses the YAML for reading with the methods of Document,
+// using opts. Every value read from it is the same as Get, or GetWith with
+// the same opts, would return. The error reports YAML that cannot be
+// parsed and documents breaking a limit set by opts.
+//
+//	doc, err := gyaml.ParseDocument(config)
+//	if err != nil {
+//		return err
+//	}
+//	host := doc.Get("database.host").String()
+//	port := doc.Get("database.port").Int()
+func ParseDocument(yamlStr string, opts ...Option) (*Document, error) {
+	trace := startTrace()
+	o := applyOptions(opts)
+	root, err := parseDocument(yamlStr, o)
+	if trace != nil {
+		trace.end("Parse", "", len(yamlStr), root.Exists(), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(opts) > 0 {
+		root.opts = &o
+	}
+	return &Document{root: root, opts: o}, nil
+}
+
+// Root returns the whole document, as Get with an empty path does.
+func (d *Document) Root() Result {
+	return d.root
+}
+
+// Get searches the document for the specified path.
+func (d *Document) Get(path string) Result {
+	if path == "" {
+		return d.root
+	}
+	if _, _, ok := documentPath(path); ok {
+		r, _ := d.GetE(path)
+		r.opts = d.root.opts
+		return r
+	}
+	return d.root.Get(path)
+}
+
+// GetE searches the document for the specified path like GetE, reporting
+// the errors that the options of the document ask for.
+func (d *Document) GetE(path string) (Result, error) {
+	if n, rest, ok := documentPath(path); ok {
+		doc, err := d.document(n)
+		if err == nil {
+			doc, err = resolveIn(doc, rest, d.opts)
+		}
+		doc.opts, doc.path = &d.opts, path
+		return doc, err
+	}
+	return resolveIn(d.root, path, d.opts)
+}
+
+// document returns document n of the stream, or Null when the stream holds
+// fewer documents, as selectDocument does. Only the first document is kept
+// by ParseDocument, so the stream is parsed the first time it is needed
+// and its documents kept for the paths after it.
+func (d *Document) document(n int) (Result, error) {
+	d.once.Do(func() {
+		d.stream, d.streamErr = streamDocuments(d.root.Raw, d.opts)
+	})
+	if d.streamErr != nil {
+		return Result{Type: Null}, d.streamErr
+	}
+	return nthDocument(d.stream, n), nil
+}
+
+// GetMany searches the document for each of the paths, returning their
+// Results in the same order.
+func (d *Document) GetMany(paths ...string) []Result {
+	results := make([]Result, len(paths))
+	for i, path := range paths {
+		results[i] = d.Get(path)
+	}
+	return results
+}
+
+// ForEach iterates through the top-level values of the document, as
+// Result.ForEach does.
+func (d *Document) ForEach(iterator func(key, value Result) bool) {
+	d.root.ForEach(iterator)
+}
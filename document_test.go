@@ -0,0 +1,116 @@
+package gyaml
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestDocumentMatchesGet(t *testing.T) {
+	for _, yamlStr := range []string{complexYAML, benchmarkYAML, orderedYAML, boundsYAML} {
+		doc, err := ParseDocument(yamlStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for path := range suitePaths(t) {
+			if diff := refMismatch(doc.Get(path), Get(yamlStr, path)); diff != "" {
+				t.Errorf("Document.Get(%q) differs from Get: %s", path, diff)
+			}
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	doc, err := ParseDocument(benchmarkYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := doc.GetMany("users.0.name", "users.#", "missing")
+	if len(results) != 3 || results[0].String() != Get(benchmarkYAML, "users.0.name").String() ||
+		results[1].Int() != Get(benchmarkYAML, "users.#").Int() || results[2].Exists() {
+		t.Errorf("Unexpected results %v", results)
+	}
+	if doc.Root().Raw != benchmarkYAML {
+		t.Errorf("Expected the root to hold the document")
+	}
+	var keys []string
+	doc.ForEach(func(key, _ Result) bool {
+		keys = append(keys, key.String())
+		return true
+	})
+	if len(keys) != len(Parse(benchmarkYAML).Map()) {
+		t.Errorf("Unexpected keys %q", keys)
+	}
+
+	// Reads from many goroutines share the parsed document
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, path := range []string{"users.#.name", "config.database", `users.#(age>25)#.name`} {
+				if got, want := doc.Get(path).Raw, Get(benchmarkYAML, path).Raw; got != want {
+					t.Errorf("%s: expected %q, got %q", path, want, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDocumentStream(t *testing.T) {
+	const stream = "a: 1\n---\nb: [x, y]\n---\nc: {d: 3}\n"
+	doc, err := ParseDocument(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"a", "@0.a", "@1.b.1", "@2|c.d", "@1", "@3", "@1.missing"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, r := range doc.GetMany(paths...) {
+				if want := Get(stream, paths[i]); r.Raw != want.Raw || r.Exists() != want.Exists() {
+					t.Errorf("%s: expected %q, got %q", paths[i], want.Raw, r.Raw)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The stream is parsed once, and its documents shared by the paths
+	first, _ := doc.GetE("@1")
+	second, _ := doc.GetE("@1")
+	if len(doc.stream) != 3 || first.decoded == nil || first.decoded != second.decoded {
+		t.Errorf("Expected the documents of the stream to be kept, got %d", len(doc.stream))
+	}
+}
+
+func TestDocumentOptions(t *testing.T) {
+	if _, err := ParseDocument("a: ["); err == nil {
+		t.Errorf("Expected an error for invalid YAML")
+	}
+	if _, err := ParseDocument("a: [[1]]", WithMaxDepth(1)); err == nil {
+		t.Errorf("Expected the depth limit to be enforced")
+	}
+
+	doc, err := ParseDocument(boundsYAML, WithStrictBounds(), WithStrictTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"list.7", "rows.1.0", "rows.1.3"} {
+		got, gotErr := doc.GetE(path)
+		want, wantErr := GetE(boundsYAML, path, WithStrictBounds(), WithStrictTypes())
+		if got.Raw != want.Raw || errors.Is(gotErr, ErrIndexOutOfRange) != errors.Is(wantErr, ErrIndexOutOfRange) {
+			t.Errorf("%s: expected %q, %v, got %q, %v", path, want.Raw, wantErr, got.Raw, gotErr)
+		}
+	}
+	if !doc.Get("list").strict() {
+		t.Errorf("Expected the values to keep the options")
+	}
+
+	if doc, err := ParseDocument(""); err != nil || doc.Get("a").Exists() {
+		t.Errorf("Expected an empty document to hold nothing, got %v", err)
+	}
+}
@@ -381,3 +381,32 @@ func TestMemoryUsage(t *testing.T) {
 		}
 	}
 }
+
+func TestFloat32Values(t *testing.T) {
+	// Values decoded into float32 by custom structures
+	r := makeResult(float32(3.1415927))
+	if r.String() != "3.1415927" || r.Float() != 3.1415927 || r.Num != 3.1415927 {
+		t.Errorf("Expected 3.1415927 throughout, got String %q, Float %v, Num %v", r.String(), r.Float(), r.Num)
+	}
+
+	items := []interface{}{
+		map[string]interface{}{"name": "pi", "x": float32(3.1415927)},
+		map[string]interface{}{"name": "e", "x": float32(2.7182817)},
+	}
+	for _, query := range []string{"x=3.1415927", "x>=3.1415927", "x<=3.1415927"} {
+		if got := handleArrayQuery(items, query).Get("name").String(); got != "pi" {
+			t.Errorf("Expected #(%s) to match pi, got %q", query, got)
+		}
+	}
+	if handleArrayQuery(items, "x>3.1415927").Exists() {
+		t.Error("Expected #(x>3.1415927) not to match")
+	}
+	if got := getByPath(items, "#(x=2.7182817).x"); got.Float() != 2.7182817 || got.String() != "2.7182817" {
+		t.Errorf("Expected 2.7182817, got %q", got.String())
+	}
+
+	// Raw is preferred over Num when they disagree
+	if f := (Result{Type: Number, Raw: "0.1", Num: float64(float32(0.1))}).Float(); f != 0.1 {
+		t.Errorf("Expected 0.1, got %v", f)
+	}
+}
@@ -0,0 +1,193 @@
+package gyaml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// marshalYAML encodes v like yaml.Marshal does, for the values produced by
+// decoding YAML into interface{}. The value is built into a node tree by
+// encodeNode rather than by yaml.Node.Encode, which round-trips through
+// the emitter and inherits its mistakes; every document produced here
+// parses back to the value it came from.
+func marshalYAML(v interface{}) ([]byte, error) {
+	node, err := encodeNode(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(node)
+}
+
+// encodeNode builds the node tree for a decoded YAML value. Values of
+// other Go types are handed to yaml.Node.Encode.
+func encodeNode(v interface{}) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case string:
+		return stringNode(v), nil
+	case bool:
+		return plainNode(strconv.FormatBool(v)), nil
+	case int:
+		return plainNode(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return plainNode(strconv.FormatInt(v, 10)), nil
+	case uint64:
+		return plainNode(strconv.FormatUint(v, 10)), nil
+	case float64:
+		return plainNode(formatYAMLFloat(v, 64)), nil
+	case float32:
+		return plainNode(formatYAMLFloat(float64(v), 32)), nil
+	case synthNumber:
+		return plainNode(formatNumber(float64(v))), nil
+	case *yaml.Node:
+		return v, nil
+	case *big.Int:
+		return plainNode(v.String()), nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, elem := range v {
+			child, err := encodeNode(elem)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case map[string]interface{}:
+		// Keys are emitted in sorted order at every level
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			child, err := encodeNode(v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, stringNode(k), child)
+		}
+		return node, nil
+	case map[interface{}]interface{}:
+		keys := make([]interface{}, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sortKeys(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			key, err := encodeNode(k)
+			if err != nil {
+				return nil, err
+			}
+			child, err := encodeNode(v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, key, child)
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(v); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+// sortKeys orders mapping keys by their canonical string form, so that
+// mixed-type keys are emitted in the same order on every run. Keys with
+// the same form, such as 1 and "1" in a hand-built map, are ordered by
+// type name.
+func sortKeys(keys []interface{}) {
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := canonicalKey(keys[i]), canonicalKey(keys[j])
+		if ki != kj {
+			return ki < kj
+		}
+		return fmt.Sprintf("%T", keys[i]) < fmt.Sprintf("%T", keys[j])
+	})
+}
+
+// canonicalKey returns the string form of a mapping key, as it would be
+// written in a YAML document.
+func canonicalKey(k interface{}) string {
+	switch k := k.(type) {
+	case string:
+		return k
+	case nil:
+		return "null"
+	case float64:
+		return formatYAMLFloat(k, 64)
+	case float32:
+		return formatYAMLFloat(float64(k), 32)
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+// plainNode returns an untagged plain scalar, whose type is resolved from
+// its text when parsed back.
+func plainNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+// stringNode returns a string scalar. Strings that would read back as
+// another type are quoted, as are strings the emitter cannot reproduce in
+// block style: leading line breaks are dropped there, and Unicode line
+// separators are read back as plain newlines.
+func stringNode(s string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	switch {
+	case strings.HasPrefix(s, "\n") || strings.ContainsAny(s, "\u2028\u2029"):
+		node.Style = yaml.DoubleQuotedStyle
+	case isOldBool(s) || base60Float.MatchString(s) || s == "<<":
+		// "<<" would be read back as a merge key
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return node
+}
+
+// formatYAMLFloat formats f the way the YAML encoder does, except that
+// whole numbers keep a fractional part so that they are read back as
+// floats rather than integers.
+func formatYAMLFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if strings.IndexAny(s, ".e") < 0 {
+		s += ".0"
+	}
+	return s
+}
+
+// base60Float matches YAML 1.1 sexagesimal numbers such as 1:20, which
+// older parsers read as numbers.
+var base60Float = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+(?:\.[0-9_]*)?$`)
+
+// isOldBool reports whether s is a YAML 1.1 boolean that YAML 1.2 reads
+// as a string.
+func isOldBool(s string) bool {
+	switch s {
+	case "y", "Y", "yes", "Yes", "YES", "on", "On", "ON",
+		"n", "N", "no", "No", "NO", "off", "Off", "OFF":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package gyaml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalDeterministicKeyOrder(t *testing.T) {
+	keys := []interface{}{"b", 2, "a", true, 1.5, "10", nil, 10, false, "B"}
+
+	var first string
+	for run := 0; run < 10; run++ {
+		// Insert keys in a different rotation on every run
+		inner := make(map[interface{}]interface{})
+		outer := make(map[string]interface{})
+		for i := range keys {
+			k := keys[(i+run)%len(keys)]
+			inner[k] = fmt.Sprintf("%T", k)
+			outer[fmt.Sprintf("%T %v", k, k)] = map[interface{}]interface{}{k: []interface{}{k}}
+		}
+		outer["mixed"] = inner
+
+		raw := makeResult(outer).Raw
+		if run == 0 {
+			first = raw
+			continue
+		}
+		if raw != first {
+			t.Fatalf("run %d produced different Raw:\n%s\n---\n%s", run, raw, first)
+		}
+	}
+}
+
+func TestMarshalMixedKeysSorted(t *testing.T) {
+	m := map[interface{}]interface{}{true: "t", 2: "two", "a": "x", 1.5: "f", "1": "s", 1: "i"}
+	raw := makeResult(m).Raw
+	expected := "1: i\n\"1\": s\n1.5: f\n2: two\na: x\ntrue: t\n"
+	if raw != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, raw)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{"yes": "no", "on": "1:20", "empty": "", "nil": nil},
+		[]interface{}{"\nleading", "trailing\n", "line\u2028sep", math.Inf(-1), 1e21, int64(-5), uint64(math.MaxUint64)},
+		map[interface{}]interface{}{1: "one", "\n": "newline key", false: []interface{}{}},
+	}
+	for _, v := range values {
+		raw, err := marshalYAML(v)
+		if err != nil {
+			t.Fatalf("marshalYAML(%#v) failed: %v", v, err)
+		}
+		var back interface{}
+		if err := yaml.Unmarshal(raw, &back); err != nil {
+			t.Fatalf("Raw does not parse: %v\n%s", err, raw)
+		}
+		again, _ := marshalYAML(back)
+		if string(again) != string(raw) {
+			t.Errorf("Raw changed after a round trip:\n%s\n---\n%s", raw, again)
+		}
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	yml := `
+groups:
+  - name: a
+    price: 1.5
+    members: [x, y, z]
+  - name: b
+    price: 2
+    members: [x]
+`
+	defer func(orig func(float64, bool) string) { NumberFormat = orig }(NumberFormat)
+
+	if got := Get(yml, "groups.#").String(); got != "2" {
+		t.Errorf("Expected '2' by default, got %q", got)
+	}
+	if got := Get(yml, "groups.#.members.#").Raw; got != "- 3\n- 1\n" {
+		t.Errorf("Unexpected default Raw %q", got)
+	}
+	if got := Get(yml, "groups.#.price|@sum"); got.String() != "3.5" || got.JSON() != "3.5" || got.Float() != 3.5 {
+		t.Errorf("Unexpected default sum %q %s", got.String(), got.JSON())
+	}
+
+	var calls []bool
+	NumberFormat = func(f float64, isInt bool) string {
+		calls = append(calls, isInt)
+		return strconv.FormatFloat(f, 'f', 2, 64)
+	}
+	if got := Get(yml, "groups.#").String(); got != "2.00" {
+		t.Errorf("Expected '2.00', got %q", got)
+	}
+	if len(calls) != 1 || !calls[0] {
+		t.Errorf("Expected one call for a whole number, got %v", calls)
+	}
+	if got := (Result{Type: Number, Num: 0.375}).String(); got != "0.38" {
+		t.Errorf("Expected '0.38', got %q", got)
+	}
+
+	counts := Get(yml, "groups.#.members.#")
+	if counts.Raw != "- 3.00\n- 1.00\n" {
+		t.Errorf("Expected the emitted counts to use the format, got %q", counts.Raw)
+	}
+	if n := counts.Array()[0].Int(); n != 3 {
+		t.Errorf("Expected the decoded count to stay 3, got %d", n)
+	}
+
+	// and in multipaths
+	tests := map[string]string{
+		`{"n":groups.#}`:              "\"n\": 2.00\n",
+		"[groups.#]":                  "- 2.00\n",
+		"[groups.0.price,groups.#]":   "- 1.5\n- 2.00\n",
+		"{groups.0.members.#,x}":      "'#': 3.00\n",
+		"groups.#.{name,members.#}":   "- name: a\n  '#': 3.00\n- name: b\n  '#': 1.00\n",
+		`{"all":[groups.#,groups.#]}`: "all:\n    - 2.00\n    - 2.00\n",
+	}
+	for path, want := range tests {
+		if got := Get(yml, path).Raw; got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+	// and in aggregations, which have no source text either
+	for path, want := range map[string]string{
+		"groups.#.price|@sum":     "3.50",
+		"groups.#.price|@avg":     "1.75",
+		"groups.0.members|@count": "3.00",
+		"groups|@count":           "2.00",
+		"groups.#.price|@max":     "2",
+	} {
+		if got := Get(yml, path).String(); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+	if got := Get(yml, `{"total":groups.#.price|@sum}`).Raw; got != "total: 3.50\n" {
+		t.Errorf("Expected the sum to use the format, got %q", got)
+	}
+	if n := Get(yml, `{"n":groups.#}`).Get("n").Int(); n != 2 {
+		t.Errorf("Expected the decoded count to stay 2, got %d", n)
+	}
+
+	// Numbers with source text are left alone
+	if got := Get(yml, "groups.0.price").String(); got != "1.5" {
+		t.Errorf("Expected '1.5', got %q", got)
+	}
+	if got := Get(yml, "groups.#.price").Raw; got != "- 1.5\n- 2\n" {
+		t.Errorf("Expected prices as written, got %q", got)
+	}
+}
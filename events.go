@@ -0,0 +1,297 @@
+package gyaml
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EventKind identifies an Event.
+type EventKind int
+
+const (
+	// DocumentStart begins a document of the stream
+	DocumentStart EventKind = iota
+	// DocumentEnd ends a document
+	DocumentEnd
+	// MappingStart begins an object
+	MappingStart
+	// MappingEnd ends an object
+	MappingEnd
+	// SequenceStart begins an array
+	SequenceStart
+	// SequenceEnd ends an array
+	SequenceEnd
+	// Key is a scalar mapping key. The events of its value follow. Keys
+	// that are objects or arrays are reported as the events of their
+	// content instead.
+	Key
+	// Scalar is a scalar value
+	Scalar
+	// Alias refers to an anchored value, whose name is in Value. Aliases
+	// are not expanded.
+	Alias
+)
+
+// String returns the name of the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case DocumentStart:
+		return "DocumentStart"
+	case DocumentEnd:
+		return "DocumentEnd"
+	case MappingStart:
+		return "MappingStart"
+	case MappingEnd:
+		return "MappingEnd"
+	case SequenceStart:
+		return "SequenceStart"
+	case SequenceEnd:
+		return "SequenceEnd"
+	case Key:
+		return "Key"
+	case Scalar:
+		return "Scalar"
+	case Alias:
+		return "Alias"
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Style is the way a value is written in the document.
+type Style int
+
+const (
+	// StylePlain is an unquoted scalar, or a block style array or object
+	StylePlain Style = iota
+	// StyleDoubleQuoted is a "double quoted" scalar
+	StyleDoubleQuoted
+	// StyleSingleQuoted is a 'single quoted' scalar
+	StyleSingleQuoted
+	// StyleLiteral is a | block scalar
+	StyleLiteral
+	// StyleFolded is a > block scalar
+	StyleFolded
+	// StyleFlow is a [flow] array or a {flow} object
+	StyleFlow
+)
+
+// Event is one step of a pass over a YAML stream.
+type Event struct {
+	// Kind identifies the event
+	Kind EventKind
+	// Tag is the tag of the value, explicit or resolved, such as "!!str"
+	// or "!!int" for scalars and "!!map" for objects
+	Tag string
+	// Style is the way the value is written
+	Style Style
+	// Anchor is the anchor defined on the value, without the "&"
+	Anchor string
+	// Value is the text of a key or scalar, as decoded from its quoting,
+	// or the name of the anchor an alias refers to
+	Value string
+	// Path leads to the value from the top of its document, in the
+	// syntax of Get, so that Get on the document reads the value. Key
+	// events have the path of their value, and end events that of their
+	// start. It is empty for the top value and the document events, and
+	// values under keys that are not scalars have the path of their
+	// object.
+	Path string
+	// Line and Column give the position of the value in the stream,
+	// counting from 1. End events have the position of their start.
+	Line, Column int
+}
+
+// ForEachEvent reads the YAML stream from r and calls fn for each event
+// of each document, in document order, until fn returns false. It returns
+// the error that stopped the reading of the stream, if any, after the
+// events of the documents before it.
+//
+// Each document is read whole before its events are reported, so memory
+// use is bounded by the largest document rather than by the stream. Tags
+// are reported as written, so sets and ordered maps are not rewritten as
+// they are for Get. The path of each event lets a consumer pick values
+// out without building a tree of its own:
+//
+//	gyaml.ForEachEvent(r, func(ev gyaml.Event) bool {
+//		if ev.Kind == gyaml.Scalar && strings.HasSuffix(ev.Path, ".image") {
+//			images = append(images, ev.Value)
+//		}
+//		return true
+//	})
+func ForEachEvent(r io.Reader, fn func(ev Event) bool) error {
+	dec := yaml.NewDecoder(r)
+	var paths eventPaths
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !walkEvents(&doc, func(kind EventKind, node *yaml.Node) bool {
+			ev := newEvent(kind, node)
+			ev.Path = paths.next(kind, node)
+			return fn(ev)
+		}) {
+			return nil
+		}
+	}
+}
+
+// newEvent returns the event of the given kind for a node.
+func newEvent(kind EventKind, node *yaml.Node) Event {
+	ev := Event{
+		Kind:   kind,
+		Anchor: node.Anchor,
+		Line:   node.Line,
+		Column: node.Column,
+	}
+	switch kind {
+	case Key, Scalar, Alias:
+		ev.Value = node.Value
+	}
+	if kind != Alias && node.Kind != yaml.DocumentNode {
+		ev.Tag = node.ShortTag()
+	}
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		ev.Style = StyleDoubleQuoted
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		ev.Style = StyleSingleQuoted
+	case node.Style&yaml.LiteralStyle != 0:
+		ev.Style = StyleLiteral
+	case node.Style&yaml.FoldedStyle != 0:
+		ev.Style = StyleFolded
+	case node.Style&yaml.FlowStyle != 0:
+		ev.Style = StyleFlow
+	}
+	return ev
+}
+
+// eventPaths follows the events of a document to give the path of each.
+// It holds a frame for every array and object that has started and not
+// ended.
+type eventPaths struct {
+	frames []pathFrame
+}
+
+// pathFrame is an array or object being reported.
+type pathFrame struct {
+	path    string
+	mapping bool
+	// index is the index of the next element of an array
+	index int
+	// key is the path of the value of the last key of an object, and
+	// wantKey is set while the next event starts a key
+	key     string
+	wantKey bool
+}
+
+// next returns the path of the event of the given kind for node.
+func (p *eventPaths) next(kind EventKind, node *yaml.Node) string {
+	switch kind {
+	case DocumentStart, DocumentEnd:
+		p.frames = p.frames[:0]
+		return ""
+	case MappingEnd, SequenceEnd:
+		f := p.frames[len(p.frames)-1]
+		p.frames = p.frames[:len(p.frames)-1]
+		p.done()
+		return f.path
+	case Key:
+		f := &p.frames[len(p.frames)-1]
+		f.key, f.wantKey = joinPath(f.path, Escape(node.Value)), false
+		return f.key
+	}
+
+	// A value starts
+	var path string
+	if len(p.frames) > 0 {
+		f := &p.frames[len(p.frames)-1]
+		switch {
+		case !f.mapping:
+			path = joinPath(f.path, strconv.Itoa(f.index))
+		case f.wantKey:
+			// A key that is not a scalar
+			path = f.path
+		default:
+			path = f.key
+		}
+	}
+	if kind == MappingStart || kind == SequenceStart {
+		p.frames = append(p.frames, pathFrame{path: path, mapping: kind == MappingStart, wantKey: true})
+	} else {
+		p.done()
+	}
+	return path
+}
+
+// done records that a value of the innermost array or object has ended.
+func (p *eventPaths) done() {
+	if len(p.frames) == 0 {
+		return
+	}
+	f := &p.frames[len(p.frames)-1]
+	switch {
+	case !f.mapping:
+		f.index++
+	case f.wantKey:
+		// The value of a key that is not a scalar follows
+		f.key, f.wantKey = f.path, false
+	default:
+		f.wantKey = true
+	}
+}
+
+// walkEvents walks a node tree in document order, calling fn with the
+// kind of each event and the node it comes from. It returns false when fn
+// stopped the walk by returning false. Callers that only need the node
+// avoid building an Event.
+func walkEvents(node *yaml.Node, fn func(kind EventKind, node *yaml.Node) bool) bool {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if !fn(DocumentStart, node) {
+			return false
+		}
+		for _, child := range node.Content {
+			if !walkEvents(child, fn) {
+				return false
+			}
+		}
+		return fn(DocumentEnd, node)
+	case yaml.MappingNode:
+		if !fn(MappingStart, node) {
+			return false
+		}
+		for i, child := range node.Content {
+			if i%2 == 0 && child.Kind == yaml.ScalarNode {
+				if !fn(Key, child) {
+					return false
+				}
+			} else if !walkEvents(child, fn) {
+				return false
+			}
+		}
+		return fn(MappingEnd, node)
+	case yaml.SequenceNode:
+		if !fn(SequenceStart, node) {
+			return false
+		}
+		for _, child := range node.Content {
+			if !walkEvents(child, fn) {
+				return false
+			}
+		}
+		return fn(SequenceEnd, node)
+	case yaml.ScalarNode:
+		return fn(Scalar, node)
+	case yaml.AliasNode:
+		return fn(Alias, node)
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestForEachEvent(t *testing.T) {
+	doc := `
+a: &x 1
+b: [two, "three"]
+c: *x
+---
+- |
+  text
+`
+	var got []string
+	err := ForEachEvent(strings.NewReader(doc), func(ev Event) bool {
+		s := ev.Kind.String()
+		if ev.Value != "" {
+			s += " " + ev.Value
+		}
+		if ev.Tag != "" {
+			s += " " + ev.Tag
+		}
+		if ev.Anchor != "" {
+			s += " &" + ev.Anchor
+		}
+		if ev.Style != StylePlain {
+			s += fmt.Sprintf(" style=%d", ev.Style)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s", ev.Line, ev.Column, s))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"2:1 DocumentStart",
+		"2:1 MappingStart !!map",
+		"2:1 Key a !!str",
+		"2:4 Scalar 1 !!int &x",
+		"3:1 Key b !!str",
+		fmt.Sprintf("3:4 SequenceStart !!seq style=%d", StyleFlow),
+		"3:5 Scalar two !!str",
+		fmt.Sprintf("3:10 Scalar three !!str style=%d", StyleDoubleQuoted),
+		fmt.Sprintf("3:4 SequenceEnd !!seq style=%d", StyleFlow),
+		"4:1 Key c !!str",
+		"4:4 Alias x",
+		"2:1 MappingEnd !!map",
+		"2:1 DocumentEnd",
+		"5:1 DocumentStart",
+		"6:1 SequenceStart !!seq",
+		fmt.Sprintf("6:3 Scalar text\n !!str style=%d", StyleLiteral),
+		"6:1 SequenceEnd !!seq",
+		"5:1 DocumentEnd",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Events:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestForEachEventStopsAndFails(t *testing.T) {
+	count := 0
+	err := ForEachEvent(strings.NewReader("a: 1\n---\nb: 2\n"), func(ev Event) bool {
+		count++
+		return ev.Kind != Key
+	})
+	if err != nil || count != 3 {
+		t.Errorf("Expected to stop at the first key, got %d events (%v)", count, err)
+	}
+
+	var kinds []EventKind
+	err = ForEachEvent(strings.NewReader("a: 1\n---\nb: [\n"), func(ev Event) bool {
+		kinds = append(kinds, ev.Kind)
+		return true
+	})
+	if err == nil {
+		t.Error("Expected an error for the invalid document")
+	}
+	if len(kinds) != 6 || kinds[5] != DocumentEnd {
+		t.Errorf("Expected the events of the first document, got %v", kinds)
+	}
+	if err := ForEachEvent(errReader{}, func(Event) bool { return true }); err == nil {
+		t.Error("Expected the read error")
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+// flattenEvents is a consumer of the event API: it maps the path of every
+// scalar in the first document to its text, using nothing but events.
+func flattenEvents(t *testing.T, doc string) map[string]string {
+	t.Helper()
+	flat := map[string]string{}
+	err := ForEachEvent(strings.NewReader(doc), func(ev Event) bool {
+		switch ev.Kind {
+		case Scalar:
+			if ev.Tag != "!!null" {
+				flat[ev.Path] = ev.Value
+			}
+		case DocumentEnd:
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return flat
+}
+
+func TestEventPaths(t *testing.T) {
+	doc := `a:
+  b: [1, {c: 2}]
+  "d.e": 3
+? [complex]
+: 4
+---
+- x
+`
+	var got []string
+	err := ForEachEvent(strings.NewReader(doc), func(ev Event) bool {
+		got = append(got, ev.Kind.String()+" "+ev.Path)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"DocumentStart ", "MappingStart ",
+		"Key a", "MappingStart a",
+		"Key a.b", "SequenceStart a.b", "Scalar a.b.0", "MappingStart a.b.1", "Key a.b.1.c", "Scalar a.b.1.c",
+		"MappingEnd a.b.1", "SequenceEnd a.b",
+		`Key a.d\.e`, `Scalar a.d\.e`,
+		"MappingEnd a",
+		// Keys that are not scalars, and their values, have the path of
+		// their object
+		"SequenceStart ", "Scalar 0", "SequenceEnd ", "Scalar ",
+		"MappingEnd ", "DocumentEnd ",
+		"DocumentStart ", "SequenceStart ", "Scalar 0", "SequenceEnd ", "DocumentEnd ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Events:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFlattenFromEvents(t *testing.T) {
+	for _, doc := range []string{testYAML, complexYAML, benchmarkYAML, helmValuesYAML[:strings.Index(helmValuesYAML, "base:")]} {
+		flat := flattenEvents(t, doc)
+		if len(flat) < 5 {
+			t.Fatalf("Expected scalars to be found, got %v", flat)
+		}
+		for path, text := range flat {
+			r := Get(doc, path)
+			if !r.Exists() || r.Type == YAML {
+				t.Errorf("Path %q from events does not lead to a scalar: %#v", path, r)
+				continue
+			}
+			if r.Type == String && r.String() != text || r.Type != String && !Parse(text).Equal(r) {
+				t.Errorf("Path %q: events give %q, Get gives %q", path, text, r.String())
+			}
+		}
+	}
+}
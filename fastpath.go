@@ -0,0 +1,191 @@
+package gyaml
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isSimplePath reports whether path is a plain chain of keys and array
+// indices, such as "config.database.host" or "users.0.name". Such paths
+// carry no anchors, operators, modifiers, pipes, escapes, brackets or
+// multipaths, so they can be resolved by walking the node tree directly
+// instead of going through getByPath.
+func isSimplePath(path string) bool {
+	return path != "" && path[0] != '&' && strings.IndexAny(path, "#\\[{@|") < 0
+}
+
+// absentKey reports whether a key that the path must go through appears
+// nowhere in the text of the document, so that the path cannot resolve.
+// Only the keys before the first component that is not a plain key are
+// looked for, and only those that can be written in no other way: strings
+// without blanks or quotes that a plain scalar reads as a string, in a
+// document without escapes or NUL bytes, which could spell them otherwise
+// in quoted scalars or in UTF-16. Keys holding line breaks, which block
+// and folded scalars rewrite, and keys such as 1 or true, which match
+// keys of other types written as 0x1 or True, are never looked for.
+func absentKey(yamlStr, path string) bool {
+	if path == "" || path[0] == '&' || path[0] == '@' || strings.ContainsAny(yamlStr, "\\\x00") {
+		return false
+	}
+	if i := strings.IndexAny(path, "#\\[{@|("); i >= 0 && path[i] == '|' {
+		path = path[:i]
+	} else if i >= 0 {
+		// The component holding the special character is not a key
+		path = path[:strings.LastIndexByte(path[:i], '.')+1]
+	}
+	for len(path) > 0 {
+		var part string
+		if i := strings.IndexByte(path, '.'); i >= 0 {
+			part, path = path[:i], path[i+1:]
+		} else {
+			part, path = path, ""
+		}
+		if part == "" || strings.ContainsAny(part, " \t'\"\r\n\u0085\u2028\u2029") {
+			continue
+		}
+		if (&yaml.Node{Kind: yaml.ScalarNode, Value: part}).ShortTag() != "!!str" {
+			continue
+		}
+		if !strings.Contains(yamlStr, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSimple resolves a simple path against the YAML text. It parses the
+// document into a yaml.Node tree, which is considerably cheaper than
+// decoding it into Go maps, walks the keys without allocating, and only
+// decodes the node that was found.
+//
+// The second return value is false when the document uses a construct the
+// walker does not follow (merge keys), in which case the caller must fall
+// back to the general engine. data holds the text as bytes when the
+// caller has them, and is nil otherwise.
+func getSimple(yamlStr string, data []byte, path string) (Result, bool, error) {
+	root, err := parseCached(yamlStr, data)
+	if err != nil || root == nil {
+		return Result{Type: Null}, true, err
+	}
+	found, ok := lookupNode(root, path)
+	if !ok {
+		return Result{}, false, nil
+	}
+	if found == nil {
+		return Result{Type: Null}, true, nil
+	}
+	value, err := decodeNode(found)
+	if err != nil {
+		return Result{Type: Null}, true, nil
+	}
+	d := decodedValue{src: yamlStr}
+	return d.at(value, found), true, nil
+}
+
+// lookupNode walks a simple path through a node tree. It returns nil when
+// the path does not resolve, and false when the tree uses a construct the
+// walker does not follow (merge keys, and keys that are not strings).
+func lookupNode(node *yaml.Node, path string) (*yaml.Node, bool) {
+	for len(path) > 0 {
+		var part string
+		if i := strings.IndexByte(path, '.'); i >= 0 {
+			part, path = path[:i], path[i+1:]
+		} else {
+			part, path = path, ""
+		}
+		if part == "" {
+			continue
+		}
+		node = resolveAlias(node)
+		if node.Kind == yaml.SequenceNode {
+			idx, _, inRange := indexOf(part, len(node.Content))
+			if !inRange {
+				return nil, true
+			}
+			node = node.Content[idx]
+			continue
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, true
+		}
+		var found *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || key.ShortTag() != "!!str" {
+				// Merge keys and keys of other types are matched by the
+				// general engine
+				return nil, false
+			}
+			if key.Value == part {
+				if found != nil {
+					// Duplicate keys are rejected by the decoder.
+					return nil, true
+				}
+				found = node.Content[i+1]
+			}
+		}
+		if found == nil {
+			return nil, true
+		}
+		node = found
+	}
+	return resolveAlias(node), true
+}
+
+// nodeExists reports whether a node found by lookupNode holds a value.
+// Null scalars and scalars that fail to decode, such as "!!int abc", hold
+// none; containers are not decoded.
+func nodeExists(node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode {
+		return true
+	}
+	switch node.ShortTag() {
+	case "!!null":
+		return false
+	case "!!str":
+		return true
+	}
+	var v interface{}
+	return node.Decode(&v) == nil && v != nil
+}
+
+// resolveAlias follows alias nodes to the node they refer to.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// parseIndex reports whether part is an integer in the form accepted by
+// strconv.Atoi, without allocating on failure. Parts that are not integers
+// are map keys.
+func parseIndex(part string) (int, bool) {
+	s := part
+	neg := false
+	if s[0] == '+' || s[0] == '-' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, false
+	}
+	if len(s) > 18 {
+		n, err := strconv.Atoi(part)
+		return n, err == nil
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
@@ -0,0 +1,204 @@
+package gyaml
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestSimplePathParity checks that the fast path agrees with the general
+// engine for plain key and index chains.
+func TestSimplePathParity(t *testing.T) {
+	docs := []string{
+		testYAML,
+		benchmarkYAML,
+		`
+defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  <<: *defaults
+  name: "api"
+alias: *defaults
+"key with spaces": 1
+"1": "quoted numeric key"
+true: "bool key"
+nested:
+  empty: {}
+  list: []
+  nil: null
+`,
+		"just a scalar",
+		"# only a comment",
+	}
+	paths := []string{
+		"name.first", "name", "age", "children", "children.1", "children.3",
+		"children.-1", "friends.1.hobbies.0", "friends.0.first.x",
+		"users.0.profile.settings.theme", "users.2.profile.hobbies",
+		"config.database", "config..database.port", "missing.key",
+		"service.timeout", "service.name", "alias.retries", "key with spaces",
+		"1", "true", "nested.empty", "nested.list", "nested.nil", "nested.nil.x",
+		"+0", "00", "users.+1.name", "users.01.name",
+	}
+
+	for _, doc := range docs {
+		var root interface{}
+		if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+			t.Fatalf("invalid fixture: %v", err)
+		}
+		for _, path := range paths {
+			if !isSimplePath(path) {
+				t.Fatalf("%q should be a simple path", path)
+			}
+			want := getByPath(root, path)
+			got := Get(doc, path)
+			if got.Type != want.Type || got.Str != want.Str || got.Num != want.Num || !reflect.DeepEqual(got.Value(), want.Value()) {
+				t.Errorf("path %q: fast path %+v, general engine %+v", path, got, want)
+			}
+			if got.Type != YAML && got.Raw != want.Raw {
+				t.Errorf("path %q: fast path Raw %q, general engine %q", path, got.Raw, want.Raw)
+			}
+		}
+	}
+}
+
+func TestSimplePathDetection(t *testing.T) {
+	tests := []struct {
+		path   string
+		simple bool
+	}{
+		{"config.database.host", true},
+		{"users.0.name", true},
+		{"", false},
+		{"users.#", false},
+		{"users.#.name", false},
+		{`users.#(id=2)`, false},
+		{"{name,age}", false},
+	}
+	for _, test := range tests {
+		if got := isSimplePath(test.path); got != test.simple {
+			t.Errorf("isSimplePath(%q) = %v, expected %v", test.path, got, test.simple)
+		}
+	}
+}
+
+func TestSimplePathInvalidDocuments(t *testing.T) {
+	if Get("a: [1, 2", "a").Exists() {
+		t.Error("Malformed YAML should return Null")
+	}
+	if Get("a: 1\na: 2\n", "a").Exists() {
+		t.Error("Duplicate keys on the resolved path should return Null")
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	tests := []struct {
+		part string
+		idx  int
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"42", 42, true},
+		{"-1", -1, true},
+		{"+3", 3, true},
+		{"-", 0, false},
+		{"1a", 0, false},
+		{"name", 0, false},
+		{"1234567890123456789", 1234567890123456789, true},
+		{"99999999999999999999", 0, false},
+	}
+	for _, test := range tests {
+		idx, ok := parseIndex(test.part)
+		if ok != test.ok || (ok && idx != test.idx) {
+			t.Errorf("parseIndex(%q) = %d, %v; expected %d, %v", test.part, idx, ok, test.idx, test.ok)
+		}
+	}
+}
+
+// TestHasAgreesWithExists checks Has against Get(...).Exists() for every
+// path of the test suite, and for the values the node walk decides on
+// without decoding.
+func TestHasAgreesWithExists(t *testing.T) {
+	docs := []string{testYAML, complexYAML, edgeCaseYAML, benchmarkYAML, orderedYAML, hygieneYAML}
+	for path := range suitePaths(t) {
+		for _, doc := range docs {
+			if got, want := Has(doc, path), Get(doc, path).Exists(); got != want {
+				t.Errorf("Has(%q) = %v, Get(...).Exists() = %v", path, got, want)
+			}
+		}
+	}
+
+	doc := "null: ~\nempty:\nalias: *n\ntagged: !!null x\nbadint: !!int abc\ncustom: !foo ~\n" +
+		"list: []\nmap: {}\nstr: \"\"\nnum: 0\nmerged:\n  <<: {a: 1}\n"
+	doc = "base: &n ~\n" + doc
+	for _, path := range []string{
+		"null", "empty", "alias", "tagged", "badint", "custom", "list", "map", "str",
+		"num", "missing", "list.0", "str.x", "merged.a", "merged.b", "list.#", "",
+	} {
+		if got, want := Has(doc, path), Get(doc, path).Exists(); got != want {
+			t.Errorf("Has(%q) = %v, Get(...).Exists() = %v", path, got, want)
+		}
+	}
+	// Block and folded keys are written otherwise than they read
+	for _, tt := range []struct{ doc, path string }{
+		{"? |\n  multi\n  line\n: 5\n", "multi\nline\n"},
+		{"? |-\n  multi\n  line\n: 5\n", "multi\nline"},
+		{"? >-\n  ab\n\n  cd\n: 5\n", "ab\ncd"},
+		{"? >\n  ab\n\n  cd\n: {x: 1}\n", "ab\ncd\n.x"},
+		{"a: 1\r\n? |-\r\n  b\r\n  c\r\n: 5\r\n", "b\nc"},
+	} {
+		if !Get(tt.doc, tt.path).Exists() {
+			t.Errorf("%q: expected %q to exist", tt.doc, tt.path)
+		}
+		if got, want := Exists(tt.doc, tt.path), Get(tt.doc, tt.path).Exists(); got != want {
+			t.Errorf("%q: Exists(%q) = %v, Get(...).Exists() = %v", tt.doc, tt.path, got, want)
+		}
+	}
+	if Has("", "a") || Has("a: [", "a") {
+		t.Error("Has reported a value in an empty or invalid document")
+	}
+}
+
+func TestExistsScansForKeys(t *testing.T) {
+	tests := []struct {
+		doc, path string
+		absent    bool
+	}{
+		{"spec: {replicas: 3}", "spec.template", true},
+		{"spec: {replicas: 3}", "status.replicas", true},
+		{"spec: {replicas: 3}", "spec.replicas", false},
+		{"spec: {replicas: 3}", "spec.missing.#", true},
+		{"spec: {replicas: 3}", "spec.missing|@keys", true},
+		{"spec: {replicas: 3}", "missing.#(a=1)", true},
+		{"spec: {replicas: 3}", "spec.x#y", false},
+		{"spec: {replicas: 3}", "{missing}", false},
+		{"spec: {replicas: 3}", "@1.missing", false},
+		{"spec: {replicas: 3}", "&anchor.missing", false},
+		// Keys that could be written in another way are not looked for
+		{"\"na\\x6de\": 1", "name", false},
+		{"True: 1", "true", false},
+		{"0x1: a", "1", false},
+		{"~: a", "null", false},
+		{"a b: 1", "a b", false},
+		{"x: 1", "x.1", false},
+	}
+	for _, tt := range tests {
+		if got := absentKey(tt.doc, tt.path); got != tt.absent {
+			t.Errorf("absentKey(%q, %q) = %v, want %v", tt.doc, tt.path, got, tt.absent)
+		}
+	}
+
+	// The scan agrees with Get wherever keys are spelled in other ways
+	utf16 := "\xff\xfen\x00a\x00m\x00e\x00:\x00 \x001\x00\n\x00"
+	for _, doc := range []string{
+		"\"na\\x6de\": 1", "'it''s': 1", "True: 1\nNULL: 2\n0x1f: 3", "base: &b {name: x}\nother:\n  <<: *b",
+		"? |\n  name\n: 1", "\"na\\\n  me\": 1", utf16,
+	} {
+		for _, path := range []string{"name", "it's", "true", "null", "31", "other.name", "name\n", "missing"} {
+			if got, want := Exists(doc, path), Get(doc, path).Exists(); got != want {
+				t.Errorf("Exists(%q, %q) = %v, Get(...).Exists() = %v", doc, path, got, want)
+			}
+		}
+	}
+}
@@ -3,12 +3,24 @@
 //
 // This package is inspired by tidwall/gjson but works with YAML instead of JSON.
 // GYAML supports YAML-specific features like multi-line strings, comments, and various boolean representations.
+//
+// Inputs are never modified: no function changes the documents, byte
+// slices or path lists passed to it. Results never alias memory owned by
+// the caller; GetBytes copies its input, so the slice may be reused as
+// soon as it returns.
 package gyaml
 
 import (
+	"bufio"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -31,18 +43,131 @@ const (
 	YAML
 )
 
+// String returns the name of the type, such as "Number", or "Type(7)"
+// for a value that is not one of the types above, so that messages
+// printing a Type with %v are readable.
+func (t Type) String() string {
+	switch t {
+	default:
+		return "Type(" + strconv.Itoa(int(t)) + ")"
+	case Null:
+		return "Null"
+	case False:
+		return "False"
+	case Number:
+		return "Number"
+	case String:
+		return "String"
+	case True:
+		return "True"
+	case YAML:
+		return "YAML"
+	}
+}
+
 // Result represents a YAML value that is returned from Get()
 type Result struct {
 	// Type is the YAML type
 	Type Type
-	// Raw is the raw YAML value
+	// Raw is the raw YAML value. For objects and arrays read from a
+	// document, Raw is their text in the source, comments and quoting
+	// included, with the indentation of their first line removed. For
+	// those built from decoded values, Raw is a faithful serialization:
+	// parsing it back yields the same values and key types. It is left
+	// empty for the arrays and objects from GetRef; see MarshalRaw.
 	Raw string
 	// Str is the YAML string
 	Str string
 	// Num is the YAML number
 	Num float64
-	// Index of raw value in original YAML, or -1
+	// Index is the byte offset of the value in the document it was read
+	// from, and End the offset just past it, so that the document sliced
+	// from Index to End holds the value as written. Positions are known
+	// where Elements knows them, and both are 0 elsewhere. For the keys
+	// passed by ForEach, Index is instead the position of the entry,
+	// counting from 0.
 	Index int
+	End   int
+
+	// decoded caches the decoded form of a YAML value
+	decoded *decodedValue
+	// opts holds the options the result was resolved with, if any
+	opts *Options
+	// path is the path the result was reached by, when known
+	path string
+	// null is set for a null written in the document, as opposed to a
+	// missing value
+	null bool
+}
+
+// decodedValue holds the decoded tree behind a YAML Result. It is kept
+// behind a pointer so that Result stays comparable.
+type decodedValue struct {
+	v interface{}
+	// node is the node v was decoded from, when known. It records the
+	// order of mapping keys in the document.
+	node *yaml.Node
+	// stream is set when v holds the documents of a multi-document stream
+	stream bool
+	// src is the text the positions in node refer to, when known
+	src string
+	// ref is set for values reached through GetRef, whose arrays and
+	// objects leave Raw empty until MarshalRaw
+	ref bool
+	// built is set when node was made up for a value built along a path,
+	// such as a multipath object, whose text is written from it
+	built bool
+	// lines indexes the lines of src once they are needed, and is shared
+	// with the values within
+	lines atomic.Pointer[lineIndex]
+}
+
+// at returns the Result for a value within d and its node, which keeps
+// the source text of d.
+func (d *decodedValue) at(value interface{}, node *yaml.Node) Result {
+	var r Result
+	switch value.(type) {
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		if node != nil && d.src != "" {
+			// Raw is taken from the source text below
+			r = Result{Type: YAML, decoded: &decodedValue{v: value, node: node, ref: d.ref}}
+			return withSourceRaw(d.withSpan(r, node))
+		}
+	}
+	if d.ref {
+		r = withNode(makeRefResult(value), value, node)
+	} else {
+		r = makeNodeResult(value, node)
+	}
+	if node != nil {
+		r = d.withSpan(r, node)
+	}
+	return r
+}
+
+// unmarshal is the decoder used for Raw text. Tests replace it to count
+// how often Raw is parsed.
+var unmarshal = decodeYAML
+
+// decode returns the decoded form of a YAML result, along with the node it
+// was decoded from, using the cached tree when the Result was built from
+// one and parsing Raw otherwise. The node is nil when it is not known.
+func (t Result) decode() (*decodedValue, bool) {
+	if t.decoded != nil {
+		return t.decoded, true
+	}
+	var node yaml.Node
+	if err := unmarshal([]byte(t.Raw), &node); err != nil {
+		return nil, false
+	}
+	if node.Kind == 0 {
+		return &decodedValue{}, true
+	}
+	v, err := decodeNode(&node)
+	if err != nil {
+		return nil, false
+	}
+	return &decodedValue{v: v, node: &node, src: t.Raw}, true
 }
 
 // String returns a string representation of the value.
@@ -54,11 +179,17 @@ func (t Result) String() string {
 		return t.Str
 	case Number:
 		if len(t.Raw) == 0 {
-			return strconv.FormatFloat(t.Num, 'f', -1, 64)
+			return formatNumber(t.Num)
+		}
+		if basedInteger.MatchString(t.Raw) {
+			// Integers written in another base are given in decimal
+			if n := t.BigInt(); n != nil {
+				return n.String()
+			}
 		}
 		return t.Raw
 	case YAML:
-		return t.Raw
+		return t.rawText()
 	case True:
 		return "true"
 	case False:
@@ -66,33 +197,101 @@ func (t Result) String() string {
 	}
 }
 
+// GoString returns a description of the result for the %#v verb: its
+// type, the path it was reached by when known, and its value, shortened
+// to a line.
+func (t Result) GoString() string {
+	var sb strings.Builder
+	sb.WriteString("gyaml.Result{Type: ")
+	sb.WriteString(t.Type.String())
+	if t.path != "" {
+		fmt.Fprintf(&sb, ", Path: %q", t.path)
+	}
+	switch t.Type {
+	case Number:
+		sb.WriteString(", Value: ")
+		sb.WriteString(t.String())
+	case String, YAML:
+		fmt.Fprintf(&sb, ", Value: %q", shorten(t.String(), 60))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// shorten cuts s to at most n runes, marking the cut with "...".
+func shorten(s string, n int) string {
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos] + "..."
+		}
+		i++
+	}
+	return s
+}
+
+// NumberFormat, when set, formats the numbers that have no source text,
+// such as counts from "servers.#" and the results of @sum, @avg and
+// @count. String returns its result for them, and
+// it is used for them in the Raw of arrays and objects built by
+// projections and multipaths. Numbers read from a document always keep
+// their source text. isInt reports whether f is a whole number.
+//
+// A format that does not read back as the same number, such as "3.00"
+// for 3, gives up the guarantee that Raw parses back to the same values.
+var NumberFormat func(f float64, isInt bool) string
+
+// formatNumber formats a number that has no source text.
+func formatNumber(f float64) string {
+	if NumberFormat != nil {
+		return NumberFormat(f, f == math.Trunc(f) && !math.IsInf(f, 0))
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// synthNumber is a number without source text in a value being emitted.
+type synthNumber float64
+
 // Bool returns a boolean representation of the value.
+// With strict types it returns false wherever BoolE reports an error.
 func (t Result) Bool() bool {
+	if t.strict() {
+		b, _ := t.BoolE()
+		return b
+	}
 	switch t.Type {
 	default:
 		return false
 	case True:
 		return true
 	case String:
-		lower := strings.ToLower(t.Str)
-		// Handle YAML boolean-like strings
-		switch lower {
-		case "true", "yes", "on", "1":
-			return true
-		case "false", "no", "off", "0", "":
-			return false
-		default:
-			// Try standard parsing
-			b, _ := strconv.ParseBool(lower)
-			return b
-		}
+		b, _ := parseBoolString(t.Str)
+		return b
 	case Number:
 		return t.Num != 0
 	}
 }
 
+// parseBoolString reads the strings that Bool converts, in any case:
+// true, yes, on, t and 1, and false, no, off, f and 0. It returns false
+// for other strings.
+func parseBoolString(s string) (value, ok bool) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "on", "t", "1":
+		return true, true
+	case "false", "no", "off", "f", "0":
+		return false, true
+	}
+	return false, false
+}
+
 // Int returns an integer representation of the value.
+// With strict types it returns 0 wherever IntE reports an error.
 func (t Result) Int() int64 {
+	if t.strict() {
+		n, _ := t.IntE()
+		return n
+	}
 	switch t.Type {
 	default:
 		return 0
@@ -104,7 +303,7 @@ func (t Result) Int() int64 {
 	case Number:
 		// Check if we can parse from Raw to avoid float64 precision loss
 		if t.Raw != "" {
-			if n, err := strconv.ParseInt(strings.TrimSpace(t.Raw), 10, 64); err == nil {
+			if n, err := parseInt(t.Raw); err == nil {
 				return n
 			}
 		}
@@ -113,7 +312,12 @@ func (t Result) Int() int64 {
 }
 
 // Uint returns an unsigned integer representation of the value.
+// With strict types it returns 0 wherever UintE reports an error.
 func (t Result) Uint() uint64 {
+	if t.strict() {
+		n, _ := t.UintE()
+		return n
+	}
 	switch t.Type {
 	default:
 		return 0
@@ -127,12 +331,23 @@ func (t Result) Uint() uint64 {
 		if t.Num < 0 {
 			return 0
 		}
+		// Raw holds the number as written, which Num may only approximate
+		if t.Raw != "" {
+			if n, err := parseUint(t.Raw); err == nil {
+				return n
+			}
+		}
 		return uint64(t.Num)
 	}
 }
 
 // Float returns a float64 representation of the value.
+// With strict types it returns 0 wherever FloatE reports an error.
 func (t Result) Float() float64 {
+	if t.strict() {
+		f, _ := t.FloatE()
+		return f
+	}
 	switch t.Type {
 	default:
 		return 0
@@ -142,26 +357,122 @@ func (t Result) Float() float64 {
 		n, _ := strconv.ParseFloat(t.Str, 64)
 		return n
 	case Number:
+		// Raw holds the number as written, which Num may only approximate
+		if t.Raw != "" {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(t.Raw), 64); err == nil {
+				return n
+			}
+		}
 		return t.Num
 	}
 }
 
+// timestampLayouts are the forms of a YAML timestamp.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-1-2T15:4:5.999999999Z07:00",
+	"2006-1-2t15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999",
+	"2006-1-2",
+}
+
+// Time returns a time.Time representation of the value. Strings are read
+// as RFC 3339 or YAML timestamps; other values give the zero time.
+func (t Result) Time() time.Time {
+	tm, _ := parseTimestamp(t.String())
+	return tm
+}
+
+// parseTimestamp parses s as an RFC 3339 or YAML timestamp.
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		if tm, err := time.Parse(layout, s); err == nil {
+			return tm, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Bytes returns the bytes of a binary value: a scalar tagged !!binary, or
+// a string holding base64 text, which is decoded. Line breaks and blanks
+// in the text are ignored. It returns nil for any other value.
+//
+// The String of a !!binary scalar holds its bytes, and its Raw the base64
+// text as written. The tag is known for scalars reached by a plain chain
+// of keys and indices, and through Array and ForEach; elsewhere Bytes
+// reads the String as base64.
+func (t Result) Bytes() []byte {
+	if t.Type != String {
+		return nil
+	}
+	if t.Raw != "" {
+		return []byte(t.Str)
+	}
+	text := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, t.Str)
+	if text == "" {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// Less returns true if a token is less than another token.
+// Values of different types are ordered by Type. Strings are compared
+// case-insensitively unless caseSensitive is set, numbers by value and
+// anything else by Raw.
+func (t Result) Less(token Result, caseSensitive bool) bool {
+	if t.Type < token.Type {
+		return true
+	}
+	if t.Type > token.Type {
+		return false
+	}
+	switch t.Type {
+	case String:
+		if caseSensitive {
+			return t.Str < token.Str
+		}
+		return strings.ToLower(t.Str) < strings.ToLower(token.Str)
+	case Number:
+		if t.Num == token.Num {
+			// Integers beyond a float64 may still differ
+			if a, ok := parseBigInt(t.Raw); ok {
+				if b, ok := parseBigInt(token.Raw); ok {
+					return a.Cmp(b) < 0
+				}
+			}
+		}
+		return t.Num < token.Num
+	}
+	return t.rawText() < token.rawText()
+}
+
 // Array returns an array of values.
 func (t Result) Array() []Result {
 	if t.Type != YAML {
 		return nil
 	}
-	var any interface{}
-	if err := yaml.Unmarshal([]byte(t.Raw), &any); err != nil {
+	d, ok := t.decode()
+	if !ok {
 		return nil
 	}
-	arr, ok := any.([]interface{})
+	arr, ok := d.v.([]interface{})
 	if !ok {
 		return nil
 	}
 	results := make([]Result, len(arr))
 	for i, v := range arr {
-		results[i] = makeResult(v)
+		results[i] = t.child(d.at(v, elementNode(d.node, i, len(arr))), strconv.Itoa(i))
 	}
 	return results
 }
@@ -171,19 +482,197 @@ func (t Result) Map() map[string]Result {
 	if t.Type != YAML {
 		return nil
 	}
-	var any interface{}
-	if err := yaml.Unmarshal([]byte(t.Raw), &any); err != nil {
+	d, ok := t.decode()
+	if !ok {
+		return nil
+	}
+	switch obj := d.v.(type) {
+	case map[string]interface{}:
+		keys, nodes := orderedKeys(obj, d.node)
+		results := make(map[string]Result, len(obj))
+		for i, k := range keys {
+			results[k] = t.child(d.at(obj[k], nodes[i]), Escape(k))
+		}
+		return results
+	case map[interface{}]interface{}:
+		// Non-string keys are stringified as written in YAML, and string
+		// keys take precedence over keys that stringify the same way
+		keys, nodes := orderedAnyKeys(obj, d.node)
+		results := make(map[string]Result, len(obj))
+		for i, k := range keys {
+			if _, isString := k.(string); !isString {
+				key := canonicalKey(k)
+				results[key] = t.child(d.at(obj[k], nodes[i]), Escape(key))
+			}
+		}
+		for i, k := range keys {
+			if s, isString := k.(string); isString {
+				results[s] = t.child(d.at(obj[k], nodes[i]), Escape(s))
+			}
+		}
+		return results
+	}
+	return nil
+}
+
+// MapStringString returns the entries of an object with each value
+// converted like String, for label and annotation maps that other APIs
+// take as map[string]string: numbers and booleans give their text, nulls
+// give "", and arrays and objects give their YAML text. It returns nil for
+// values that are not objects.
+func (t Result) MapStringString() map[string]string {
+	m := t.Map()
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v.String()
+	}
+	return out
+}
+
+// MapString returns the entries of an object with scalar values converted
+// like String, and arrays and objects as their Value, so that nested
+// values keep their structure. Nulls are given as nil. It returns nil for
+// values that are not objects.
+func (t Result) MapString() map[string]interface{} {
+	m := t.Map()
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch {
+		case v.Type == YAML:
+			out[k] = v.Value()
+		case v.Type == Null:
+			out[k] = nil
+		default:
+			out[k] = v.String()
+		}
+	}
+	return out
+}
+
+// Keys returns the keys of an object, in the order ForEach visits them:
+// as written in the document where that is known, and sorted otherwise.
+// Keys that are not strings are given in their written form. It returns
+// nil for values that are not objects.
+func (t Result) Keys() []string {
+	if t.Type != YAML {
+		return nil
+	}
+	d, ok := t.decode()
+	if !ok {
 		return nil
 	}
-	obj, ok := any.(map[string]interface{})
+	switch obj := d.v.(type) {
+	case map[string]interface{}:
+		keys, _ := orderedKeys(obj, d.node)
+		return keys
+	case map[interface{}]interface{}:
+		keys, _ := orderedAnyKeys(obj, d.node)
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = canonicalKey(k)
+		}
+		return names
+	}
+	return nil
+}
+
+// Values returns the values of an object, in the order of Keys. It
+// returns nil for values that are not objects; Array returns the elements
+// of an array.
+func (t Result) Values() []Result {
+	if t.Type != YAML {
+		return nil
+	}
+	d, ok := t.decode()
 	if !ok {
 		return nil
 	}
-	results := make(map[string]Result)
-	for k, v := range obj {
-		results[k] = makeResult(v)
+	var values []Result
+	switch obj := d.v.(type) {
+	case map[string]interface{}:
+		values = make([]Result, 0, len(obj))
+	case map[interface{}]interface{}:
+		values = make([]Result, 0, len(obj))
+	default:
+		return nil
 	}
-	return results
+	t.ForEach(func(_, value Result) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// StringSlice returns the elements of an array as strings, each converted
+// like String, so that arrays and objects within it give their YAML text
+// and nulls give "". It returns nil for values that are not arrays.
+func (t Result) StringSlice() []string {
+	arr := t.Array()
+	if arr == nil {
+		return nil
+	}
+	out := make([]string, len(arr))
+	for i, elem := range arr {
+		out[i] = elem.String()
+	}
+	return out
+}
+
+// IntSlice returns the elements of an array as integers, each converted
+// like Int, so that numeric strings are parsed and anything else that is
+// not a number gives 0. It returns nil for values that are not arrays.
+func (t Result) IntSlice() []int64 {
+	arr := t.Array()
+	if arr == nil {
+		return nil
+	}
+	out := make([]int64, len(arr))
+	for i, elem := range arr {
+		out[i] = elem.Int()
+	}
+	return out
+}
+
+// FloatSlice returns the elements of an array as floats, each converted
+// like Float. It returns nil for values that are not arrays.
+func (t Result) FloatSlice() []float64 {
+	arr := t.Array()
+	if arr == nil {
+		return nil
+	}
+	out := make([]float64, len(arr))
+	for i, elem := range arr {
+		out[i] = elem.Float()
+	}
+	return out
+}
+
+// GroupBy groups the elements of an array by the value at path in each,
+// as String gives it, like the @group modifier:
+//
+//	byRegion := gyaml.Get(doc, "servers").GroupBy("region")
+//	for _, server := range byRegion["eu-west-1"] { ... }
+//
+// Elements without a value at path are left out, and the members of each
+// group keep their order. It returns nil for values that are not arrays.
+func (t Result) GroupBy(path string) map[string][]Result {
+	arr := t.Array()
+	if arr == nil {
+		return nil
+	}
+	groups := make(map[string][]Result)
+	for _, elem := range arr {
+		if key := elem.Get(path); key.Exists() {
+			groups[key.String()] = append(groups[key.String()], elem)
+		}
+	}
+	return groups
 }
 
 // Get returns the result for the specified path.
@@ -191,15 +680,110 @@ func (t Result) Get(path string) Result {
 	if t.Type != YAML {
 		return Result{}
 	}
-	return Get(t.Raw, path)
+	if len(path) == 0 && t.decoded != nil && t.decoded.ref {
+		return t.child(t, path)
+	}
+	if t.decoded == nil || len(path) == 0 {
+		result, _ := get(t.Raw, nil, path)
+		return t.child(result, path)
+	}
+	if t.decoded.stream {
+		// Streams delegate to their first document, unless the path
+		// selects another
+		docs, rest := t.Array(), path
+		doc := docs[0]
+		if n, after, ok := documentPath(path); ok {
+			doc, rest = Result{Type: Null}, after
+			if n < len(docs) {
+				doc = docs[n]
+			}
+		}
+		result := doc
+		if rest != "" {
+			result = doc.Get(rest)
+		}
+		result.path = joinPath(t.path, path)
+		return result
+	}
+	if name, rest, ok := anchorPath(path); ok {
+		result := t.decoded.anchor(name)
+		result.opts = t.opts
+		if rest != "" {
+			result = result.Get(rest)
+		}
+		return t.child(result, path)
+	}
+	r := &resolver{ref: t.decoded.ref, tree: t.decoded}
+	if t.opts != nil {
+		r.opts = *t.opts
+	}
+	result := t.decoded.keepNode(r.getByPath(t.decoded.v, path), path)
+	return t.child(result, path)
+}
+
+// child passes the options of a result on to a value reached from it by
+// path.
+func (t Result) child(r Result, path string) Result {
+	r.opts = t.opts
+	r.path = joinPath(t.path, path)
+	return r
+}
+
+// joinPath appends path to the path of a parent value. It is joined with
+// a pipe when the parent is built by its path rather than found in the
+// document, such as the matches of a #(...)# query, as a dot would apply
+// the rest of the path to each of its elements.
+func joinPath(parent, path string) string {
+	if parent == "" {
+		return path
+	}
+	if path == "" {
+		return parent
+	}
+	if builtPath(parent) {
+		return parent + "|" + path
+	}
+	return parent + "." + path
+}
+
+// builtPath reports whether a path builds its value, with a projection,
+// a query for all matches, a modifier, a multipath or a slice, rather than
+// leading to a value of the document.
+func builtPath(path string) bool {
+	if _, rest, ok := documentPath(path); ok {
+		path = rest
+	}
+	if !strings.ContainsAny(path, "#@{[|") {
+		return false
+	}
+	segments, err := SplitPath(path)
+	if err != nil {
+		return false
+	}
+	for _, seg := range segments {
+		switch seg.Kind {
+		case SegmentKey, SegmentIndex:
+		case SegmentQuery:
+			if strings.HasSuffix(seg.Text, "#") {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
 }
 
 // Value returns the raw interface{} value.
+// For YAML results the returned tree is shared with the Result and should
+// be treated as read-only. Integers in it too large for 64 bits are a
+// *big.Int.
 func (t Result) Value() interface{} {
 	if t.Type == YAML {
-		var any interface{}
-		yaml.Unmarshal([]byte(t.Raw), &any)
-		return any
+		if d, ok := t.decode(); ok {
+			return d.v
+		}
+		return nil
 	}
 	switch t.Type {
 	default:
@@ -215,12 +799,196 @@ func (t Result) Value() interface{} {
 	}
 }
 
+// nativeValue returns the value as it would be decoded from YAML. Unlike
+// Value, integers are returned as int (or int64/uint64 when they don't
+// fit), so that values collected into new containers keep their type.
+func (t Result) nativeValue() interface{} {
+	if t.Type != Number {
+		return t.Value()
+	}
+	raw := strings.TrimSpace(t.Raw)
+	if raw == "" && t.Num == math.Trunc(t.Num) && math.Abs(t.Num) < 1<<53 {
+		// Counts and other synthesized integers
+		return int(t.Num)
+	}
+	if n, err := parseInt(raw); err == nil {
+		if int64(int(n)) == n {
+			return int(n)
+		}
+		return n
+	}
+	if n, err := parseUint(raw); err == nil {
+		return n
+	}
+	if n, ok := parseBigInt(raw); ok {
+		return n
+	}
+	return t.Num
+}
+
+// Len returns the number of elements in an array, the number of entries
+// in an object, or the number of documents in a stream from ParseStream.
+// It returns 0 for any other value.
+func (t Result) Len() int {
+	if t.Type != YAML {
+		return 0
+	}
+	switch v := t.Value().(type) {
+	case []interface{}:
+		return len(v)
+	case map[string]interface{}:
+		return len(v)
+	case map[interface{}]interface{}:
+		return len(v)
+	}
+	return 0
+}
+
+// Count returns the same as Len.
+func (t Result) Count() int {
+	return t.Len()
+}
+
+// Scalar returns the result as a single scalar value, as a normalization
+// point before Int, String and the other conversions. A scalar result is
+// returned as is, and a one-element array gives its element when that is
+// a scalar. Objects, other arrays and null give a Null result.
+//
+// Several operations wrap a scalar in a YAML result:
+//
+//   - projections such as "friends.#.first" always produce an array, even
+//     when a single element has the key;
+//   - Parse, ParseStream and ForEachLine return a document holding a single
+//     scalar, such as "42", as a YAML result;
+//   - ArrayOf and Append build arrays from single values.
+func (t Result) Scalar() Result {
+	if t.Type != YAML {
+		return t
+	}
+	d, ok := t.decode()
+	if !ok {
+		return Result{Type: Null}
+	}
+	if arr, ok := d.v.([]interface{}); ok {
+		if len(arr) != 1 {
+			return Result{Type: Null}
+		}
+		return t.Array()[0].scalarValue()
+	}
+	return t.scalarValue()
+}
+
+// scalarValue returns a result holding a scalar as a scalar result, and
+// Null for arrays and objects.
+func (t Result) scalarValue() Result {
+	if t.Type != YAML {
+		return t
+	}
+	d, ok := t.decode()
+	if !ok {
+		return Result{Type: Null}
+	}
+	switch d.v.(type) {
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		return Result{Type: Null}
+	}
+	return t.child(d.at(d.v, d.node), "")
+}
+
 // Exists returns true if value exists.
+// Unlike gjson, where a literal null exists, a YAML null (null, ~ or an
+// empty value) does not exist, as it carries no value; IsNull tells it
+// from a missing value.
+// Projections such as "friends.#.nickname" always produce an array, so
+// they exist even when no element had a value. Use ExistsNonEmpty to
+// also rule out empty arrays and objects.
 func (t Result) Exists() bool {
 	return t.Type != Null
 }
 
+// IsNull reports whether the value is a null written in the document, as
+// null, ~ or an empty value, rather than missing. Both are Null results
+// that do not exist, so that a key set to null can override a default
+// only where IsNull is checked:
+//
+//	if r := gyaml.Get(override, "timeout"); r.Exists() || r.IsNull() {
+//		// the override sets timeout, possibly to nothing
+//	}
+func (t Result) IsNull() bool {
+	return t.Type == Null && t.null
+}
+
+// IsArray reports whether the value is an array. Arrays and objects are
+// both YAML results; values read from a document answer without parsing
+// their Raw again.
+func (t Result) IsArray() bool {
+	if t.Type != YAML {
+		return false
+	}
+	d, ok := t.decode()
+	if !ok {
+		return false
+	}
+	_, isArray := d.v.([]interface{})
+	return isArray
+}
+
+// IsObject reports whether the value is an object, whatever the type of
+// its keys.
+func (t Result) IsObject() bool {
+	if t.Type != YAML {
+		return false
+	}
+	d, ok := t.decode()
+	if !ok {
+		return false
+	}
+	switch d.v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	}
+	return false
+}
+
+// IsBool reports whether the value is true or false. YAML 1.1 booleans
+// such as yes are strings, which Bool reads as booleans.
+func (t Result) IsBool() bool {
+	return t.Type == True || t.Type == False
+}
+
+// IsNumber reports whether the value is a number.
+func (t Result) IsNumber() bool {
+	return t.Type == Number
+}
+
+// IsString reports whether the value is a string. Timestamps and !!binary
+// values are strings.
+func (t Result) IsString() bool {
+	return t.Type == String
+}
+
+// ExistsNonEmpty returns true if the value exists and is not an empty
+// array or object. Documents holding a scalar, such as Parse("42"), are
+// not empty. It decodes the value at most once.
+func (t Result) ExistsNonEmpty() bool {
+	if t.Type != YAML {
+		return t.Exists()
+	}
+	switch v := t.Value().(type) {
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	case map[interface{}]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
 // ForEach iterates through values.
+// Object keys are visited in the order they appear in the document where
+// that order is known, and in sorted order otherwise. The key's Index
+// holds its position, counting from 0.
 func (t Result) ForEach(iterator func(key, value Result) bool) {
 	if !t.Exists() {
 		return
@@ -228,26 +996,45 @@ func (t Result) ForEach(iterator func(key, value Result) bool) {
 	if t.Type != YAML {
 		return
 	}
-	var any interface{}
-	if err := yaml.Unmarshal([]byte(t.Raw), &any); err != nil {
+	d, ok := t.decode()
+	if !ok {
 		return
 	}
-	switch obj := any.(type) {
+	switch obj := d.v.(type) {
 	case map[string]interface{}:
-		for k, v := range obj {
-			if !iterator(Result{Type: String, Str: k}, makeResult(v)) {
+		keys, nodes := orderedKeys(obj, d.node)
+		for i, k := range keys {
+			if !iterator(Result{Type: String, Str: k, Index: i}, t.child(d.at(obj[k], nodes[i]), Escape(k))) {
+				return
+			}
+		}
+	case map[interface{}]interface{}:
+		keys, nodes := orderedAnyKeys(obj, d.node)
+		for i, k := range keys {
+			key := canonicalKey(k)
+			if !iterator(Result{Type: String, Str: key, Index: i}, t.child(d.at(obj[k], nodes[i]), Escape(key))) {
 				return
 			}
 		}
 	case []interface{}:
 		for i, v := range obj {
-			if !iterator(Result{Type: Number, Num: float64(i)}, makeResult(v)) {
+			key := Result{Type: Number, Num: float64(i), Index: i}
+			if !iterator(key, t.child(d.at(v, elementNode(d.node, i, len(obj))), strconv.Itoa(i))) {
 				return
 			}
 		}
 	}
 }
 
+// valueResult creates a Result for a value found in a document, where nil
+// stands for a null written in it.
+func valueResult(value interface{}) Result {
+	if value == nil {
+		return Result{Type: Null, null: true}
+	}
+	return makeResult(value)
+}
+
 // makeResult creates a Result from an interface{} value
 func makeResult(value interface{}) Result {
 	if value == nil {
@@ -283,16 +1070,25 @@ func makeResult(value interface{}) Result {
 	case uint64:
 		return Result{Type: Number, Num: float64(v), Raw: strconv.FormatUint(v, 10)}
 	case float32:
-		return Result{Type: Number, Num: float64(v), Raw: strconv.FormatFloat(float64(v), 'g', -1, 32)}
+		return Result{Type: Number, Num: widenFloat32(v), Raw: strconv.FormatFloat(float64(v), 'g', -1, 32)}
 	case float64:
 		return Result{Type: Number, Num: v, Raw: strconv.FormatFloat(v, 'g', -1, 64)}
+	case synthNumber:
+		// Without source text, so that String formats it with NumberFormat
+		return Result{Type: Number, Num: float64(v)}
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return Result{Type: Number, Num: f, Raw: v.String()}
+	case time.Time:
+		// Timestamps are strings, as in JSON; see Time
+		return Result{Type: String, Str: v.Format(time.RFC3339Nano)}
 	default:
 		// For complex types, marshal back to YAML
-		raw, err := yaml.Marshal(v)
+		raw, err := marshalYAML(v)
 		if err != nil {
 			return Result{Type: Null}
 		}
-		return Result{Type: YAML, Raw: string(raw)}
+		return Result{Type: YAML, Raw: string(raw), decoded: &decodedValue{v: v}}
 	}
 }
 
@@ -300,69 +1096,375 @@ func makeResult(value interface{}) Result {
 // A path is in dot syntax, such as "name.last" or "age".
 // When the value is found it's returned immediately.
 func Get(yamlStr, path string) Result {
+	trace := startTrace()
+	result, err := get(yamlStr, nil, path)
+	if trace != nil {
+		trace.end("Get", path, len(yamlStr), result.Exists(), err)
+	}
+	return result
+}
+
+// Count returns the number of elements of the array, or of entries of the
+// object, at path, as the path followed by ".#" gives it:
+//
+//	n := gyaml.Count(doc, "spec.containers")
+//
+// A projection such as "servers.#.ports" counts its elements, one for
+// each server with ports, rather than the ports of each. It returns 0 when
+// there is no value at path, and for scalars.
+func Count(yamlStr, path string) int {
+	return Get(yamlStr, path).Len()
+}
+
+// get resolves a path for Get, and reports why the document could not be
+// read. data holds the text of the document as bytes when the caller has
+// them, so that it is parsed without another copy, and is nil otherwise.
+func get(yamlStr string, data []byte, path string) (Result, error) {
+	result, err := resolve(yamlStr, data, path)
+	result.path = path
+	return result, err
+}
+
+// resolve resolves a path for get.
+func resolve(yamlStr string, data []byte, path string) (Result, error) {
 	if len(yamlStr) == 0 {
-		return Result{Type: Null}
+		return Result{Type: Null}, nil
 	}
 
-	var root interface{}
-	if err := yaml.Unmarshal([]byte(yamlStr), &root); err != nil {
-		return Result{Type: Null}
+	if n, rest, ok := documentPath(path); ok {
+		doc, err := selectDocument(yamlStr, n, Options{})
+		if err != nil || rest == "" {
+			return doc, err
+		}
+		return doc.Get(rest), nil
 	}
 
-	// If path is empty, return the entire document
-	if len(path) == 0 {
-		return Result{Type: YAML, Raw: yamlStr}
+	if _, _, ok := anchorPath(path); ok {
+		doc, err := parseDocument(yamlStr, Options{})
+		if err != nil || doc.decoded == nil {
+			return doc, err
+		}
+		return doc.Get(path), nil
+	}
+
+	// Plain key chains skip the general engine
+	if isSimplePath(path) {
+		if result, ok, err := getSimple(yamlStr, data, path); ok {
+			return result, err
+		}
 	}
 
-	return getByPath(root, path)
+	doc, err := parseSource(yamlStr, data, Options{})
+	if err != nil || len(path) == 0 || doc.decoded == nil {
+		// An empty path returns the entire document
+		return doc, err
+	}
+	r := &resolver{tree: doc.decoded}
+	return doc.decoded.keepNode(r.getByPath(doc.decoded.v, path), path), nil
+}
+
+// Exists reports whether the path resolves to a value, the same as
+// Get(yamlStr, path).Exists(), doing as little work as it can for the
+// answer. A key of the path that appears nowhere in the text is reported
+// absent before the document is parsed, which makes looking for a missing
+// key in a large document a scan of its text. For plain key chains it
+// then only walks the parsed node tree, without building a Result:
+// containers are reported present without being decoded, so a container
+// holding duplicate keys, which Get returns as Null, is reported present.
+func Exists(yamlStr, path string) bool {
+	if len(yamlStr) == 0 || absentKey(yamlStr, path) {
+		return false
+	}
+	if isSimplePath(path) {
+		root, err := parseCached(yamlStr, nil)
+		if err != nil || root == nil {
+			return false
+		}
+		if found, ok := lookupNode(root, path); ok {
+			return found != nil && nodeExists(found)
+		}
+	}
+	result, _ := get(yamlStr, nil, path)
+	return result.Exists()
+}
+
+// Has is the same as Exists.
+//
+// Deprecated: use Exists.
+func Has(yamlStr, path string) bool {
+	return Exists(yamlStr, path)
 }
 
 // GetBytes searches YAML bytes for the specified path.
+// The bytes are copied, so the Result does not refer to yamlBytes, and
+// parsed as they are, without a second copy.
 func GetBytes(yamlBytes []byte, path string) Result {
-	return Get(string(yamlBytes), path)
+	trace := startTrace()
+	result, err := get(string(yamlBytes), yamlBytes, path)
+	if trace != nil {
+		trace.end("Get", path, len(yamlBytes), result.Exists(), err)
+	}
+	return result
+}
+
+// textBytes returns data, the text of a document as the caller passed it
+// in bytes, or the bytes of yamlStr when data is nil.
+func textBytes(yamlStr string, data []byte) []byte {
+	if data != nil {
+		return data
+	}
+	return []byte(yamlStr)
+}
+
+// GetValueAt searches a decoded Go value for the specified path, with the
+// same path syntax and Result semantics as Get, as if the value had been
+// written out as YAML. It suits values from another decoder, such as
+// encoding/json:
+//
+//	var config map[string]interface{}
+//	json.Unmarshal(data, &config)
+//	gyaml.GetValueAt(config, "servers.#(port>1024).name")
+//
+// The values that yaml.v3 decodes into an interface{} are used as they
+// are: map[string]interface{}, map[interface{}]interface{},
+// []interface{}, strings, booleans, numbers and time.Time. Other values,
+// such as structs, typed slices and typed maps, are converted as yaml.v3
+// would encode them, so struct fields are named by their yaml tags or
+// their lowercased names. Values that cannot be encoded, such as
+// functions and channels, read as null, so they do not exist. Keys are
+// visited in sorted order, as Go maps have no order of their own. The
+// value passed in is never modified.
+func GetValueAt(root interface{}, path string) Result {
+	result := getByPath(buildValue(root), path)
+	result.path = path
+	return result
 }
 
 // Parse parses the YAML and returns a result.
 func Parse(yamlStr string) Result {
-	if len(yamlStr) == 0 {
-		return Result{Type: Null}
+	trace := startTrace()
+	doc, err := parseDocument(yamlStr, Options{})
+	if trace != nil {
+		trace.end("Parse", "", len(yamlStr), doc.Exists(), err)
 	}
+	return doc
+}
 
-	var root interface{}
-	if err := yaml.Unmarshal([]byte(yamlStr), &root); err != nil {
-		return Result{Type: Null}
+// ParseBytes parses YAML bytes like Parse. The bytes are copied, so the
+// Result does not refer to yamlBytes, and parsed as they are, without a
+// second copy.
+func ParseBytes(yamlBytes []byte) Result {
+	trace := startTrace()
+	doc, err := parseSource(string(yamlBytes), yamlBytes, Options{})
+	if trace != nil {
+		trace.end("Parse", "", len(yamlBytes), doc.Exists(), err)
 	}
+	return doc
+}
 
-	return Result{Type: YAML, Raw: yamlStr}
+// FromNode returns the Result of a node tree decoded with yaml.v3, so that
+// paths and queries apply to a tree already at hand without writing it out
+// and parsing it again:
+//
+//	var doc yaml.Node
+//	yaml.NewDecoder(r).Decode(&doc)
+//	gyaml.FromNode(&doc).Get("spec.containers.#.image")
+//
+// node is a document node or the value node within one. Keys are read in
+// the order of the tree, but as there is no source text, values have no
+// Index and the Raw of arrays and objects is written out from their
+// values. The tree is not modified, and may be used again once FromNode
+// returns. It returns a Null result for nil, an empty document, or a tree
+// that does not decode.
+func FromNode(node *yaml.Node) Result {
+	if node != nil && node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return Result{Type: Null}
+		}
+		node = node.Content[0]
+	}
+	if node == nil || node.Kind == 0 {
+		return Result{Type: Null}
+	}
+	if needsNormalize(node) {
+		node = cloneNode(node, map[*yaml.Node]*yaml.Node{})
+		normalizeNode(node)
+	}
+	v, err := decodeNode(node)
+	if err != nil {
+		return Result{Type: Null}
+	}
+	d := &decodedValue{v: v, node: node}
+	return d.at(v, node)
 }
 
 // Valid returns true if the YAML is valid.
 func Valid(yamlStr string) bool {
+	ok, _ := ValidWithError(yamlStr)
+	return ok
+}
+
+// ValidBytes returns true if the YAML bytes are valid, without copying
+// them.
+func ValidBytes(yamlBytes []byte) bool {
+	trace := startTrace()
+	err := validate(yamlBytes)
+	if trace != nil {
+		trace.end("Valid", "", len(yamlBytes), true, err)
+	}
+	return err == nil
+}
+
+// validate returns the error of the parser for data, or nil when it is
+// valid YAML.
+func validate(data []byte) error {
 	var root interface{}
-	return yaml.Unmarshal([]byte(yamlStr), &root) == nil
+	return yaml.Unmarshal(data, &root)
+}
+
+// ValidWithError reports whether the YAML is valid like Valid, along with
+// a *SyntaxError giving the position of the problem when it is not:
+//
+//	if ok, err := gyaml.ValidWithError(doc); !ok {
+//		var serr *gyaml.SyntaxError
+//		if errors.As(err, &serr) {
+//			fmt.Printf("config.yaml:%d:%d: %s\n", serr.Line, serr.Column, serr.Msg)
+//		}
+//	}
+func ValidWithError(yamlStr string) (bool, error) {
+	trace := startTrace()
+	err := validate([]byte(yamlStr))
+	if err != nil {
+		err = newSyntaxError(yamlStr, err)
+	}
+	if trace != nil {
+		trace.end("Valid", "", len(yamlStr), true, err)
+	}
+	return err == nil, err
+}
+
+// SyntaxError describes why a document is not valid YAML, or not valid
+// TOML for FromTOMLE.
+type SyntaxError struct {
+	// Line is the line the parser reports the problem at, counting from
+	// 1, or 0 when it does not say
+	Line int
+	// Column is the column of the first character on Line, counting from
+	// 1, as the parser reports lines only. It is 0 when Line is.
+	Column int
+	// Msg is the message of the parser, without the position
+	Msg string
+	// Err is the error of the parser, which is nil for TOML
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		return "gyaml: " + e.Msg
+	}
+	return fmt.Sprintf("gyaml: line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Unwrap returns the error of the parser.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// newSyntaxError reads the position out of a parser error for src, such
+// as "yaml: line 3: did not find expected key". Of the errors found while
+// decoding, such as duplicate keys, the first one is kept.
+func newSyntaxError(src string, err error) *SyntaxError {
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	serr := &SyntaxError{Msg: strings.TrimPrefix(msg, "yaml: "), Err: err}
+	if strings.HasPrefix(serr.Msg, "line ") {
+		num, text, found := strings.Cut(serr.Msg[len("line "):], ": ")
+		if n, convErr := strconv.Atoi(num); found && convErr == nil && n > 0 {
+			serr.Line, serr.Msg = n, text
+			serr.Column = 1
+			lines := strings.Split(src, "\n")
+			if n <= len(lines) {
+				line := lines[n-1]
+				serr.Column += len(line) - len(strings.TrimLeft(line, " \t"))
+			}
+		}
+	}
+	return serr
+}
+
+// resolver carries the options of a path resolution and records the first
+// problem found along the way. The zero value resolves with the package
+// defaults.
+type resolver struct {
+	opts Options
+	err  error
+	// ref leaves Raw empty in the arrays and objects found, as for GetRef
+	ref bool
+	// tree, when known, is the value the path is resolved in, whose node
+	// gives modifiers the order of the keys of its objects
+	tree *decodedValue
+}
+
+// nodeOf returns the node an object of the tree was decoded from, or nil
+// for other values and when it is unknown.
+func (r *resolver) nodeOf(v interface{}) *yaml.Node {
+	if r.tree == nil || r.tree.node == nil {
+		return nil
+	}
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return findNode(r.tree.node, r.tree.v, v)
+	}
+	return nil
+}
+
+// fail records err unless an earlier error was recorded.
+func (r *resolver) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// result returns the Result of a value found by r.
+func (r *resolver) result(v interface{}) Result {
+	if r.ref {
+		return makeRefResult(v)
+	}
+	return valueResult(v)
 }
 
 // getByPath navigates through the parsed YAML structure using the path
 func getByPath(root interface{}, path string) Result {
+	return (&resolver{}).getByPath(root, path)
+}
+
+// getByPath navigates through the parsed YAML structure using the path
+func (r *resolver) getByPath(root interface{}, path string) Result {
 	if path == "" {
 		// For empty path, return the root as YAML type if it's complex
 		if root == nil {
 			return Result{Type: Null}
 		}
-		switch v := root.(type) {
-		case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
-			return makeResult(v)
-		default:
-			// Complex type, marshal to YAML and return as YAML type
-			raw, err := yaml.Marshal(v)
-			if err != nil {
-				return Result{Type: Null}
-			}
-			return Result{Type: YAML, Raw: string(raw)}
-		}
+		// Complex types are marshaled to YAML and returned as YAML type
+		return r.result(root)
 	}
 
-	parts := strings.Split(path, ".")
+	all := pathParts(path)
+	if stage, rest, ok := cutPipe(path, all); ok {
+		// A | applies the rest of the path to the result so far
+		result := r.getByPath(root, stage)
+		if !result.Exists() {
+			return Result{Type: Null}
+		}
+		return r.getByPath(result.nativeValue(), rest)
+	}
+	parts := make([]string, len(all))
+	for i, part := range all {
+		parts[i] = part.text
+	}
 	current := root
 
 	for i, part := range parts {
@@ -370,231 +1472,165 @@ func getByPath(root interface{}, path string) Result {
 			continue
 		}
 
-		// Handle array length with #
-		if part == "#" {
-			// Check if this is the last part or if next part is empty
-			if i == len(parts)-1 {
-				switch v := current.(type) {
-				case []interface{}:
-					return Result{Type: Number, Num: float64(len(v))}
-				case map[string]interface{}:
-					return Result{Type: Number, Num: float64(len(v))}
-				default:
+		switch segmentKind(part, i == len(parts)-1) {
+		case SegmentMultipath:
+			value, node, ok := r.multipath(current, part)
+			if !ok {
+				return Result{Type: Null}
+			}
+			if i == len(parts)-1 && node != nil {
+				// Keep the keys in the order they were asked for
+				d := decodedValue{ref: r.ref}
+				return withNodeRaw(d.at(value, node))
+			}
+			current = value
+			continue
+
+		case SegmentModifier:
+			if mod, arg, ok := lookupModifier(part); ok {
+				next, ok := mod(current, r.nodeOf(current), arg)
+				if !ok {
 					return Result{Type: Null}
 				}
-			} else {
-				// This is #.something, collect remaining path and handle array operation
-				remainingPath := strings.Join(parts[i+1:], ".")
-				return handleArrayOperation(current, remainingPath)
+				current = next
+				continue
+			}
+			// Not a modifier: read it as a key
+		case SegmentLength:
+			// Handle array length with #
+			switch v := current.(type) {
+			case []interface{}:
+				return Result{Type: Number, Num: float64(len(v))}
+			case map[string]interface{}:
+				return Result{Type: Number, Num: float64(len(v))}
+			case map[interface{}]interface{}:
+				return Result{Type: Number, Num: float64(len(v))}
+			default:
+				return Result{Type: Null}
 			}
-		}
 
-		// Handle array queries like #(key=value)
-		if strings.HasPrefix(part, "#(") && strings.HasSuffix(part, ")") {
+		case SegmentQuery:
+			if strings.HasSuffix(part, ")#") {
+				// #(key=value)# keeps every match, and the rest of the
+				// path applies to each of them
+				matches, ok := r.queryMatches(current, part[2:len(part)-2], false)
+				switch rest := strings.Join(parts[i+1:], "."); {
+				case !ok:
+					return Result{Type: Null}
+				case rest == "#":
+					// A trailing # counts the matches
+					return Result{Type: Number, Num: float64(len(matches))}
+				default:
+					return r.handleArrayOperation(matches, rest)
+				}
+			}
+			// Handle array queries like #(key=value)
 			query := part[2 : len(part)-1] // Remove #( and )
-			result := handleArrayQuery(current, query)
+			result := r.handleArrayQuery(current, query)
 			if !result.Exists() {
 				return result
 			}
 			// If there are more parts after the query, continue processing
 			if i < len(parts)-1 {
 				remainingPath := strings.Join(parts[i+1:], ".")
-				// Parse the result back to interface{} for further processing
-				var parsed interface{}
-				if result.Type == YAML {
-					err := yaml.Unmarshal([]byte(result.Raw), &parsed)
-					if err != nil {
-						return Result{Type: Null}
-					}
-				} else {
-					parsed = result.Value()
-				}
-				return getByPath(parsed, remainingPath)
+				// Continue from the decoded value of the match
+				return r.getByPath(result.Value(), remainingPath)
 			}
 			return result
-		}
 
-		// Handle array access with wildcard or specific operations that start with #
-		// But first check if it's actually a map key that starts with #
-		if strings.HasPrefix(part, "#") && part != "#" {
+		case SegmentProjection:
+			if part == "#" {
+				// This is #.something, collect remaining path and handle array operation
+				remainingPath := strings.Join(parts[i+1:], ".")
+				return r.handleArrayOperation(current, remainingPath)
+			}
 			// Check if current is a map and has this exact key
 			if obj, ok := current.(map[string]interface{}); ok {
-				if _, exists := obj[part]; exists {
+				if val, exists := obj[unescapeKey(part)]; exists {
 					// It's a real key that starts with #, treat as normal key
-					current = obj[part]
+					current = val
 					continue
 				}
 			}
 			// Only treat as array operation if it's not a real key
 			remaining := part[1:]
-			return handleArrayOperation(current, remaining)
+			return r.handleArrayOperation(current, remaining)
 		}
 
-		// Handle array index
-		if idx, err := strconv.Atoi(part); err == nil {
-			switch v := current.(type) {
-			case []interface{}:
-				if idx < 0 || idx >= len(v) {
-					return Result{Type: Null}
-				}
-				current = v[idx]
-				continue
-			default:
-				return Result{Type: Null}
-			}
-		}
-
-		// Handle map access
-		switch v := current.(type) {
-		case map[string]interface{}:
-			val, exists := v[part]
-			if !exists {
-				return Result{Type: Null}
-			}
-			current = val
-		case map[interface{}]interface{}:
-			val, exists := v[part]
-			if !exists {
-				return Result{Type: Null}
-			}
-			current = val
-		default:
+		// Handle array index and map access
+		next, ok := r.stepKey(current, part)
+		if !ok {
 			return Result{Type: Null}
 		}
+		current = next
 	}
 
-	return makeResult(current)
+	return r.result(current)
 }
 
-// handleArrayQuery handles queries like #(key=value)
-func handleArrayQuery(current interface{}, query string) Result {
-	arr, ok := current.([]interface{})
-	if !ok {
-		return Result{Type: Null}
-	}
-
-	// Parse the query - support various operators
-	var key, operator, value string
-
-	// Try different operators in order of precedence
-	operators := []string{">=", "<=", "!=", ">", "<", "="}
-	for _, op := range operators {
-		if strings.Contains(query, op) {
-			parts := strings.SplitN(query, op, 2)
-			if len(parts) == 2 {
-				key = strings.TrimSpace(parts[0])
-				operator = op
-				value = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
-				break
-			}
-		}
-	}
-
-	// If no operator found, assume equality
-	if operator == "" {
-		if strings.Contains(query, "=") {
-			parts := strings.SplitN(query, "=", 2)
-			if len(parts) == 2 {
-				key = strings.TrimSpace(parts[0])
-				operator = "="
-				value = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
-			}
-		} else {
-			return Result{Type: Null}
-		}
-	}
+// stepKey resolves a single path component that is an array index or a
+// map key against current.
+func stepKey(current interface{}, part string) (interface{}, bool) {
+	return (&resolver{}).stepKey(current, part)
+}
 
-	for _, item := range arr {
-		if obj, ok := item.(map[string]interface{}); ok {
-			if val, exists := obj[key]; exists {
-				if matchesCondition(val, operator, value) {
-					return makeResult(item)
-				}
-			}
-		} else {
-			// Handle direct array of values (e.g., [1, 2, 3, 4, 5])
-			if key == "" && operator != "" {
-				if matchesCondition(item, operator, value) {
-					return makeResult(item)
-				}
-			}
+// stepKey resolves a single path component that is an array index or a
+// map key against current. Components that look like indices are keys
+// when current is a map.
+func (r *resolver) stepKey(current interface{}, part string) (interface{}, bool) {
+	switch v := current.(type) {
+	case []interface{}:
+		idx, ok := r.index(part, len(v))
+		if !ok {
+			return nil, false
 		}
+		return v[idx], true
+	case map[string]interface{}:
+		val, exists := v[unescapeKey(part)]
+		return val, exists
+	case map[interface{}]interface{}:
+		return r.lookupKey(v, unescapeKey(part))
 	}
-
-	return Result{Type: Null}
+	return nil, false
 }
 
-// matchesCondition checks if a value matches the given condition
-func matchesCondition(val interface{}, operator, expected string) bool {
-	valStr := fmt.Sprintf("%v", val)
+// ErrAmbiguousKey is reported by GetE and GetWithOptions when a path
+// component matches both a string key and a key of another type written
+// the same way, as 1 and "1" in {0x1: a, "1": b}. The string key is used,
+// so the error is a warning: the Result is still valid.
+var ErrAmbiguousKey = errors.New("gyaml: ambiguous key")
 
-	switch operator {
-	case "=":
-		return valStr == expected
-	case "!=":
-		return valStr != expected
-	case ">":
-		return compareNumbers(val, expected) > 0
-	case "<":
-		return compareNumbers(val, expected) < 0
-	case ">=":
-		return compareNumbers(val, expected) >= 0
-	case "<=":
-		return compareNumbers(val, expected) <= 0
-	default:
-		return false
-	}
-}
-
-// compareNumbers compares two values as numbers, returns:
-// 1 if val > expected, -1 if val < expected, 0 if equal or not comparable
-func compareNumbers(val interface{}, expectedStr string) int {
-	// Convert val to float64
-	var valFloat float64
-	switch v := val.(type) {
-	case int:
-		valFloat = float64(v)
-	case int8, int16, int32, int64:
-		if i, err := strconv.ParseInt(fmt.Sprintf("%v", v), 10, 64); err == nil {
-			valFloat = float64(i)
-		} else {
-			return 0
+// lookupKey finds key in a map with keys of mixed types. A string key
+// equal to key takes precedence over a key of another type whose
+// canonical form is key, such as the integer 1 for "1". Among keys of
+// other types, the one that sorts first, as in ForEach, is used, so that
+// the result does not depend on the order of map iteration.
+func (r *resolver) lookupKey(m map[interface{}]interface{}, key string) (interface{}, bool) {
+	val, exact := m[key]
+	var best interface{}
+	found := false
+	for k, v := range m {
+		if _, isString := k.(string); isString || canonicalKey(k) != key {
+			continue
 		}
-	case uint, uint8, uint16, uint32, uint64:
-		if i, err := strconv.ParseUint(fmt.Sprintf("%v", v), 10, 64); err == nil {
-			valFloat = float64(i)
-		} else {
-			return 0
+		if exact {
+			r.fail(fmt.Errorf("%w: %q matches both a string key and the %T key %v; using the string key", ErrAmbiguousKey, key, k, k))
+			break
 		}
-	case float32:
-		valFloat = float64(v)
-	case float64:
-		valFloat = v
-	default:
-		// Try to parse as string
-		if f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64); err == nil {
-			valFloat = f
-		} else {
-			return 0
+		if !found || fmt.Sprintf("%T", k) < fmt.Sprintf("%T", best) {
+			best, val, found = k, v, true
 		}
 	}
-
-	// Convert expected to float64
-	expectedFloat, err := strconv.ParseFloat(expectedStr, 64)
-	if err != nil {
-		return 0
-	}
-
-	if valFloat > expectedFloat {
-		return 1
-	} else if valFloat < expectedFloat {
-		return -1
-	}
-	return 0
+	return val, exact || found
 }
 
 // handleArrayOperation handles operations like #.key (get all values of key from array elements)
 func handleArrayOperation(current interface{}, path string) Result {
+	return (&resolver{}).handleArrayOperation(current, path)
+}
+
+// handleArrayOperation handles operations like #.key (get all values of key from array elements)
+func (r *resolver) handleArrayOperation(current interface{}, path string) Result {
 	arr, ok := current.([]interface{})
 	if !ok {
 		return Result{Type: Null}
@@ -602,31 +1638,90 @@ func handleArrayOperation(current interface{}, path string) Result {
 
 	if path == "" {
 		// Return the whole array
-		return makeResult(arr)
+		return r.result(arr)
 	}
 
-	var results []interface{}
+	var results, emitted []interface{}
+	var nodes []*yaml.Node
+	synthesized, ordered := false, false
 	for _, item := range arr {
 		// For each item in the array, get the value at the specified path
-		itemResult := getByPath(item, path)
-		if itemResult.Exists() {
-			results = append(results, itemResult.Value())
+		itemResult := r.getByPath(item, path)
+		if itemResult.Exists() || itemResult.IsNull() {
+			// Counts are emitted with NumberFormat
+			value, synth := emittedValue(itemResult)
+			results = append(results, itemResult.nativeValue())
+			emitted = append(emitted, value)
+			synthesized = synthesized || synth
+			var node *yaml.Node
+			if itemResult.decoded != nil && itemResult.decoded.node != nil {
+				// Multipath objects come with the order of their keys
+				node, ordered = itemResult.decoded.node, true
+			}
+			nodes = append(nodes, node)
 		}
 	}
 
-	return makeResult(results)
+	result := r.result(results)
+	if synthesized {
+		raw, err := marshalYAML(emitted)
+		if err != nil {
+			return Result{Type: Null}
+		}
+		result = Result{Type: YAML, Raw: string(raw), decoded: &decodedValue{v: results, ref: r.ref}}
+	}
+	if ordered && result.decoded != nil {
+		result.decoded.node = sequenceNode(results, nodes)
+		if !synthesized {
+			result = withNodeRaw(result)
+		}
+	}
+	return result
 }
 
-// ForEachLine iterates through each line of a YAML document.
+// ForEachLine iterates through each line of a YAML document. It stops
+// at a line longer than DefaultMaxLineSize.
 func ForEachLine(yamlStr string, iterator func(line Result) bool) {
-	lines := strings.Split(yamlStr, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	_ = ForEachLineWithOptions(yamlStr, Options{}, iterator)
+}
+
+// ForEachLineWithOptions iterates through each line of a YAML document
+// like ForEachLine. Lines are read one at a time, so memory use is bounded
+// by opts.MaxLineSize, or DefaultMaxLineSize when it is not set, rather
+// than by the document; a longer line stops the iteration with an error
+// wrapping ErrLimit. A line holding a scalar longer than opts.MaxScalarSize stops it with an error
+// wrapping ErrLimit.
+func ForEachLineWithOptions(yamlStr string, opts Options, iterator func(line Result) bool) error {
+	max := opts.MaxLineSize
+	switch {
+	case max == 0:
+		max = DefaultMaxLineSize
+	case max < 0:
+		// Lines can't be longer than the document
+		max = len(yamlStr) + 1
+	}
+	scanner := bufio.NewScanner(strings.NewReader(yamlStr))
+	scanner.Buffer(nil, max)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		if !iterator(Parse(line)) {
-			return
+		result, err := parseDocument(line, opts)
+		if errors.Is(err, ErrLimit) {
+			return fmt.Errorf("gyaml: line %d: %w", n, err)
+		}
+		if !iterator(result) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("%w: line %d is longer than %d bytes: %v", ErrLimit, n+1, max, err)
 		}
+		return err
 	}
+	return nil
 }
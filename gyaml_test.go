@@ -1,6 +1,7 @@
 package gyaml
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -112,6 +113,44 @@ func TestValid(t *testing.T) {
 	}
 }
 
+func TestValidWithError(t *testing.T) {
+	if ok, err := ValidWithError(testYAML); !ok || err != nil {
+		t.Errorf("Expected YAML to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		yaml   string
+		line   int
+		column int
+		msg    string
+	}{
+		{"key: value\n  bad: indent\n", 2, 3, "mapping values are not allowed in this context"},
+		{"a: 1\na: 2\n", 2, 1, `mapping key "a" already defined at line 1`},
+		{"a:\n  b: 1\n c: 2\n", 2, 3, "did not find expected key"},
+		{"a: 'x", 0, 0, "found unexpected end of stream"},
+	}
+	for _, test := range tests {
+		ok, err := ValidWithError(test.yaml)
+		var serr *SyntaxError
+		if ok || !errors.As(err, &serr) {
+			t.Errorf("%q: expected a SyntaxError, got %v", test.yaml, err)
+			continue
+		}
+		if serr.Line != test.line || serr.Column != test.column || serr.Msg != test.msg {
+			t.Errorf("%q: unexpected error %d:%d %q", test.yaml, serr.Line, serr.Column, serr.Msg)
+		}
+		if serr.Err == nil || errors.Unwrap(err) != serr.Err {
+			t.Errorf("%q: expected the parser error to be kept", test.yaml)
+		}
+		if Valid(test.yaml) {
+			t.Errorf("%q: expected Valid to agree", test.yaml)
+		}
+	}
+	if _, err := ValidWithError("key: value\n  bad: indent\n"); err.Error() != "gyaml: line 2, column 3: mapping values are not allowed in this context" {
+		t.Errorf("Unexpected message %q", err)
+	}
+}
+
 func TestForEach(t *testing.T) {
 	result := Get(testYAML, "name")
 	count := 0
@@ -155,6 +194,35 @@ func TestTypes(t *testing.T) {
 	}
 }
 
+func TestTypePredicates(t *testing.T) {
+	doc := Parse("list: [1]\nmap: {a: 1}\nints: {1: a}\nflag: false\nyes: yes\nn: 1.5\ns: x\nnothing: ~\n")
+	tests := []struct {
+		path                                string
+		array, object, boolean, number, str bool
+	}{
+		{"list", true, false, false, false, false},
+		{"map", false, true, false, false, false},
+		{"ints", false, true, false, false, false},
+		{"flag", false, false, true, false, false},
+		{"yes", false, false, false, false, true},
+		{"n", false, false, false, true, false},
+		{"s", false, false, false, false, true},
+		{"nothing", false, false, false, false, false},
+		{"missing", false, false, false, false, false},
+		{"list.#", false, false, false, true, false},
+		{"map.@values", true, false, false, false, false},
+	}
+	for _, tt := range tests {
+		r := doc.Get(tt.path)
+		if r.IsArray() != tt.array || r.IsObject() != tt.object || r.IsBool() != tt.boolean || r.IsNumber() != tt.number || r.IsString() != tt.str {
+			t.Errorf("%s: got %v %v %v %v %v", tt.path, r.IsArray(), r.IsObject(), r.IsBool(), r.IsNumber(), r.IsString())
+		}
+	}
+	if !Parse("[a]").IsArray() || !Parse("a: 1").IsObject() || Parse("[a").IsArray() {
+		t.Errorf("Unexpected predicates for parsed documents")
+	}
+}
+
 func TestGetBytes(t *testing.T) {
 	yamlBytes := []byte(testYAML)
 	result := GetBytes(yamlBytes, "name.first")
@@ -162,3 +230,142 @@ func TestGetBytes(t *testing.T) {
 		t.Errorf("Expected 'Tom', got '%s'", result.String())
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	for _, doc := range []string{testYAML, "", "- a\n- b\n", "a: [1, 2"} {
+		want, got := Parse(doc), ParseBytes([]byte(doc))
+		if got.Type != want.Type || got.Raw != want.Raw || got.Get("name.first").String() != want.Get("name.first").String() {
+			t.Errorf("ParseBytes(%q) = %v, expected %v", doc, got, want)
+		}
+		if ValidBytes([]byte(doc)) != Valid(doc) {
+			t.Errorf("ValidBytes(%q) = %v, expected %v", doc, !Valid(doc), Valid(doc))
+		}
+	}
+	if ValidBytes(nil) != Valid("") {
+		t.Errorf("ValidBytes(nil) differs from Valid(\"\")")
+	}
+}
+
+func TestBytes(t *testing.T) {
+	doc := `
+icon: !!binary |
+  R0lGODlh
+  AQABAA==
+key: !!binary c2VjcmV0
+encoded: aGVsbG8=
+plain: hello world
+port: 8080
+blobs: [!!binary AAEC, !!binary AwQF]
+`
+	tests := []struct {
+		name string
+		r    Result
+		want []byte
+	}{
+		{"binary block", Get(doc, "icon"), []byte("GIF89a\x01\x00\x01\x00")},
+		{"binary scalar", Get(doc, "key"), []byte("secret")},
+		{"base64 string", Get(doc, "encoded"), []byte("hello")},
+		{"not base64", Get(doc, "plain"), nil},
+		{"number", Get(doc, "port"), nil},
+		{"array", Get(doc, "blobs"), nil},
+		{"element", Get(doc, "blobs.1"), []byte{3, 4, 5}},
+		{"from Array", Get(doc, "blobs").Array()[0], []byte{0, 1, 2}},
+		{"from Parse", Parse(doc).Get("key"), []byte("secret")},
+		{"from GetRef", GetRef(doc, "blobs").Get("0"), []byte{0, 1, 2}},
+		{"from a query", Get(doc, "blobs|@reverse|0"), nil},
+		{"missing", Get(doc, "missing"), nil},
+	}
+	for _, test := range tests {
+		got := test.r.Bytes()
+		if string(got) != string(test.want) || (got == nil) != (test.want == nil) {
+			t.Errorf("%s: Bytes() = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	// The String holds the bytes, and Raw the text as written
+	if key := Get(doc, "key"); key.String() != "secret" || key.Raw != "c2VjcmV0" {
+		t.Errorf("Unexpected binary scalar %q %q", key.String(), key.Raw)
+	}
+}
+
+func TestScalar(t *testing.T) {
+	doc := `
+friends:
+  - {first: Dale, age: 44, nets: [ig]}
+  - {last: Craig}
+when: 2024-01-15
+`
+	for _, c := range []struct {
+		name string
+		r    Result
+		typ  Type
+		str  string
+	}{
+		{"scalar", Get(doc, "friends.0.age"), Number, "44"},
+		{"projection with one hit", Get(doc, "friends.#.first"), String, "Dale"},
+		{"projected count", Get(doc, "friends.#.nets.#"), Number, "1"},
+		{"scalar document", Parse("42"), Number, "42"},
+		{"timestamp document", Parse("2024-01-15"), String, "2024-01-15"},
+		{"stream of one scalar", ParseStream("true"), True, "true"},
+		{"built array", ArrayOf("x"), String, "x"},
+		{"null document", Parse("~"), Null, ""},
+		{"projection with two hits", Get(doc, "friends.#.nets"), Null, ""},
+		{"object", Get(doc, "friends.0"), Null, ""},
+		{"array of an object", ArrayOf(Object()), Null, ""},
+		{"one-element array of an array", ArrayOf(ArrayOf(1)), Null, ""},
+		{"empty array", ArrayOf(), Null, ""},
+		{"missing", Get(doc, "missing"), Null, ""},
+	} {
+		got := c.r.Scalar()
+		if got.Type != c.typ || got.String() != c.str {
+			t.Errorf("%s: Scalar() = %#v, want %v %q", c.name, got, c.typ, c.str)
+		}
+	}
+
+	var lines []int64
+	ForEachLine("1\n2\n", func(line Result) bool {
+		lines = append(lines, line.Scalar().Int())
+		return true
+	})
+	if len(lines) != 2 || lines[0] != 1 || lines[1] != 2 {
+		t.Errorf("Expected scalar lines 1 and 2, got %v", lines)
+	}
+}
+
+func TestCount(t *testing.T) {
+	doc := `
+spec:
+  containers:
+    - {name: app, ports: [80, 443]}
+    - {name: sidecar}
+    - {name: proxy, ports: [8080]}
+  labels: {app: shop, tier: front}
+  replicas: 3
+  empty: []
+  none: ~
+`
+	tests := map[string]int{
+		"spec.containers":                  3,
+		"spec.labels":                      2,
+		"spec":                             5,
+		"spec.containers.#.ports":          2,
+		"spec.containers.0.ports":          2,
+		`spec.containers.#(name!="app")#`:  2,
+		"spec.containers.#.ports|@flatten": 3,
+		"spec.labels|@keys":                2,
+		"spec.empty":                       0,
+		"spec.replicas":                    0,
+		"spec.none":                        0,
+		"spec.missing":                     0,
+		"spec.containers.1.ports":          0,
+		"":                                 1,
+	}
+	for path, want := range tests {
+		if got := Count(doc, path); got != want {
+			t.Errorf("Count(%q) = %d, want %d", path, got, want)
+		}
+	}
+	if got := Count("", "a"); got != 0 {
+		t.Errorf("Expected 0 for an empty document, got %d", got)
+	}
+}
@@ -0,0 +1,48 @@
+// Package gyamltest provides assertions on gyaml Results for tests.
+//
+//	func TestConfig(t *testing.T) {
+//		gyamltest.AssertEqual(t, "{host: localhost, port: 5432}", gyaml.Get(config, "database"))
+//	}
+package gyamltest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yongPhone/gyaml"
+)
+
+// maxReported bounds the differences listed in a failure.
+const maxReported = 20
+
+// AssertEqual checks that actual holds the value written in expectedYAML,
+// as compared by gyaml.Diff: key order, quoting and number formatting do
+// not matter. On a mismatch it marks the test as failed and lists the
+// differing values path by path, relative to actual, and it returns
+// false. An empty expectedYAML expects a value that does not exist.
+func AssertEqual(t testing.TB, expectedYAML string, actual gyaml.Result) bool {
+	t.Helper()
+	expected, err := gyaml.GetE(expectedYAML, "")
+	if err != nil {
+		t.Errorf("gyamltest: expected value is not valid YAML: %v", err)
+		return false
+	}
+	diffs := gyaml.Diff(expected, actual)
+	if len(diffs) == 0 {
+		return true
+	}
+	var sb strings.Builder
+	sb.WriteString("gyamltest: ")
+	sb.WriteString(actual.GoString())
+	sb.WriteString(" differs from the expected value (expected != actual):")
+	for i, d := range diffs {
+		if i == maxReported {
+			sb.WriteString("\n    ...")
+			break
+		}
+		sb.WriteString("\n    ")
+		sb.WriteString(d.String())
+	}
+	t.Error(sb.String())
+	return false
+}
@@ -0,0 +1,80 @@
+package gyamltest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yongPhone/gyaml"
+)
+
+// recorder captures the failures reported to it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+const doc = `
+database:
+  host: localhost
+  port: 5432
+  replicas: [a, b]
+name: web
+`
+
+func TestAssertEqualPasses(t *testing.T) {
+	for _, c := range []struct {
+		expected string
+		path     string
+	}{
+		{"{port: 5432.0, replicas: [a, b], host: localhost}", "database"},
+		{`"web"`, "name"},
+		{"5432", "database.port"},
+		{"[a, b]", "database.replicas"},
+		{"", "missing"},
+	} {
+		r := &recorder{TB: t}
+		if !AssertEqual(r, c.expected, gyaml.Get(doc, c.path)) || len(r.errors) > 0 {
+			t.Errorf("AssertEqual(%q, %q) failed: %v", c.expected, c.path, r.errors)
+		}
+	}
+}
+
+func TestAssertEqualReportsDifferences(t *testing.T) {
+	r := &recorder{TB: t}
+	if AssertEqual(r, "{host: db, port: 5432, replicas: [a, c, d]}", gyaml.Get(doc, "database")) {
+		t.Fatal("Expected AssertEqual to fail")
+	}
+	if len(r.errors) != 1 {
+		t.Fatalf("Expected one failure, got %v", r.errors)
+	}
+	msg := r.errors[0]
+	for _, want := range []string{
+		`Path: "database"`,
+		`host: "db" != "localhost"`,
+		`replicas.1: "c" != "b"`,
+		`replicas.2: "d" != null`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected the failure to contain %q, got:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "\n    port") {
+		t.Errorf("Expected equal values to be left out, got:\n%s", msg)
+	}
+
+	r = &recorder{TB: t}
+	if AssertEqual(r, "a: [", gyaml.Get(doc, "name")) || len(r.errors) != 1 {
+		t.Errorf("Expected invalid YAML to fail the assertion, got %v", r.errors)
+	}
+}
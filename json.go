@@ -0,0 +1,172 @@
+package gyaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToJSON converts the first document of a YAML stream to JSON, as
+// Parse(yamlStr).JSON() does. The error is that of parsing, when the
+// YAML is not valid; an empty document converts to null.
+func ToJSON(yamlStr string) (string, error) {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil {
+		return "", newSyntaxError(yamlStr, err)
+	}
+	return doc.JSON(), nil
+}
+
+// JSON returns the value as compact JSON, for tools that only read JSON.
+// Values that JSON has no form for are written as follows:
+//
+//   - keys that are not strings, such as 1 or true, are written as
+//     strings, in their YAML form. A string key takes precedence over a
+//     key of another type written the same way, as in Map.
+//   - timestamps are strings, as written in the document, or in RFC 3339
+//     for values built in code.
+//   - binary values, tagged !!binary, are strings holding their base64
+//     text. Strings that are not valid UTF-8 are written the same way.
+//   - infinities and NaN are null, as JSON has no number for them.
+//
+// Keys are in the order of the document where it is known, as in
+// ForEach. A Result that does not exist gives null. Pass the text to
+// json.Indent for indented output.
+func (t Result) JSON() string {
+	var buf bytes.Buffer
+	switch t.Type {
+	case YAML:
+		d, ok := t.decode()
+		if !ok {
+			return "null"
+		}
+		writeJSON(&buf, d.v, d.node)
+	case String:
+		if t.Raw != "" {
+			// A !!binary scalar keeps its base64 text in Raw
+			writeJSONString(&buf, base64.StdEncoding.EncodeToString([]byte(t.Str)))
+		} else {
+			writeJSONString(&buf, t.Str)
+		}
+	default:
+		writeJSON(&buf, t.nativeValue(), nil)
+	}
+	return buf.String()
+}
+
+// writeJSON writes v, a decoded YAML value, as JSON to buf. node is the
+// node v was decoded from, or nil when unknown.
+func writeJSON(buf *bytes.Buffer, v interface{}, node *yaml.Node) {
+	if node != nil {
+		node = resolveAlias(node)
+	}
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		writeJSONFloat(buf, v, 64)
+	case float32:
+		writeJSONFloat(buf, float64(v), 32)
+	case synthNumber:
+		buf.WriteString(formatNumber(float64(v)))
+	case *big.Int:
+		buf.WriteString(v.String())
+	case string:
+		if node != nil && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!binary" || !utf8.ValidString(v) {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		writeJSONString(buf, v)
+	case time.Time:
+		if node != nil && node.Kind == yaml.ScalarNode {
+			writeJSONString(buf, node.Value)
+		} else {
+			writeJSONString(buf, v.Format(time.RFC3339Nano))
+		}
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, elem, elementNode(node, i, len(v)))
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys, nodes := orderedKeys(v, node)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, k)
+			buf.WriteByte(':')
+			writeJSON(buf, v[k], nodes[i])
+		}
+		buf.WriteByte('}')
+	case map[interface{}]interface{}:
+		keys, nodes := orderedAnyKeys(v, node)
+		// Keys written the same way are written once, with the value of
+		// the string key if there is one
+		names := make([]string, 0, len(keys))
+		at := make(map[string]int, len(keys))
+		for i, k := range keys {
+			name := canonicalKey(k)
+			j, seen := at[name]
+			switch _, isString := k.(string); {
+			case !seen:
+				at[name] = i
+				names = append(names, name)
+			case isString:
+				keys[j], nodes[j] = k, nodes[i]
+			}
+		}
+		buf.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			j := at[name]
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			writeJSON(buf, v[keys[j]], nodes[j])
+		}
+		buf.WriteByte('}')
+	default:
+		writeJSON(buf, buildValue(v), nil)
+	}
+}
+
+// writeJSONFloat writes a float as a JSON number, or null for infinities
+// and NaN.
+func writeJSONFloat(buf *bytes.Buffer, f float64, bitSize int) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		buf.WriteString("null")
+		return
+	}
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// writeJSONString writes s as a JSON string, leaving <, > and & as they
+// are.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	// Encode ends the value with a newline
+	buf.Truncate(buf.Len() - 1)
+}
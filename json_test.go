@@ -0,0 +1,92 @@
+package gyaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const jsonYAML = `
+name: web <prod>
+port: 8080
+big: 9007199254740993
+ratio: 0.5
+limits: [.inf, -.inf, .nan]
+enabled: yes
+ok: true
+missing: ~
+created: 2001-12-14 21:59:43.10 -5
+day: 2024-01-02
+icon: !!binary R0lGODlhAQABAA==
+codes: {404: not found, true: yes, 0x1: uno, "1": one}
+defaults: &d {timeout: 30, retries: 3}
+service:
+  <<: *d
+  zone: b
+items:
+  - {z: 1, a: 2}
+  - "text"
+`
+
+func TestToJSON(t *testing.T) {
+	got, err := ToJSON(jsonYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"web <prod>","port":8080,"big":9007199254740993,"ratio":0.5,` +
+		`"limits":[null,null,null],"enabled":"yes","ok":true,"missing":null,` +
+		`"created":"2001-12-14 21:59:43.10 -5","day":"2024-01-02","icon":"R0lGODlhAQABAA==",` +
+		`"codes":{"404":"not found","true":"yes","1":"one"},` +
+		`"defaults":{"timeout":30,"retries":3},"service":{"timeout":30,"retries":3,"zone":"b"},` +
+		`"items":[{"z":1,"a":2},"text"]}`
+	if got != want {
+		t.Errorf("ToJSON =\n%s\nwant\n%s", got, want)
+	}
+	if !json.Valid([]byte(got)) {
+		t.Errorf("ToJSON gave invalid JSON")
+	}
+
+	if got, err := ToJSON(""); err != nil || got != "null" {
+		t.Errorf("ToJSON of an empty document = %q, %v", got, err)
+	}
+	var serr *SyntaxError
+	if _, err := ToJSON("a: [1"); !errors.As(err, &serr) {
+		t.Errorf("Expected a SyntaxError, got %v", err)
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{Get(jsonYAML, "name"), `"web <prod>"`},
+		{Get(jsonYAML, "big"), "9007199254740993"},
+		{Get(jsonYAML, "ratio"), "0.5"},
+		{Get(jsonYAML, "limits.0"), "null"},
+		{Get(jsonYAML, "ok"), "true"},
+		{Get(jsonYAML, "missing"), "null"},
+		{Get(jsonYAML, "nothing"), "null"},
+		{Get(jsonYAML, "icon"), `"R0lGODlhAQABAA=="`},
+		{Get(jsonYAML, "items.0"), `{"z":1,"a":2}`},
+		{Get(jsonYAML, "items.#"), "2"},
+		{Get(jsonYAML, "items|@reverse"), `["text",{"z":1,"a":2}]`},
+		{Result{Type: YAML, Raw: "b: 1\na: [x]\n"}, `{"b":1,"a":["x"]}`},
+		{ArrayOf(1.5, map[interface{}]interface{}{2: "b", 1: "a"}), `[1.5,{"1":"a","2":"b"}]`},
+		{Object().SetKey("nested", map[string]int{"b": 2, "a": 1}), `{"nested":{"a":1,"b":2}}`},
+	}
+	for _, test := range tests {
+		if got := test.result.JSON(); got != test.want {
+			t.Errorf("JSON of %q = %s, want %s", test.result.Raw, got, test.want)
+		}
+	}
+}
+
+func TestJSONInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSON(&buf, []interface{}{"\xff\xfe", "ok"}, nil)
+	if got := buf.String(); got != `["//4=","ok"]` {
+		t.Errorf("Expected invalid UTF-8 as base64, got %s", got)
+	}
+}
@@ -0,0 +1,266 @@
+package gyaml
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minifiedYAML builds a single-line flow mapping of about size bytes.
+func minifiedYAML(size int) string {
+	var sb strings.Builder
+	sb.Grow(size + 64)
+	sb.WriteString("{")
+	for i := 0; sb.Len() < size; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("key")
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteString(": value")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func TestForEachLineLongLineBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a 64 MB document")
+	}
+	doc := "first: 1\n" + minifiedYAML(64<<20) + "last: 2\n"
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	var lines []string
+	err := ForEachLineWithOptions(doc, Options{MaxLineSize: 1 << 20}, func(line Result) bool {
+		lines = append(lines, line.Get("first").String())
+		return true
+	})
+
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrLimit) {
+		t.Fatalf("Expected a limit error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected the error to name the line, got %v", err)
+	}
+	if len(lines) != 1 || lines[0] != "1" {
+		t.Errorf("Expected the first line before the error, got %v", lines)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 8<<20 {
+		t.Errorf("Expected memory use bounded by the line limit, allocated %d bytes", alloc)
+	}
+}
+
+func TestForEachLineWithOptions(t *testing.T) {
+	doc := "a: 1\n# comment\n\n" + minifiedYAML(4096) + "b: 2\n"
+	count := 0
+	err := ForEachLineWithOptions(doc, Options{MaxLineSize: 8192}, func(line Result) bool {
+		count++
+		return true
+	})
+	if err != nil || count != 3 {
+		t.Errorf("Expected 3 lines and no error, got %d (%v)", count, err)
+	}
+
+	count = 0
+	ForEachLine(doc, func(line Result) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Errorf("Expected iteration to stop after 2 lines, got %d", count)
+	}
+}
+
+func TestMaxScalarSize(t *testing.T) {
+	big := strings.Repeat("x", 1<<20)
+	doc := "small: ok\nbig: " + big + "\n"
+
+	if _, err := GetWithOptions(doc, "small", Options{MaxScalarSize: 1 << 10}); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected a limit error, got %v", err)
+	}
+	r, err := GetWithOptions(doc, "big", Options{MaxScalarSize: 2 << 20})
+	if err != nil || len(r.String()) != len(big) {
+		t.Errorf("Expected the scalar under the limit, got %d bytes (%v)", len(r.String()), err)
+	}
+	err = ForEachLineWithOptions(doc, Options{MaxScalarSize: 1 << 10}, func(line Result) bool {
+		return true
+	})
+	if !errors.Is(err, ErrLimit) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected a limit error on line 2, got %v", err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	doc := "a:\n  b:\n    - [1, 2]\nc: 1\n"
+	if _, err := GetE(doc, "c", WithMaxDepth(4)); err != nil {
+		t.Errorf("Expected a depth of 4 to pass, got %v", err)
+	}
+	_, err := GetE(doc, "c", WithMaxDepth(3))
+	if !errors.Is(err, ErrLimit) || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected a limit error on line 3, got %v", err)
+	}
+	if r := GetWith(doc, "c", WithMaxDepth(3)); r.Exists() {
+		t.Errorf("Expected Null past the depth limit, got %q", r.Raw)
+	}
+	if r := ParseWith("42", WithMaxDepth(1)); !r.Exists() {
+		t.Errorf("Expected scalars to have no depth, got %q", r.Raw)
+	}
+}
+
+// laughsYAML builds a "billion laughs" document of the given number of
+// levels, each an array of nine aliases of the one before.
+func laughsYAML(levels int) string {
+	var sb strings.Builder
+	sb.WriteString("l0: &l0 [lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 1; i <= levels; i++ {
+		prev := "*l" + strconv.Itoa(i-1)
+		sb.WriteString("l" + strconv.Itoa(i) + ": &l" + strconv.Itoa(i) + " [")
+		sb.WriteString(strings.TrimSuffix(strings.Repeat(prev+", ", 9), ", "))
+		sb.WriteString("]\n")
+	}
+	return sb.String()
+}
+
+func TestMaxAliasExpansion(t *testing.T) {
+	doc := laughsYAML(2)
+	// 9 aliases of l0 (10 values each) in l1, 9 of l1 (91 each) in l2
+	if _, err := ParseWithOptions(doc, Options{MaxAliasExpansion: 909}); err != nil {
+		t.Errorf("Expected 909 values to pass, got %v", err)
+	}
+	_, err := ParseWithOptions(doc, Options{MaxAliasExpansion: 908})
+	if !errors.Is(err, ErrLimit) || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected a limit error on line 3, got %v", err)
+	}
+
+	// The check does not expand the aliases itself
+	doc = laughsYAML(30)
+	r, err := GetWithOptions(doc, "l0.0", Options{MaxAliasExpansion: 1 << 20})
+	if !errors.Is(err, ErrLimit) || r.Exists() {
+		t.Errorf("Expected a limit error, got %v", err)
+	}
+	if _, err := GetE(streamYAML+"---\n"+doc, "@3.l0.0", WithMaxAliasExpansion(1000)); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected the limit to apply to the documents of a stream, got %v", err)
+	}
+	if r := GetWith("a: &a 1\nb: *a\n", "b", WithMaxAliasExpansion(1)); r.Int() != 1 {
+		t.Errorf("Expected a single alias to pass, got %s", r.GoString())
+	}
+}
+
+func TestMaxDocumentSize(t *testing.T) {
+	doc := "a: 1\nb: 2\n"
+	if r, err := ParseWithOptions(doc, Options{MaxDocumentSize: len(doc)}); err != nil || r.Get("b").Int() != 2 {
+		t.Errorf("Expected the document to pass, got %v", err)
+	}
+	if _, err := GetE(doc, "a", WithMaxDocumentSize(len(doc)-1)); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected a limit error, got %v", err)
+	}
+	if _, err := GetE(doc+"---\nc: 3\n", "@1.c", WithMaxDocumentSize(len(doc))); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected the limit to apply to the whole stream, got %v", err)
+	}
+	if r, err := ParseWithOptions("a: [", Options{}); r.Exists() || err == nil {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestMaxScalarSizeBeforeParse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a 16 MB document")
+	}
+	doc := "small: ok\nbig: " + strings.Repeat("x", 16<<20) + "\n"
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	_, err := GetWithOptions(doc, "small", Options{MaxScalarSize: 1 << 20})
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrLimit) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Expected a limit error on line 2, got %v", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Errorf("Expected the scalar to be rejected before parsing, allocated %d bytes", alloc)
+	}
+	if _, err := GetE("a: 1\n---\n"+doc, "@0.a", WithMaxScalarSize(1<<20)); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected the limit to apply to the documents of a stream, got %v", err)
+	}
+	// Get reads only the first document
+	if r, err := GetWithOptions("a: 1\n---\n"+doc, "a", Options{MaxScalarSize: 1 << 20}); err != nil || r.Int() != 1 {
+		t.Errorf("Expected the first document to pass, got %v", err)
+	}
+}
+
+func TestScanScalarsAgreesWithParse(t *testing.T) {
+	// The scan before parsing never rejects a document holding no scalar
+	// longer than the limit
+	docs := append([]string{
+		"a: \"\\x41\\x42\\u0043\\U00000044\\L\\P\"\n",
+		"a: &anchor-with-a-long-name x\nb: *anchor-with-a-long-name\n",
+		"a: !<tag:yaml.org,2002:str> x\nb: !!str y\nc: !local z\n",
+		"a: x # a comment much longer than any scalar\n# another long comment line\n",
+		"%YAML 1.1\n%TAG !e! tag:example.com,2000:app/\n---\na: !e!foo x\n...\n",
+		"\ufeffa: b\n",
+		"a: |+2\n    indented\n\n",
+		"a: >-\n  folded\n  text\nb: \"quoted\n  across # lines\"\n",
+		"{a: [b, c d], e: {f: g}}\n",
+		"- 'it''s'\n- \"tab\\there\"\n- http://example.com/path?q=1#frag\n",
+		"a: \"x y\u2028z\"\n",
+		"a:    spaced   words   \t\nb: c\r\nd: e\r\n",
+	}, contractDocs...)
+	for _, doc := range docs {
+		root, err := parseNode([]byte(doc))
+		if err != nil || root == nil {
+			t.Fatalf("%q: %v", doc, err)
+		}
+		longest := 0
+		walkEvents(root, func(kind EventKind, n *yaml.Node) bool {
+			if (kind == Key || kind == Scalar) && len(n.Value) > longest {
+				longest = len(n.Value)
+			}
+			return true
+		})
+		if err := scanScalars(doc, longest, false); err != nil {
+			t.Errorf("%q: %v", doc, err)
+		}
+		if _, err := GetWithOptions(doc, "a", Options{MaxScalarSize: longest}); err != nil {
+			t.Errorf("%q: %v", doc, err)
+		}
+	}
+}
+
+func TestForEachLineDefaultLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a document with a line longer than DefaultMaxLineSize")
+	}
+	doc := "a: 1\nlong: " + strings.Repeat("x", DefaultMaxLineSize) + "\nb: 2\n"
+	count := 0
+	err := ForEachLineWithOptions(doc, Options{}, func(line Result) bool {
+		count++
+		return true
+	})
+	if !errors.Is(err, ErrLimit) || !strings.Contains(err.Error(), "line 2") || count != 1 {
+		t.Errorf("Expected a limit error on line 2 after 1 line, got %d (%v)", count, err)
+	}
+	count = 0
+	ForEachLine(doc, func(line Result) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("Expected ForEachLine to stop at the long line, got %d lines", count)
+	}
+	count = 0
+	err = ForEachLineWithOptions(doc, Options{MaxLineSize: -1}, func(line Result) bool {
+		count++
+		return true
+	})
+	if err != nil || count != 3 {
+		t.Errorf("Expected 3 lines without a limit, got %d (%v)", count, err)
+	}
+}
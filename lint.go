@@ -0,0 +1,177 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintKind identifies a problem reported by Lint.
+type LintKind int
+
+const (
+	// LintSyntax is a document that is not valid YAML
+	LintSyntax LintKind = iota
+	// LintDuplicateKey is a key written more than once in the same
+	// object
+	LintDuplicateKey
+	// LintTabIndent is a line indented with a tab, which YAML does not
+	// allow
+	LintTabIndent
+	// LintAmbiguousScalar is a plain scalar read as a string, such as yes
+	// or 1:20, that YAML 1.1 parsers read as a boolean or a number
+	LintAmbiguousScalar
+)
+
+// String returns the name of the kind.
+func (k LintKind) String() string {
+	switch k {
+	case LintSyntax:
+		return "syntax"
+	case LintDuplicateKey:
+		return "duplicate-key"
+	case LintTabIndent:
+		return "tab-indent"
+	case LintAmbiguousScalar:
+		return "ambiguous-scalar"
+	}
+	return "LintKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// LintIssue is a problem found by Lint.
+type LintIssue struct {
+	// Kind is the kind of problem
+	Kind LintKind
+	// Line and Column give the position of the problem, counting from 1,
+	// or 0 when it is not known
+	Line   int
+	Column int
+	// Msg describes the problem
+	Msg string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Kind, i.Msg)
+}
+
+// Lint reports the common mistakes in a YAML document, or in every
+// document of a "---" separated stream, in the order of their positions:
+// keys repeated in an object, lines indented with tabs, and plain scalars
+// that YAML 1.1 parsers read as booleans or numbers. A document that
+// cannot be parsed gets a LintSyntax issue, along with the tabs found in
+// the stream, which are a frequent cause, and the issues of the documents
+// before it:
+//
+//	for _, issue := range gyaml.Lint(doc) {
+//		fmt.Printf("config.yaml:%s\n", issue)
+//	}
+//
+// Valid rejects repeated keys too, but Lint reports every one of them.
+// It returns nil when nothing is found.
+func Lint(yamlStr string) []LintIssue {
+	var issues []LintIssue
+	blockLines := map[int]bool{}
+	dec := yaml.NewDecoder(strings.NewReader(yamlStr))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			serr := newSyntaxError(yamlStr, err)
+			issues = append(issues, LintIssue{Kind: LintSyntax, Line: serr.Line, Column: serr.Column, Msg: serr.Msg})
+			break
+		}
+		for line := range blockScalarLines(yamlStr, &doc) {
+			blockLines[line] = true
+		}
+		issues = lintNode(&doc, issues)
+	}
+	issues = append(issues, lintTabs(yamlStr, blockLines)...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// lintTabs reports the lines of src indented with a tab, except those in
+// skip, which hold the content of block scalars.
+func lintTabs(src string, skip map[int]bool) []LintIssue {
+	var issues []LintIssue
+	for n, line := range strings.Split(src, "\n") {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if tab := strings.IndexByte(indent, '\t'); tab >= 0 && !skip[n+1] && strings.TrimSpace(line) != "" {
+			issues = append(issues, LintIssue{Kind: LintTabIndent, Line: n + 1, Column: tab + 1, Msg: "tab used for indentation"})
+		}
+	}
+	return issues
+}
+
+// blockScalarLines returns the lines of src that hold the content of the
+// | and > block scalars under node.
+func blockScalarLines(src string, node *yaml.Node) map[int]bool {
+	lines := map[int]bool{}
+	index := newLineIndex(src)
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			// The content starts on the line after the indicator
+			span := index.span(n)
+			for i := 1; i <= strings.Count(src[span.Start:span.End], "\n"); i++ {
+				lines[n.Line+i] = true
+			}
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(node)
+	return lines
+}
+
+// lintNode appends the repeated keys and ambiguous scalars under node to
+// issues. Aliases are not followed, so that anchored values are reported
+// once.
+func lintNode(node *yaml.Node, issues []LintIssue) []LintIssue {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Style == 0 && node.Tag == "!!str" {
+			switch {
+			case isOldBool(node.Value):
+				issues = append(issues, LintIssue{Kind: LintAmbiguousScalar, Line: node.Line, Column: node.Column,
+					Msg: fmt.Sprintf("%s is a string, but a boolean in YAML 1.1; quote it to keep it a string", node.Value)})
+			case base60Float.MatchString(node.Value):
+				issues = append(issues, LintIssue{Kind: LintAmbiguousScalar, Line: node.Line, Column: node.Column,
+					Msg: fmt.Sprintf("%s is a string, but a number in YAML 1.1; quote it to keep it a string", node.Value)})
+			}
+		}
+	case yaml.MappingNode:
+		seen := map[string]*yaml.Node{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || isMergeKey(key) {
+				continue
+			}
+			id := key.ShortTag() + ":" + key.Value
+			if first, ok := seen[id]; ok {
+				issues = append(issues, LintIssue{Kind: LintDuplicateKey, Line: key.Line, Column: key.Column,
+					Msg: fmt.Sprintf("key %q already defined at line %d", key.Value, first.Line)})
+			} else {
+				seen[id] = key
+			}
+		}
+	}
+	for _, child := range node.Content {
+		issues = lintNode(child, issues)
+	}
+	return issues
+}
@@ -0,0 +1,64 @@
+package gyaml
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	doc := `name: web
+name: api
+deploy:
+  enabled: yes
+  window: 1:30
+  replicas: 3
+  replicas: 4
+on: push
+quoted: "no"
+script: |
+  echo one
+  	echo two
+base: &base {a: 1}
+copy:
+  <<: *base
+  <<: *base
+`
+	want := []string{
+		`2:1: duplicate-key: key "name" already defined at line 1`,
+		"4:12: ambiguous-scalar: yes is a string, but a boolean in YAML 1.1; quote it to keep it a string",
+		"5:11: ambiguous-scalar: 1:30 is a string, but a number in YAML 1.1; quote it to keep it a string",
+		`7:3: duplicate-key: key "replicas" already defined at line 6`,
+		"8:1: ambiguous-scalar: on is a string, but a boolean in YAML 1.1; quote it to keep it a string",
+	}
+	issues := Lint(doc)
+	if got := fmt.Sprint(issues); got != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if issues := Lint(testYAML); issues != nil {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+
+	// A tab in the indentation makes the document invalid
+	issues = Lint("app:\n\tname: web\n")
+	if len(issues) != 2 || issues[0].Kind != LintTabIndent || issues[0].Line != 2 || issues[0].Column != 1 ||
+		issues[1].Kind != LintSyntax {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+	// Every document of a stream is linted
+	issues = Lint("a: 1\n---\nb: 1\nb: 2\n")
+	if got := fmt.Sprint(issues); got != `[4:1: duplicate-key: key "b" already defined at line 3]` {
+		t.Errorf("Unexpected issues %v", got)
+	}
+	issues = Lint("a: yes\n---\nb: [\n")
+	if len(issues) != 2 || issues[0].Kind != LintAmbiguousScalar || issues[0].Line != 1 ||
+		issues[1].Kind != LintSyntax || issues[1].Line < 3 {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+	if issues := Lint("a: 1\n---\nb: [\n"); len(issues) != 1 || issues[0].Kind != LintSyntax {
+		t.Errorf("Expected a syntax error in the second document, got %v", issues)
+	}
+	if s := LintKind(9).String(); s != "LintKind(9)" {
+		t.Errorf("Unexpected name %s", s)
+	}
+}
@@ -0,0 +1,271 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Loader loads a configuration from YAML files layered over each other,
+// such as a base file and the overlay of an environment, and from
+// environment variables:
+//
+//	loader := gyaml.Loader{
+//		Files:       []string{"config.yaml", "config." + env + ".yaml", "config.local.yaml"},
+//		SkipMissing: true,
+//		EnvPrefix:   "APP_",
+//	}
+//	doc, err := loader.Load()
+//	port := doc.Get("server.port").Int()
+//
+// Each file is merged over the ones before it: objects are merged key by
+// key, all the way down, while arrays and scalars, null among them,
+// replace the value below them. Keys keep the order they are first
+// written in, and values their text, so that timestamps, large integers
+// and tagged values read as they do in their file. Only the first
+// document of a file is read, with its anchors and merge keys resolved,
+// and files without one are skipped.
+type Loader struct {
+	// Files are the files to read, in order, each merged over the ones
+	// before it
+	Files []string
+	// FS, when set, is the file system the files are read from, such as
+	// an embed.FS, instead of that of the operating system
+	FS fs.FS
+	// SkipMissing skips the files that do not exist, for overlays that
+	// only some environments have, instead of failing
+	SkipMissing bool
+	// EnvPrefix, when set, lets environment variables starting with it
+	// override the values of the files. The rest of the name of a
+	// variable is the path of the value in upper case, with the dots
+	// and any character other than a letter or digit written as
+	// underscores: with the prefix APP_, APP_DATABASE_MAX_CONNS sets
+	// database.max-conns or database.max_conns, and APP_SERVERS_0_PORT
+	// sets servers.0.port. Only values held by the files can be set, as
+	// a name could stand for several paths otherwise, and values that
+	// are not arrays or objects, which includes nulls left to be filled
+	// in; a variable standing for several of them sets them all. The
+	// value of a variable is read as a YAML scalar, so that 5432 is a
+	// number and true a boolean, or as a flow array or object when it
+	// starts with [ or {, and as a string when it reads as nothing else.
+	EnvPrefix string
+	// Environ is the environment, in the form of os.Environ, which is
+	// read when Environ is nil
+	Environ []string
+	// Options are those of ParseDocument, applied to the merged
+	// document, and the limits among them to each file
+	Options []Option
+}
+
+// Load reads the files, merges them and applies the environment
+// variables, returning the result as a Document. Without any file, the
+// document is empty. The error names the file or variable that could not
+// be read or applied.
+func (l Loader) Load() (*Document, error) {
+	opts := applyOptions(l.Options)
+	var merged *yaml.Node
+	for _, name := range l.Files {
+		text, err := l.readFile(name)
+		if l.SkipMissing && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gyaml: Load: %w", err)
+		}
+		doc, err := parseDocument(text, opts)
+		if err != nil {
+			if !errors.Is(err, ErrLimit) {
+				err = newSyntaxError(text, err)
+			}
+			return nil, fmt.Errorf("gyaml: Load: %s: %w", name, err)
+		}
+		if doc.decoded != nil && doc.decoded.v != nil && doc.decoded.node != nil {
+			merged = mergeNodes(merged, expandNode(doc.decoded.node))
+		}
+	}
+
+	text := ""
+	if merged != nil {
+		raw, err := yaml.Marshal(merged)
+		if err != nil {
+			return nil, fmt.Errorf("gyaml: Load: %w", err)
+		}
+		text = string(raw)
+	}
+	if l.EnvPrefix != "" {
+		var err error
+		if text, err = l.applyEnv(text); err != nil {
+			return nil, err
+		}
+	}
+	return ParseDocument(text, l.Options...)
+}
+
+// readFile returns the text of a file, from FS when it is set.
+func (l Loader) readFile(name string) (string, error) {
+	var data []byte
+	var err error
+	if l.FS != nil {
+		data, err = fs.ReadFile(l.FS, name)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	return string(data), err
+}
+
+// applyEnv returns the document with the values named by the environment
+// variables starting with EnvPrefix set.
+func (l Loader) applyEnv(text string) (string, error) {
+	environ := l.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	var paths map[string][]string
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, l.EnvPrefix) || name == l.EnvPrefix {
+			continue
+		}
+		if paths == nil {
+			paths = envPaths(text)
+		}
+		for _, path := range paths[name[len(l.EnvPrefix):]] {
+			var err error
+			if text, err = Set(text, path, envValue(value)); err != nil {
+				return "", fmt.Errorf("gyaml: Load: %s: %w", name, err)
+			}
+		}
+	}
+	return text, nil
+}
+
+// envPaths returns the paths of the values of a document that are not
+// arrays or objects, null among them, by the name of the environment
+// variable that stands for them, without its prefix. The paths of each
+// name are sorted.
+func envPaths(text string) map[string][]string {
+	paths := map[string][]string{}
+	doc, err := parseDocument(text, Options{})
+	if err != nil || doc.decoded == nil {
+		return paths
+	}
+	walkTree(nil, doc.decoded.v, doc.decoded.node, func(e *walkEntry) bool {
+		switch e.value.(type) {
+		case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+			return true
+		}
+		segs := make([]string, len(e.parts))
+		for i, part := range e.parts {
+			segs[i] = unescapeKey(part)
+		}
+		name := strings.Map(func(c rune) rune {
+			switch {
+			case c >= 'a' && c <= 'z':
+				return c - 'a' + 'A'
+			case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+				return c
+			}
+			return '_'
+		}, strings.Join(segs, "."))
+		paths[name] = append(paths[name], e.path())
+		return true
+	})
+	for _, p := range paths {
+		sort.Strings(p)
+	}
+	return paths
+}
+
+// envValue reads the value of an environment variable as a YAML scalar,
+// or a flow array or object when it starts with [ or {, and as a string
+// otherwise.
+func envValue(s string) interface{} {
+	var v interface{}
+	if err := decodeYAML([]byte(s), &v); err != nil {
+		return s
+	}
+	switch v.(type) {
+	case nil:
+		if t := strings.TrimSpace(s); t == "~" || strings.EqualFold(t, "null") {
+			return nil
+		}
+		// Empty values and comments
+		return s
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		if t := strings.TrimSpace(s); strings.HasPrefix(t, "[") || strings.HasPrefix(t, "{") {
+			return v
+		}
+		return s
+	}
+	return v
+}
+
+// expandNode returns a copy of the tree under node with its aliases
+// replaced by copies of the values they refer to, and its merge keys by
+// the entries they bring in, as in visitEntries: an entry written in the
+// mapping takes precedence over a merged one, and among merged ones the
+// first. Anchors are dropped, as nothing refers to them any more.
+func expandNode(node *yaml.Node) *yaml.Node {
+	node = resolveAlias(node)
+	c := *node
+	c.Anchor = ""
+	c.Content = nil
+	switch node.Kind {
+	case yaml.MappingNode:
+		at := map[string]int{}
+		visitEntries(node, func(key, value *yaml.Node) {
+			if key.Kind == yaml.ScalarNode {
+				id := key.ShortTag() + ":" + key.Value
+				if _, seen := at[id]; seen {
+					return
+				}
+				at[id] = len(c.Content) + 1
+			}
+			c.Content = append(c.Content, expandNode(key), expandNode(value))
+		})
+		visitOverrides(node, func(key, value *yaml.Node) {
+			if i, ok := at[key.ShortTag()+":"+key.Value]; ok {
+				c.Content[i] = expandNode(value)
+			}
+		})
+	default:
+		for _, child := range node.Content {
+			c.Content = append(c.Content, expandNode(child))
+		}
+	}
+	return &c
+}
+
+// mergeNodes merges overlay over base, both trees without aliases:
+// mappings key by key, all the way down, with new keys added after those
+// of base, while any other node of overlay replaces base. base is updated
+// in place.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil || base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+	at := make(map[string]int, len(base.Content)/2)
+	for i := 0; i+1 < len(base.Content); i += 2 {
+		if key := base.Content[i]; key.Kind == yaml.ScalarNode {
+			at[key.ShortTag()+":"+key.Value] = i + 1
+		}
+	}
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if key.Kind == yaml.ScalarNode {
+			id := key.ShortTag() + ":" + key.Value
+			if j, ok := at[id]; ok {
+				base.Content[j] = mergeNodes(base.Content[j], value)
+				continue
+			}
+			at[id] = len(base.Content) + 1
+		}
+		base.Content = append(base.Content, key, value)
+	}
+	return base
+}
@@ -0,0 +1,239 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var loaderFiles = fstest.MapFS{
+	"config.yaml": {Data: []byte(`
+defaults: &defaults
+  timeout: 30s
+server:
+  host: 0.0.0.0
+  port: 8080
+  tls: {enabled: false, cert: /etc/cert.pem}
+database:
+  <<: *defaults
+  host: localhost
+  max-conns: 10
+  password: ~
+servers:
+  - {name: web, port: 80}
+  - {name: api, port: 81}
+features: [a, b]
+`)},
+	"config.production.yaml": {Data: []byte(`
+server:
+  port: 443
+  tls: {enabled: true}
+database:
+  host: db.internal
+features: [c]
+debug: null
+`)},
+	"empty.yaml":  {Data: []byte("# nothing yet\n")},
+	"scalar.yaml": {Data: []byte("just text\n")},
+	"broken.yaml": {Data: []byte("a: [1\n")},
+}
+
+func TestLoader(t *testing.T) {
+	doc, err := Loader{
+		Files: []string{"config.yaml", "empty.yaml", "config.production.yaml", "config.local.yaml"},
+		FS:    loaderFiles, SkipMissing: true,
+	}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"server.host":        "0.0.0.0",
+		"server.port":        "443",
+		"server.tls.enabled": "true",
+		"server.tls.cert":    "/etc/cert.pem",
+		"database.host":      "db.internal",
+		"database.timeout":   "30s",
+		"database.max-conns": "10",
+		"servers.1.name":     "api",
+		"features":           "[c]\n",
+		"defaults.timeout":   "30s",
+		"database.password":  "",
+		"debug":              "",
+		"server.tls|@keys|#": "2",
+	}
+	for path, want := range tests {
+		if got := doc.Get(path).String(); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+	if !doc.Get("debug").IsNull() || doc.Get("server.port").Type != Number {
+		t.Errorf("Unexpected types of debug and server.port")
+	}
+
+	// Later files replace values of other types whole
+	doc, err = Loader{Files: []string{"config.yaml", "scalar.yaml"}, FS: loaderFiles}.Load()
+	if err != nil || doc.Root().Value() != "just text" {
+		t.Errorf("Unexpected %v, %v", doc.Root(), err)
+	}
+	doc, err = Loader{}.Load()
+	if err != nil || doc.Root().Exists() {
+		t.Errorf("Expected an empty document, got %v, %v", doc.Root(), err)
+	}
+}
+
+func TestLoaderKeepsText(t *testing.T) {
+	files := fstest.MapFS{
+		"base.yaml": {Data: []byte(`
+zeta: 1
+alpha:
+  d: 2001-12-14
+  big: 123456789012345678901234567890
+  blob: !!binary aGVsbG8=
+mid: {y: 1, x: 2}
+`)},
+		"overlay.yaml": {Data: []byte(`
+mid: {w: 3, y: 4}
+beta: 2002-01-01T10:00:00Z
+alpha:
+  big: 123456789012345678901234567891
+`)},
+	}
+	doc, err := Loader{Files: []string{"base.yaml", "overlay.yaml"}, FS: files}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(doc.Root().Keys(), ","); got != "zeta,alpha,mid,beta" {
+		t.Errorf("Expected the keys in file order, got %s", got)
+	}
+	if got := strings.Join(doc.Get("mid").Keys(), ","); got != "y,x,w" {
+		t.Errorf("Expected merged keys after those of the base, got %s", got)
+	}
+	tests := map[string]string{
+		"alpha.d":   "2001-12-14",
+		"alpha.big": "123456789012345678901234567891",
+		"beta":      "2002-01-01T10:00:00Z",
+		"mid.y":     "4",
+	}
+	for path, want := range tests {
+		if got := doc.Get(path).String(); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+	if blob := doc.Get("alpha.blob"); string(blob.Bytes()) != "hello" || blob.Raw != "aGVsbG8=" {
+		t.Errorf("Expected the binary value to stay binary, got %q %q", blob.Str, blob.Raw)
+	}
+	if got := doc.Get("alpha.big").BigInt(); got == nil || got.String() != "123456789012345678901234567891" {
+		t.Errorf("Unexpected %v", got)
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	_, err := Loader{Files: []string{"config.yaml", "missing.yaml"}, FS: loaderFiles}.Load()
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+	var serr *SyntaxError
+	_, err = Loader{Files: []string{"config.yaml", "broken.yaml"}, FS: loaderFiles}.Load()
+	if !errors.As(err, &serr) || !strings.HasPrefix(err.Error(), "gyaml: Load: broken.yaml: ") {
+		t.Errorf("Expected a syntax error naming the file, got %v", err)
+	}
+	_, err = Loader{Files: []string{"config.yaml"}, FS: loaderFiles, Options: []Option{WithMaxDepth(2)}}.Load()
+	if !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected the limits to apply to each file, got %v", err)
+	}
+}
+
+func TestLoaderFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	base, overlay := filepath.Join(dir, "base.yaml"), filepath.Join(dir, "overlay.yaml")
+	if err := os.WriteFile(base, []byte("a: {b: 1, c: 2}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(overlay, []byte("a: {c: 3}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Loader{Files: []string{base, overlay, filepath.Join(dir, "local.yaml")}, SkipMissing: true}.Load()
+	if err != nil || doc.Get("a.b").Int() != 1 || doc.Get("a.c").Int() != 3 {
+		t.Errorf("Unexpected %v, %v", doc.Root(), err)
+	}
+}
+
+func TestLoaderEnv(t *testing.T) {
+	doc, err := Loader{
+		Files:     []string{"config.yaml", "config.production.yaml"},
+		FS:        loaderFiles,
+		EnvPrefix: "APP_",
+		Environ: []string{
+			"APP_SERVER_PORT=9443",
+			"APP_SERVER_TLS_ENABLED=false",
+			"APP_DATABASE_MAX_CONNS=50",
+			"APP_DATABASE_PASSWORD=s3cret: really",
+			"APP_SERVERS_1_NAME=gateway",
+			"APP_FEATURES_0=[x, y]",
+			"APP_DATABASE_HOST=",
+			"APP_NEW_KEY=ignored",
+			"APP_SERVER=ignored",
+			"APP_=ignored",
+			"OTHER_SERVER_PORT=1",
+			"PATH=/bin",
+		},
+	}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]interface{}{
+		"server.port":        int64(9443),
+		"server.tls.enabled": false,
+		"database.max-conns": int64(50),
+		"database.password":  "s3cret: really",
+		"servers.1.name":     "gateway",
+		"servers.0.name":     "web",
+		"features.0":         []interface{}{"x", "y"},
+		"database.host":      "",
+		"new.key":            nil,
+		"server.host":        "0.0.0.0",
+	}
+	for path, want := range tests {
+		if got := doc.Get(path).Value(); fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", want) {
+			t.Errorf("%s: got %#v, want %#v", path, got, want)
+		}
+	}
+
+	// Without a prefix the environment is not read
+	doc, err = Loader{Files: []string{"config.yaml"}, FS: loaderFiles, Environ: []string{"SERVER_PORT=1"}}.Load()
+	if err != nil || doc.Get("server.port").Int() != 8080 {
+		t.Errorf("Unexpected %v, %v", doc.Get("server.port"), err)
+	}
+}
+
+func TestEnvValue(t *testing.T) {
+	tests := []struct {
+		s    string
+		want interface{}
+	}{
+		{"5432", 5432},
+		{"1.5", 1.5},
+		{"true", true},
+		{"hello world", "hello world"},
+		{"~", nil},
+		{"null", nil},
+		{"", ""},
+		{"# not a comment", "# not a comment"},
+		{"a: b", "a: b"},
+		{"- a", "- a"},
+		{"{a: 1}", map[string]interface{}{"a": 1}},
+		{"[1, 2]", []interface{}{1, 2}},
+		{"[unclosed", "[unclosed"},
+	}
+	for _, tt := range tests {
+		if got := envValue(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("envValue(%q) = %#v, want %#v", tt.s, got, tt.want)
+		}
+	}
+}
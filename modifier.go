@@ -0,0 +1,403 @@
+package gyaml
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// modifierFunc transforms a decoded value for a modifier, given the node
+// it was decoded from, or nil when unknown, and the text after the colon
+// of the modifier, if any. It returns false when the value has no result.
+type modifierFunc func(v interface{}, node *yaml.Node, arg string) (interface{}, bool)
+
+// modifiers holds the modifiers by name, without the @: the built-in ones
+// and those added by AddModifier, under modifiersMu.
+var modifiers = map[string]modifierFunc{
+	"this":    modThis,
+	"reverse": modReverse,
+	"sort":    modSort,
+	"flatten": modFlatten,
+	"keys":    modKeys,
+	"values":  modValues,
+	"sum":     modSum,
+	"avg":     modAvg,
+	"min":     modMin,
+	"max":     modMax,
+	"count":   modCount,
+}
+
+func init() {
+	// @group resolves paths, which look modifiers up, so it cannot be in
+	// the initializer of modifiers
+	modifiers["group"] = modGroup
+}
+
+// modifiersMu guards modifiers.
+var modifiersMu sync.RWMutex
+
+// AddModifier binds a custom modifier to a name, so that paths can use it
+// as @name, or @name:arg to pass it an argument:
+//
+//	gyaml.AddModifier("lower", func(yaml, arg string) string {
+//		return strings.ToLower(yaml)
+//	})
+//	gyaml.Get(doc, "servers.#.name|@lower")
+//
+// fn is given the value as a YAML document and the text after the colon,
+// or "" without one, and returns the YAML of the result. An empty or
+// invalid result does not exist. A built-in modifier of the same name is
+// replaced. AddModifier is safe for concurrent use with Get.
+func AddModifier(name string, fn func(yaml, arg string) string) {
+	modifiersMu.Lock()
+	defer modifiersMu.Unlock()
+	modifiers[name] = func(v interface{}, _ *yaml.Node, arg string) (interface{}, bool) {
+		raw, err := marshalYAML(v)
+		if err != nil {
+			return nil, false
+		}
+		out := fn(string(raw), arg)
+		if strings.TrimSpace(out) == "" {
+			return nil, false
+		}
+		var result interface{}
+		if err := decodeYAML([]byte(out), &result); err != nil {
+			return nil, false
+		}
+		return result, true
+	}
+}
+
+// ModifierExists reports whether a modifier is bound to name. Like the
+// gjson function it mirrors, it ignores fn.
+func ModifierExists(name string, fn func(yaml, arg string) string) bool {
+	modifiersMu.RLock()
+	defer modifiersMu.RUnlock()
+	_, ok := modifiers[name]
+	return ok
+}
+
+// lookupModifier returns the modifier named by a path component such as
+// "@flatten:{deep: true}", and its argument.
+func lookupModifier(part string) (modifierFunc, string, bool) {
+	name, arg, _ := strings.Cut(part[1:], ":")
+	modifiersMu.RLock()
+	mod, ok := modifiers[name]
+	modifiersMu.RUnlock()
+	return mod, arg, ok
+}
+
+// modifierOption reports whether the argument of a modifier, an object
+// such as {deep: true}, sets the option name to true.
+func modifierOption(arg, name string) bool {
+	if arg == "" {
+		return false
+	}
+	var opts map[string]interface{}
+	if err := decodeYAML([]byte(arg), &opts); err != nil {
+		return false
+	}
+	on, _ := opts[name].(bool)
+	return on
+}
+
+// modThis returns the value itself.
+func modThis(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	return v, true
+}
+
+// modReverse reverses an array. Other values are returned unchanged.
+func modReverse(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return v, true
+	}
+	out := make([]interface{}, len(arr))
+	for i, elem := range arr {
+		out[len(arr)-1-i] = elem
+	}
+	return out, true
+}
+
+// modSort sorts an array in the order of Result.Less, case-sensitively:
+// null, false, numbers, strings, true, then arrays and objects, which
+// keep their order. Other values are returned unchanged.
+func modSort(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return v, true
+	}
+	out := make([]interface{}, len(arr))
+	copy(out, arr)
+	results := make([]Result, len(out))
+	for i, elem := range out {
+		results[i] = sortKey(elem)
+	}
+	sort.Stable(byLess{out, results})
+	return out, true
+}
+
+// sortKey returns the Result that an element sorts by. Arrays and objects
+// are not written out, as they only compare by their type.
+func sortKey(v interface{}) Result {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		return Result{Type: YAML}
+	}
+	return makeResult(v)
+}
+
+// byLess sorts values by their Results.
+type byLess struct {
+	values  []interface{}
+	results []Result
+}
+
+func (s byLess) Len() int { return len(s.values) }
+
+func (s byLess) Less(i, j int) bool {
+	a, b := s.results[i], s.results[j]
+	if a.Type == YAML && b.Type == YAML {
+		return false
+	}
+	return a.Less(b, true)
+}
+
+func (s byLess) Swap(i, j int) {
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+	s.results[i], s.results[j] = s.results[j], s.results[i]
+}
+
+// modFlatten splices the arrays nested in an array into it, one level
+// deep, or all the way down with the argument {deep: true}. Other values
+// are returned unchanged.
+func modFlatten(v interface{}, _ *yaml.Node, arg string) (interface{}, bool) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return v, true
+	}
+	return flatten(arr, modifierOption(arg, "deep"), []interface{}{}), true
+}
+
+// flatten appends the elements of arr to out, splicing nested arrays.
+func flatten(arr []interface{}, deep bool, out []interface{}) []interface{} {
+	for _, elem := range arr {
+		inner, ok := elem.([]interface{})
+		switch {
+		case !ok:
+			out = append(out, elem)
+		case deep:
+			out = flatten(inner, true, out)
+		default:
+			out = append(out, inner...)
+		}
+	}
+	return out
+}
+
+// modKeys returns the keys of an object, in the order of ForEach and
+// Keys. Other values have no keys.
+func modKeys(v interface{}, node *yaml.Node, _ string) (interface{}, bool) {
+	keys, values := objectEntries(v, node)
+	if values == nil {
+		return nil, false
+	}
+	out := make([]interface{}, len(keys))
+	for i, key := range keys {
+		out[i] = key
+	}
+	return out, true
+}
+
+// modValues returns the values of an object, in the order of ForEach and
+// Values, or an array itself. Other values have no values.
+func modValues(v interface{}, node *yaml.Node, _ string) (interface{}, bool) {
+	if arr, ok := v.([]interface{}); ok {
+		return arr, true
+	}
+	_, values := objectEntries(v, node)
+	if values == nil {
+		return nil, false
+	}
+	return values, true
+}
+
+// objectEntries returns the keys and values of an object in the order of
+// ForEach: that of node, the node the object was decoded from, and sorted
+// without one. It returns nil values for anything else.
+func objectEntries(v interface{}, node *yaml.Node) ([]string, []interface{}) {
+	values := []interface{}{}
+	var keys []string
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		keys, _ = orderedKeys(obj, node)
+		for _, k := range keys {
+			values = append(values, obj[k])
+		}
+	case map[interface{}]interface{}:
+		ordered, _ := orderedAnyKeys(obj, node)
+		for _, k := range ordered {
+			keys = append(keys, canonicalKey(k))
+			values = append(values, obj[k])
+		}
+	default:
+		return nil, nil
+	}
+	return keys, values
+}
+
+// numericElements returns the numbers of an array, skipping its other
+// elements, and false for anything but an array.
+func numericElements(v interface{}) ([]interface{}, bool) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	nums := []interface{}{}
+	for _, elem := range arr {
+		if isNumeric(elem) {
+			nums = append(nums, elem)
+		}
+	}
+	return nums, true
+}
+
+// modSum adds up the numbers of an array, exactly while they are all
+// integers, and as float64 otherwise. Elements that are not numbers are
+// skipped, and an array without numbers sums to 0. Other values have no
+// sum. Like counts, the sum has no source text, so that NumberFormat
+// formats it, unless it is an integer a float64 cannot hold exactly.
+func modSum(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	nums, ok := numericElements(v)
+	if !ok {
+		return nil, false
+	}
+	exact, isInt := new(big.Int), true
+	var sum float64
+	for _, n := range nums {
+		sum += toFloat(n)
+		if i, ok := bigIntValue(n); ok && isInt {
+			exact.Add(exact, i)
+		} else {
+			isInt = false
+		}
+	}
+	switch {
+	case isInt && exact.CmpAbs(maxExactFloat) <= 0:
+		return synthNumber(exact.Int64()), true
+	case isInt && exact.IsInt64():
+		return exact.Int64(), true
+	case isInt:
+		return exact, true
+	}
+	return synthNumber(sum), true
+}
+
+// maxExactFloat is 2^53, beyond which a float64 skips integers.
+var maxExactFloat = big.NewInt(1 << 53)
+
+// modAvg returns the mean of the numbers of an array, skipping its other
+// elements, as a number without source text like modSum. An array without
+// numbers, and any other value, has no mean.
+func modAvg(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	nums, ok := numericElements(v)
+	if !ok || len(nums) == 0 {
+		return nil, false
+	}
+	var sum float64
+	for _, n := range nums {
+		sum += toFloat(n)
+	}
+	return synthNumber(sum / float64(len(nums))), true
+}
+
+// modMin returns the least number of an array, as it is written there.
+func modMin(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	return extremum(v, -1)
+}
+
+// modMax returns the greatest number of an array, as it is written there.
+func modMax(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	return extremum(v, 1)
+}
+
+// extremum returns the number of an array that compares as sign against
+// all others, the first of equal ones, skipping elements that are not
+// numbers. An array without numbers, and any other value, has none.
+func extremum(v interface{}, sign int) (interface{}, bool) {
+	nums, ok := numericElements(v)
+	if !ok || len(nums) == 0 {
+		return nil, false
+	}
+	best := nums[0]
+	for _, n := range nums[1:] {
+		if compareElements(n, best) == sign {
+			best = n
+		}
+	}
+	return best, true
+}
+
+// compareElements compares two decoded numbers, exactly when both are
+// integers.
+func compareElements(a, b interface{}) int {
+	if x, ok := bigIntValue(a); ok {
+		if y, ok := bigIntValue(b); ok {
+			return x.Cmp(y)
+		}
+	}
+	x, y := toFloat(a), toFloat(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// modCount returns the number of elements of an array, of whatever type,
+// or of entries of an object, as a number without source text like a
+// count from "#". Other values have no count.
+func modCount(v interface{}, _ *yaml.Node, _ string) (interface{}, bool) {
+	if arr, ok := v.([]interface{}); ok {
+		return synthNumber(len(arr)), true
+	}
+	_, values := objectEntries(v, nil)
+	if values == nil {
+		return nil, false
+	}
+	return synthNumber(len(values)), true
+}
+
+// modGroup groups the elements of an array by the value at the path given
+// as argument, as @group:region, or quoted as @group:"zone.name" for a
+// path with dots, into an object of arrays keyed by the value as String
+// gives it. Elements without a value at the path are left out, and the
+// members of each group keep their order. Other values, and a modifier
+// without a path, have no groups: servers.#|@group:region groups the count
+// of servers.#, not the array.
+func modGroup(v interface{}, _ *yaml.Node, arg string) (interface{}, bool) {
+	if strings.HasPrefix(arg, `"`) || strings.HasPrefix(arg, "'") {
+		if err := decodeYAML([]byte(arg), &arg); err != nil {
+			return nil, false
+		}
+	}
+	arr, ok := v.([]interface{})
+	if !ok || arg == "" {
+		return nil, false
+	}
+	groups := map[string]interface{}{}
+	for _, elem := range arr {
+		key := getByPath(elem, arg)
+		if !key.Exists() {
+			continue
+		}
+		members, _ := groups[key.String()].([]interface{})
+		groups[key.String()] = append(members, elem)
+	}
+	return groups, true
+}
@@ -0,0 +1,247 @@
+package gyaml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const modifierYAML = `
+matrix:
+  - [1, 2]
+  - [3, [4, 5]]
+  - 6
+mixed: [b, 10, null, a, true, 2.5, false, [x], B]
+servers:
+  - name: web
+    tags: [b, a]
+  - name: db
+    tags: [d, c]
+labels: {tier: front, app: shop}
+"@timestamp": "2024-01-15"
+"@keys": literal
+`
+
+func TestModifiers(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"matrix|@flatten", "[1 2 3 [4 5] 6]"},
+		{"matrix|@flatten:{deep: true}", "[1 2 3 4 5 6]"},
+		{`matrix|@flatten:{"deep": true}|#`, "6"},
+		{"matrix.@reverse.0", "6"},
+		{"mixed|@sort", "[<nil> false 2.5 10 B a b true [x]]"},
+		{"servers.#.name|@sort", "[db web]"},
+		{"servers.#.tags.@sort", "[[a b] [c d]]"},
+		{"servers.#.tags|@flatten|@sort|@reverse", "[d c b a]"},
+		{"labels.@keys", "[tier app]"},
+		{"labels|@values", "[front shop]"},
+		{"servers|@values|#", "2"},
+		{"labels.@this.app", "shop"},
+		{"@this.labels.tier", "front"},
+		{"servers.0.name|@reverse", "web"},
+		{"servers.0.name.@keys", ""},
+		{"servers|@keys", ""},
+		// A component naming no modifier is a key, and escapes make any
+		// component a key
+		{"@timestamp", "2024-01-15"},
+		{`\@keys`, "literal"},
+		{"@nosuch", ""},
+	}
+	for _, test := range tests {
+		r := Get(modifierYAML, test.path)
+		got := r.String()
+		if r.Type == YAML {
+			got = fmt.Sprint(r.Value())
+		}
+		if got != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+
+	// Modifiers and pipes work from Results and in batches too
+	servers := Get(modifierYAML, "servers")
+	if got := servers.Get("#.name|@reverse|0").String(); got != "db" {
+		t.Errorf("Expected db, got %q", got)
+	}
+	results := GetMany(modifierYAML, "labels|@keys|0", "labels.app", "servers.#.name|1")
+	if results[0].String() != "tier" || results[1].String() != "shop" || results[2].String() != "db" {
+		t.Errorf("Unexpected batch results %v", results)
+	}
+	if got := GetRef(modifierYAML, "matrix").Get("@flatten:{deep: true}|#").Int(); got != 6 {
+		t.Errorf("Expected 6 elements, got %d", got)
+	}
+}
+
+func TestSplitPathModifiers(t *testing.T) {
+	segments, err := SplitPath(`servers.#.tags|@flatten:{deep: true, sep: "."}.0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{
+		{Kind: SegmentKey, Text: "servers", Pos: 0},
+		{Kind: SegmentProjection, Text: "#", Pos: 8},
+		{Kind: SegmentKey, Text: "tags", Pos: 10},
+		{Kind: SegmentModifier, Text: `@flatten:{deep: true, sep: "."}`, Pos: 15, Pipe: true},
+		{Kind: SegmentIndex, Text: "0", Pos: 47},
+	}
+	if fmt.Sprint(segments) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, segments)
+	}
+	segments, _ = SplitPath("friends|#")
+	if len(segments) != 2 || segments[1].Kind != SegmentLength || !segments[1].Pipe {
+		t.Errorf("Expected a trailing # after a pipe to count, got %v", segments)
+	}
+}
+
+func TestAddModifier(t *testing.T) {
+	AddModifier("upper", func(yaml, arg string) string {
+		return strings.ToUpper(yaml)
+	})
+	AddModifier("pick", func(yaml, arg string) string {
+		// Keeps the elements whose field named by the argument is set
+		var out []string
+		field := Parse(arg).Get("field").String()
+		Parse(yaml).ForEach(func(_, elem Result) bool {
+			if elem.Get(field).Exists() {
+				out = append(out, "- "+elem.Get("name").String())
+			}
+			return true
+		})
+		return strings.Join(out, "\n")
+	})
+	AddModifier("nothing", func(yaml, arg string) string { return "" })
+	defer func() {
+		modifiersMu.Lock()
+		delete(modifiers, "upper")
+		delete(modifiers, "pick")
+		delete(modifiers, "nothing")
+		modifiersMu.Unlock()
+	}()
+
+	if !ModifierExists("upper", nil) || !ModifierExists("reverse", nil) || ModifierExists("lower", nil) {
+		t.Error("Unexpected ModifierExists results")
+	}
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"servers.#.name|@upper", "[WEB DB]"},
+		{"servers.0.name.@upper", "WEB"},
+		{"servers.#.name|@upper|@sort|0", "DB"},
+		{`servers|@pick:{"field": "tags"}`, "[web db]"},
+		{`servers|@pick:{field: missing}`, ""},
+		{"servers|@nothing", ""},
+	}
+	for _, test := range tests {
+		r := Get(modifierYAML, test.path)
+		got := r.String()
+		if r.Type == YAML {
+			got = fmt.Sprint(r.Value())
+		}
+		if got != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+}
+
+func TestAggregationModifiers(t *testing.T) {
+	orders := `
+orders:
+  - {id: 1, total: 12}
+  - {id: 2, total: 30.5}
+  - {id: 3, total: 7}
+  - {id: 4}
+  - {id: 5, total: "n/a"}
+counts: [3, 9223372036854775807, 1]
+big: [9007199254740993, 9007199254740992]
+empty: []
+labels: {tier: front, app: shop}
+`
+	tests := []struct {
+		path string
+		want string
+		typ  Type
+	}{
+		{"orders.#.total|@sum", "49.5", Number},
+		{"orders.#.id|@sum", "15", Number},
+		{"orders.#.total|@avg", "16.5", Number},
+		{"orders.#.id|@avg", "3", Number},
+		{"orders.#.total|@min", "7", Number},
+		{"orders.#.total|@max", "30.5", Number},
+		{"orders.#.total|@count", "4", Number},
+		{"orders|@count", "5", Number},
+		{"labels|@count", "2", Number},
+		{"orders.#(total>10)#.total|@sum", "42.5", Number},
+		{"big|@max", "9007199254740993", Number},
+		{"big|@min", "9007199254740992", Number},
+		{"empty|@sum", "0", Number},
+		{"empty|@count", "0", Number},
+		{"empty|@avg", "", Null},
+		{"empty|@min", "", Null},
+		{"labels|@sum", "", Null},
+		{"orders.0.id|@max", "", Null},
+	}
+	for _, tt := range tests {
+		r := Get(orders, tt.path)
+		if r.String() != tt.want || r.Type != tt.typ {
+			t.Errorf("%s: got %v %q, want %v %q", tt.path, r.Type, r.String(), tt.typ, tt.want)
+		}
+	}
+
+	// Integers are added exactly until they overflow an int64
+	if got := Get(orders, "orders.#.id|@sum").Int(); got != 15 {
+		t.Errorf("Expected 15, got %d", got)
+	}
+	if got := Get(orders, "counts|@sum").Float(); got != 9223372036854775811 {
+		t.Errorf("Expected the sum as a float, got %v", got)
+	}
+	if got := Get(orders, "big|@sum").Int(); got != 18014398509481985 {
+		t.Errorf("Expected an exact sum, got %d", got)
+	}
+}
+
+func TestGroupModifier(t *testing.T) {
+	servers := `
+servers:
+  - {name: a, region: eu, zone: {id: 1}}
+  - {name: b, region: us, zone: {id: 2}}
+  - {name: c, region: eu, zone: {id: 1}}
+  - {name: d}
+  - 7
+`
+	tests := []struct {
+		path, want string
+	}{
+		{"servers|@group:region|@keys", "[eu us]"},
+		{"servers.@group:region.eu.#.name", "[a c]"},
+		{"servers|@group:region.us.0.name", "b"},
+		{`servers|@group:"zone.id"|@keys`, "[1 2]"},
+		{`servers|@group:'zone.id'.1|#`, "2"},
+		{"servers|@group:region|@values|#.#", "[2 1]"},
+		{"servers|@group:name|@count", "4"},
+		{"servers|@group:missing|@count", "0"},
+		{"servers|@group", ""},
+		{"servers.0|@group:region", ""},
+		{"servers.#|@group:region", ""},
+	}
+	for _, tt := range tests {
+		r := Get(servers, tt.path)
+		got := r.String()
+		if r.Type == YAML {
+			got = fmt.Sprint(r.Value())
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	groups := Get(servers, "servers").GroupBy("zone.id")
+	if len(groups) != 2 || len(groups["1"]) != 2 || groups["1"][1].Get("name").String() != "c" || groups["2"][0].Get("name").String() != "b" {
+		t.Errorf("Unexpected groups %v", groups)
+	}
+	if groups := Get(servers, "servers.0").GroupBy("region"); groups != nil {
+		t.Errorf("Expected nil for an object, got %v", groups)
+	}
+}
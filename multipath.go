@@ -0,0 +1,144 @@
+package gyaml
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// multipath resolves a multipath component, such as {name,"years":age} or
+// [name,age], against current. Each path is resolved like the rest of a
+// path from current, and those that do not resolve are left out, while
+// those holding null are kept. An
+// object is keyed by the last component of each path, unless the path is
+// preceded by a quoted key and a colon. Objects also come with a node
+// holding their keys in order, which ForEach follows, as do arrays holding
+// numbers without source text, such as counts, which are written with
+// NumberFormat. It returns false for a component that is not closed.
+func (r *resolver) multipath(current interface{}, part string) (interface{}, *yaml.Node, bool) {
+	if !isClosedComposite(part) {
+		return nil, nil, false
+	}
+	elems := splitComposite(part[1 : len(part)-1])
+	if part[0] == '[' {
+		arr, emitted := []interface{}{}, []interface{}{}
+		written := false
+		for _, elem := range elems {
+			if v := r.getByPath(current, elem); v.Exists() || v.IsNull() {
+				value, synth := emittedValue(v)
+				arr, emitted = append(arr, v.nativeValue()), append(emitted, value)
+				_, built := value.(*yaml.Node)
+				written = written || synth || built
+			}
+		}
+		if written {
+			if node, err := encodeNode(emitted); err == nil {
+				return arr, node, true
+			}
+		}
+		return arr, nil, true
+	}
+
+	obj := map[string]interface{}{}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, elem := range elems {
+		key, path := multipathKey(elem)
+		v := r.getByPath(current, path)
+		if !v.Exists() && !v.IsNull() {
+			continue
+		}
+		value := v.nativeValue()
+		if _, dup := obj[key]; dup {
+			// A later path of the same key replaces the value
+			node = removeKey(node, key)
+		}
+		emitted, _ := emittedValue(v)
+		valueNode, err := encodeNode(emitted)
+		if err != nil {
+			continue
+		}
+		obj[key] = value
+		node.Content = append(node.Content, stringNode(key), valueNode)
+	}
+	return obj, node, true
+}
+
+// emittedValue returns the value of r to write out in a value built from
+// it, and reports whether it is a synthNumber: a number without source
+// text, such as a count, which is written with NumberFormat. A value
+// built along a path, such as a multipath, is written from its node.
+func emittedValue(r Result) (interface{}, bool) {
+	switch {
+	case r.Type == Number && r.Raw == "":
+		return synthNumber(r.Num), true
+	case r.decoded != nil && r.decoded.built:
+		return r.decoded.node, false
+	}
+	return r.nativeValue(), false
+}
+
+// splitComposite splits the inside of a multipath at the commas outside
+// quotes, brackets, braces and parentheses. Blanks around each path are
+// removed, and empty paths are left out.
+func splitComposite(s string) []string {
+	var elems []string
+	depth := 0
+	var quote byte
+	start := 0
+	add := func(end int) {
+		if elem := strings.TrimSpace(s[start:end]); elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{' || c == '[' || c == '(':
+			depth++
+		case c == '}' || c == ']' || c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(s))
+	return elems
+}
+
+// multipathKey returns the key and the path of an element of a multipath
+// object: the quoted key before a colon, as in "years":age, or else the
+// last component of the path.
+func multipathKey(elem string) (string, string) {
+	if key, n, ok := quotedKey(elem); ok {
+		if rest := strings.TrimSpace(elem[n:]); strings.HasPrefix(rest, ":") {
+			return key, strings.TrimSpace(rest[1:])
+		}
+	}
+	parts := pathParts(elem)
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i].text != "" {
+			return unescapeKey(parts[i].text), elem
+		}
+	}
+	return elem, elem
+}
+
+// removeKey returns an object node without the entry of key.
+func removeKey(node *yaml.Node, key string) *yaml.Node {
+	out := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			out.Content = append(out.Content, node.Content[i], node.Content[i+1])
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package gyaml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const multipathYAML = `
+app:
+  name: shop
+  version: 2.1.0
+database:
+  port: 5432
+servers:
+  - name: web
+    ip: 10.0.0.1
+  - name: db
+    ip: 10.0.0.2
+"a.b": dotted
+`
+
+func TestMultipaths(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"{app.name,app.version,database.port}", "map[name:shop port:5432 version:2.1.0]"},
+		{"[app.name,database.port]", "[shop 5432]"},
+		{"[servers.#.name,servers.#.ip]", "[[web db] [10.0.0.1 10.0.0.2]]"},
+		{`{"service":app.name,"port":database.port}`, "map[port:5432 service:shop]"},
+		{`{'db port':database.port}`, "map[db port:5432]"},
+		{`{a\.b,app.name}`, "map[a.b:dotted name:shop]"},
+		{"servers.#.{name,ip}", "[map[ip:10.0.0.1 name:web] map[ip:10.0.0.2 name:db]]"},
+		{"servers.0.[name, ip]", "[web 10.0.0.1]"},
+		{`[servers.#(name="db").ip,servers.#(name="x").ip]`, "[10.0.0.2]"},
+		{"[app.name,database.port]|@reverse", "[5432 shop]"},
+		{"{app.name,database.port}.port", "5432"},
+		{"[app.name,database.port].1", "5432"},
+		{"[app.name,database.port].#", "2"},
+		{"[app.name,database.port,servers.#.name]|@flatten", "[shop 5432 web db]"},
+		// Paths that do not resolve are left out
+		{"{app.name,missing,app.missing}", "map[name:shop]"},
+		{"[missing]", "[]"},
+		{"{}", "map[]"},
+		{"{app.name", ""},
+	}
+	for _, test := range tests {
+		r := Get(multipathYAML, test.path)
+		got := r.String()
+		if r.Type == YAML {
+			got = fmt.Sprint(r.Value())
+		}
+		if got != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+}
+
+func TestMultipathKeyOrder(t *testing.T) {
+	// Objects are listed in the order of their paths
+	for _, r := range []Result{
+		Get(multipathYAML, "{database.port,app.version,app.name}"),
+		GetRef(multipathYAML, "{database.port,app.version,app.name}"),
+		Parse(multipathYAML).Get("{database.port,app.version,app.name}"),
+	} {
+		var keys []string
+		r.ForEach(func(key, _ Result) bool {
+			keys = append(keys, key.String())
+			return true
+		})
+		if got := strings.Join(keys, ","); got != "port,version,name" {
+			t.Errorf("Expected the keys in the order of the paths, got %s", got)
+		}
+	}
+
+	// A later path of the same key replaces the value in place of the first
+	var pairs []string
+	Get(multipathYAML, `{app.name,"other":database.port,"name":app.version}`).ForEach(func(key, value Result) bool {
+		pairs = append(pairs, key.String()+"="+value.String())
+		return true
+	})
+	if got := strings.Join(pairs, ","); got != "other=5432,name=2.1.0" {
+		t.Errorf("Unexpected entries %s", got)
+	}
+
+	// The text of objects, and of projections of them, follows the paths
+	// too, with keys quoted where they would not read back as strings
+	raws := map[string]string{
+		"{database.port,app.name}":             "port: 5432\nname: shop\n",
+		"servers.#.{ip,name}":                  "- ip: 10.0.0.1\n  name: web\n- ip: 10.0.0.2\n  name: db\n",
+		`servers.#(name="db")#.{ip,"on":name}`: "- ip: 10.0.0.2\n  \"on\": db\n",
+		`{"123":app.name,"<<":database.port}`:  "\"123\": shop\n\"<<\": 5432\n",
+	}
+	for path, want := range raws {
+		r := Get(multipathYAML, path)
+		if r.Raw != want {
+			t.Errorf("%s: got %q, want %q", path, r.Raw, want)
+		}
+		if got, want := fmt.Sprint(Parse(r.Raw).Value()), fmt.Sprint(r.Value()); got != want {
+			t.Errorf("%s: Raw reads back as %s, want %s", path, got, want)
+		}
+	}
+
+	results := GetMany(multipathYAML, "{app.name,database.port}", "[servers.#.ip]|@flatten|0")
+	if fmt.Sprint(results[0].Value()) != "map[name:shop port:5432]" || results[1].String() != "10.0.0.1" {
+		t.Errorf("Unexpected batch results %v", results)
+	}
+}
+
+func TestMultipathNulls(t *testing.T) {
+	// Paths set to null are kept, as gjson keeps them, while missing paths
+	// are left out
+	yml := "a: null\nb: 1\nl:\n  - x: null\n  - x: 2\n  - y: 3\n"
+	raws := map[string]string{
+		"{a,b,c}":       "a: null\nb: 1\n",
+		"[a,b,c]":       "- null\n- 1\n",
+		"l.#.x":         "- null\n- 2\n",
+		"l.#.{x}":       "- x: null\n- x: 2\n- {}\n",
+		"l.#.[x]":       "- - null\n- - 2\n- []\n",
+		"{a,\"n\":l.#}": "a: null\n\"n\": 3\n",
+	}
+	for path, want := range raws {
+		if got := Get(yml, path).Raw; got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+	r := Get(yml, "{a,b}")
+	if !r.Get("a").IsNull() || r.Get("b").Int() != 1 {
+		t.Errorf("Unexpected object %v", r.Value())
+	}
+	if n := Get(yml, "l.#.x|#").Int(); n != 2 {
+		t.Errorf("Expected 2 values in the projection, got %d", n)
+	}
+}
+
+func TestSplitPathMultipaths(t *testing.T) {
+	segments, err := SplitPath(`servers.#.{name,"addr":ip}|@values`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{
+		{Kind: SegmentKey, Text: "servers", Pos: 0},
+		{Kind: SegmentProjection, Text: "#", Pos: 8},
+		{Kind: SegmentMultipath, Text: `{name,"addr":ip}`, Pos: 10},
+		{Kind: SegmentModifier, Text: "@values", Pos: 27, Pipe: true},
+	}
+	if fmt.Sprint(segments) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, segments)
+	}
+	for _, path := range []string{"{name,age", "a.[b,c", "{a,b]"} {
+		if _, err := SplitPath(path); err == nil || !strings.Contains(err.Error(), "unclosed multipath") {
+			t.Errorf("SplitPath(%q): expected an unclosed multipath error, got %v", path, err)
+		}
+	}
+}
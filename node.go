@@ -0,0 +1,551 @@
+package gyaml
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseNode parses the first document in data into a node tree and
+// normalizes the YAML 1.1 collection tags the rest of the package does not
+// handle natively. It returns nil for an empty document.
+func parseNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	normalizeNode(doc.Content[0])
+	return doc.Content[0], nil
+}
+
+// decodeYAML decodes the first document in data into out, like
+// yaml.Unmarshal, after the normalization done by parseNode.
+func decodeYAML(data []byte, out interface{}) error {
+	node, err := parseNode(data)
+	if err != nil || node == nil {
+		return err
+	}
+	return node.Decode(out)
+}
+
+// normalizeNode rewrites !!set and !!omap collections in place:
+//
+//   - a !!set, a mapping whose values are all null, becomes a sequence of
+//     its keys, so that counting, queries and iteration see its members.
+//   - an !!omap, a sequence of single-pair mappings, becomes a mapping.
+//
+// Collections that don't have the expected shape are left untouched.
+func normalizeNode(node *yaml.Node) {
+	switch {
+	case node.Kind == yaml.MappingNode && node.Tag == "!!set":
+		members := make([]*yaml.Node, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			members = append(members, node.Content[i])
+		}
+		node.Kind, node.Tag, node.Content = yaml.SequenceNode, "!!seq", members
+	case node.Kind == yaml.SequenceNode && node.Tag == "!!omap":
+		pairs := make([]*yaml.Node, 0, len(node.Content)*2)
+		for _, item := range node.Content {
+			if item.Kind != yaml.MappingNode || len(item.Content) != 2 {
+				pairs = nil
+				break
+			}
+			pairs = append(pairs, item.Content...)
+		}
+		if pairs != nil {
+			node.Kind, node.Tag, node.Content = yaml.MappingNode, "!!map", pairs
+		}
+	}
+	for _, child := range node.Content {
+		normalizeNode(child)
+	}
+}
+
+// needsNormalize reports whether normalizeNode would change the tree
+// under node.
+func needsNormalize(node *yaml.Node) bool {
+	if node.Kind == yaml.MappingNode && node.Tag == "!!set" || node.Kind == yaml.SequenceNode && node.Tag == "!!omap" {
+		return true
+	}
+	for _, child := range node.Content {
+		if needsNormalize(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneNode returns a deep copy of the tree under node, with aliases
+// pointing to the copies of their anchors. copies maps the nodes copied
+// so far to their copies.
+func cloneNode(node *yaml.Node, copies map[*yaml.Node]*yaml.Node) *yaml.Node {
+	if c, ok := copies[node]; ok {
+		return c
+	}
+	c := *node
+	copies[node] = &c
+	if node.Alias != nil {
+		c.Alias = cloneNode(node.Alias, copies)
+	}
+	c.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		c.Content[i] = cloneNode(child, copies)
+	}
+	return &c
+}
+
+// makeNodeResult creates a Result from a decoded value and the node it was
+// decoded from. Container Results keep the node, which records the order
+// of the keys in the document.
+func makeNodeResult(value interface{}, node *yaml.Node) Result {
+	return withNode(valueResult(value), value, node)
+}
+
+// withNode records in r, the Result of value, the node value was decoded
+// from.
+func withNode(r Result, value interface{}, node *yaml.Node) Result {
+	if r.decoded != nil {
+		r.decoded.node = node
+	}
+	if _, ok := value.(time.Time); ok && node != nil && node.Kind == yaml.ScalarNode {
+		// Keep timestamps as written
+		r.Str = node.Value
+	}
+	return withNumberText(withBinaryText(r, node), node)
+}
+
+// withBinaryText keeps the base64 text of a !!binary scalar in Raw, as Str
+// holds the bytes it decodes to.
+func withBinaryText(r Result, node *yaml.Node) Result {
+	if r.Type == String && node != nil && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!binary" {
+		r.Raw = node.Value
+	}
+	return r
+}
+
+// keepNode records in result, resolved by path from the value of d, the
+// node path leads to in d: the key order and source text of arrays and
+// objects, the tag of binary scalars, and the position of the value. The
+// node of simple paths is looked up, and that of other arrays and objects
+// searched for by keepFound.
+func (d *decodedValue) keepNode(result Result, path string) Result {
+	if d.node == nil || !isSimplePath(path) {
+		return d.keepFound(result)
+	}
+	node, _ := lookupNode(d.node, path)
+	return d.attach(result, node)
+}
+
+// keepFound records in result, an array or object found within the value
+// of d by a query or modifier, its node in d, when it is one of the values
+// of d.
+func (d *decodedValue) keepFound(result Result) Result {
+	if d.node == nil || result.decoded == nil || result.decoded.node != nil {
+		return result
+	}
+	node := findNode(d.node, d.v, result.decoded.v)
+	if node == nil {
+		node = d.projectedNode(result.decoded.v)
+	}
+	return d.attach(result, node)
+}
+
+// projectedNode returns a node made up for v, an array built by a
+// projection, whose elements are the nodes in d of those that are arrays
+// or objects of d, so that their keys keep the order of the document. It
+// returns nil when v is not an array or none of its elements are found.
+func (d *decodedValue) projectedNode(v interface{}) *yaml.Node {
+	if _, ok := v.([]interface{}); !ok {
+		return nil
+	}
+	index := map[containerID]*yaml.Node{}
+	indexNodes(d.node, d.v, index)
+	return projectedNode(v, index)
+}
+
+// projectedNode returns the node made up for v from the nodes of the
+// containers in index, for the method of the same name.
+func projectedNode(v interface{}, index map[containerID]*yaml.Node) *yaml.Node {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	nodes := make([]*yaml.Node, len(arr))
+	found := false
+	for i, elem := range arr {
+		if id, ok := idOf(elem); ok {
+			if nodes[i] = index[id]; nodes[i] == nil {
+				nodes[i] = projectedNode(elem, index)
+			}
+			found = found || nodes[i] != nil
+		}
+	}
+	if !found {
+		return nil
+	}
+	return sequenceNode(arr, nodes)
+}
+
+// containerID identifies an array or object by its storage, as
+// sameContainer compares them.
+type containerID struct {
+	ptr uintptr
+	len int
+}
+
+// idOf returns the identity of an array or object, and false for other
+// values and for empty arrays, which are never the same.
+func idOf(v interface{}) (containerID, bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return containerID{}, false
+		}
+		return containerID{reflect.ValueOf(v).Pointer(), len(v)}, true
+	case map[string]interface{}, map[interface{}]interface{}:
+		return containerID{ptr: reflect.ValueOf(v).Pointer()}, true
+	}
+	return containerID{}, false
+}
+
+// indexNodes records in index the node of each array and object of v,
+// where v was decoded from node, following the walk of findNode.
+func indexNodes(node *yaml.Node, v interface{}, index map[containerID]*yaml.Node) {
+	node = resolveAlias(node)
+	id, ok := idOf(v)
+	if !ok {
+		return
+	}
+	if _, seen := index[id]; seen {
+		return
+	}
+	index[id] = node
+	switch v := v.(type) {
+	case []interface{}:
+		if node.Kind != yaml.SequenceNode || len(node.Content) != len(v) {
+			return
+		}
+		for i, elem := range v {
+			indexNodes(node.Content[i], elem, index)
+		}
+	case map[string]interface{}:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || isMergeKey(key) {
+				continue
+			}
+			if value, ok := v[key.Value]; ok {
+				indexNodes(node.Content[i+1], value, index)
+			}
+		}
+	}
+}
+
+// sequenceNode returns a node made up for arr, holding the known nodes of
+// its elements and nodes encoded from the values of the others. Made up
+// nodes have no position, so they are never read as source text.
+func sequenceNode(arr []interface{}, nodes []*yaml.Node) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: make([]*yaml.Node, len(arr))}
+	for i, elem := range arr {
+		if nodes[i] != nil {
+			seq.Content[i] = nodes[i]
+			continue
+		}
+		var node yaml.Node
+		if err := node.Encode(elem); err != nil {
+			return nil
+		}
+		seq.Content[i] = &node
+	}
+	return seq
+}
+
+// withNodeRaw writes the Raw text of an array or object built along a
+// path, such as a multipath object, from its node, so that the text
+// follows the order of its keys rather than sorting them. Values from
+// GetRef are left for MarshalRaw to do the same.
+func withNodeRaw(r Result) Result {
+	if r.Type != YAML || r.decoded == nil || r.decoded.node == nil {
+		return r
+	}
+	r.decoded.built = true
+	if r.decoded.ref {
+		return r
+	}
+	if raw, err := yaml.Marshal(r.decoded.node); err == nil {
+		r.Raw = string(raw)
+	}
+	return r
+}
+
+// attach records in result its node within d, which may be nil.
+func (d *decodedValue) attach(result Result, node *yaml.Node) Result {
+	if result.decoded != nil {
+		result.decoded.node = node
+	} else {
+		result = withNumberText(withBinaryText(withTimeText(result, node), node), node)
+	}
+	return withSourceRaw(d.withSpan(result, node))
+}
+
+// withTimeText keeps the text of a timestamp as written in Str, as
+// withNode does, for a Result made from its decoded time.
+func withTimeText(r Result, node *yaml.Node) Result {
+	if r.Type == String && node != nil && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		r.Str = node.Value
+	}
+	return r
+}
+
+// findNode returns the node of target, an array or object within v, where
+// v was decoded from node. It returns nil when target is not one of the
+// arrays or objects of v, as for those built by projections.
+func findNode(node *yaml.Node, v, target interface{}) *yaml.Node {
+	node = resolveAlias(node)
+	if sameContainer(v, target) {
+		return node
+	}
+	switch v := v.(type) {
+	case []interface{}:
+		if node.Kind != yaml.SequenceNode || len(node.Content) != len(v) {
+			return nil
+		}
+		for i, elem := range v {
+			if found := findNode(node.Content[i], elem, target); found != nil {
+				return found
+			}
+		}
+	case map[string]interface{}:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || isMergeKey(key) {
+				continue
+			}
+			if value, ok := v[key.Value]; ok {
+				if found := findNode(node.Content[i+1], value, target); found != nil {
+					return found
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sameContainer reports whether a and b are the same array or object, not
+// merely equal ones. Empty arrays are never the same.
+func sameContainer(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		return ok && len(a) > 0 && len(a) == len(b) && &a[0] == &b[0]
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		return ok && reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	case map[interface{}]interface{}:
+		b, ok := b.(map[interface{}]interface{})
+		return ok && reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return false
+}
+
+// isMergeKey reports whether a mapping key is the merge key "<<".
+func isMergeKey(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.Value == "<<" && key.ShortTag() == "!!merge"
+}
+
+// keepMergeKeys retags the merge keys under node as plain strings, so that
+// each "<<" decodes as a key holding the values it refers to rather than
+// merging their keys into its mapping.
+func keepMergeKeys(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if isMergeKey(node.Content[i]) {
+				node.Content[i].Tag = "!!str"
+			}
+		}
+	}
+	for _, child := range node.Content {
+		keepMergeKeys(child)
+	}
+}
+
+// visitOverrides calls fn with the key and value nodes of the entries
+// written in a mapping node that has merge keys, with aliases resolved.
+// Those take precedence over the entries the merge keys bring in, wherever
+// they are written. It does nothing for other nodes.
+func visitOverrides(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	if node == nil {
+		return
+	}
+	node = resolveAlias(node)
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	merges := false
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if isMergeKey(resolveAlias(node.Content[i])) {
+			merges = true
+			break
+		}
+	}
+	if !merges {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := resolveAlias(node.Content[i])
+		if key.Kind == yaml.ScalarNode && !isMergeKey(key) {
+			fn(key, resolveAlias(node.Content[i+1]))
+		}
+	}
+}
+
+// orderedKeys returns the keys of obj in the order they appear in the
+// mapping node, with the keys brought in by merge keys in place of the
+// "<<" entry, followed by any keys of obj the node does not account for in
+// sorted order. Without a node, all keys are returned sorted. Each key is
+// paired with the node holding its value, or nil when unknown: for a key
+// both merged and written in the mapping, the one written, as
+// visitOverrides gives it.
+func orderedKeys(obj map[string]interface{}, node *yaml.Node) ([]string, []*yaml.Node) {
+	keys := make([]string, 0, len(obj))
+	nodes := make([]*yaml.Node, 0, len(obj))
+	at := make(map[string]int, len(obj))
+	visitEntries(node, func(key, value *yaml.Node) {
+		if key.Kind != yaml.ScalarNode {
+			return
+		}
+		if _, seen := at[key.Value]; seen {
+			return
+		}
+		if _, ok := obj[key.Value]; !ok {
+			return
+		}
+		at[key.Value] = len(keys)
+		keys = append(keys, key.Value)
+		nodes = append(nodes, value)
+	})
+	visitOverrides(node, func(key, value *yaml.Node) {
+		if i, ok := at[key.Value]; ok {
+			nodes[i] = value
+		}
+	})
+
+	if len(keys) < len(obj) {
+		var rest []string
+		for k := range obj {
+			if _, seen := at[k]; !seen {
+				rest = append(rest, k)
+			}
+		}
+		sort.Strings(rest)
+		for _, k := range rest {
+			keys = append(keys, k)
+			nodes = append(nodes, nil)
+		}
+	}
+	return keys, nodes
+}
+
+// orderedAnyKeys returns the keys of an object with keys of several types
+// in the order they appear in the mapping node, like orderedKeys, followed
+// by those the node does not account for in the order of sortKeys.
+func orderedAnyKeys(obj map[interface{}]interface{}, node *yaml.Node) ([]interface{}, []*yaml.Node) {
+	keys := make([]interface{}, 0, len(obj))
+	nodes := make([]*yaml.Node, 0, len(obj))
+	at := make(map[interface{}]int, len(obj))
+	visitEntries(node, func(key, value *yaml.Node) {
+		if key.Kind != yaml.ScalarNode {
+			return
+		}
+		var k interface{}
+		if err := key.Decode(&k); err != nil {
+			return
+		}
+		if _, seen := at[k]; seen {
+			return
+		}
+		if _, ok := obj[k]; !ok {
+			return
+		}
+		at[k] = len(keys)
+		keys = append(keys, k)
+		nodes = append(nodes, value)
+	})
+	visitOverrides(node, func(key, value *yaml.Node) {
+		var k interface{}
+		if err := key.Decode(&k); err == nil {
+			if i, ok := at[k]; ok {
+				nodes[i] = value
+			}
+		}
+	})
+
+	if len(keys) < len(obj) {
+		var rest []interface{}
+		for k := range obj {
+			if _, seen := at[k]; !seen {
+				rest = append(rest, k)
+			}
+		}
+		sortKeys(rest)
+		for _, k := range rest {
+			keys = append(keys, k)
+			nodes = append(nodes, nil)
+		}
+	}
+	return keys, nodes
+}
+
+// visitEntries calls fn with the key and value nodes of each entry of a
+// mapping node in order, with aliases resolved and the entries brought in
+// by merge keys in place of the "<<" entry. It does nothing for a nil node
+// or one that is not a mapping.
+func visitEntries(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	if node == nil {
+		return
+	}
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.SequenceNode:
+		// "<<: [*a, *b]" merges several mappings
+		for _, item := range node.Content {
+			visitEntries(item, fn)
+		}
+		return
+	case yaml.MappingNode:
+	default:
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := resolveAlias(node.Content[i])
+		if isMergeKey(key) {
+			visitEntries(node.Content[i+1], fn)
+			continue
+		}
+		fn(key, resolveAlias(node.Content[i+1]))
+	}
+}
+
+// elementNode returns the node of the i-th element of a sequence node, or
+// nil when node is not a sequence of the expected length.
+func elementNode(node *yaml.Node, i, n int) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	node = resolveAlias(node)
+	if node.Kind != yaml.SequenceNode || len(node.Content) != n {
+		return nil
+	}
+	return resolveAlias(node.Content[i])
+}
@@ -0,0 +1,148 @@
+package gyaml
+
+import (
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const collectionTagsYAML = `
+roles: !!set
+  ? admin
+  ? editor
+  ? viewer
+baseball: !!set {Mark McGwire, Sammy Sosa, Ken Griffey}
+steps: !!omap
+  - build: make
+  - test: go test ./...
+  - deploy: ./deploy.sh
+users:
+  - name: alice
+    groups: !!set {wheel, staff}
+  - name: bob
+    groups: !!set {staff}
+shared: &shared !!set {a, b}
+copy: *shared
+`
+
+func TestSetTag(t *testing.T) {
+	if n := Get(collectionTagsYAML, "roles.#").Int(); n != 3 {
+		t.Errorf("Expected 3 set members, got %d", n)
+	}
+	if Get(collectionTagsYAML, "roles.1").String() != "editor" {
+		t.Errorf("Expected 'editor', got '%s'", Get(collectionTagsYAML, "roles.1").String())
+	}
+	if Get(collectionTagsYAML, `roles.#(="admin")`).String() != "admin" {
+		t.Error("Membership query should match 'admin'")
+	}
+	if Get(collectionTagsYAML, `roles.#(="root")`).Exists() {
+		t.Error("Membership query should not match 'root'")
+	}
+	if Get(collectionTagsYAML, "baseball.#").Int() != 3 {
+		t.Error("Expected 3 members in flow set")
+	}
+	if Get(collectionTagsYAML, "users.#.groups.#").String() != "- 2\n- 1\n" {
+		t.Errorf("Unexpected group counts: %q", Get(collectionTagsYAML, "users.#.groups.#").Raw)
+	}
+	if Get(collectionTagsYAML, "copy.#").Int() != 2 {
+		t.Error("Aliased set should be normalized as well")
+	}
+
+	var members []string
+	Get(collectionTagsYAML, "roles").ForEach(func(key, value Result) bool {
+		members = append(members, value.String())
+		return true
+	})
+	if len(members) != 3 || members[0] != "admin" || members[2] != "viewer" {
+		t.Errorf("Unexpected set iteration: %v", members)
+	}
+}
+
+func TestOmapTag(t *testing.T) {
+	if Get(collectionTagsYAML, "steps.test").String() != "go test ./..." {
+		t.Errorf("Expected 'go test ./...', got '%s'", Get(collectionTagsYAML, "steps.test").String())
+	}
+	if n := Get(collectionTagsYAML, "steps.#").Int(); n != 3 {
+		t.Errorf("Expected 3 omap entries, got %d", n)
+	}
+	steps := Get(collectionTagsYAML, "steps").Map()
+	if steps["deploy"].String() != "./deploy.sh" {
+		t.Error("Expected omap to behave as a mapping")
+	}
+	count := 0
+	Parse(collectionTagsYAML).Get("steps").ForEach(func(key, value Result) bool {
+		count++
+		return true
+	})
+	if count != 3 {
+		t.Errorf("Expected 3 omap entries in ForEach, got %d", count)
+	}
+}
+
+func TestMalformedOmapLeftAlone(t *testing.T) {
+	yml := "steps: !!omap\n  - build: make\n    extra: x\n"
+	if Get(yml, "steps.#").Int() != 1 {
+		t.Error("Malformed omap should stay a sequence")
+	}
+}
+
+func TestFromNode(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(testYAML), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"name.first", "age", "children.#", `friends.#(last="Murphy").first`, "friends.#.nets.0", "@keys"} {
+		want := Get(testYAML, path)
+		for _, node := range []*yaml.Node{&doc, doc.Content[0]} {
+			if got := FromNode(node).Get(path); !got.Equal(want) || got.Index != 0 {
+				t.Errorf("FromNode(%v).Get(%q) = %v, want %v", node.Kind, path, got, want)
+			}
+		}
+	}
+	root := FromNode(&doc)
+	if got, want := fmt.Sprint(root.Keys()), fmt.Sprint(Parse(testYAML).Keys()); got != want {
+		t.Errorf("Expected the keys in document order %s, got %s", want, got)
+	}
+
+	// Collection tags are read as by Parse, without changing the tree
+	var tagged yaml.Node
+	if err := yaml.Unmarshal([]byte(collectionTagsYAML), &tagged); err != nil {
+		t.Fatal(err)
+	}
+	r := FromNode(&tagged)
+	if got := r.Get("copy.#").Int(); got != 2 {
+		t.Errorf("Expected the aliased set to have 2 members, got %d", got)
+	}
+	if got := r.Get("steps.test").String(); got != "go test ./..." {
+		t.Errorf("Unexpected omap value %q", got)
+	}
+	if roles := tagged.Content[0].Content[1]; roles.Kind != yaml.MappingNode || roles.Tag != "!!set" {
+		t.Errorf("Expected the tree to be left as it was, got kind %v tag %s", roles.Kind, roles.Tag)
+	}
+
+	// Trees built in code have no positions
+	built := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "z"}, {Kind: yaml.ScalarNode, Value: "1"},
+		{Kind: yaml.ScalarNode, Value: "a"}, {Kind: yaml.SequenceNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: "x"}}},
+	}}
+	r = FromNode(built)
+	if got := fmt.Sprint(r.Keys()); got != "[z a]" {
+		t.Errorf("Expected the keys in tree order, got %s", got)
+	}
+	if got := r.Get("z"); got.Type != Number || got.Int() != 1 {
+		t.Errorf("Unexpected z %v", got)
+	}
+	if got := r.Get("a.0").String(); got != "x" {
+		t.Errorf("Unexpected a.0 %q", got)
+	}
+	if got := FromNode(&yaml.Node{Kind: yaml.ScalarNode, Value: "42"}); got.Int() != 42 {
+		t.Errorf("Expected a scalar root, got %v", got)
+	}
+
+	for _, node := range []*yaml.Node{nil, {}, {Kind: yaml.DocumentNode}} {
+		if r := FromNode(node); r.Exists() {
+			t.Errorf("Expected a Null result for %v, got %v", node, r)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package gyaml
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decimalNumber matches the decimal integers and floats of the YAML 1.2
+// core schema.
+var decimalNumber = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+
+// basedInteger matches the integers written with the prefix of another
+// base than ten: 0x and 0o of YAML 1.2, and 0b of YAML 1.1, with the
+// underscores YAML 1.1 allows between digits. The octals of YAML 1.1 such
+// as 0755 have no prefix, and are given in decimal like other integers.
+var basedInteger = regexp.MustCompile(`^[-+]?0([xX][0-9a-fA-F_]+|[oO][0-7_]+|[bB][01_]+)$`)
+
+// octalInteger matches the integers with a leading zero, which yaml.v3
+// reads as octal, as YAML 1.1 does.
+var octalInteger = regexp.MustCompile(`^[-+]?0[0-9]+$`)
+
+// withNumberText keeps the text of a number as written in Raw where the
+// decoded value only approximates it, as for integers beyond uint64 and
+// floats with more digits than a float64 holds, and for integers written
+// in another base. Other numbers keep the Raw of their decoded value, so
+// that 1e3 reads as 1000.
+func withNumberText(r Result, node *yaml.Node) Result {
+	if r.Type != Number || node == nil || node.Kind != yaml.ScalarNode || r.Raw == node.Value {
+		return r
+	}
+	if basedInteger.MatchString(node.Value) {
+		r.Raw = node.Value
+		return r
+	}
+	if !decimalNumber.MatchString(node.Value) || !decimalNumber.MatchString(r.Raw) || octalInteger.MatchString(node.Value) {
+		return r
+	}
+	prec := decimalPrec(node.Value)
+	if p := decimalPrec(r.Raw); p > prec {
+		prec = p
+	}
+	written, _, err := big.ParseFloat(node.Value, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return r
+	}
+	decoded, _, err := big.ParseFloat(r.Raw, 10, prec, big.ToNearestEven)
+	if err == nil && written.Cmp(decoded) != 0 {
+		r.Raw = node.Value
+	}
+	return r
+}
+
+// BigInt returns the value as a *big.Int, exactly as written for integers
+// of any size, which Int and Uint can only hold up to 64 bits. Floats give
+// their integer part, and strings holding a number are parsed. It returns
+// nil for other values, and for infinities and NaN.
+func (t Result) BigInt() *big.Int {
+	f := t.BigFloat()
+	if f == nil || f.IsInf() {
+		return nil
+	}
+	n, _ := f.Int(nil)
+	return n
+}
+
+// BigFloat returns the value as a *big.Float, with the precision needed
+// to keep every digit of an integer as written, and at least that of a
+// float64. Strings holding a number are parsed. It returns nil for other
+// values, and for NaN.
+func (t Result) BigFloat() *big.Float {
+	var text string
+	switch t.Type {
+	case Number:
+		text = t.Raw
+		if text == "" {
+			text = formatNumber(t.Num)
+		}
+	case String:
+		text = t.Str
+	default:
+		return nil
+	}
+	text = strings.TrimSpace(text)
+	if f, ok := parseBigFloat(text); ok {
+		return f
+	}
+	if basedInteger.MatchString(text) {
+		if n, ok := new(big.Int).SetString(strings.Replace(text, "_", "", -1), 0); ok {
+			return new(big.Float).SetInt(n)
+		}
+	}
+	if t.Type == Number && !math.IsNaN(t.Num) {
+		// Special values and numbers NumberFormat writes in its own way
+		return new(big.Float).SetFloat64(t.Num)
+	}
+	return nil
+}
+
+// parseBigFloat parses a decimal number with enough precision for each of
+// its digits.
+func parseBigFloat(s string) (*big.Float, bool) {
+	if !decimalNumber.MatchString(s) {
+		return nil, false
+	}
+	f, _, err := big.ParseFloat(s, 10, decimalPrec(s), big.ToNearestEven)
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// parseInt parses the text of an integer, in decimal or in the base its
+// prefix gives, as yaml.v3 reads it.
+func parseInt(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if basedInteger.MatchString(raw) {
+		return strconv.ParseInt(strings.Replace(raw, "_", "", -1), 0, 64)
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+// parseUint parses the text of an unsigned integer like parseInt.
+func parseUint(raw string) (uint64, error) {
+	raw = strings.TrimSpace(raw)
+	if basedInteger.MatchString(raw) {
+		return strconv.ParseUint(strings.Replace(raw, "_", "", -1), 0, 64)
+	}
+	return strconv.ParseUint(raw, 10, 64)
+}
+
+// decimalPrec returns a precision in bits that holds every digit of the
+// decimal number s, and at least that of a float64.
+func decimalPrec(s string) uint {
+	if prec := uint(len(s)) * 4; prec > 64 {
+		return prec
+	}
+	return 64
+}
+
+// sizeText matches a byte size: a number and an optional unit, with
+// blanks allowed between them.
+var sizeText = regexp.MustCompile(`^([0-9]+(?:\.[0-9]*)?|\.[0-9]+)\s*([A-Za-z]*)$`)
+
+// sizeUnits are the multiples of the byte known to SizeBytes, by their
+// name in lower case: the decimal ones of SI, and the binary ones of IEC,
+// also written as Kubernetes does without the B.
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "m": 1e6, "mb": 1e6, "g": 1e9, "gb": 1e9,
+	"t": 1e12, "tb": 1e12, "p": 1e15, "pb": 1e15, "e": 1e18, "eb": 1e18,
+	"ki": 1 << 10, "kib": 1 << 10, "mi": 1 << 20, "mib": 1 << 20, "gi": 1 << 30, "gib": 1 << 30,
+	"ti": 1 << 40, "tib": 1 << 40, "pi": 1 << 50, "pib": 1 << 50, "ei": 1 << 60, "eib": 1 << 60,
+}
+
+// SizeBytes returns the number of bytes of a size such as "100MB",
+// "2GiB", "512Ki" or "1.5 GB". Units are read without regard to case: K,
+// M, G, T, P and E with or without a B are powers of 1000, and Ki, Mi,
+// Gi, Ti, Pi and Ei with or without a B are powers of 1024. A number
+// without a unit is a count of bytes, and fractions of a byte are dropped.
+// It returns 0 for other values, and for sizes beyond an int64.
+func (t Result) SizeBytes() int64 {
+	switch t.Type {
+	case Number:
+		n, ok := parseSize(t.String())
+		if !ok {
+			return 0
+		}
+		return n
+	case String:
+		n, _ := parseSize(t.Str)
+		return n
+	}
+	return 0
+}
+
+// parseSize parses s as a byte size, reporting whether it is one.
+func parseSize(s string) (int64, bool) {
+	m := sizeText.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	unit, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, false
+	}
+	r, ok := new(big.Rat).SetString(m[1])
+	if !ok {
+		return 0, false
+	}
+	r.Mul(r, new(big.Rat).SetInt64(unit))
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, false
+	}
+	return n.Int64(), true
+}
+
+// decodeNode decodes the value of node like node.Decode, but keeps the
+// integers too large for 64 bits, which yaml.v3 decodes to the nearest
+// float64, as the *big.Int they are written as.
+func decodeNode(node *yaml.Node) (interface{}, error) {
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+	if hasBigInteger(node) {
+		v = keepBigIntegers(v, node)
+	}
+	return v, nil
+}
+
+// hasBigInteger reports whether an integer too large for 64 bits is
+// written under node.
+func hasBigInteger(node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode {
+		_, ok := bigIntegerText(node)
+		return ok
+	}
+	for _, child := range node.Content {
+		if hasBigInteger(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepBigIntegers returns v, decoded from node, with the float64 values of
+// the integers too large for 64 bits replaced by their *big.Int. Arrays
+// and objects are updated in place.
+func keepBigIntegers(v interface{}, node *yaml.Node) interface{} {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.ScalarNode:
+		n, ok := bigIntegerText(node)
+		if !ok {
+			return v
+		}
+		switch f := v.(type) {
+		case float64:
+			if nf, _ := new(big.Float).SetInt(n).Float64(); nf == f {
+				return n
+			}
+		case *big.Int:
+			// Merged before an entry written in the mapping
+			return n
+		}
+	case yaml.SequenceNode:
+		if arr, ok := v.([]interface{}); ok && len(arr) == len(node.Content) {
+			for i, elem := range arr {
+				arr[i] = keepBigIntegers(elem, node.Content[i])
+			}
+		}
+	case yaml.MappingNode:
+		// Entries written in the mapping come last, to take precedence over
+		// the merged ones
+		keep := func(key, value *yaml.Node) {
+			switch obj := v.(type) {
+			case map[string]interface{}:
+				if elem, ok := obj[key.Value]; ok {
+					obj[key.Value] = keepBigIntegers(elem, value)
+				}
+			case map[interface{}]interface{}:
+				var k interface{}
+				if err := key.Decode(&k); err != nil {
+					return
+				}
+				if elem, ok := obj[k]; ok {
+					obj[k] = keepBigIntegers(elem, value)
+				}
+			}
+		}
+		visitEntries(node, keep)
+		visitOverrides(node, keep)
+	}
+	return v
+}
+
+// bigIntegerText returns the integer written by a scalar node when it is
+// too large for an int64 or uint64, and false otherwise. yaml.v3 resolves
+// such integers as floats, unless tagged !!int.
+func bigIntegerText(node *yaml.Node) (*big.Int, bool) {
+	if node.Kind != yaml.ScalarNode || len(node.Value) < 19 {
+		return nil, false
+	}
+	switch tag := node.ShortTag(); {
+	case tag == "!!float" && node.Style&yaml.TaggedStyle == 0:
+	case tag == "!!int":
+	default:
+		return nil, false
+	}
+	n, ok := parseBigInt(node.Value)
+	if !ok || n.IsInt64() || n.IsUint64() {
+		return nil, false
+	}
+	return n, true
+}
+
+// parseBigInt parses the text of an integer of any size, in decimal or in
+// the base its prefix gives, as parseInt does.
+func parseBigInt(raw string) (*big.Int, bool) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case basedInteger.MatchString(raw):
+		return new(big.Int).SetString(strings.Replace(raw, "_", "", -1), 0)
+	case octalInteger.MatchString(raw):
+		return nil, false
+	}
+	return new(big.Int).SetString(strings.TrimPrefix(raw, "+"), 10)
+}
+
+// bigIntValue returns a decoded integer as a *big.Int, and false for
+// values that are not integers.
+func bigIntValue(val interface{}) (*big.Int, bool) {
+	switch v := val.(type) {
+	case *big.Int:
+		return v, true
+	case int:
+		return big.NewInt(int64(v)), true
+	case int8:
+		return big.NewInt(int64(v)), true
+	case int16:
+		return big.NewInt(int64(v)), true
+	case int32:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint8:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint16:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	}
+	return nil, false
+}
@@ -0,0 +1,218 @@
+package gyaml
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const bigYAML = `
+max: 18446744073709551615
+huge: 123456789012345678901234567890
+neg: -123456789012345678901234567890
+precise: 1.000000000000000000001
+exp: 1e3
+small: 42
+half: -2.5
+inf: .inf
+text: "98765432109876543210"
+word: hello
+ids: [9007199254740993, 9007199254740992, 18446744073709551615]
+`
+
+func TestNumberText(t *testing.T) {
+	tests := map[string]string{
+		"max":     "18446744073709551615",
+		"huge":    "123456789012345678901234567890",
+		"neg":     "-123456789012345678901234567890",
+		"precise": "1.000000000000000000001",
+		"exp":     "1000",
+		"half":    "-2.5",
+	}
+	for path, want := range tests {
+		if got := Get(bigYAML, path); got.Type != Number || got.Raw != want || got.String() != want {
+			t.Errorf("%s: got %v %q", path, got.Type, got.Raw)
+		}
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	tests := map[string]string{
+		"max":     "18446744073709551615",
+		"huge":    "123456789012345678901234567890",
+		"neg":     "-123456789012345678901234567890",
+		"precise": "1",
+		"exp":     "1000",
+		"small":   "42",
+		"half":    "-2",
+		"text":    "98765432109876543210",
+		"ids.#":   "3",
+	}
+	for path, want := range tests {
+		if got := Get(bigYAML, path).BigInt(); got == nil || got.String() != want {
+			t.Errorf("%s: got %v, want %s", path, got, want)
+		}
+	}
+	for _, path := range []string{"inf", "word", "missing", "ids"} {
+		if got := Get(bigYAML, path).BigInt(); got != nil {
+			t.Errorf("%s: expected nil, got %v", path, got)
+		}
+	}
+}
+
+func TestBigFloat(t *testing.T) {
+	got := Get(bigYAML, "precise").BigFloat()
+	if got == nil || got.Cmp(big.NewFloat(1)) <= 0 || got.Text('f', 21) != "1.000000000000000000001" {
+		t.Errorf("Unexpected %v", got)
+	}
+	if got := Get(bigYAML, "half").BigFloat(); got == nil || got.Cmp(big.NewFloat(-2.5)) != 0 {
+		t.Errorf("Unexpected %v", got)
+	}
+	if got := Get(bigYAML, "inf").BigFloat(); got == nil || !got.IsInf() || got.Sign() <= 0 {
+		t.Errorf("Expected +Inf, got %v", got)
+	}
+	if got := Get("n: .nan", "n").BigFloat(); got != nil {
+		t.Errorf("Expected nil for NaN, got %v", got)
+	}
+	if got := Get(bigYAML, "word").BigFloat(); got != nil {
+		t.Errorf("Expected nil for a word, got %v", got)
+	}
+}
+
+func TestQueryBigIntegers(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"ids.#(>9007199254740992)#", "9007199254740993,18446744073709551615"},
+		{"ids.#(<=9007199254740992)#", "9007199254740992"},
+		{"ids.#(>=18446744073709551615)#", "18446744073709551615"},
+		{"ids.#(<18446744073709551615)#", "9007199254740993,9007199254740992"},
+		{"huge.#(>123456789012345678901234567889)#", "123456789012345678901234567890"},
+		{"huge.#(>123456789012345678901234567890)#", ""},
+		{"huge.#(=123456789012345678901234567890)#", "123456789012345678901234567890"},
+		{"huge.#(!=123456789012345678901234567891)#", "123456789012345678901234567890,5,-123456789012345678901234567890"},
+		{"huge.#(<-123456789012345678901234567889)#", "-123456789012345678901234567890"},
+	}
+	const doc = "ids: [9007199254740993, 9007199254740992, 18446744073709551615]\n" +
+		"huge: [123456789012345678901234567890, 5, -123456789012345678901234567890]\n"
+	for _, tt := range tests {
+		if got := strings.Join(Get(doc, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	if ok, _ := EvalCondition(Get(bigYAML, "huge"), "=123456789012345678901234567890"); !ok {
+		t.Errorf("Expected the condition to hold")
+	}
+}
+
+func TestBigIntegerValues(t *testing.T) {
+	doc := `ids: [123456789012345678901234567891, 5, 123456789012345678901234567890]
+tagged: !!float 123456789012345678901234567890
+defaults: &defaults {limit: 123456789012345678901234567890}
+merged:
+  <<: *defaults
+  limit: 123456789012345678901234567891
+early:
+  limit: 123456789012345678901234567891
+  <<: *defaults
+`
+	tests := []struct {
+		path, raw, json string
+	}{
+		{"ids|@reverse", "- 123456789012345678901234567890\n- 5\n- 123456789012345678901234567891\n", "[123456789012345678901234567890,5,123456789012345678901234567891]"},
+		{"ids|@sort", "- 5\n- 123456789012345678901234567890\n- 123456789012345678901234567891\n", "[5,123456789012345678901234567890,123456789012345678901234567891]"},
+		{"ids|@max", "123456789012345678901234567891", "123456789012345678901234567891"},
+		{"ids|@min", "5", "5"},
+		{"ids|@sum", "246913578024691357802469135786", "246913578024691357802469135786"},
+		{"ids.0", "123456789012345678901234567891", "123456789012345678901234567891"},
+		{"merged.limit", "123456789012345678901234567891", "123456789012345678901234567891"},
+		{"merged|@values", "- 123456789012345678901234567891\n", "[123456789012345678901234567891]"},
+		{"early|@values", "- 123456789012345678901234567891\n", "[123456789012345678901234567891]"},
+	}
+	for _, tt := range tests {
+		got := Get(doc, tt.path)
+		if got.Raw != tt.raw || got.JSON() != tt.json {
+			t.Errorf("%s: got %q and %s, want %q and %s", tt.path, got.Raw, got.JSON(), tt.raw, tt.json)
+		}
+	}
+	if got := fmt.Sprint(Get(doc, "ids").Value()); got != "[123456789012345678901234567891 5 123456789012345678901234567890]" {
+		t.Errorf("Unexpected values %s", got)
+	}
+	if _, ok := Get(doc, "@this").Value().(map[string]interface{})["tagged"].(float64); !ok {
+		t.Errorf("Expected a number tagged !!float to stay a float64")
+	}
+	canonical, err := Canonical("b: 123456789012345678901234567890\na: [18446744073709551616]\n")
+	if err != nil || canonical != "a:\n    - 18446744073709551616\nb: 123456789012345678901234567890\n" {
+		t.Errorf("Unexpected canonical form %q, %v", canonical, err)
+	}
+}
+
+func TestBasedIntegers(t *testing.T) {
+	doc := "hex: 0x1F\noctal: 0o755\nbinary: 0b1010\nneg: -0x10\nmax: 0xFFFFFFFFFFFFFFFF\nwide: 0x1_0000\nold: 0755\nmodes: [0o644, 0o600]\n"
+	tests := []struct {
+		path, raw, str string
+		n              int64
+	}{
+		{"hex", "0x1F", "31", 31},
+		{"octal", "0o755", "493", 493},
+		{"binary", "0b1010", "10", 10},
+		{"neg", "-0x10", "-16", -16},
+		{"wide", "0x1_0000", "65536", 65536},
+		{"old", "493", "493", 493},
+		{"modes.1", "0o600", "384", 384},
+	}
+	for _, tt := range tests {
+		r := Get(doc, tt.path)
+		if r.Type != Number || r.Raw != tt.raw || r.String() != tt.str || r.Int() != tt.n || r.Float() != float64(tt.n) {
+			t.Errorf("%s: got %v %q %q %d %v", tt.path, r.Type, r.Raw, r.String(), r.Int(), r.Float())
+		}
+	}
+	max := Get(doc, "max")
+	if max.Uint() != 1<<64-1 || max.BigInt().String() != "18446744073709551615" || max.String() != "18446744073709551615" {
+		t.Errorf("Unexpected %d, %v, %q", max.Uint(), max.BigInt(), max.String())
+	}
+	if got := Get(doc, "hex").JSON(); got != "31" {
+		t.Errorf("Unexpected JSON %q", got)
+	}
+	if got := Get(doc, "octal").Canonical(); got != "493\n" {
+		t.Errorf("Unexpected canonical form %q", got)
+	}
+	if got := Get(doc, "modes|@reverse").JSON(); got != "[384,420]" {
+		t.Errorf("Unexpected JSON %q", got)
+	}
+	var mode uint32
+	if err := Get(doc, "octal").Unmarshal(&mode); err != nil || mode != 0755 {
+		t.Errorf("Unexpected %o, %v", mode, err)
+	}
+}
+
+func TestSizeBytes(t *testing.T) {
+	tests := map[string]int64{
+		"100MB":      100e6,
+		"2GiB":       2 << 30,
+		"512Ki":      512 << 10,
+		"1.5 GB":     1500e6,
+		"1.5KiB":     1536,
+		"10kb":       10e3,
+		"64":         64,
+		"3 B":        3,
+		".5M":        500e3,
+		"8EiB":       0,
+		"1.2.3MB":    0,
+		"-1MB":       0,
+		"MB":         0,
+		"12 parsecs": 0,
+	}
+	for text, want := range tests {
+		if got := (Result{Type: String, Str: text}).SizeBytes(); got != want {
+			t.Errorf("%q: got %d, want %d", text, got, want)
+		}
+	}
+	doc := "limit: 100Mi\nraw: 4096\nhex: 0x400\nneg: -5\nflag: true\n"
+	for path, want := range map[string]int64{"limit": 100 << 20, "raw": 4096, "hex": 1024, "neg": 0, "flag": 0, "missing": 0} {
+		if got := Get(doc, path).SizeBytes(); got != want {
+			t.Errorf("%s: got %d, want %d", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,738 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options controls how values are compared and converted.
+// The zero value gives the default, lenient behavior of Get.
+type Options struct {
+	// StrictTypes turns best-effort coercions into errors. Ordering
+	// comparisons in queries, such as #(port>100), only match numbers,
+	// and the E accessors only convert values of the requested type.
+	StrictTypes bool
+	// YAML11Booleans lets strict conversions read the YAML 1.1 booleans
+	// (yes, no, on, off, y, n) from strings. It has no effect in lenient
+	// mode, which always reads them.
+	YAML11Booleans bool
+	// MaxLineSize limits the length of a line in ForEachLineWithOptions.
+	// Zero means DefaultMaxLineSize, and a negative value means no limit.
+	MaxLineSize int
+	// MaxScalarSize limits the length of a single scalar. The text is
+	// scanned before it is parsed, and documents with a longer scalar on a
+	// single line are rejected then; those holding a longer scalar spread
+	// over several lines are rejected once parsed, before any value is
+	// decoded from them. Zero means no limit.
+	MaxScalarSize int
+	// MaxDepth limits how deeply arrays and objects nest. A document
+	// holding an object of arrays has a depth of 2; scalars add nothing.
+	// Documents nested deeper are rejected once parsed. Zero means no
+	// limit.
+	MaxDepth int
+	// MaxDocumentSize limits the length of the document in bytes. Longer
+	// documents are rejected before they are parsed. Zero means no limit.
+	MaxDocumentSize int
+	// MaxAliasExpansion limits the number of values that aliases expand
+	// to, the values of nested aliases included. Documents whose aliases
+	// expand to more, such as the "billion laughs", are rejected once
+	// parsed, before any value is decoded from them. Zero means no limit.
+	MaxAliasExpansion int
+	// StrictBounds reports indices that address no element of their
+	// array, wrapping ErrIndexOutOfRange. The Result is Null either way.
+	StrictBounds bool
+	// KeepMergeKeys reads merge keys (<<) as plain keys holding the
+	// values they refer to, instead of merging the keys of those values
+	// into the object, to see the structure as it is written.
+	KeepMergeKeys bool
+}
+
+// Option sets one of the Options for a single call, such as
+//
+//	gyaml.GetWith(yaml, "ports.#(port>100)", gyaml.WithStrictTypes())
+//
+// Options apply to the call they are passed to and to the Results it
+// returns, and never change the defaults of other calls.
+type Option func(*Options)
+
+// WithStrictTypes sets StrictTypes: ordering comparisons in queries only
+// match numbers, and the E accessors of the Results only convert values
+// of the requested type.
+func WithStrictTypes() Option {
+	return func(o *Options) { o.StrictTypes = true }
+}
+
+// WithYAML11Booleans sets YAML11Booleans, so that BoolE reads yes, no,
+// on, off, y and n with strict types.
+func WithYAML11Booleans() Option {
+	return func(o *Options) { o.YAML11Booleans = true }
+}
+
+// WithStrictBounds sets StrictBounds, so that GetE reports indices
+// outside their array.
+func WithStrictBounds() Option {
+	return func(o *Options) { o.StrictBounds = true }
+}
+
+// WithKeepMergeKeys sets KeepMergeKeys, so that with
+//
+//	defaults: &defaults
+//	  timeout: 30
+//	service:
+//	  <<: *defaults
+//
+// service.timeout does not exist, while service.<<.timeout is 30.
+func WithKeepMergeKeys() Option {
+	return func(o *Options) { o.KeepMergeKeys = true }
+}
+
+// WithMaxLineSize sets MaxLineSize, the longest line
+// ForEachLineWithOptions reads.
+func WithMaxLineSize(n int) Option {
+	return func(o *Options) { o.MaxLineSize = n }
+}
+
+// WithMaxScalarSize sets MaxScalarSize. Documents holding a longer
+// scalar are rejected before it is parsed where it is written on a single
+// line, and before any value is decoded from them otherwise.
+func WithMaxScalarSize(n int) Option {
+	return func(o *Options) { o.MaxScalarSize = n }
+}
+
+// WithMaxDepth sets MaxDepth. Documents nesting arrays and objects more
+// deeply are rejected before any value is decoded from them.
+func WithMaxDepth(n int) Option {
+	return func(o *Options) { o.MaxDepth = n }
+}
+
+// WithMaxDocumentSize sets MaxDocumentSize. Longer documents are rejected
+// before they are parsed.
+func WithMaxDocumentSize(n int) Option {
+	return func(o *Options) { o.MaxDocumentSize = n }
+}
+
+// WithMaxAliasExpansion sets MaxAliasExpansion. Documents whose aliases
+// expand to more values are rejected before any value is decoded from
+// them.
+func WithMaxAliasExpansion(n int) Option {
+	return func(o *Options) { o.MaxAliasExpansion = n }
+}
+
+// applyOptions returns the Options set by opts, starting from the zero
+// value.
+func applyOptions(opts []Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// DefaultMaxLineSize is the longest line ForEachLine and
+// ForEachLineWithOptions read when MaxLineSize is not set.
+const DefaultMaxLineSize = 1 << 20
+
+// ErrLimit is wrapped by the errors reporting that a document exceeds a
+// limit set in Options.
+var ErrLimit = errors.New("gyaml: limit exceeded")
+
+// checkText enforces the limits set in o on the text of a document, or of
+// a stream of documents, before it is parsed: the document must not be
+// longer than MaxDocumentSize, and must not visibly hold a scalar longer
+// than MaxScalarSize.
+func (o Options) checkText(yamlStr string, stream bool) error {
+	if o.MaxDocumentSize > 0 && len(yamlStr) > o.MaxDocumentSize {
+		return fmt.Errorf("%w: document is %d bytes, more than %d", ErrLimit, len(yamlStr), o.MaxDocumentSize)
+	}
+	if o.MaxScalarSize > 0 && len(yamlStr) > o.MaxScalarSize {
+		return scanScalars(yamlStr, o.MaxScalarSize, stream)
+	}
+	return nil
+}
+
+// scanScalars reports a scalar longer than max bytes found in the text
+// without parsing it, so that the parser never builds it. Within a line,
+// a run of text between blanks, indicators, comments, escapes, anchors
+// and tags is written as is in the value of a single scalar, which is at
+// least as long; runs are only ever cut short, so that a document passing
+// checkNode passes here too. Scalars spread over several lines are left
+// to checkNode. Unless stream is set, only the first document is read,
+// as parseNode does.
+func scanScalars(yamlStr string, max int, stream bool) error {
+	content := false
+	for n, rest := 1, yamlStr; rest != ""; n++ {
+		line := rest
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		if isDocumentMarker(line) {
+			if !stream && (content || line[0] == '.') {
+				return nil
+			}
+			line = line[3:]
+		} else if strings.HasPrefix(line, "%") {
+			// A directive
+			continue
+		}
+		if longest, seen := longestRun(line); longest > max {
+			return fmt.Errorf("%w: scalar at line %d is at least %d bytes, more than %d", ErrLimit, n, longest, max)
+		} else if seen {
+			content = true
+		}
+	}
+	return nil
+}
+
+// isDocumentMarker reports whether a line starts with --- or ..., which
+// start and end a document.
+func isDocumentMarker(line string) bool {
+	if !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "...") {
+		return false
+	}
+	return len(line) == 3 || strings.IndexByte(" \t\r", line[3]) >= 0
+}
+
+// longestRun returns the length of the longest run of scalar text in a
+// line, as described for scanScalars, and reports whether the line holds
+// anything but blanks and a comment.
+func longestRun(line string) (int, bool) {
+	longest, start, end := 0, -1, -1
+	seen, blank := false, true
+	endRun := func() {
+		if start >= 0 && end-start > longest {
+			longest = end - start
+		}
+		start = -1
+	}
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			// Blanks within a run belong to the scalar, unless they end it
+			blank = true
+			i++
+			continue
+		case c == '#' && blank:
+			endRun()
+			return longest, seen
+		case c >= 0x80 && unicodeBreak(line[i:]) > 0:
+			// Line breaks, and the byte order mark
+			endRun()
+			i += unicodeBreak(line[i:])
+			blank = true
+			continue
+		case c == '\\':
+			// An escape in a double-quoted scalar may stand for fewer bytes
+			endRun()
+			i += 2
+			if i <= len(line) {
+				switch line[i-1] {
+				case 'x':
+					i += 2
+				case 'u':
+					i += 4
+				case 'U':
+					i += 8
+				}
+			}
+		case c == '&' || c == '*' || c == '!':
+			// Anchors, aliases and tags are not part of the value
+			endRun()
+			stop := " \t\r,[]{}"
+			if strings.HasPrefix(line[i:], "!<") {
+				stop = ">"
+			}
+			for i++; i < len(line) && strings.IndexByte(stop, line[i]) < 0; i++ {
+			}
+		case strings.IndexByte("-?:,[]{}#|>'\"%@`+<", c) >= 0:
+			endRun()
+			i++
+		default:
+			if start < 0 {
+				start = i
+			}
+			i++
+			end = i
+		}
+		seen, blank = true, false
+	}
+	endRun()
+	return longest, seen
+}
+
+// checkNode enforces the limits set in o on node, the root of a document
+// of size bytes, before any value is decoded from it.
+func (o Options) checkNode(node *yaml.Node, size int) error {
+	maxScalar := o.MaxScalarSize
+	if size <= maxScalar {
+		// No scalar can be longer than the document
+		maxScalar = 0
+	}
+	if maxScalar > 0 || o.MaxDepth > 0 {
+		if err := checkLimits(node, maxScalar, o.MaxDepth); err != nil {
+			return err
+		}
+	}
+	if o.MaxAliasExpansion > 0 {
+		return checkAliases(node, o.MaxAliasExpansion)
+	}
+	return nil
+}
+
+// checkAliases reports a document whose aliases expand to more than max
+// values. Each alias counts the values under its anchored value, and
+// those of the aliases found there. The count of an anchored value is
+// computed once, so that the check itself does not expand anything.
+func checkAliases(node *yaml.Node, max int) error {
+	counts := map[*yaml.Node]int{}
+	// size returns the number of values n expands to, stopping at max+1
+	var size func(n *yaml.Node) int
+	size = func(n *yaml.Node) int {
+		if n.Kind == yaml.AliasNode && n.Alias != nil {
+			n = n.Alias
+		}
+		if c, ok := counts[n]; ok {
+			return c
+		}
+		// Guards against an alias within its own anchored value
+		counts[n] = max + 1
+		total := 1
+		for _, child := range n.Content {
+			if total += size(child); total > max {
+				total = max + 1
+				break
+			}
+		}
+		counts[n] = total
+		return total
+	}
+	expanded := 0
+	var err error
+	var walk func(n *yaml.Node) bool
+	walk = func(n *yaml.Node) bool {
+		if n.Kind == yaml.AliasNode && n.Alias != nil {
+			if expanded += size(n.Alias); expanded > max {
+				err = fmt.Errorf("%w: aliases expand to more than %d values, at line %d", ErrLimit, max, n.Line)
+				return false
+			}
+			return true
+		}
+		for _, child := range n.Content {
+			if !walk(child) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(node)
+	return err
+}
+
+// checkLimits reports the first scalar in node longer than maxScalar
+// bytes, or the first array or object nested deeper than maxDepth, in a
+// single pass over the events of node. A limit of zero is not checked.
+func checkLimits(node *yaml.Node, maxScalar, maxDepth int) error {
+	var err error
+	depth := 0
+	walkEvents(node, func(kind EventKind, n *yaml.Node) bool {
+		switch kind {
+		case MappingStart, SequenceStart:
+			if depth++; maxDepth > 0 && depth > maxDepth {
+				err = fmt.Errorf("%w: value at line %d is nested %d deep, more than %d", ErrLimit, n.Line, depth, maxDepth)
+			}
+		case MappingEnd, SequenceEnd:
+			depth--
+		case Key, Scalar:
+			if maxScalar > 0 && len(n.Value) > maxScalar {
+				err = fmt.Errorf("%w: scalar at line %d is %d bytes, more than %d", ErrLimit, n.Line, len(n.Value), maxScalar)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// unicodeBreak returns the length of the line break or byte order mark
+// that s starts with, and 0 if it starts with neither.
+func unicodeBreak(s string) int {
+	for _, b := range []string{"\u0085", "\u2028", "\u2029", "\ufeff"} {
+		if strings.HasPrefix(s, b) {
+			return len(b)
+		}
+	}
+	return 0
+}
+
+// TypeError is returned when a value cannot be used as the type an
+// operation requires.
+type TypeError struct {
+	// Value is the offending value
+	Value Result
+	// Want names the type that was required
+	Want string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("gyaml: cannot use %s as %s", describe(e.Value), e.Want)
+}
+
+// describe returns a short description of a value for error messages.
+func describe(t Result) string {
+	switch t.Type {
+	case Null:
+		return "null"
+	case True, False:
+		return "bool " + t.String()
+	case Number:
+		return "number " + t.String()
+	case String:
+		return "string " + strconv.Quote(t.Str)
+	case YAML:
+		if t.IsArray() {
+			return "array"
+		}
+		return "object"
+	default:
+		return t.Type.String()
+	}
+}
+
+// GetE searches the YAML for the specified path like Get, and reports
+// why the document could not be read. It also reports a path component
+// that matches more than one key, wrapping ErrAmbiguousKey, along with
+// the value Get returns. With WithStrictTypes, it also reports queries
+// that compare values of the wrong type, and with WithStrictBounds,
+// indices outside their array.
+func GetE(yamlStr, path string, opts ...Option) (Result, error) {
+	return GetWithOptions(yamlStr, path, applyOptions(opts))
+}
+
+// GetWith searches the YAML for the specified path like Get, using opts.
+// A document that breaks a limit set by opts returns a Null result; use
+// GetE to learn why.
+func GetWith(yamlStr, path string, opts ...Option) Result {
+	if len(opts) == 0 {
+		return Get(yamlStr, path)
+	}
+	r, _ := GetWithOptions(yamlStr, path, applyOptions(opts))
+	return r
+}
+
+// ParseWith parses the YAML like Parse, using opts. A document that
+// breaks a limit set by opts returns a Null result.
+func ParseWith(yamlStr string, opts ...Option) Result {
+	trace := startTrace()
+	o := applyOptions(opts)
+	doc, err := parseDocument(yamlStr, o)
+	doc.opts = &o
+	if trace != nil {
+		trace.end("Parse", "", len(yamlStr), doc.Exists(), err)
+	}
+	return doc
+}
+
+// ParseWithOptions parses the YAML using opts like ParseWith, and reports
+// why the document could not be read, such as a limit it breaks:
+//
+//	doc, err := gyaml.ParseWithOptions(untrusted, gyaml.Options{
+//		MaxDocumentSize:   1 << 20,
+//		MaxAliasExpansion: 10000,
+//		MaxDepth:          64,
+//	})
+//	if errors.Is(err, gyaml.ErrLimit) {
+//		return err
+//	}
+func ParseWithOptions(yamlStr string, opts Options) (Result, error) {
+	trace := startTrace()
+	doc, err := parseDocument(yamlStr, opts)
+	doc.opts = &opts
+	if trace != nil {
+		trace.end("Parse", "", len(yamlStr), doc.Exists(), err)
+	}
+	return doc, err
+}
+
+// GetWithOptions searches the YAML for the specified path using opts.
+// The error reports a document that cannot be parsed and, with
+// StrictTypes, the first query element whose value has the wrong type;
+// such elements never match. With StrictBounds, it reports the first
+// index outside its array. The returned Result and the values reached
+// from it convert with the same options.
+func GetWithOptions(yamlStr, path string, opts Options) (Result, error) {
+	trace := startTrace()
+	result, err := getWithOptions(yamlStr, path, opts)
+	if trace != nil {
+		trace.end("Get", path, len(yamlStr), result.Exists(), err)
+	}
+	return result, err
+}
+
+// getWithOptions resolves a path for GetWithOptions.
+func getWithOptions(yamlStr, path string, opts Options) (Result, error) {
+	if n, rest, ok := documentPath(path); ok {
+		doc, err := selectDocument(yamlStr, n, opts)
+		if err == nil {
+			doc, err = resolveIn(doc, rest, opts)
+		}
+		doc.opts, doc.path = &opts, path
+		return doc, err
+	}
+	doc, err := parseDocument(yamlStr, opts)
+	if err != nil {
+		doc.opts, doc.path = &opts, path
+		return doc, err
+	}
+	return resolveIn(doc, path, opts)
+}
+
+// resolveIn resolves a path in a parsed document using opts, reporting
+// the errors that opts ask for.
+func resolveIn(doc Result, path string, opts Options) (Result, error) {
+	if path == "" || doc.decoded == nil {
+		doc.opts, doc.path = &opts, path
+		return doc, nil
+	}
+	if name, rest, ok := anchorPath(path); ok {
+		anchored := doc.decoded.anchor(name)
+		if anchored.Type != YAML && rest != "" {
+			anchored = Result{Type: Null}
+		}
+		result, err := resolveIn(anchored, rest, opts)
+		result.path = path
+		return result, err
+	}
+	r := &resolver{opts: opts, tree: doc.decoded}
+	result := doc.decoded.keepNode(r.getByPath(doc.decoded.v, path), path)
+	result.opts, result.path = &opts, path
+	return result, r.err
+}
+
+// parseDocument parses the YAML into a document Result, enforcing the
+// limits set in opts before any value is decoded.
+func parseDocument(yamlStr string, opts Options) (Result, error) {
+	return parseSource(yamlStr, nil, opts)
+}
+
+// parseSource parses the YAML like parseDocument. data holds its text as
+// bytes when the caller has them, so that it is parsed without another
+// copy, and is nil otherwise.
+func parseSource(yamlStr string, data []byte, opts Options) (Result, error) {
+	if len(yamlStr) == 0 {
+		return Result{Type: Null}, nil
+	}
+	if err := opts.checkText(yamlStr, false); err != nil {
+		return Result{Type: Null}, err
+	}
+	// Merge keys are retagged in place, so the tree is not shared
+	var node *yaml.Node
+	var err error
+	if opts.KeepMergeKeys {
+		node, err = parseNode(textBytes(yamlStr, data))
+	} else {
+		node, err = parseCached(yamlStr, data)
+	}
+	if err != nil {
+		return Result{Type: Null}, err
+	}
+	if node != nil {
+		if err := opts.checkNode(node, len(yamlStr)); err != nil {
+			return Result{Type: Null}, err
+		}
+	}
+	if node != nil && opts.KeepMergeKeys {
+		keepMergeKeys(node)
+	}
+	var root interface{}
+	if node != nil {
+		if root, err = decodeNode(node); err != nil {
+			return Result{Type: Null}, err
+		}
+	}
+	d := &decodedValue{v: root, node: node, src: yamlStr}
+	return d.withSpan(Result{Type: YAML, Raw: yamlStr, decoded: d}, node), nil
+}
+
+// strict reports whether the result converts with strict types.
+func (t Result) strict() bool {
+	return t.opts != nil && t.opts.StrictTypes
+}
+
+// lenient returns the result without its options, for the conversions
+// that the E accessors share with the plain ones.
+func (t Result) lenient() Result {
+	t.opts = nil
+	return t
+}
+
+// IntE returns the value as an integer, with an error where Int would
+// fall back to a best guess or to 0: numbers with a fractional part or
+// outside the range of an int64, strings that are not integers, null,
+// objects and arrays. With strict types only numbers convert.
+func (t Result) IntE() (int64, error) {
+	switch t.Type {
+	case Number:
+		if t.Num != math.Trunc(t.Num) {
+			return 0, &TypeError{Value: t, Want: "integer"}
+		}
+		if n, ok := t.int64Value(); ok {
+			return n, nil
+		}
+		return 0, &TypeError{Value: t, Want: "int64"}
+	case String:
+		if !t.strict() {
+			if n, err := strconv.ParseInt(t.Str, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case True, False:
+		if !t.strict() {
+			return t.lenient().Int(), nil
+		}
+	}
+	return 0, &TypeError{Value: t, Want: "integer"}
+}
+
+// UintE returns the value as an unsigned integer, with an error where
+// Uint would fall back to a best guess or to 0: negative numbers, numbers
+// with a fractional part or beyond the range of a uint64, strings that
+// are not unsigned integers, null, objects and arrays. With strict types
+// only numbers convert.
+func (t Result) UintE() (uint64, error) {
+	switch t.Type {
+	case Number:
+		if t.Num < 0 || t.Num != math.Trunc(t.Num) {
+			break
+		}
+		if n, ok := t.uint64Value(); ok {
+			return n, nil
+		}
+		return 0, &TypeError{Value: t, Want: "uint64"}
+	case String:
+		if !t.strict() {
+			if n, err := strconv.ParseUint(t.Str, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case True, False:
+		if !t.strict() {
+			return t.lenient().Uint(), nil
+		}
+	}
+	return 0, &TypeError{Value: t, Want: "unsigned integer"}
+}
+
+// int64Value returns a number as an int64, read from Raw when it holds
+// the integer as written, and false when it is outside the range of an
+// int64.
+func (t Result) int64Value() (int64, bool) {
+	if t.Raw != "" {
+		if n, err := parseInt(t.Raw); err == nil {
+			return n, true
+		}
+		if _, ok := parseBigInt(t.Raw); ok {
+			return 0, false
+		}
+	}
+	// -2^63 and 2^63 are exact as float64
+	if !(t.Num >= math.MinInt64 && t.Num < -math.MinInt64) {
+		return 0, false
+	}
+	return int64(t.Num), true
+}
+
+// uint64Value returns a number as a uint64 like int64Value, and false when
+// it is outside the range of a uint64.
+func (t Result) uint64Value() (uint64, bool) {
+	if t.Raw != "" {
+		if n, err := parseUint(t.Raw); err == nil {
+			return n, true
+		}
+		if _, ok := parseBigInt(t.Raw); ok {
+			return 0, false
+		}
+	}
+	// 2^64 is exact as float64
+	if !(t.Num >= 0 && t.Num < 2*-math.MinInt64) {
+		return 0, false
+	}
+	return uint64(t.Num), true
+}
+
+// FloatE returns the value as a float64, with an error where Float would
+// fall back to 0. With strict types only numbers convert.
+func (t Result) FloatE() (float64, error) {
+	switch t.Type {
+	case Number:
+		return t.lenient().Float(), nil
+	case String:
+		if !t.strict() {
+			if n, err := strconv.ParseFloat(t.Str, 64); err == nil {
+				return n, nil
+			}
+		}
+	case True, False:
+		if !t.strict() {
+			return t.lenient().Float(), nil
+		}
+	}
+	return 0, &TypeError{Value: t, Want: "number"}
+}
+
+// BoolE returns the value as a bool, with an error where Bool would fall
+// back to false. With strict types only booleans convert, and strings
+// holding YAML 1.1 booleans when YAML11Booleans is set.
+func (t Result) BoolE() (bool, error) {
+	switch t.Type {
+	case True:
+		return true, nil
+	case False:
+		return false, nil
+	case String:
+		if t.strict() {
+			if t.opts.YAML11Booleans && isOldBool(t.Str) {
+				return yaml11True(t.Str), nil
+			}
+			break
+		}
+		if b, ok := parseBoolString(t.Str); ok {
+			return b, nil
+		}
+	case Number:
+		if !t.strict() {
+			return t.Num != 0, nil
+		}
+	}
+	return false, &TypeError{Value: t, Want: "bool"}
+}
+
+// StringE returns the value as a string, with an error where String
+// would return an empty string for null, or the YAML text of an object or
+// array. With strict types only strings convert, and numbers and booleans
+// give an error too.
+func (t Result) StringE() (string, error) {
+	switch t.Type {
+	case String:
+		return t.Str, nil
+	case Number, True, False:
+		if !t.strict() {
+			return t.String(), nil
+		}
+	}
+	return "", &TypeError{Value: t, Want: "string"}
+}
+
+// yaml11True reports whether a YAML 1.1 boolean string reads as true.
+func yaml11True(s string) bool {
+	switch strings.ToLower(s) {
+	case "y", "yes", "on":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,395 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const hygieneYAML = `
+services:
+  - name: web
+    port: "8080"
+  - name: api
+    port: 9090
+  - name: db
+    port: 5432
+replicas: "3"
+ratio: 2.5
+debug: yes
+verbose: true
+`
+
+func TestStrictQueryComparison(t *testing.T) {
+	lenient, err := GetWithOptions(hygieneYAML, "services.#(port>6000).name", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lenient.String() != "web" {
+		t.Errorf("Expected lenient mode to match the string port, got '%s'", lenient.String())
+	}
+
+	strict, err := GetWithOptions(hygieneYAML, "services.#(port>6000).name", Options{StrictTypes: true})
+	if strict.String() != "api" {
+		t.Errorf("Expected strict mode to skip the string port, got '%s'", strict.String())
+	}
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected a TypeError, got %v", err)
+	}
+	if typeErr.Value.Type != String || typeErr.Want != "number" {
+		t.Errorf("Unexpected error details: %v", err)
+	}
+
+	// Equality is not a coercion
+	if _, err := GetWithOptions(hygieneYAML, "services.#(name=db).port", Options{StrictTypes: true}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if Get(hygieneYAML, "services.#(port>6000).name").String() != "web" {
+		t.Error("Get should stay lenient")
+	}
+}
+
+func TestStrictConversions(t *testing.T) {
+	lenient, _ := GetWithOptions(hygieneYAML, "", Options{})
+	strict, _ := GetWithOptions(hygieneYAML, "", Options{StrictTypes: true})
+
+	if n, err := lenient.Get("replicas").IntE(); err != nil || n != 3 {
+		t.Errorf("Expected 3, got %d (%v)", n, err)
+	}
+	if _, err := strict.Get("replicas").IntE(); err == nil {
+		t.Error("Expected an error for a string under strict types")
+	}
+	if strict.Get("replicas").Int() != 0 {
+		t.Error("Expected Int to return 0 under strict types")
+	}
+	if n, err := strict.Get("services.1.port").IntE(); err != nil || n != 9090 {
+		t.Errorf("Expected 9090, got %d (%v)", n, err)
+	}
+	if _, err := lenient.Get("ratio").IntE(); err == nil || lenient.Get("ratio").Int() != 2 {
+		t.Error("Expected IntE to reject a fractional number that Int truncates")
+	}
+	if _, err := strict.Get("ratio").IntE(); err == nil {
+		t.Error("Expected an error for a fractional number under strict types")
+	}
+	if f, err := strict.Get("ratio").FloatE(); err != nil || f != 2.5 {
+		t.Errorf("Expected 2.5, got %v (%v)", f, err)
+	}
+	if _, err := strict.Get("services.0.port").FloatE(); err == nil {
+		t.Error("Expected an error for a string under strict types")
+	}
+
+	if !lenient.Get("debug").Bool() {
+		t.Error("Expected lenient mode to read yes as true")
+	}
+	if strict.Get("debug").Bool() {
+		t.Error("Expected strict mode to reject yes")
+	}
+	if _, err := strict.Get("debug").BoolE(); err == nil {
+		t.Error("Expected an error for yes under strict types")
+	}
+	yaml11, _ := GetWithOptions(hygieneYAML, "debug", Options{StrictTypes: true, YAML11Booleans: true})
+	if b, err := yaml11.BoolE(); err != nil || !b {
+		t.Errorf("Expected yes to be true with YAML 1.1 booleans, got %v (%v)", b, err)
+	}
+	if !strict.Get("verbose").Bool() {
+		t.Error("Expected true to stay true")
+	}
+}
+
+func TestStrictOptionsReachChildren(t *testing.T) {
+	strict, _ := GetWithOptions(hygieneYAML, "services", Options{StrictTypes: true})
+	ports := 0
+	strict.ForEach(func(_, value Result) bool {
+		if _, err := value.Get("port").IntE(); err == nil {
+			ports++
+		}
+		return true
+	})
+	if ports != 2 {
+		t.Errorf("Expected 2 numeric ports, got %d", ports)
+	}
+	if strict.Array()[0].Get("port").Int() != 0 {
+		t.Error("Expected options to reach array elements")
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	if _, err := Get(hygieneYAML, "missing").IntE(); err == nil {
+		t.Error("Expected an error for a missing value")
+	}
+	if _, err := Get(hygieneYAML, "services").FloatE(); err == nil {
+		t.Error("Expected an error for an array")
+	}
+	if _, err := Get(hygieneYAML, "services.0.name").BoolE(); err == nil {
+		t.Error("Expected an error for a string that is not a boolean")
+	}
+	if b, err := Get(hygieneYAML, "debug").BoolE(); err != nil || !b {
+		t.Errorf("Expected true, got %v (%v)", b, err)
+	}
+	_, err := Get(hygieneYAML, "services").IntE()
+	if err == nil || err.Error() != "gyaml: cannot use array as integer" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestConversionsAgree(t *testing.T) {
+	// Wherever an E accessor succeeds, it returns what its plain
+	// counterpart does
+	yml := `
+values: [y, n, Y, "yes", "No", "ON", "off", "t", "F", "TRUE", "tRuE", "1", "0", "",
+  "2", " 2", "2 ", "-3", "1e3", "0x10", "inf", 3.7, -3.7, 0.5, 7, -7, 0, 1e3, 1e30,
+  18446744073709551615, .inf, .nan, true, false, null]
+`
+	for _, opts := range []Options{{}, {StrictTypes: true}, {StrictTypes: true, YAML11Booleans: true}} {
+		values, err := GetWithOptions(yml, "values", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values.ForEach(func(_, v Result) bool {
+			if b, err := v.BoolE(); err == nil && b != v.Bool() {
+				t.Errorf("strict=%v: BoolE(%q) = %v, Bool = %v", opts.StrictTypes, v.String(), b, v.Bool())
+			}
+			if n, err := v.IntE(); err == nil && n != v.Int() {
+				t.Errorf("strict=%v: IntE(%q) = %v, Int = %v", opts.StrictTypes, v.String(), n, v.Int())
+			}
+			if n, err := v.UintE(); err == nil && n != v.Uint() {
+				t.Errorf("strict=%v: UintE(%q) = %v, Uint = %v", opts.StrictTypes, v.String(), n, v.Uint())
+			}
+			if f, err := v.FloatE(); err == nil && f != v.Float() && !(f != f && v.Float() != v.Float()) {
+				t.Errorf("strict=%v: FloatE(%q) = %v, Float = %v", opts.StrictTypes, v.String(), f, v.Float())
+			}
+			if s, err := v.StringE(); err == nil && s != v.String() {
+				t.Errorf("strict=%v: StringE(%q) = %v, String = %v", opts.StrictTypes, v.String(), s, v.String())
+			}
+			return true
+		})
+	}
+
+	for _, s := range []string{"y", "n", "Y", "N", "", "maybe"} {
+		if b, err := (Result{Type: String, Str: s}).BoolE(); err == nil {
+			t.Errorf("BoolE(%q) = %v, expected an error", s, b)
+		}
+	}
+	for _, f := range []float64{3.7, -3.7, 0.5} {
+		r := Result{Type: Number, Num: f}
+		if n, err := r.IntE(); err == nil {
+			t.Errorf("IntE(%v) = %d, expected an error", f, n)
+		}
+		if n, err := r.UintE(); err == nil {
+			t.Errorf("UintE(%v) = %d, expected an error", f, n)
+		}
+	}
+}
+
+func TestStringAndUintErrors(t *testing.T) {
+	strict, _ := GetWithOptions(hygieneYAML, "", Options{StrictTypes: true})
+
+	if s, err := Get(hygieneYAML, "services.0.name").StringE(); err != nil || s != "web" {
+		t.Errorf("Expected web, got %q (%v)", s, err)
+	}
+	if s, err := Get(hygieneYAML, "ratio").StringE(); err != nil || s != "2.5" {
+		t.Errorf("Expected 2.5, got %q (%v)", s, err)
+	}
+	if _, err := strict.Get("ratio").StringE(); err == nil {
+		t.Error("Expected an error for a number under strict types")
+	}
+	for _, path := range []string{"missing", "services", "services.0"} {
+		if _, err := Get(hygieneYAML, path).StringE(); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+	_, err := Get(hygieneYAML, "services.0").StringE()
+	if err == nil || err.Error() != "gyaml: cannot use object as string" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if n, err := Get(hygieneYAML, "replicas").UintE(); err != nil || n != 3 {
+		t.Errorf("Expected 3, got %d (%v)", n, err)
+	}
+	if n, err := strict.Get("services.2.port").UintE(); err != nil || n != 5432 {
+		t.Errorf("Expected 5432, got %d (%v)", n, err)
+	}
+	for _, r := range []Result{strict.Get("replicas"), strict.Get("ratio"), Parse("-1"), Get("n: -1", "n"), Get(hygieneYAML, "missing")} {
+		if _, err := r.Scalar().UintE(); err == nil {
+			t.Errorf("Expected an error for %#v", r)
+		}
+	}
+	if strict.Get("replicas").Uint() != 0 {
+		t.Error("Expected Uint to return 0 under strict types")
+	}
+	if n, err := Get("big: 18446744073709551615", "big").UintE(); err != nil || n != 18446744073709551615 {
+		t.Errorf("Expected the largest value, got %d (%v)", n, err)
+	}
+}
+
+func TestIntegerRange(t *testing.T) {
+	tests := []struct {
+		raw       string
+		int, uint string
+	}{
+		{"9223372036854775807", "9223372036854775807", "9223372036854775807"},
+		{"9223372036854775808", "", "9223372036854775808"},
+		{"-9223372036854775808", "-9223372036854775808", ""},
+		{"-9223372036854775809", "", ""},
+		{"18446744073709551615", "", "18446744073709551615"},
+		{"18446744073709551616", "", ""},
+		{"123456789012345678901234567890", "", ""},
+		{"1e30", "", ""},
+		{"-1e30", "", ""},
+		{"1e3", "1000", "1000"},
+		{"0x7FFFFFFFFFFFFFFF", "9223372036854775807", "9223372036854775807"},
+		{"0x10000000000000000", "", ""},
+	}
+	for _, tt := range tests {
+		r := Get("n: "+tt.raw, "n")
+		n, err := r.IntE()
+		if got := fmt.Sprint(n); tt.int == "" && err == nil || tt.int != "" && (err != nil || got != tt.int) {
+			t.Errorf("%s: IntE() = %s, %v", tt.raw, got, err)
+		}
+		var typeErr *TypeError
+		if err != nil && !errors.As(err, &typeErr) {
+			t.Errorf("%s: expected a TypeError, got %v", tt.raw, err)
+		}
+		u, err := r.UintE()
+		if got := fmt.Sprint(u); tt.uint == "" && err == nil || tt.uint != "" && (err != nil || got != tt.uint) {
+			t.Errorf("%s: UintE() = %s, %v", tt.raw, got, err)
+		}
+	}
+}
+
+func TestGetE(t *testing.T) {
+	if _, err := GetE("a: [1, 2\n", "a"); err == nil {
+		t.Error("Expected a parse error")
+	}
+	r, err := GetE(hygieneYAML, "services.#(port>6000).name")
+	if err != nil || r.String() != "web" {
+		t.Errorf("Expected 'web', got '%s' (%v)", r.String(), err)
+	}
+	if r, err := GetE("", "a"); err != nil || r.Exists() {
+		t.Errorf("Expected empty input to give no result and no error, got %v", err)
+	}
+}
+
+func TestFunctionalOptions(t *testing.T) {
+	path := "services.#(port>6000).name"
+	if got := GetWith(hygieneYAML, path, WithStrictTypes()).String(); got != "api" {
+		t.Errorf("Expected strict mode to skip the string port, got '%s'", got)
+	}
+	// The options of a call do not outlive it
+	if got := Get(hygieneYAML, path).String(); got != "web" {
+		t.Errorf("Expected Get to stay lenient, got '%s'", got)
+	}
+	if got := GetWith(hygieneYAML, path).String(); got != "web" {
+		t.Errorf("Expected GetWith without options to be lenient, got '%s'", got)
+	}
+	if _, err := GetE(hygieneYAML, path); err != nil {
+		t.Errorf("Expected GetE without options to be lenient, got %v", err)
+	}
+
+	doc := ParseWith(hygieneYAML, WithStrictTypes(), WithYAML11Booleans())
+	if b, err := doc.Get("debug").BoolE(); err != nil || !b {
+		t.Errorf("Expected debug to read as true, got %v (%v)", b, err)
+	}
+	if _, err := doc.Get("replicas").IntE(); err == nil {
+		t.Errorf("Expected a strict error for the string replicas")
+	}
+	if _, err := Parse(hygieneYAML).Get("replicas").IntE(); err != nil {
+		t.Errorf("Expected Parse to stay lenient, got %v", err)
+	}
+}
+
+func TestFunctionalOptionsConcurrent(t *testing.T) {
+	path := "services.#(port>6000).name"
+	var wg sync.WaitGroup
+	errs := make(chan string, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(strict bool) {
+			defer wg.Done()
+			var opts []Option
+			want := "web"
+			if strict {
+				opts, want = []Option{WithStrictTypes()}, "api"
+			}
+			if got := GetWith(hygieneYAML, path, opts...).String(); got != want {
+				errs <- got
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	close(errs)
+	for got := range errs {
+		t.Errorf("Options leaked between goroutines: got '%s'", got)
+	}
+}
+
+func TestKeepMergeKeys(t *testing.T) {
+	doc := `defaults: &defaults
+  timeout: 30
+extra: &extra {retries: 2}
+service:
+  <<: *defaults
+  name: web
+multi:
+  <<: [*defaults, *extra]
+`
+	tests := []struct {
+		path             string
+		merged, unmerged string
+	}{
+		{"service.timeout", "30", ""},
+		{"service.<<.timeout", "", "30"},
+		{"service.name", "web", "web"},
+		{"multi.retries", "2", ""},
+		{"multi.<<.1.retries", "", "2"},
+		{"service|@keys", "[timeout name]", "[<< name]"},
+	}
+	parsed := ParseWith(doc, WithKeepMergeKeys())
+	for _, test := range tests {
+		for _, r := range []Result{Get(doc, test.path), GetWith(doc, test.path)} {
+			if got := fmt.Sprint(r.Value()); r.Exists() && got != test.merged || !r.Exists() && test.merged != "" {
+				t.Errorf("Get(%q) = %s, expected %q", test.path, got, test.merged)
+			}
+		}
+		for _, r := range []Result{GetWith(doc, test.path, WithKeepMergeKeys()), parsed.Get(test.path)} {
+			if got := fmt.Sprint(r.Value()); r.Exists() && got != test.unmerged || !r.Exists() && test.unmerged != "" {
+				t.Errorf("Get(%q) keeping merge keys = %s, expected %q", test.path, got, test.unmerged)
+			}
+		}
+	}
+
+	// The Raw text of the object keeps its "<<" key as a key
+	service := GetWith(doc, "service", WithKeepMergeKeys())
+	if back := Parse(service.Raw); !reflect.DeepEqual(back.Value(), service.Value()) {
+		t.Errorf("Expected Raw to read back as the same value, got %q", service.Raw)
+	}
+}
+
+func TestMergeKeyOverrides(t *testing.T) {
+	doc := `defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  <<: *defaults
+  name: web
+  retries: 5
+`
+	service := Get(doc, "service")
+	var entries []string
+	service.ForEach(func(key, value Result) bool {
+		entries = append(entries, key.String()+"="+value.String())
+		return true
+	})
+	if got := fmt.Sprint(entries); got != "[timeout=30 retries=5 name=web]" {
+		t.Errorf("Expected the retries written in the mapping, got %s", got)
+	}
+	if got := fmt.Sprint(service.Values()); got != "[30 5 web]" {
+		t.Errorf("Expected the values written in the mapping, got %s", got)
+	}
+	if got := service.JSON(); got != `{"timeout":30,"retries":5,"name":"web"}` {
+		t.Errorf("Unexpected JSON %s", got)
+	}
+}
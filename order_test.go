@@ -0,0 +1,316 @@
+package gyaml
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const orderedYAML = `
+sections:
+  zeta: 1
+  alpha: 2
+  mu: 3
+  beta: 4
+  omega: 5
+defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  name: api
+  <<: *defaults
+  retries: 5
+list:
+  - b: 1
+    a: 2
+`
+
+// forEachKeys collects the keys visited by ForEach and checks that each
+// position 0..n-1 is reported exactly once, in order.
+func forEachKeys(t *testing.T, r Result) []string {
+	t.Helper()
+	var keys []string
+	r.ForEach(func(key, value Result) bool {
+		if key.Index != len(keys) {
+			t.Errorf("Key %q: expected position %d, got %d", key.String(), len(keys), key.Index)
+		}
+		keys = append(keys, key.String())
+		return true
+	})
+	return keys
+}
+
+func TestForEachDocumentOrder(t *testing.T) {
+	expected := "zeta,alpha,mu,beta,omega"
+	for _, r := range []Result{
+		Get(orderedYAML, "sections"),
+		Parse(orderedYAML).Get("sections"),
+		{Type: YAML, Raw: "zeta: 1\nalpha: 2\nmu: 3\nbeta: 4\nomega: 5\n"},
+	} {
+		keys := strings.Join(forEachKeys(t, r), ",")
+		if keys != expected {
+			t.Errorf("Expected keys %s, got %s", expected, keys)
+		}
+	}
+
+	keys := strings.Join(forEachKeys(t, Parse(orderedYAML).Get("list.0")), ",")
+	if keys != "b,a" {
+		t.Errorf("Expected keys b,a, got %s", keys)
+	}
+}
+
+func TestNestedDocumentOrder(t *testing.T) {
+	doc := `
+items:
+  - meta: {zeta: 1, alpha: 2}
+    name: a
+  - meta: {mu: 3, beta: 4}
+    name: b
+mixed: {3: c, 1: a, true: t, x: y}
+`
+	tests := []struct {
+		name     string
+		result   Result
+		expected string
+	}{
+		{"Map", Parse(doc).Get("items.0").Map()["meta"], "zeta,alpha"},
+		{"projection", Get(doc, "items.#.meta").Array()[1], "mu,beta"},
+		{"Result.Get projection", Parse(doc).Get("items.#.meta|1"), "mu,beta"},
+		{"projected multipath", Get(doc, "items.#.{name,meta}").Array()[0], "name,meta"},
+		{"nested projection", Get(doc, "[items.#.meta]|0").Array()[0], "zeta,alpha"},
+		{"mixed keys", Get(doc, "mixed"), "3,1,true,x"},
+		{"mixed keys Map", Parse("m: {b: {z: 1, a: 2}, 1: x}").Get("m").Map()["b"], "z,a"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(forEachKeys(t, test.result), ","); got != test.expected {
+			t.Errorf("%s: expected keys %s, got %s", test.name, test.expected, got)
+		}
+	}
+
+	// A projection keeps the text of the objects it was built from
+	if got := Get(doc, "items.#.meta").Array()[0].Raw; got != "{zeta: 1, alpha: 2}\n" {
+		t.Errorf("Unexpected Raw %q", got)
+	}
+}
+
+func TestForEachMergeKeyOrder(t *testing.T) {
+	service := Parse(orderedYAML).Get("service")
+	keys := strings.Join(forEachKeys(t, service), ",")
+	if keys != "name,timeout,retries" {
+		t.Errorf("Expected keys name,timeout,retries, got %s", keys)
+	}
+	if service.Get("retries").Int() != 5 {
+		t.Errorf("Expected explicit key to override merged value, got %d", service.Get("retries").Int())
+	}
+}
+
+func TestForEachSortedWithoutNode(t *testing.T) {
+	// Built values have no node
+	r := Object().SetKey("c", 1).SetKey("a", 2).SetKey("b", 3)
+	keys := strings.Join(forEachKeys(t, r), ",")
+	if keys != "a,b,c" {
+		t.Errorf("Expected sorted keys a,b,c, got %s", keys)
+	}
+
+	// Query results are values of the document, and keep its order
+	r = Get(`items: [{c: 1, a: 2, b: 3}]`, "items.#(a=2)")
+	if keys := strings.Join(forEachKeys(t, r), ","); keys != "c,a,b" {
+		t.Errorf("Expected the keys in document order, got %s", keys)
+	}
+}
+
+func TestForEachArrayPositions(t *testing.T) {
+	count := 0
+	Get(testYAML, "children").ForEach(func(key, value Result) bool {
+		if key.Index != count || key.Int() != int64(count) {
+			t.Errorf("Expected position %d, got Index=%d Int=%d", count, key.Index, key.Int())
+		}
+		count++
+		return true
+	})
+	if count != 3 {
+		t.Errorf("Expected 3 elements, got %d", count)
+	}
+}
+
+// siblingsYAML has enough sibling keys that map iteration order would
+// differ from run to run.
+func siblingsYAML() string {
+	var sb strings.Builder
+	sb.WriteString("services:\n")
+	for i := 0; i < 40; i++ {
+		// Keys are not written in sorted order
+		fmt.Fprintf(&sb, "  svc%02d:\n    image: img%d\n    tags: [t%d, u%d]\n", (i*17)%40, i, i, i)
+	}
+	sb.WriteString("mixed: {3: c, 1: a, true: t, 2.5: f, \"2\": b}\n")
+	return sb.String()
+}
+
+// orderSnapshot gathers the output of every API returning several values.
+func orderSnapshot(doc string) []string {
+	var out []string
+	for _, r := range GetAllKeys(doc, "image") {
+		out = append(out, "GetAllKeys "+r.path+"="+r.String())
+	}
+	for _, p := range Parse(doc).WalkPaths(0) {
+		out = append(out, "WalkPaths "+p)
+	}
+	Get(doc, "services").ForEach(func(key, value Result) bool {
+		out = append(out, "ForEach "+key.String()+"="+value.Get("image").String())
+		return true
+	})
+	Get(doc, "mixed").ForEach(func(key, value Result) bool {
+		out = append(out, "ForEach mixed "+key.String()+"="+value.String())
+		return true
+	})
+	// Results built from decoded values have no node
+	synthesized := makeResult(Get(doc, "services").Value())
+	synthesized.ForEach(func(key, value Result) bool {
+		out = append(out, "synthesized "+key.String())
+		return true
+	})
+	out = append(out, "Raw "+synthesized.Raw)
+	for _, r := range Get(doc, "services.svc05.tags").Array() {
+		out = append(out, "Array "+r.String())
+	}
+	for _, d := range Diff(Get(doc, "services"), Object()) {
+		out = append(out, "Diff "+d.Path)
+	}
+	return out
+}
+
+func TestMultiResultOrderStable(t *testing.T) {
+	doc := siblingsYAML()
+	first := orderSnapshot(doc)
+	if len(first) < 300 {
+		t.Fatalf("Expected a large snapshot, got %d entries", len(first))
+	}
+	for run := 1; run < 100; run++ {
+		if got := orderSnapshot(doc); !reflect.DeepEqual(got, first) {
+			t.Fatalf("Run %d differs from the first run", run)
+		}
+	}
+}
+
+func TestMultiResultOrderDocumented(t *testing.T) {
+	doc := siblingsYAML()
+
+	// Node-aware results follow the document
+	paths := Parse(doc).WalkPaths(2)
+	if paths[0] != "services" || paths[1] != "services.svc00" || paths[2] != "services.svc17" {
+		t.Errorf("Expected document order, got %q", paths[:3])
+	}
+	images := GetAllKeys(doc, "image")
+	if len(images) != 40 || images[0].String() != "img0" || images[39].String() != "img39" {
+		t.Errorf("Expected matches in document order, got %d", len(images))
+	}
+
+	// Values without a node are visited in sorted key order
+	var keys []string
+	makeResult(Get(doc, "services").Value()).ForEach(func(key, value Result) bool {
+		keys = append(keys, key.String())
+		return true
+	})
+	if len(keys) != 40 || keys[0] != "svc00" || keys[1] != "svc01" || keys[39] != "svc39" {
+		t.Errorf("Expected sorted keys, got %q", keys)
+	}
+	keys = forEachKeys(t, Get(doc, "mixed"))
+	if got := strings.Join(keys, ","); got != "3,1,true,2.5,2" {
+		t.Errorf("Expected mixed keys in document order, got %s", got)
+	}
+	keys = forEachKeys(t, makeResult(Get(doc, "mixed").Value()))
+	if got := strings.Join(keys, ","); got != "1,2,2.5,3,true" {
+		t.Errorf("Expected mixed keys without a node in sorted order, got %s", got)
+	}
+}
+
+func TestKeysAndValues(t *testing.T) {
+	service := Get(orderedYAML, "service")
+	if got := strings.Join(service.Keys(), ","); got != "name,timeout,retries" {
+		t.Errorf("Expected keys name,timeout,retries, got %s", got)
+	}
+	var values []string
+	for _, v := range service.Values() {
+		values = append(values, v.String())
+	}
+	if got := strings.Join(values, ","); got != "api,30,5" {
+		t.Errorf("Expected values api,30,5, got %s", got)
+	}
+	for _, path := range []string{"service|@keys", "service.@keys"} {
+		if got := fmt.Sprint(Get(orderedYAML, path).Value()); got != "[name timeout retries]" {
+			t.Errorf("%s: expected the keys of Keys, got %s", path, got)
+		}
+	}
+	if got := fmt.Sprint(Parse(orderedYAML).Get("service|@values").Value()); got != "[api 30 5]" {
+		t.Errorf("Expected the values of Values, got %s", got)
+	}
+
+	if got := strings.Join(Parse("{3: c, 1: a, x: y}").Keys(), ","); got != "3,1,x" {
+		t.Errorf("Expected mixed keys 3,1,x, got %s", got)
+	}
+	if got := strings.Join(makeResult(map[string]interface{}{"b": 1, "a": 2}).Keys(), ","); got != "a,b" {
+		t.Errorf("Expected sorted keys without a node, got %s", got)
+	}
+	if keys := Parse("{}").Keys(); keys == nil || len(keys) != 0 {
+		t.Errorf("Expected no keys for an empty object, got %v", keys)
+	}
+	for _, r := range []Result{Get(orderedYAML, "list"), Get(orderedYAML, "service.name"), Get(orderedYAML, "missing")} {
+		if r.Keys() != nil || r.Values() != nil {
+			t.Errorf("Expected no keys or values for %s", r.Raw)
+		}
+	}
+}
+
+func TestSlices(t *testing.T) {
+	doc := Parse("mixed: [1, '2', 3.5, true, ~, [a], {b: c}]\nnums: [0x10, -2, 1e3]\nname: x\n")
+	if got := doc.Get("mixed").StringSlice(); !reflect.DeepEqual(got, []string{"1", "2", "3.5", "true", "", "[a]\n", "{b: c}\n"}) {
+		t.Errorf("Unexpected strings %q", got)
+	}
+	if got := doc.Get("mixed").IntSlice(); !reflect.DeepEqual(got, []int64{1, 2, 3, 1, 0, 0, 0}) {
+		t.Errorf("Unexpected ints %v", got)
+	}
+	if got := doc.Get("nums").IntSlice(); !reflect.DeepEqual(got, []int64{16, -2, 1000}) {
+		t.Errorf("Unexpected ints %v", got)
+	}
+	if got := doc.Get("nums").FloatSlice(); !reflect.DeepEqual(got, []float64{16, -2, 1000}) {
+		t.Errorf("Unexpected floats %v", got)
+	}
+	if got := doc.Get("mixed.#.b").StringSlice(); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Unexpected query strings %q", got)
+	}
+	if got := Parse("[]").FloatSlice(); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty slice, got %v", got)
+	}
+	for _, r := range []Result{doc.Get("name"), doc.Get("missing"), doc} {
+		if r.StringSlice() != nil || r.IntSlice() != nil || r.FloatSlice() != nil {
+			t.Errorf("Expected nil slices for %s", r.Raw)
+		}
+	}
+}
+
+func TestMapString(t *testing.T) {
+	doc := Parse("labels: {app: web, replicas: 3, ratio: 0.5, canary: false, owner: ~, 8080: http, ports: [80, 443]}\nname: x\n")
+	want := map[string]string{"app": "web", "replicas": "3", "ratio": "0.5", "canary": "false", "owner": "", "8080": "http", "ports": "[80, 443]\n"}
+	if got := doc.Get("labels").MapStringString(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected %q", got)
+	}
+	got := doc.Get("labels").MapString()
+	if got["replicas"] != "3" || got["canary"] != "false" || got["8080"] != "http" || got["owner"] != nil {
+		t.Errorf("Unexpected scalars %v", got)
+	}
+	if _, ok := got["owner"]; !ok {
+		t.Errorf("Expected the null entry to be kept")
+	}
+	if ports, ok := got["ports"].([]interface{}); !ok || len(ports) != 2 {
+		t.Errorf("Expected the array to keep its structure, got %#v", got["ports"])
+	}
+	if m := Parse("{}").MapStringString(); m == nil || len(m) != 0 {
+		t.Errorf("Expected an empty map, got %v", m)
+	}
+	for _, r := range []Result{doc.Get("name"), doc.Get("labels.ports"), doc.Get("missing")} {
+		if r.MapString() != nil || r.MapStringString() != nil {
+			t.Errorf("Expected nil maps for %s", r.Raw)
+		}
+	}
+}
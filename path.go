@@ -0,0 +1,439 @@
+package gyaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// splitPath splits a path into its dot-separated components. Dots that are
+// escaped with a backslash, or that appear inside a query's parentheses or
+// quotes, do not separate components. The components are returned in
+// their raw form, escapes included, so they can be joined back together;
+// a bracketed key such as ["app.kubernetes.io/name"] is returned escaped
+// as by Escape. A | separates components like a dot, and pathParts tells
+// the two apart.
+func splitPath(path string) []string {
+	parts := pathParts(path)
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		texts[i] = part.text
+	}
+	return texts
+}
+
+// pathPart is a component of a path, with the offsets of its source text.
+// pipe is set for a component that follows a |.
+type pathPart struct {
+	text       string
+	start, end int
+	pipe       bool
+}
+
+// pathParts splits a path into components like splitPath, keeping track
+// of where each one is written.
+func pathParts(path string) []pathPart {
+	var parts []pathPart
+	depth := 0
+	var quote byte
+	start := 0
+	bracketed, pipe := false, false
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == '@' && depth == 0 && i == start:
+			// The argument of a modifier may hold separators
+			i = modifierEnd(path, i) - 1
+		case (c == '.' || c == '|') && depth == 0:
+			if !bracketed {
+				parts = append(parts, pathPart{path[start:i], start, i, pipe})
+			}
+			start, bracketed, pipe = i+1, false, c == '|'
+		case c == '{' && depth == 0 && i == start:
+			i = compositeEnd(path, i) - 1
+		case c == '[' && depth == 0:
+			key, n, ok := bracketKey(path[i:])
+			if !ok {
+				if i == start {
+					// A multipath array
+					i = compositeEnd(path, i) - 1
+				}
+				continue
+			}
+			if i > start {
+				parts = append(parts, pathPart{path[start:i], start, i, pipe})
+				pipe = false
+			}
+			parts = append(parts, pathPart{Escape(key), i, i + n, pipe})
+			i += n - 1
+			start, bracketed, pipe = i+1, true, false
+		}
+	}
+	if bracketed && start == len(path) {
+		return parts
+	}
+	return append(parts, pathPart{path[start:], start, len(path), pipe})
+}
+
+// modifierEnd returns the offset just past the modifier at path[i], such
+// as @reverse or @flatten:{deep: true}. An argument that starts with a
+// bracket, brace or quote runs to its closing counterpart, so it may hold
+// dots and pipes; any other argument ends at the next dot or pipe.
+func modifierEnd(path string, i int) int {
+	j := i + 1
+	for j < len(path) && path[j] != '.' && path[j] != '|' && path[j] != ':' {
+		j++
+	}
+	if j == len(path) || path[j] != ':' {
+		return j
+	}
+	j++
+	if j == len(path) || !strings.ContainsRune("{[\"'", rune(path[j])) {
+		for j < len(path) && path[j] != '.' && path[j] != '|' {
+			j++
+		}
+		return j
+	}
+	depth := 0
+	var quote byte
+	for ; j < len(path); j++ {
+		c := path[j]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				j++
+			} else if c == quote {
+				quote = 0
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			if depth--; depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return j
+}
+
+// compositeEnd returns the offset just past the multipath at path[i], such
+// as {name,age} or [name,age], or len(path) when it is not closed.
+// Brackets, braces and parentheses nest, and quotes and escapes are
+// skipped.
+func compositeEnd(path string, i int) int {
+	depth := 0
+	var quote byte
+	for j := i; j < len(path); j++ {
+		c := path[j]
+		switch {
+		case c == '\\':
+			j++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{' || c == '[' || c == '(':
+			depth++
+		case c == '}' || c == ']' || c == ')':
+			if depth--; depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(path)
+}
+
+// isClosedComposite reports whether s is a single multipath closed by the
+// bracket or brace that matches its first character.
+func isClosedComposite(s string) bool {
+	if len(s) < 2 || compositeEnd(s, 0) != len(s) {
+		return false
+	}
+	return s[0] == '{' && s[len(s)-1] == '}' || s[0] == '[' && s[len(s)-1] == ']'
+}
+
+// cutPipe splits a path at its first |, returning the path before it and
+// the path after it.
+func cutPipe(path string, parts []pathPart) (string, string, bool) {
+	for _, part := range parts[1:] {
+		if part.pipe {
+			return path[:part.start-1], path[part.start:], true
+		}
+	}
+	return path, "", false
+}
+
+// bracketKey reads the key of a bracketed component at the start of s,
+// such as ["a.b"] or ['a.b'], returning the key and the length of the
+// component.
+func bracketKey(s string) (string, int, bool) {
+	if len(s) < 4 || s[0] != '[' {
+		return "", 0, false
+	}
+	key, n, ok := quotedKey(s[1:])
+	if !ok || n+1 >= len(s) || s[n+1] != ']' {
+		return "", 0, false
+	}
+	return key, n + 2, true
+}
+
+// quotedKey reads a key in single or double quotes at the start of s,
+// returning the key and the length of its text. Inside the quotes, a
+// backslash escapes the next character.
+func quotedKey(s string) (string, int, bool) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') {
+		return "", 0, false
+	}
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			sb.WriteByte(s[i])
+		case c == quote:
+			return sb.String(), i + 1, true
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// unescapeKey removes the backslash escapes from a path component that is
+// used as a map key.
+func unescapeKey(part string) string {
+	if strings.IndexByte(part, '\\') < 0 {
+		return part
+	}
+	var sb strings.Builder
+	sb.Grow(len(part))
+	for i := 0; i < len(part); i++ {
+		if part[i] == '\\' && i+1 < len(part) {
+			i++
+		}
+		sb.WriteByte(part[i])
+	}
+	return sb.String()
+}
+
+// isSafeKeyChar reports whether c can appear unescaped in a path key.
+// Control characters, spaces and non-ASCII bytes have no meaning in the
+// path grammar and are left alone.
+func isSafeKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || c <= ' ' || c > '~' ||
+		c == '_' || c == '-' || c == ':' || c == '/'
+}
+
+// Escape escapes a map key so that it can be used as a single component of
+// a path, no matter which characters it contains. Keys that would
+// otherwise be read as an array index are escaped as well.
+//
+//	gyaml.Get(yaml, "labels."+gyaml.Escape("app.kubernetes.io/name"))
+func Escape(key string) string {
+	if key == "" {
+		return key
+	}
+	_, isIndex := parseIndex(key)
+	needs := isIndex
+	for i := 0; i < len(key) && !needs; i++ {
+		needs = !isSafeKeyChar(key[i])
+	}
+	if !needs {
+		return key
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(key) + 4)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !isSafeKeyChar(c) || (i == 0 && isIndex) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// SegmentKind identifies the role of a path segment.
+type SegmentKind int
+
+const (
+	// SegmentKey is a map key, such as "name" or "app\.kubernetes\.io"
+	SegmentKey SegmentKind = iota
+	// SegmentIndex is an array index, such as "0"
+	SegmentIndex
+	// SegmentLength is a trailing "#", which counts the elements of an
+	// array or the entries of an object
+	SegmentLength
+	// SegmentQuery is an array query, such as "#(age>40)" for the first
+	// match or "#(age>40)#" for all of them
+	SegmentQuery
+	// SegmentProjection applies the rest of the path to every element of
+	// an array: a "#" followed by more segments, or the "#key" shorthand.
+	// A "#key" segment is read as a key when the object has that key.
+	SegmentProjection
+	// SegmentModifier is a modifier, such as "@reverse" or
+	// "@flatten:{deep: true}". A segment naming no modifier is read as a
+	// key, so keys such as "@timestamp" need no escape.
+	SegmentModifier
+	// SegmentSlice is reserved for array slices. The current grammar has
+	// no slices, so SplitPath does not produce it.
+	SegmentSlice
+	// SegmentMultipath builds an object or array from several paths, such
+	// as "{name,age}" or "[name,age]"
+	SegmentMultipath
+)
+
+// String returns the name of the segment kind.
+func (k SegmentKind) String() string {
+	switch k {
+	case SegmentKey:
+		return "Key"
+	case SegmentIndex:
+		return "Index"
+	case SegmentLength:
+		return "Length"
+	case SegmentQuery:
+		return "Query"
+	case SegmentProjection:
+		return "Projection"
+	case SegmentModifier:
+		return "Modifier"
+	case SegmentSlice:
+		return "Slice"
+	case SegmentMultipath:
+		return "Multipath"
+	}
+	return "SegmentKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Segment is one component of a path.
+type Segment struct {
+	// Kind is the role of the segment
+	Kind SegmentKind
+	// Text is the literal text of the segment, escapes included. For a
+	// bracketed key such as ["a.b"], it is the key escaped as by Escape
+	Text string
+	// Pos is the byte offset of the segment in the path
+	Pos int
+	// Pipe is set when the segment follows a | rather than a dot, so that
+	// it applies to the result of the path before it
+	Pipe bool
+}
+
+// SplitPath splits a path into the segments Get resolves it by. Empty
+// segments, which Get skips, are left out. An error is returned for a
+// path ending in a lone backslash, holding a query whose parentheses or
+// quotes are not closed, or holding a bracketed key or a multipath that
+// is not closed.
+func SplitPath(path string) ([]Segment, error) {
+	if err := checkPath(path); err != nil {
+		return nil, err
+	}
+	parts := pathParts(path)
+	segments := make([]Segment, 0, len(parts))
+	for i, part := range parts {
+		if part.text != "" {
+			kind := segmentKind(part.text, i == len(parts)-1 || parts[i+1].pipe)
+			if kind == SegmentMultipath && !isClosedComposite(part.text) {
+				return nil, fmt.Errorf("gyaml: path %q has an unclosed multipath at offset %d", path, part.start)
+			}
+			segments = append(segments, Segment{
+				Kind: kind,
+				Text: part.text,
+				Pos:  part.start,
+				Pipe: part.pipe,
+			})
+		}
+	}
+	return segments, nil
+}
+
+// segmentKind classifies a raw path component. last is set for the final
+// component of the path.
+func segmentKind(part string, last bool) SegmentKind {
+	switch {
+	case part == "#":
+		if last {
+			return SegmentLength
+		}
+		return SegmentProjection
+	case strings.HasPrefix(part, "#(") && (strings.HasSuffix(part, ")") || strings.HasSuffix(part, ")#")):
+		return SegmentQuery
+	case part[0] == '#':
+		return SegmentProjection
+	case part[0] == '@':
+		return SegmentModifier
+	case part[0] == '{' || part[0] == '[':
+		return SegmentMultipath
+	}
+	if _, ok := parseIndex(part); ok {
+		return SegmentIndex
+	}
+	return SegmentKey
+}
+
+// checkPath reports the syntax errors that splitPath glosses over.
+func checkPath(path string) error {
+	depth := 0
+	var quote byte
+	open := 0
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\':
+			if i == len(path)-1 {
+				return fmt.Errorf("gyaml: path %q ends in an escape", path)
+			}
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == '(':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == '[' && depth == 0 && i+1 < len(path) && (path[i+1] == '"' || path[i+1] == '\''):
+			_, n, ok := bracketKey(path[i:])
+			if !ok {
+				return fmt.Errorf("gyaml: path %q has an unclosed bracketed key at offset %d", path, i)
+			}
+			i += n - 1
+		}
+	}
+	if depth > 0 || quote != 0 {
+		return fmt.Errorf("gyaml: path %q has an unclosed query at offset %d", path, open)
+	}
+	return nil
+}
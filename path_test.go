@@ -0,0 +1,335 @@
+package gyaml
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"a.b.c", []string{"a", "b", "c"}},
+		{"a..b", []string{"a", "", "b"}},
+		{`a\.b.c`, []string{`a\.b`, "c"}},
+		{`servers.#(ip="192.168.1.10").name`, []string{"servers", `#(ip="192.168.1.10")`, "name"}},
+		{`items.#(name='a.b').id`, []string{"items", `#(name='a.b')`, "id"}},
+		{`a\\.b`, []string{`a\\`, "b"}},
+	}
+	for _, test := range tests {
+		got := splitPath(test.path)
+		if strings.Join(got, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("splitPath(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"name", "name"},
+		{"key with spaces", "key with spaces"},
+		{"app.kubernetes.io/name", `app\.kubernetes\.io/name`},
+		{"#special", `\#special`},
+		{`C:\path`, `C:\\path`},
+		{"42", `\42`},
+		{"-1", `\-1`},
+		{"1a", "1a"},
+		{"日本語", "日本語"},
+		{"line\nbreak", "line\nbreak"},
+	}
+	for _, test := range tests {
+		if got := Escape(test.key); got != test.expected {
+			t.Errorf("Escape(%q) = %q, expected %q", test.key, got, test.expected)
+		}
+	}
+}
+
+func TestEscapedPaths(t *testing.T) {
+	yml := `
+labels:
+  app.kubernetes.io/name: web
+  "#special": hash
+  "42": numeric
+  "C:\\path": windows
+servers:
+  - name: a
+    ip: "192.168.1.10"
+  - name: b
+    ip: "192.168.1.11"
+`
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{`labels.app\.kubernetes\.io/name`, "web"},
+		{`labels.\#special`, "hash"},
+		{`labels.\42`, "numeric"},
+		{`labels.C:\\path`, "windows"},
+		{`servers.#(ip="192.168.1.11").name`, "b"},
+	}
+	for _, test := range tests {
+		if got := Get(yml, test.path).String(); got != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+	if Get(yml, "labels.42").String() != "numeric" {
+		t.Error("Numeric component on a map should match the key")
+	}
+}
+
+func TestBracketedKeys(t *testing.T) {
+	yml := `
+metadata:
+  labels:
+    app.kubernetes.io/name: web
+    "a#b(c)": parens
+    "0": zero
+    'say "hi"': quoted
+items:
+  - {name: a, "tier=front": true}
+  - {name: b, "tier=front": false}
+`
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{`metadata.labels["app.kubernetes.io/name"]`, "web"},
+		{`metadata.labels['app.kubernetes.io/name']`, "web"},
+		{`metadata["labels"]["a#b(c)"]`, "parens"},
+		{`["metadata"].labels.["0"]`, "zero"},
+		{`metadata.labels["say \"hi\""]`, "quoted"},
+		{`metadata.labels['say "hi"']`, "quoted"},
+		{`items.#(["tier=front"]=false).name`, "b"},
+		{`items.1["name"]`, "b"},
+	}
+	for _, test := range tests {
+		if got := Get(yml, test.path).String(); got != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+
+	// A bracketed key is always a key, and brackets without quotes are
+	// part of a plain key
+	if Get("list: [a]\nkeys: {'0': x, 'k[0]': y}", `list["0"]`).Exists() {
+		t.Error("Expected a bracketed key not to index an array")
+	}
+	if got := Get("keys: {'k[0]': y}", "keys.k[0]").String(); got != "y" {
+		t.Errorf("Expected brackets without quotes to be part of the key, got %q", got)
+	}
+
+	segments, err := SplitPath(`metadata.labels["app.kubernetes.io/name"].x`)
+	if err != nil || len(segments) != 4 || segments[2].Text != `app\.kubernetes\.io/name` || segments[2].Pos != 15 || segments[3].Pos != 42 {
+		t.Errorf("Unexpected segments %v, %v", segments, err)
+	}
+	if _, err := SplitPath(`labels["app`); err == nil {
+		t.Error("Expected an error for an unclosed bracketed key")
+	}
+}
+
+// randomKey builds a key from runes that are awkward for both the path
+// grammar and the YAML emitter.
+func randomKey(rng *rand.Rand) string {
+	pool := []rune("ab.#\\()|@*?[]{}=\"' \t\n:-0123456789é日本😀\u00a0\u2028")
+	n := 1 + rng.Intn(8)
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteRune(pool[rng.Intn(len(pool))])
+	}
+	return sb.String()
+}
+
+func TestEscapeRoundTripProperty(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		outer, inner := randomKey(rng), randomKey(rng)
+		doc := map[string]interface{}{
+			outer: map[string]interface{}{inner: i},
+		}
+		out, err := marshalYAML(doc)
+		if err != nil {
+			t.Fatalf("marshal failed for keys %q, %q: %v", outer, inner, err)
+		}
+		path := Escape(outer) + "." + Escape(inner)
+		if got := Get(string(out), path); got.Int() != int64(i) {
+			t.Errorf("keys %q, %q: Get(%q) = %v, expected %d\n%s", outer, inner, path, got, i, out)
+		}
+
+		// Keys must also survive a synthesized container Result
+		raw := makeResult(doc).Raw
+		if got := Get(raw, path); got.Int() != int64(i) {
+			t.Errorf("keys %q, %q: Get(Raw, %q) = %v, expected %d\n%s", outer, inner, path, got, i, raw)
+		}
+		var back map[string]interface{}
+		if err := yaml.Unmarshal([]byte(raw), &back); err != nil || back[outer] == nil {
+			t.Errorf("keys %q, %q: Raw does not parse back: %v\n%s", outer, inner, err, raw)
+		}
+	}
+}
+
+func TestSplitPathSegments(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"name.first", "Key:name@0 Key:first@5"},
+		{"children.1", "Key:children@0 Index:1@9"},
+		{"children.#", "Key:children@0 Length:#@9"},
+		{"friends.#.age", "Key:friends@0 Projection:#@8 Key:age@10"},
+		{"friends.#first", "Key:friends@0 Projection:#first@8"},
+		{`friends.#(last="Murphy").first`, `Key:friends@0 Query:#(last="Murphy")@8 Key:first@25`},
+		{`items.#(name='a.b').id`, `Key:items@0 Query:#(name='a.b')@6 Key:id@20`},
+		{`labels.app\.kubernetes\.io/name`, `Key:labels@0 Key:app\.kubernetes\.io/name@7`},
+		{`\1.a`, `Key:\1@0 Key:a@3`},
+		{"a..b.", "Key:a@0 Key:b@3"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		segments, err := SplitPath(test.path)
+		if err != nil {
+			t.Errorf("SplitPath(%q) returned error: %v", test.path, err)
+			continue
+		}
+		var got []string
+		for _, s := range segments {
+			got = append(got, s.Kind.String()+":"+s.Text+"@"+strconv.Itoa(s.Pos))
+			if test.path[s.Pos:s.Pos+len(s.Text)] != s.Text {
+				t.Errorf("SplitPath(%q): segment %q is not at offset %d", test.path, s.Text, s.Pos)
+			}
+		}
+		if strings.Join(got, " ") != test.expected {
+			t.Errorf("SplitPath(%q) = %s, expected %s", test.path, strings.Join(got, " "), test.expected)
+		}
+	}
+}
+
+func TestSplitPathErrors(t *testing.T) {
+	for _, path := range []string{`a\`, "a.#(b=1", `a.#(b="x).c`, "#((a=1)"} {
+		if _, err := SplitPath(path); err == nil {
+			t.Errorf("Expected an error for %q", path)
+		}
+	}
+}
+
+// TestSplitPathAgreesWithGet checks every literal path passed to Get in
+// the test suite: it must split without error, and joining its segments
+// back together must resolve to the same value.
+func TestSplitPathAgreesWithGet(t *testing.T) {
+	paths := suitePaths(t)
+	if len(paths) < 100 {
+		t.Fatalf("Expected to find the paths of the test suite, found %d", len(paths))
+	}
+
+	docs := []string{testYAML, complexYAML, edgeCaseYAML, benchmarkYAML, orderedYAML, hygieneYAML}
+	for path := range paths {
+		segments, err := SplitPath(path)
+		if err != nil {
+			t.Errorf("SplitPath(%q) returned error: %v", path, err)
+			continue
+		}
+		var sb strings.Builder
+		for i, s := range segments {
+			if i > 0 && s.Pipe {
+				sb.WriteByte('|')
+			} else if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(s.Text)
+		}
+		joined := sb.String()
+		if joined == "" {
+			continue
+		}
+		for _, doc := range docs {
+			if got, want := Get(doc, joined), Get(doc, path); got.Raw != want.Raw || got.Type != want.Type {
+				t.Errorf("Path %q resolved differently after SplitPath: %q vs %q", path, got.Raw, want.Raw)
+			}
+		}
+	}
+}
+
+// suitePaths collects the literal paths passed to Get, GetE,
+// GetWithOptions and Result.Get across the test suite.
+func suitePaths(t *testing.T) map[string]bool {
+	t.Helper()
+	files, err := filepath.Glob("*_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			var arg ast.Expr
+			switch fun := call.Fun.(type) {
+			case *ast.Ident:
+				if (fun.Name == "Get" || fun.Name == "GetE" || fun.Name == "GetWithOptions") && len(call.Args) >= 2 {
+					arg = call.Args[1]
+				}
+			case *ast.SelectorExpr:
+				if fun.Sel.Name == "Get" && len(call.Args) == 1 {
+					arg = call.Args[0]
+				}
+			}
+			if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if path, err := strconv.Unquote(lit.Value); err == nil {
+					paths[path] = true
+				}
+			}
+			return true
+		})
+	}
+	return paths
+}
+
+func TestNonStringKeys(t *testing.T) {
+	yml := `
+ports: {8080: http, 443: https}
+flags: {true: on, null: none}
+both: {0x1: int, "1": string}
+`
+	if Get(yml, "ports.8080").String() != "http" || Get(yml, "ports.443").String() != "https" {
+		t.Error("Expected integer keys to match their written form")
+	}
+	if Get(yml, "flags.true").String() != "on" || Get(yml, "flags.null").String() != "none" {
+		t.Error("Expected boolean and null keys to match their written form")
+	}
+
+	// The decoder really keeps both keys
+	if n := Get(yml, "both").Len(); n != 2 {
+		t.Fatalf("Expected 2 keys, got %d", n)
+	}
+	r, err := GetE(yml, "both.1")
+	if r.String() != "string" {
+		t.Errorf("Expected the string key to win, got %q", r.String())
+	}
+	if !errors.Is(err, ErrAmbiguousKey) {
+		t.Errorf("Expected an ambiguous key error, got %v", err)
+	}
+	if Get(yml, "both.1").String() != "string" || Get(yml, "both").Map()["1"].String() != "string" {
+		t.Error("Expected the string key to win in Get and Map")
+	}
+	if _, err := GetE(yml, "ports.8080"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,741 @@
+package gyaml
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// condition is a parsed query condition, such as age>=21 in #(age>=21).
+type condition struct {
+	// key is the field of an element the condition tests, or empty to
+	// test the element itself
+	key string
+	op  *operator
+	// value is the operand, with its quotes removed
+	value string
+	// fold is set when the operator was written with a ~, as in
+	// #(env~="prod"), to compare strings without regard to case
+	fold bool
+	// presence is set for a condition on the key alone, as in #(tls),
+	// which holds when the element has the key, whatever its value, or
+	// with absent also set, as in #(!tls), when it does not. op is nil.
+	presence, absent bool
+}
+
+// operator is a comparison available in queries and conditions.
+type operator struct {
+	token string
+	match func(r *resolver, val interface{}, expected string) (bool, error)
+}
+
+// operators is the registry of comparisons. At each position of a
+// condition, longer tokens are tried before their prefixes.
+var operators = []*operator{
+	{token: ">=", match: orderedBy(func(c int) bool { return c >= 0 })},
+	{token: "<=", match: orderedBy(func(c int) bool { return c <= 0 })},
+	{token: "!=", match: func(r *resolver, val interface{}, expected string) (bool, error) {
+		return !equalValue(val, expected), nil
+	}},
+	{token: "!%", match: matchedBy(false)},
+	{token: ">", match: orderedBy(func(c int) bool { return c > 0 })},
+	{token: "<", match: orderedBy(func(c int) bool { return c < 0 })},
+	{token: "=", match: func(r *resolver, val interface{}, expected string) (bool, error) {
+		return equalValue(val, expected), nil
+	}},
+	{token: "%", match: matchedBy(true)},
+	{token: " in ", match: func(r *resolver, val interface{}, expected string) (bool, error) {
+		values, ok := parseList(expected)
+		if !ok {
+			return false, fmt.Errorf("the operand of in must be a list such as [a, b], not %q", expected)
+		}
+		for _, v := range values {
+			if equalValue(val, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	{token: " startswith ", match: testedBy(strings.HasPrefix)},
+	{token: " endswith ", match: testedBy(strings.HasSuffix)},
+	{token: " contains ", match: testedBy(strings.Contains)},
+	{token: " has ", match: func(r *resolver, val interface{}, expected string) (bool, error) {
+		arr, ok := val.([]interface{})
+		if !ok {
+			if r.opts.StrictTypes {
+				return false, &TypeError{Value: makeResult(val), Want: "array"}
+			}
+			return false, nil
+		}
+		for _, elem := range arr {
+			if equalValue(elem, expected) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+}
+
+// parseList splits the operand of the in operator, a list such as
+// ["a", "b"] or [1, 2], into its values with their quotes removed. It
+// returns false when the operand is not a list.
+func parseList(expr string) ([]string, bool) {
+	expr = strings.TrimSpace(expr)
+	if len(expr) < 2 || expr[0] != '[' || expr[len(expr)-1] != ']' {
+		return nil, false
+	}
+	inner := strings.TrimSpace(expr[1 : len(expr)-1])
+	if inner == "" {
+		return []string{}, true
+	}
+	parts := splitCompound(inner, ',')
+	values := make([]string, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+		values[i] = part
+	}
+	return values, true
+}
+
+// lookupOperator returns the operator with the given token, or nil.
+func lookupOperator(token string) *operator {
+	for _, op := range operators {
+		if op.token == token {
+			return op
+		}
+	}
+	return nil
+}
+
+// equalValue reports whether a value equals the operand of = and !=:
+// timestamps are equal when they are the same instant, durations when
+// they are as long, byte sizes when they hold as many bytes, and other
+// values when their text is the operand.
+func equalValue(val interface{}, expected string) bool {
+	if c, ok := compareTimes(val, expected); ok {
+		return c == 0
+	}
+	if c, ok := compareDurations(val, expected); ok {
+		return c == 0
+	}
+	if c, ok := compareSizes(val, expected); ok {
+		return c == 0
+	}
+	return fmt.Sprintf("%v", val) == expected
+}
+
+// orderedBy returns the match function of an ordering operator, which
+// compares the value and the operand chronologically when the operand is
+// a timestamp, by length when it is a duration, by bytes when it is a byte
+// size, as text when the value is a string and the operand is not a
+// number, and as numbers otherwise. Values that compare in none of these
+// ways do not match. With strict types, the values compared as numbers
+// must be numbers and an error is reported otherwise.
+func orderedBy(accept func(c int) bool) func(r *resolver, val interface{}, expected string) (bool, error) {
+	return func(r *resolver, val interface{}, expected string) (bool, error) {
+		if c, ok := compareTimes(val, expected); ok {
+			return accept(c), nil
+		}
+		if _, ok := parseTimestamp(expected); ok {
+			// A timestamp orders only against other timestamps
+			if r.opts.StrictTypes {
+				return false, &TypeError{Value: makeResult(val), Want: "timestamp"}
+			}
+			return false, nil
+		}
+		if c, ok := compareDurations(val, expected); ok {
+			return accept(c), nil
+		}
+		if _, ok := parseDuration(expected); ok {
+			// So does a duration against other durations
+			if r.opts.StrictTypes {
+				return false, &TypeError{Value: makeResult(val), Want: "duration"}
+			}
+			return false, nil
+		}
+		if c, ok := compareSizes(val, expected); ok {
+			return accept(c), nil
+		}
+		if _, ok := parseSize(expected); ok && !isNumberText(expected) {
+			// And a byte size against other sizes and numbers of bytes
+			if r.opts.StrictTypes {
+				return false, &TypeError{Value: makeResult(val), Want: "size"}
+			}
+			return false, nil
+		}
+		if str, ok := val.(string); ok && !isNumberText(expected) {
+			// Strings order as text against an operand that is not a number
+			return accept(strings.Compare(str, expected)), nil
+		}
+		if r.opts.StrictTypes {
+			if !isNumeric(val) {
+				return false, &TypeError{Value: makeResult(val), Want: "number"}
+			}
+			if !isNumberText(expected) {
+				return false, &TypeError{Value: Result{Type: String, Str: expected}, Want: "number"}
+			}
+		}
+		c, ok := compareNumbers(val, expected)
+		return ok && accept(c), nil
+	}
+}
+
+// isNumberText reports whether s is the text of a number.
+func isNumberText(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// matchedBy returns the match function of a pattern operator, which holds
+// when the value matches the pattern, or with want false, when it does
+// not. Values other than strings are matched by their text; with strict
+// types, they are an error.
+func matchedBy(want bool) func(r *resolver, val interface{}, expected string) (bool, error) {
+	return func(r *resolver, val interface{}, expected string) (bool, error) {
+		str, ok := val.(string)
+		if !ok {
+			if r.opts.StrictTypes {
+				return false, &TypeError{Value: makeResult(val), Want: "string"}
+			}
+			str = fmt.Sprintf("%v", val)
+		}
+		return matchPattern(str, expected) == want, nil
+	}
+}
+
+// testedBy returns the match function of a string operator, which holds
+// when test does for the value and the operand. Values other than strings
+// are tested by their text; with strict types, they are an error.
+func testedBy(test func(s, operand string) bool) func(r *resolver, val interface{}, expected string) (bool, error) {
+	return func(r *resolver, val interface{}, expected string) (bool, error) {
+		str, ok := val.(string)
+		if !ok {
+			if r.opts.StrictTypes {
+				return false, &TypeError{Value: makeResult(val), Want: "string"}
+			}
+			str = fmt.Sprintf("%v", val)
+		}
+		return test(str, expected), nil
+	}
+}
+
+// matchPattern reports whether str matches pattern, in which * stands for
+// any run of characters, ? for any single character, and a backslash
+// escapes the character after it.
+func matchPattern(str, pattern string) bool {
+	// Backtrack to just after the last * on a mismatch
+	star, next := -1, 0
+	si, pi := 0, 0
+	for si < len(str) {
+		if pi < len(pattern) {
+			switch c := pattern[pi]; {
+			case c == '*':
+				star, next = pi, si
+				pi++
+				continue
+			case c == '?':
+				_, size := utf8.DecodeRuneInString(str[si:])
+				si += size
+				pi++
+				continue
+			case c == '\\' && pi+1 < len(pattern):
+				if str[si] == pattern[pi+1] {
+					si, pi = si+1, pi+2
+					continue
+				}
+			case c == str[si]:
+				si, pi = si+1, pi+1
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(str[next:])
+		next += size
+		si, pi = next, star+1
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+// parseCondition splits a condition into its key, operator and operand.
+// The operator is the first one found outside quotes. A condition without
+// an operator tests the presence of its key, or with a leading !, its
+// absence. It returns false when the condition is empty.
+func parseCondition(expr string) (condition, bool) {
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+			continue
+		}
+		for _, op := range operators {
+			for _, fold := range []bool{false, true} {
+				token := op.token
+				if fold {
+					token = foldedToken(token)
+				}
+				if strings.HasPrefix(expr[i:], token) {
+					return condition{
+						key:   strings.TrimSpace(expr[:i]),
+						op:    op,
+						value: strings.Trim(strings.TrimSpace(expr[i+len(token):]), `"'`),
+						fold:  fold,
+					}, true
+				}
+			}
+		}
+	}
+	key := strings.TrimSpace(expr)
+	absent := strings.HasPrefix(key, "!")
+	if absent {
+		key = strings.TrimSpace(key[1:])
+	}
+	if key == "" {
+		return condition{}, false
+	}
+	return condition{key: key, presence: true, absent: absent}, true
+}
+
+// foldedToken returns the case-insensitive form of an operator token: ~=
+// for =, and " ~in " for " in ".
+func foldedToken(token string) string {
+	if strings.HasPrefix(token, " ") {
+		return " ~" + token[1:]
+	}
+	return "~" + token
+}
+
+// queryExpr is a parsed array query: conditions joined by & and |, where &
+// binds more tightly. The query holds when all the conditions of any of
+// its groups hold.
+type queryExpr [][]condition
+
+// parseQuery splits an expression at the & and | outside quotes, which
+// may also be written && and ||, and parses each condition. It returns
+// false when a condition is empty.
+func parseQuery(expr string) (queryExpr, bool) {
+	var q queryExpr
+	for _, alt := range splitCompound(expr, '|') {
+		var group []condition
+		for _, term := range splitCompound(alt, '&') {
+			c, ok := parseCondition(term)
+			if !ok {
+				return nil, false
+			}
+			group = append(group, c)
+		}
+		q = append(q, group)
+	}
+	return q, true
+}
+
+// splitCompound splits expr at sep, or a doubled sep, outside quotes.
+func splitCompound(expr string, sep byte) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, expr[start:i])
+			if i+1 < len(expr) && expr[i+1] == sep {
+				i++
+			}
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// EvalCondition evaluates a condition against a value, with the same
+// operators and semantics as the conditions of array queries. The
+// condition is an operator and its operand, such as ">=100" or
+// `!="web"`, optionally preceded by a key to test a field of an object,
+// as in "port>=100". A key alone, as in "tls", holds when the object has
+// the key, whatever its value, and with a leading !, as in "!tls", when it
+// does not. Conditions can be combined with & and |, as in
+// `port>=100&proto="tcp"`, where & binds more tightly.
+//
+// A value that does not exist, or an object without the key, matches no
+// condition but "!key". The error reports an empty condition and, under
+// strict types, an operand of the wrong type.
+func EvalCondition(r Result, expr string) (bool, error) {
+	q, ok := parseQuery(expr)
+	if !ok {
+		return false, fmt.Errorf("gyaml: condition %q is empty", expr)
+	}
+	res := &resolver{}
+	if r.opts != nil {
+		res.opts = *r.opts
+	}
+	for _, group := range q {
+		all := true
+		for _, c := range group {
+			v := r
+			if c.key != "" {
+				v = r.Get(c.key)
+			}
+			if c.presence {
+				if (v.Exists() || v.IsNull()) == c.absent {
+					all = false
+					break
+				}
+				continue
+			}
+			if !v.Exists() {
+				all = false
+				break
+			}
+			matched, err := res.eval(v.nativeValue(), c)
+			if err != nil {
+				return false, err
+			}
+			if !matched {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// eval evaluates a parsed condition against a value.
+func (r *resolver) eval(val interface{}, c condition) (bool, error) {
+	if c.fold {
+		return c.op.match(r, foldValue(val), foldCase(c.value))
+	}
+	return c.op.match(r, val, c.value)
+}
+
+// foldValue returns val with its strings, and those of an array, in lower
+// case, for the operators written with a ~.
+func foldValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case string:
+		return foldCase(v)
+	case []interface{}:
+		folded := make([]interface{}, len(v))
+		for i, elem := range v {
+			if s, ok := elem.(string); ok {
+				folded[i] = foldCase(s)
+			} else {
+				folded[i] = elem
+			}
+		}
+		return folded
+	}
+	return val
+}
+
+// foldCase returns s in lower case, unless it is a timestamp, whose T and
+// Z are kept for it to be read as one.
+func foldCase(s string) string {
+	if _, ok := parseTimestamp(s); ok {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// handleArrayQuery handles queries like #(key=value)
+func handleArrayQuery(current interface{}, query string) Result {
+	return (&resolver{}).handleArrayQuery(current, query)
+}
+
+// handleArrayQuery handles queries like #(key=value)
+func (r *resolver) handleArrayQuery(current interface{}, query string) Result {
+	matches, _ := r.queryMatches(current, query, true)
+	if len(matches) == 0 {
+		return Result{Type: Null}
+	}
+	return r.result(matches[0])
+}
+
+// queryMatches returns the elements of current that match query, stopping
+// at the first one when first is set. It returns false when current is not
+// an array or the query has an empty condition.
+func (r *resolver) queryMatches(current interface{}, query string, first bool) ([]interface{}, bool) {
+	arr, ok := current.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	q, ok := parseQuery(query)
+	if !ok {
+		return nil, false
+	}
+
+	matches := []interface{}{}
+	for i, item := range arr {
+		if r.matchQuery(item, q, query, i) {
+			matches = append(matches, item)
+			if first {
+				break
+			}
+		}
+	}
+	return matches, true
+}
+
+// matchQuery reports whether the element at index i of an array holds
+// the parsed query q, written expr.
+func (r *resolver) matchQuery(item interface{}, q queryExpr, expr string, i int) bool {
+	for _, group := range q {
+		all := true
+		for _, c := range group {
+			if !r.matchElement(item, c, expr, i) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// matchElement reports whether an array element holds a condition.
+func (r *resolver) matchElement(item interface{}, c condition, expr string, i int) bool {
+	key := c.key
+	if k, n, ok := bracketKey(key); ok && n == len(key) {
+		// A bracketed key may hold operators, as in #(["a=b"]=c)
+		key = k
+	}
+	if c.presence {
+		obj, ok := item.(map[string]interface{})
+		_, exists := obj[key]
+		return ok && exists != c.absent || !ok && c.absent
+	}
+	var val interface{}
+	if obj, ok := item.(map[string]interface{}); ok {
+		v, exists := obj[key]
+		if !exists {
+			return false
+		}
+		val = v
+	} else if key == "" {
+		// Handle direct array of values (e.g., [1, 2, 3, 4, 5])
+		val = item
+	} else {
+		return false
+	}
+	matched, err := r.eval(val, c)
+	if err != nil {
+		r.fail(fmt.Errorf("gyaml: query #(%s): element %d: %w", expr, i, err))
+		return false
+	}
+	return matched
+}
+
+// matchesCondition checks if a value matches the given condition
+func matchesCondition(val interface{}, operator, expected string) bool {
+	op := lookupOperator(operator)
+	if op == nil {
+		return false
+	}
+	matched, _ := op.match(&resolver{}, val, expected)
+	return matched
+}
+
+// widenFloat32 converts f to the float64 closest to its shortest decimal
+// form, so that float32 3.1415927 becomes 3.1415927 rather than
+// 3.1415927410125732.
+func widenFloat32(f float32) float64 {
+	w, err := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	if err != nil {
+		return float64(f)
+	}
+	return w
+}
+
+// isNumeric reports whether a decoded value is a number.
+func isNumeric(val interface{}) bool {
+	switch val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, *big.Int:
+		return true
+	}
+	return false
+}
+
+// compareTimes compares a value and an operand chronologically, returning
+// false when the value is not a timestamp or a string holding one, or when
+// the operand does not hold one. Timestamps without a zone are in UTC.
+func compareTimes(val interface{}, expected string) (int, bool) {
+	var tm time.Time
+	switch v := val.(type) {
+	case time.Time:
+		tm = v
+	case string:
+		var ok bool
+		if tm, ok = parseTimestamp(v); !ok {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	want, ok := parseTimestamp(expected)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case tm.Before(want):
+		return -1, true
+	case tm.After(want):
+		return 1, true
+	}
+	return 0, true
+}
+
+// compareDurations compares a value and an operand that are both Go
+// durations such as "1m30s", returning false otherwise.
+func compareDurations(val interface{}, expected string) (int, bool) {
+	str, ok := val.(string)
+	if !ok {
+		return 0, false
+	}
+	d, ok := parseDuration(str)
+	if !ok {
+		return 0, false
+	}
+	want, ok := parseDuration(expected)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case d < want:
+		return -1, true
+	case d > want:
+		return 1, true
+	}
+	return 0, true
+}
+
+// compareSizes compares a value and an operand as byte sizes, when the
+// operand is a size with a unit such as "100MB" and the value is a size
+// or a number of bytes. It returns false otherwise.
+func compareSizes(val interface{}, expected string) (int, bool) {
+	if isNumberText(expected) {
+		return 0, false
+	}
+	want, ok := parseSize(expected)
+	if !ok {
+		return 0, false
+	}
+	var n int64
+	switch v := val.(type) {
+	case string:
+		if n, ok = parseSize(v); !ok {
+			return 0, false
+		}
+	default:
+		if !isNumeric(v) {
+			return 0, false
+		}
+		if n, ok = parseSize(fmt.Sprintf("%v", v)); !ok {
+			return 0, false
+		}
+	}
+	switch {
+	case n < want:
+		return -1, true
+	case n > want:
+		return 1, true
+	}
+	return 0, true
+}
+
+// parseDuration parses s as a Go duration. Numbers without a unit, even
+// 0, are not durations.
+func parseDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if isNumberText(s) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// compareNumbers compares two values as numbers, returning 1 if val is
+// greater than expected, -1 if it is less and 0 if they are equal, and
+// false when either is not a number. Integers are compared exactly when
+// both sides are integers, and as float64 otherwise.
+func compareNumbers(val interface{}, expectedStr string) (int, bool) {
+	if n, ok := bigIntValue(val); ok {
+		if expected, ok := new(big.Int).SetString(expectedStr, 10); ok {
+			return n.Cmp(expected), true
+		}
+	}
+	// Convert val to float64
+	var valFloat float64
+	switch v := val.(type) {
+	case int:
+		valFloat = float64(v)
+	case int8, int16, int32, int64:
+		if i, err := strconv.ParseInt(fmt.Sprintf("%v", v), 10, 64); err == nil {
+			valFloat = float64(i)
+		} else {
+			return 0, false
+		}
+	case uint, uint8, uint16, uint32, uint64:
+		if i, err := strconv.ParseUint(fmt.Sprintf("%v", v), 10, 64); err == nil {
+			valFloat = float64(i)
+		} else {
+			return 0, false
+		}
+	case float32:
+		valFloat = widenFloat32(v)
+	case float64:
+		valFloat = v
+	default:
+		// Try to parse as string
+		if f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64); err == nil {
+			valFloat = f
+		} else {
+			return 0, false
+		}
+	}
+
+	// Convert expected to float64
+	expectedFloat, err := strconv.ParseFloat(expectedStr, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if valFloat > expectedFloat {
+		return 1, true
+	} else if valFloat < expectedFloat {
+		return -1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,554 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	tests := []struct {
+		value    Result
+		expr     string
+		expected bool
+	}{
+		{Get(testYAML, "age"), ">=37", true},
+		{Get(testYAML, "age"), ">37", false},
+		{Get(testYAML, "age"), "<40", true},
+		{Get(testYAML, "age"), "<=36", false},
+		{Get(testYAML, "age"), "=37", true},
+		{Get(testYAML, "age"), "!=37", false},
+		{Get(testYAML, "name.first"), `="Tom"`, true},
+		{Get(testYAML, "name.first"), `= 'Tom'`, true},
+		{Get(testYAML, "name.first"), `!="Tom"`, false},
+		{Get(testYAML, "name"), `last="Anderson"`, true},
+		{Get(testYAML, "name"), `middle="Anderson"`, false},
+		{Get(testYAML, "missing"), "!=1", false},
+		{Result{Type: String, Str: "a>b"}, `="a>b"`, true},
+	}
+	for _, test := range tests {
+		got, err := EvalCondition(test.value, test.expr)
+		if err != nil {
+			t.Errorf("EvalCondition(%q, %q) returned error: %v", test.value.String(), test.expr, err)
+		}
+		if got != test.expected {
+			t.Errorf("EvalCondition(%q, %q) = %v, expected %v", test.value.String(), test.expr, got, test.expected)
+		}
+	}
+
+	if _, err := EvalCondition(Get(testYAML, "age"), " "); err == nil {
+		t.Error("Expected an error for an empty condition")
+	}
+}
+
+func TestEvalConditionStrict(t *testing.T) {
+	port, _ := GetWithOptions(hygieneYAML, "services.0.port", Options{StrictTypes: true})
+	matched, err := EvalCondition(port, ">100")
+	var typeErr *TypeError
+	if matched || !errors.As(err, &typeErr) {
+		t.Errorf("Expected a type error for a string port, got %v (%v)", matched, err)
+	}
+	if matched, err := EvalCondition(Get(hygieneYAML, "services.0.port"), ">100"); !matched || err != nil {
+		t.Errorf("Expected a lenient match, got %v (%v)", matched, err)
+	}
+}
+
+// TestQueriesAgreeWithEvalCondition checks that a query selects the first
+// element for which EvalCondition holds.
+func TestQueriesAgreeWithEvalCondition(t *testing.T) {
+	queries := []string{
+		`last="Murphy"`, `age>45`, `age<=47`, `age!=44`, `first="Dale"`,
+		`nets="fb"`, `age>=100`, `first="Roger"`,
+		`age>45&last="Murphy"`, `first="Dale"|first="Roger"`,
+		`age<45||age>=68&&last="Craig"`, `last="Craig"&age<50`,
+	}
+	friends := Get(testYAML, "friends")
+	for _, query := range queries {
+		want := Result{Type: Null}
+		for _, friend := range friends.Array() {
+			if ok, _ := EvalCondition(friend, query); ok {
+				want = friend
+				break
+			}
+		}
+		got := Get(testYAML, "friends.#("+query+")")
+		if got.Raw != want.Raw || got.Type != want.Type {
+			t.Errorf("Query #(%s) = %q, EvalCondition selects %q", query, got.Raw, want.Raw)
+		}
+	}
+}
+
+func TestParseConditionQuotes(t *testing.T) {
+	items := []interface{}{
+		map[string]interface{}{"name": "a>b", "id": 1},
+		map[string]interface{}{"name": "c!=d", "id": 2},
+	}
+	if got := handleArrayQuery(items, `name="c!=d"`).Get("id").Int(); got != 2 {
+		t.Errorf("Expected operators inside quotes to be ignored, got %d", got)
+	}
+	if got := handleArrayQuery(items, `name="a>b"`).Get("id").Int(); got != 1 {
+		t.Errorf("Expected the first operator to split the condition, got %d", got)
+	}
+}
+
+// TestQueryAllMatches checks that #(...)# keeps the elements for which
+// EvalCondition holds, in order, and applies the rest of the path to each.
+func TestQueryAllMatches(t *testing.T) {
+	queries := []string{`last="Murphy"`, `age>45`, `age!=44`, `age>=100`, `nets="fb"`}
+	friends := Get(testYAML, "friends")
+	for _, query := range queries {
+		var want []string
+		for _, friend := range friends.Array() {
+			if ok, _ := EvalCondition(friend, query); ok {
+				want = append(want, friend.Get("first").String())
+			}
+		}
+		got := Get(testYAML, "friends.#("+query+")#")
+		if got.Type != YAML || len(got.Array()) != len(want) {
+			t.Errorf("Query #(%s)# = %q, expected %d matches", query, got.Raw, len(want))
+		}
+		firsts := Get(testYAML, "friends.#("+query+")#.first").Array()
+		for i := range firsts {
+			if i >= len(want) || firsts[i].String() != want[i] {
+				t.Errorf("Query #(%s)#.first = %v, expected %q", query, firsts, want)
+				break
+			}
+		}
+		if count := Get(testYAML, "friends.#("+query+")#.#").Int(); count != int64(len(want)) {
+			t.Errorf("Query #(%s)#.# = %d, expected %d", query, count, len(want))
+		}
+	}
+
+	if got := Get(testYAML, "friends.#(age>45)#").Get("#").Int(); got != 2 {
+		t.Errorf("Expected the matches to form an array, got %d", got)
+	}
+	if got := Get(testYAML, "name.#(first=Tom)#"); got.Exists() {
+		t.Errorf("Expected no result on an object, got %q", got.Raw)
+	}
+	segments, _ := SplitPath("friends.#(age>45)#.first")
+	if len(segments) != 3 || segments[1].Kind != SegmentQuery {
+		t.Errorf("Expected a query segment, got %v", segments)
+	}
+
+	// Under strict types, elements of the wrong type are reported
+	_, err := GetE(hygieneYAML, "services.#(port>100)#.name", WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("Expected a type error, got %v", err)
+	}
+}
+
+func TestCompoundQueries(t *testing.T) {
+	products := `
+products:
+  - {name: lamp, price: 120, stock: 10, tags: "a|b"}
+  - {name: desk, price: 300, stock: 80}
+  - {name: pen, price: 2, stock: 5}
+  - {name: chair, price: 150, stock: 40}
+`
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{`products.#(price>100&stock<50).name`, "lamp"},
+		{`products.#(price>100&stock<50)#.name`, "[lamp chair]"},
+		{`products.#(price>100 && stock<50)#.name`, "[lamp chair]"},
+		{`products.#(name="pen"|name="desk")#.name`, "[desk pen]"},
+		{`products.#(name="pen" || price>=300)#.name`, "[desk pen]"},
+		// & binds more tightly than |
+		{`products.#(name="pen"|price>100&stock>50)#.name`, "[desk pen]"},
+		{`products.#(tags="a|b").name`, "lamp"},
+		{`products.#(price>1000&stock<50)#`, "[]"},
+		{`products.#(price>100&)`, ""},
+		{`products.#(|price>100)`, ""},
+	}
+	for _, test := range tests {
+		r := Get(products, test.path)
+		got := r.String()
+		if r.Type == YAML {
+			got = fmt.Sprint(r.Value())
+		}
+		if got != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+
+	if ok, err := EvalCondition(Get(products, "products.0"), `price>100&stock<50`); !ok || err != nil {
+		t.Errorf("Expected the compound condition to hold, got %v (%v)", ok, err)
+	}
+	if _, err := EvalCondition(Get(products, "products.0"), `price>100&`); err == nil {
+		t.Error("Expected an error for an empty term")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		str, pattern string
+		expected     bool
+	}{
+		{"web-1", "web*", true},
+		{"web-1", "*-1", true},
+		{"web-1", "w?b-?", true},
+		{"web-1", "web", false},
+		{"web", "web*", true},
+		{"", "*", true},
+		{"", "?", false},
+		{"api.example.com", "*.example.*", true},
+		{"api.example.com", "*.example", false},
+		{"aaab", "*a*ab", true},
+		{"日本", "?本", true},
+		{"a*b", `a\*b`, true},
+		{"axb", `a\*b`, false},
+		{"a?", `a\?`, true},
+	}
+	for _, test := range tests {
+		if got := matchPattern(test.str, test.pattern); got != test.expected {
+			t.Errorf("matchPattern(%q, %q) = %v, expected %v", test.str, test.pattern, got, test.expected)
+		}
+	}
+}
+
+func TestPatternQueries(t *testing.T) {
+	servers := `
+servers:
+  - {name: web-1, port: 8080}
+  - {name: db-1, port: 5432}
+  - {name: web-2, port: 8081}
+`
+	if got := Get(servers, `servers.#(name%"web*")#.port`).Raw; got != "- 8080\n- 8081\n" {
+		t.Errorf("Expected the web ports, got %q", got)
+	}
+	if got := Get(servers, `servers.#(name!%"web*").name`).String(); got != "db-1" {
+		t.Errorf("Expected db-1, got %q", got)
+	}
+	// Numbers match by their text, unless types are strict
+	if got := Get(servers, `servers.#(port%"808?")#.name`).Raw; got != "- web-1\n- web-2\n" {
+		t.Errorf("Expected numbers to match by their text, got %q", got)
+	}
+	_, err := GetE(servers, `servers.#(port%"808?")`, WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Want != "string" {
+		t.Errorf("Expected a type error under strict types, got %v", err)
+	}
+	if ok, err := EvalCondition(Get(servers, "servers.1"), `name%"db-*"`); !ok || err != nil {
+		t.Errorf("Expected EvalCondition to match, got %v (%v)", ok, err)
+	}
+}
+
+func TestTimestampQueries(t *testing.T) {
+	events := `
+events:
+  - {name: deploy, at: 2024-01-10T08:00:00Z}
+  - {name: rollback, at: 2024-01-10T10:30:00+02:00}
+  - {name: release, at: 2024-03-01}
+  - {name: planned, at: "2024-06-01T00:00:00Z"}
+  - {name: unknown, at: soon}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`events.#(at>"2024-01-10T08:00:00Z")#.name`, "rollback,release,planned"},
+		{`events.#(at<"2024-01-10T09:00:00Z")#.name`, "deploy,rollback"},
+		{`events.#(at>="2024-03-01")#.name`, "release,planned"},
+		{`events.#(at<=2024-01-10T08:30:00Z)#.name`, "deploy,rollback"},
+		{`events.#(at="2024-01-10T08:30:00Z")#.name`, "rollback"},
+		{`events.#(at="2024-03-01")#.name`, "release"},
+		{`events.#(at!="2024-03-01")#.name`, "deploy,rollback,planned,unknown"},
+		{`events.#(at>"2024-02-01"&at<"2024-12-31")#.name`, "release,planned"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(events, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	at := Get(events, "events.2")
+	if ok, err := EvalCondition(at, `at>"2024-02-29T23:59:59Z"`); !ok || err != nil {
+		t.Errorf("Expected EvalCondition to compare timestamps, got %v (%v)", ok, err)
+	}
+	if _, err := GetE(events, `events.#(at>"2024-01-01")`, WithStrictTypes()); err != nil {
+		t.Errorf("Expected timestamps to compare under strict types, got %v", err)
+	}
+	_, err := GetE(events, `events.#(at>"2024-12-01")`, WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Want != "timestamp" {
+		t.Errorf("Expected a type error for a value that is not a timestamp, got %v", err)
+	}
+}
+
+func TestStringOrderingQueries(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{`friends.#(first>"J")#.first`, "Roger,Jane"},
+		{`friends.#(first<"E")#.first`, "Dale"},
+		{`friends.#(last>="M")#.first`, "Dale,Jane"},
+		{`children.#(<="Jack")#`, "Alex,Jack"},
+		{`children.#(>"alex")#`, ""},
+		// Numbers only order against numbers
+		{`friends.#(age>"M")#.first`, ""},
+		{`friends.#(age<="M")#.first`, ""},
+		{`friends.#(age>=47)#.first`, "Roger,Jane"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(testYAML, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	if ok, err := EvalCondition(Get(testYAML, "name"), `first>="Tom"`); !ok || err != nil {
+		t.Errorf("Expected EvalCondition to compare strings, got %v (%v)", ok, err)
+	}
+	if _, err := GetE(testYAML, `friends.#(first>"J")`, WithStrictTypes()); err != nil {
+		t.Errorf("Expected strings to compare under strict types, got %v", err)
+	}
+	if _, err := GetE(testYAML, `friends.#(first>5)`, WithStrictTypes()); err == nil {
+		t.Errorf("Expected a type error ordering a string against a number")
+	}
+}
+
+func TestDurationQueries(t *testing.T) {
+	jobs := `
+jobs:
+  - {name: build, timeout: 45s}
+  - {name: test, timeout: 2m}
+  - {name: lint, timeout: 1m30s}
+  - {name: deploy, timeout: 500ms}
+  - {name: nightly, timeout: 1h}
+  - {name: manual, timeout: 30}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`jobs.#(timeout>"30s")#.name`, "build,test,lint,nightly"},
+		{`jobs.#(timeout<=1m30s)#.name`, "build,lint,deploy"},
+		{`jobs.#(timeout>="90s"&timeout<"1h")#.name`, "test,lint"},
+		{`jobs.#(timeout="120s")#.name`, "test"},
+		{`jobs.#(timeout!="1m30s")#.name`, "build,test,deploy,nightly,manual"},
+		{`jobs.#(timeout>20)#.name`, "manual"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(jobs, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	_, err := GetE(jobs, `jobs.#(timeout>"2h")`, WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Want != "duration" {
+		t.Errorf("Expected a type error for a number against a duration, got %v", err)
+	}
+}
+
+func TestSizeQueries(t *testing.T) {
+	volumes := `
+volumes:
+  - {name: data, size: 2GiB}
+  - {name: logs, size: 500MB}
+  - {name: cache, size: 1Gi}
+  - {name: tmp, size: 1048576}
+  - {name: scratch, size: big}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`volumes.#(size>"1GB")#.name`, "data,cache"},
+		{`volumes.#(size<=500MB)#.name`, "logs,tmp"},
+		{`volumes.#(size>="1MiB"&size<"1GB")#.name`, "logs,tmp"},
+		{`volumes.#(size="1024Mi")#.name`, "cache"},
+		{`volumes.#(size="1MiB")#.name`, "tmp"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(volumes, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	_, err := GetE(volumes, `volumes.#(size>"4GiB")`, WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Want != "size" {
+		t.Errorf("Expected a type error for a value that is not a size, got %v", err)
+	}
+}
+
+func TestPresenceQueries(t *testing.T) {
+	listeners := `
+listeners:
+  - {name: public, port: 443, tls: {cert: a.pem}}
+  - {name: internal, port: 8080}
+  - {name: legacy, port: 80, tls: ~}
+  - plain
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`listeners.#(tls)#.name`, "public,legacy"},
+		{`listeners.#(!tls)#.name`, "internal"},
+		{`listeners.#(! tls)#`, "{name: internal, port: 8080}\n,plain"},
+		{`listeners.#(tls&port>100)#.name`, "public"},
+		{`listeners.#(!tls|port<100)#.name`, "internal,legacy"},
+		{`listeners.#(missing)#`, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(listeners, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if got := Get(listeners, "listeners.#(tls).name").String(); got != "public" {
+		t.Errorf("Expected the first listener with tls, got %q", got)
+	}
+	public := Get(listeners, "listeners.0")
+	for expr, want := range map[string]bool{"tls": true, "!tls": false, "tls.cert": true, "!missing": true, "missing": false} {
+		if got, err := EvalCondition(public, expr); got != want || err != nil {
+			t.Errorf("EvalCondition(%q) = %v (%v), want %v", expr, got, err, want)
+		}
+	}
+	if ok, _ := EvalCondition(Get(listeners, "listeners.2"), "tls"); !ok {
+		t.Errorf("Expected a key holding null to be present")
+	}
+}
+
+func TestInQueries(t *testing.T) {
+	servers := `
+servers:
+  - {name: a, region: us-east-1, port: 80}
+  - {name: b, region: eu-west-1, port: 443}
+  - {name: c, region: ap-south-1, port: 8080}
+  - {name: "d, e", region: "us-east-1", port: 22}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`servers.#(region in ["us-east-1","eu-west-1"])#.name`, "a,b,d, e"},
+		{`servers.#(region in ['ap-south-1'])#.name`, "c"},
+		{`servers.#(region in [ap-south-1, eu-west-1])#.name`, "b,c"},
+		{`servers.#(port in [22, 443])#.name`, "b,d, e"},
+		{`servers.#(name in ["d, e", "x"])#.port`, "22"},
+		{`servers.#(region in [])#`, ""},
+		{`servers.#(region in ["us-east-1"]&port>50)#.name`, "a"},
+		{`servers.#(port in [8080]|name in [a])#.name`, "a,c"},
+		{`servers.#(name="in [a]")#`, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(servers, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if got := Get(servers, `servers.#(region in ["eu-west-1"]).port`).Int(); got != 443 {
+		t.Errorf("Expected the first match, got %d", got)
+	}
+	if ok, err := EvalCondition(Get(servers, "servers.2"), `region in [ap-south-1]`); !ok || err != nil {
+		t.Errorf("Expected EvalCondition to match, got %v (%v)", ok, err)
+	}
+	if _, err := EvalCondition(Get(servers, "servers.2"), `region in ap-south-1`); err == nil {
+		t.Errorf("Expected an error for an operand that is not a list")
+	}
+}
+
+func TestHasQueries(t *testing.T) {
+	servers := `
+servers:
+  - {name: a, roles: [web, api], ports: [80, 443]}
+  - {name: b, roles: [db]}
+  - {name: c, roles: [web], ports: [8080]}
+  - {name: d, roles: web}
+  - {name: e}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`servers.#(roles has "web")#.name`, "a,c"},
+		{`servers.#(roles has web)#.name`, "a,c"},
+		{`servers.#(roles has "cache")#.name`, ""},
+		{`servers.#(ports has 443)#.name`, "a"},
+		{`servers.#(roles has "web"&ports has 8080)#.name`, "c"},
+		{`servers.#(roles has "db"|roles has "api")#.name`, "a,b"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(servers, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if got := Get(testYAML, `friends.#(hobbies has "golf")#.first`).StringSlice(); strings.Join(got, ",") != "Dale" {
+		t.Errorf("Unexpected %q", got)
+	}
+	if ok, err := EvalCondition(Get(servers, "servers.0"), `roles has "api"`); !ok || err != nil {
+		t.Errorf("Expected EvalCondition to match, got %v (%v)", ok, err)
+	}
+	_, err := GetE(servers, `servers.#(roles has "cache")`, WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Want != "array" {
+		t.Errorf("Expected a type error for a field that is not an array, got %v", err)
+	}
+}
+
+func TestStringOperatorQueries(t *testing.T) {
+	hosts := `
+hosts:
+  - {name: db-primary, port: 5432}
+  - {name: db-replica, port: 5433}
+  - {name: web-1, port: 8080}
+  - {name: "cache startswith db", port: 6379}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`hosts.#(name startswith "db-")#.name`, "db-primary,db-replica"},
+		{`hosts.#(name endswith "replica")#.name`, "db-replica"},
+		{`hosts.#(name contains "-")#.name`, "db-primary,db-replica,web-1"},
+		{`hosts.#(name contains 'startswith')#.port`, "6379"},
+		{`hosts.#(name="cache startswith db")#.port`, "6379"},
+		{`hosts.#(port startswith 543)#.name`, "db-primary,db-replica"},
+		{`hosts.#(name startswith "db-"&port endswith 3)#.name`, "db-replica"},
+		{`hosts.#(name startswith "DB-")#`, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(hosts, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	for op, want := range map[string]bool{"startswith": true, "endswith": false, "contains": true} {
+		if got := matchesCondition("db-primary", " "+op+" ", "db"); got != want {
+			t.Errorf("matchesCondition %s = %v, want %v", op, got, want)
+		}
+	}
+	_, err := GetE(hosts, `hosts.#(port startswith 80)`, WithStrictTypes())
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Want != "string" {
+		t.Errorf("Expected a type error for a number, got %v", err)
+	}
+}
+
+func TestCaseInsensitiveQueries(t *testing.T) {
+	envs := `
+envs:
+  - {name: Production, region: us-east-1, tags: [Blue, edge]}
+  - {name: staging, region: eu-west-1, tags: [green]}
+  - {name: QA, region: eu-west-1, since: 2024-01-10T08:30:00Z}
+  - {name: production-eu, region: eu-central-1}
+`
+	tests := []struct {
+		path, want string
+	}{
+		{`envs.#(name~="PRODUCTION")#.region`, "us-east-1"},
+		{`envs.#(name="PRODUCTION")#.region`, ""},
+		{`envs.#(name~!="production")#.name`, "staging,QA,production-eu"},
+		{`envs.#(name~%"prod*")#.name`, "Production,production-eu"},
+		{`envs.#(name~!%"PROD*")#.name`, "staging,QA"},
+		{`envs.#(name~>"R")#.name`, "staging"},
+		{`envs.#(name>"R")#.name`, "staging,production-eu"},
+		{`envs.#(name ~in ["Staging","qa"])#.name`, "staging,QA"},
+		{`envs.#(tags ~has "blue")#.name`, "Production"},
+		{`envs.#(tags has "blue")#.name`, ""},
+		{`envs.#(name ~startswith "PROD")#.name`, "Production,production-eu"},
+		{`envs.#(name ~endswith "-EU")#.name`, "production-eu"},
+		{`envs.#(name ~contains "AGI")#.name`, "staging"},
+		{`envs.#(since~="2024-01-10T10:30:00+02:00")#.name`, "QA"},
+		{`envs.#(name~="qa"&region~="EU-WEST-1")#.name`, "QA"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Get(envs, tt.path).StringSlice(), ","); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if ok, err := EvalCondition(Parse("name: Production"), `name~="production"`); !ok || err != nil {
+		t.Errorf("Expected EvalCondition to ignore case, got %v, %v", ok, err)
+	}
+}
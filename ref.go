@@ -0,0 +1,84 @@
+package gyaml
+
+import "gopkg.in/yaml.v3"
+
+// GetRef searches the YAML for the specified path like Get, but leaves
+// Raw empty in the arrays and objects it returns, and in those reached
+// from them by Get, Array, Map and ForEach. Such a Result refers to the
+// decoded values, so a chain such as
+//
+//	gyaml.GetRef(yaml, "application").Get("services").Get("auth_service")
+//
+// never writes out the values in between. Every accessor returns the same
+// as for Get; String builds the YAML text on each call, and MarshalRaw
+// returns the text that Get would have put in Raw. Scalars are the same
+// as those from Get.
+func GetRef(yamlStr, path string) Result {
+	trace := startTrace()
+	result, err := getRef(yamlStr, path)
+	if trace != nil {
+		trace.end("Get", path, len(yamlStr), result.Exists(), err)
+	}
+	return result
+}
+
+// getRef resolves a path for GetRef.
+func getRef(yamlStr, path string) (Result, error) {
+	var doc Result
+	var err error
+	if n, rest, ok := documentPath(path); ok {
+		if doc, err = selectDocument(yamlStr, n, Options{}); err == nil && doc.decoded != nil {
+			doc.decoded.ref = true
+			doc.Raw = ""
+			r := doc.Get(rest)
+			r.path = path
+			return r, nil
+		}
+	} else {
+		doc, err = parseDocument(yamlStr, Options{})
+		if err == nil && doc.decoded != nil {
+			doc.decoded.ref = true
+			return doc.Get(path), nil
+		}
+	}
+	doc.path = path
+	return doc, err
+}
+
+// makeRefResult creates a Result from a decoded value like makeResult,
+// leaving Raw empty for arrays and objects.
+func makeRefResult(value interface{}) Result {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		return Result{Type: YAML, decoded: &decodedValue{v: value, ref: true}}
+	}
+	return valueResult(value)
+}
+
+// MarshalRaw returns the Raw that Get would have returned for the value.
+// That is Raw itself, except for the arrays and objects from GetRef,
+// whose text is built on each call.
+func (t Result) MarshalRaw() (string, error) {
+	if t.Type != YAML || t.Raw != "" || t.decoded == nil {
+		return t.Raw, nil
+	}
+	if raw, ok := t.decoded.sourceText(); ok {
+		return raw, nil
+	}
+	if t.decoded.built {
+		raw, err := yaml.Marshal(t.decoded.node)
+		return string(raw), err
+	}
+	raw, err := marshalYAML(t.decoded.v)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// rawText returns the YAML text of the value, building it for values from
+// GetRef.
+func (t Result) rawText() string {
+	raw, _ := t.MarshalRaw()
+	return raw
+}
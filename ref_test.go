@@ -0,0 +1,148 @@
+package gyaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// refMismatch reports how a Result from GetRef differs from the one Get
+// returns, or "" when every accessor agrees.
+func refMismatch(ref, want Result) string {
+	raw, err := ref.MarshalRaw()
+	switch {
+	case err != nil:
+		return "MarshalRaw: " + err.Error()
+	case raw != want.Raw:
+		return "MarshalRaw " + raw
+	case ref.Type != want.Type || ref.Str != want.Str || ref.Num != want.Num || ref.path != want.path:
+		return "fields " + ref.GoString()
+	case ref.String() != want.String() || ref.GoString() != want.GoString():
+		return "String " + ref.String()
+	case ref.Exists() != want.Exists() || ref.Int() != want.Int() || ref.Bool() != want.Bool():
+		return "conversions"
+	case !reflect.DeepEqual(ref.Value(), want.Value()):
+		return "Value"
+	case len(ref.Array()) != len(want.Array()) || len(ref.Map()) != len(want.Map()):
+		return "Array or Map"
+	case !reflect.DeepEqual(ref.WalkPaths(0), want.WalkPaths(0)):
+		return "WalkPaths"
+	}
+	var refKeys, wantKeys []string
+	ref.ForEach(func(key, value Result) bool {
+		refKeys = append(refKeys, key.String()+"="+value.String())
+		return true
+	})
+	want.ForEach(func(key, value Result) bool {
+		wantKeys = append(wantKeys, key.String()+"="+value.String())
+		return true
+	})
+	if !reflect.DeepEqual(refKeys, wantKeys) {
+		return "ForEach " + strings.Join(refKeys, ",")
+	}
+	return ""
+}
+
+func TestGetRefMatchesGet(t *testing.T) {
+	docs := []string{complexYAML, benchmarkYAML, orderedYAML, boundsYAML, collectYAML}
+	for path := range suitePaths(t) {
+		for _, doc := range docs {
+			if diff := refMismatch(GetRef(doc, path), Get(doc, path)); diff != "" {
+				t.Errorf("GetRef(%q) differs from Get: %s", path, diff)
+			}
+		}
+	}
+	if r := GetRef("a: [", "a"); r.Exists() {
+		t.Errorf("Expected Null for invalid YAML, got %s", r.GoString())
+	}
+}
+
+func TestGetRefMultipaths(t *testing.T) {
+	doc := "z: 1\na: 2\nm: {y: 3, b: 4}\n" + complexYAML
+	paths := []string{"{z,a}", "[z,a]", "{z,a,m}", `{"n":m,z}`, "[m,{z,a}]", "{z,a}|@this", "{z,a}.z"}
+	for path := range suitePaths(t) {
+		if strings.ContainsAny(path, "{[") {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		if diff := refMismatch(GetRef(doc, path), Get(doc, path)); diff != "" {
+			t.Errorf("GetRef(%q) differs from Get: %s", path, diff)
+		}
+	}
+	friends := `friends:
+  - {first: Dale, last: Murphy, age: 44}
+  - {first: Roger, last: Craig, age: 68}
+`
+	for _, path := range []string{"friends.#.{first,age}", "friends.#.[age,first]", "friends.#(age>50)#.{last,first}"} {
+		if diff := refMismatch(GetRef(friends, path), Get(friends, path)); diff != "" {
+			t.Errorf("GetRef(%q) differs from Get: %s", path, diff)
+		}
+	}
+	if raw, _ := GetRef(doc, "{z,a}").MarshalRaw(); raw != "z: 1\na: 2\n" {
+		t.Errorf("Expected the keys in the order asked for, got %q", raw)
+	}
+}
+
+func TestGetRefStream(t *testing.T) {
+	const stream = "name: a\n---\nname: b\nlist: [1, {x: 2}]\n---\n- x\n"
+	for _, path := range []string{"@1.name", "@1", "@1.list", "@1.list.1", "@2", "@2.0", "@0|name", "@3", "@1.missing"} {
+		ref, want := GetRef(stream, path), Get(stream, path)
+		if diff := refMismatch(ref, want); diff != "" {
+			t.Errorf("GetRef(%q) differs from Get: %s", path, diff)
+		}
+		if ref.Type == YAML && ref.Raw != "" {
+			t.Errorf("GetRef(%q): expected no Raw text, got %q", path, ref.Raw)
+		}
+	}
+	if got := GetRef(stream, "@1.name").String(); got != "b" {
+		t.Errorf("Expected b, got %q", got)
+	}
+}
+
+func TestGetRefChains(t *testing.T) {
+	ref := GetRef(complexYAML, "application").Get("services").Get("auth_service")
+	want := Get(complexYAML, "application").Get("services").Get("auth_service")
+	if ref.Raw != "" {
+		t.Errorf("Expected no Raw text, got %q", ref.Raw)
+	}
+	if diff := refMismatch(ref, want); diff != "" {
+		t.Errorf("Chained GetRef differs from Get: %s", diff)
+	}
+	for _, elem := range ref.Get("jwt.algorithms").Array() {
+		if elem.Raw != "" && elem.Type == YAML {
+			t.Errorf("Expected elements without Raw text, got %q", elem.Raw)
+		}
+	}
+	ref.ForEach(func(key, value Result) bool {
+		if value.Type == YAML && value.Raw != "" {
+			t.Errorf("%s: expected no Raw text", key.String())
+		}
+		return true
+	})
+
+	// Queries and projections keep the values by reference too
+	users := GetRef(benchmarkYAML, "users")
+	for _, path := range []string{"#.profile", `#(name="Bob").profile`, "0.profile.settings", ""} {
+		r, want := users.Get(path), Get(benchmarkYAML, "users").Get(path)
+		if path == "" {
+			// The value itself, which keeps the order of the document
+			// rather than that of its Raw text
+			want = Get(benchmarkYAML, "users")
+		}
+		if r.Raw != "" && r.Type == YAML && path != "" {
+			t.Errorf("%s: expected no Raw text", path)
+		}
+		if diff := refMismatch(r, want); diff != "" {
+			t.Errorf("%s: %s", path, diff)
+		}
+	}
+
+	// The document itself keeps its text
+	if doc := GetRef(orderedYAML, ""); doc.Raw != orderedYAML {
+		t.Errorf("Expected the document text, got %q", doc.Raw)
+	}
+	if spans := GetRef(orderedYAML, "sections").Elements(); len(spans) != 5 {
+		t.Errorf("Expected the source positions to be kept, got %v", spans)
+	}
+}
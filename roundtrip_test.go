@@ -0,0 +1,196 @@
+package gyaml
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sameResult reports whether two Results hold the same value.
+func sameResult(a, b Result) bool {
+	return a.Type == b.Type && a.Str == b.Str && a.Num == b.Num && a.Raw == b.Raw
+}
+
+// checkRawRoundTrip verifies that re-parsing the Raw of the container at
+// path gives the same values as querying doc directly, then recurses into
+// the container's children.
+func checkRawRoundTrip(t *testing.T, doc, path string) int {
+	t.Helper()
+	direct := Get(doc, path)
+	if direct.Type != YAML {
+		return 0
+	}
+	via := Parse(direct.Raw)
+	if !reflect.DeepEqual(direct.Value(), via.Value()) {
+		t.Errorf("%s: Raw does not decode to the same value\n%s", path, direct.Raw)
+		return 1
+	}
+
+	checked := 1
+	direct.ForEach(func(key, value Result) bool {
+		component := strconv.Itoa(key.Index)
+		if key.Type == String {
+			component = Escape(key.Str)
+		}
+		childPath := path + "." + component
+		if path == "" {
+			childPath = component
+		}
+		if got, want := via.Get(component), Get(doc, childPath); !sameResult(got, want) {
+			t.Errorf("%s: via Raw %+v, direct %+v", childPath, got, want)
+		}
+		checked += checkRawRoundTrip(t, doc, childPath)
+		return true
+	})
+	return checked
+}
+
+func TestRawRoundTripComplexYAML(t *testing.T) {
+	if n := checkRawRoundTrip(t, complexYAML, "application"); n < 50 {
+		t.Errorf("Expected to check at least 50 containers, checked %d", n)
+	}
+}
+
+func TestRawRoundTripFixtures(t *testing.T) {
+	for _, doc := range []string{testYAML, benchmarkYAML, orderedYAML, collectionTagsYAML} {
+		checkRawRoundTrip(t, doc, "")
+	}
+}
+
+func TestRawRoundTripNormalizedCollections(t *testing.T) {
+	doc := "roles: !!set\n  admin:\n  dev:\n  ops:\nsteps: !!omap\n  - build: make\n  - test: go test\n  - ship: ./ship.sh\n"
+	checkRawRoundTrip(t, doc, "")
+	tests := map[string]string{
+		"roles":         "- admin\n- dev\n- ops\n",
+		"roles|@values": "- admin\n- dev\n- ops\n",
+		"steps":         "build: make\ntest: go test\nship: ./ship.sh\n",
+	}
+	for path, want := range tests {
+		raw := Get(doc, path).Raw
+		if _, err := ValidWithError(raw); raw != want || err != nil {
+			t.Errorf("%s: got %q (%v), want %q", path, raw, err, want)
+		}
+	}
+	if raw := Get(doc, "@this").Raw; !Valid(raw) {
+		t.Errorf("Expected the document to stay valid, got %q", raw)
+	}
+}
+
+func TestRawRoundTripNonStringKeys(t *testing.T) {
+	doc := `
+codes:
+  200: ok
+  404: not found
+  true: yes
+  1.5: ratio
+  2.0: whole
+  null: nothing
+weights: [1.0, 2.5, 3]
+`
+	codes := Get(doc, "codes")
+	m := codes.Map()
+	if m == nil {
+		t.Fatal("Map() should handle non-string keys")
+	}
+	expected := map[string]string{"200": "ok", "404": "not found", "true": "yes", "1.5": "ratio", "2.0": "whole", "null": "nothing"}
+	for k, v := range expected {
+		if m[k].String() != v {
+			t.Errorf("Map()[%q] = %q, expected %q", k, m[k].String(), v)
+		}
+	}
+
+	via := Parse(codes.Raw)
+	if !reflect.DeepEqual(codes.Value(), via.Value()) {
+		t.Errorf("Raw changed key types:\n%s", codes.Raw)
+	}
+
+	var keys []string
+	codes.ForEach(func(key, value Result) bool {
+		keys = append(keys, key.String())
+		return true
+	})
+	if len(keys) != 6 {
+		t.Errorf("Expected 6 keys from ForEach, got %v", keys)
+	}
+
+	weights := Get(doc, "weights")
+	if !reflect.DeepEqual(weights.Value(), Parse(weights.Raw).Value()) {
+		t.Errorf("Raw changed number types:\n%s", weights.Raw)
+	}
+}
+
+func TestRawSourceText(t *testing.T) {
+	doc := `# settings
+app: &app
+  name: "web"   # quoted
+  zone: b
+  list:
+    - 1   # one
+    - 'two'
+  script: |
+    echo one
+      echo two
+  inner: &in {a: 1}
+  again: *in
+copy:
+  <<: *app
+  zone: c
+flow: [x,
+  y]
+`
+	tests := []struct {
+		path string
+		raw  string
+	}{
+		{"app.list", "- 1   # one\n- 'two'\n"},
+		{"app.inner", "&in {a: 1}\n"},
+		{"flow", "[x,\n  y]\n"},
+		{"app", `&app
+name: "web"   # quoted
+zone: b
+list:
+  - 1   # one
+  - 'two'
+script: |
+  echo one
+    echo two
+inner: &in {a: 1}
+again: *in
+`},
+		{`app.list|@this`, "- 1   # one\n- 'two'\n"},
+		{`#(zone=b)`, ""},
+	}
+	for _, test := range tests {
+		r := Get(doc, test.path)
+		if test.raw != "" && r.Raw != test.raw {
+			t.Errorf("%s: Raw = %q, expected %q", test.path, r.Raw, test.raw)
+		}
+		for _, same := range []Result{Parse(doc).Get(test.path), GetMany(doc, test.path)[0]} {
+			if same.Raw != r.Raw {
+				t.Errorf("%s: Raw %q differs from Get's %q", test.path, same.Raw, r.Raw)
+			}
+		}
+		if raw, _ := GetRef(doc, test.path).MarshalRaw(); raw != r.Raw {
+			t.Errorf("%s: MarshalRaw %q differs from Get's %q", test.path, raw, r.Raw)
+		}
+	}
+
+	// Values using anchors from elsewhere are written out from their value
+	if r := Get(doc, "copy"); r.Raw != "name: web\nzone: c\nlist:\n    - 1\n    - two\nscript: |\n    echo one\n      echo two\ninner:\n    a: 1\nagain:\n    a: 1\n" && !reflect.DeepEqual(Parse(r.Raw).Value(), r.Value()) {
+		t.Errorf("Unexpected Raw for a merged object %q", r.Raw)
+	}
+	if r := Get(doc, "app.again"); r.Raw != "&in {a: 1}\n" {
+		t.Errorf("Expected an alias to give the anchored text, got %q", r.Raw)
+	}
+
+	// Query results keep their text too
+	if r := Get(benchmarkYAML, `users.#(name="Bob")`); !strings.Contains(benchmarkYAML, strings.TrimSpace(r.Raw)) {
+		t.Errorf("Expected the text of the match, got %q", r.Raw)
+	}
+
+	for _, path := range []string{"", "app", "app.list", "copy", "flow", "app.inner"} {
+		checkRawRoundTrip(t, doc, path)
+	}
+	checkRawRoundTrip(t, spanYAML, "")
+}
@@ -0,0 +1,433 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetRaw returns the YAML document with the value at path replaced by
+// rawYAML, a YAML fragment that is spliced into the text as written,
+// without being encoded again. Only the lines of the old value change:
+// the rest of the document, its comments and formatting included, is
+// left as it is. The fragment is indented to its new place, so a block
+// written at any indentation can be inserted:
+//
+//	doc, err := gyaml.SetRaw(doc, "spec.template", "metadata:\n  labels: {app: web}  # kept\n")
+//
+// The path is a chain of plain keys and indices. When its last key is
+// missing from an existing object, the key is added after the last entry
+// of that object; all other components must exist. Indices must address
+// an existing element, or the error wraps ErrIndexOutOfRange. An empty
+// path replaces the whole document.
+//
+// Values reached through an alias cannot be set, as they are written
+// elsewhere, and only fragments on a single line fit into a [flow] array
+// or {flow} object. The result is parsed once more to check that path
+// holds the value of the fragment, and an error is returned otherwise.
+func SetRaw(yamlStr, path, rawYAML string) (string, error) {
+	raw, rawNode, err := prepareRaw(rawYAML)
+	if err != nil {
+		return yamlStr, err
+	}
+	if path == "" {
+		return raw + "\n", nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlStr), &doc); err != nil {
+		return yamlStr, err
+	}
+	if len(doc.Content) == 0 {
+		return yamlStr, fmt.Errorf("gyaml: SetRaw: the document is empty")
+	}
+	segments, err := SplitPath(path)
+	if err != nil {
+		return yamlStr, err
+	}
+
+	lines := newLineIndex(yamlStr)
+	node := doc.Content[0]
+	var out string
+	for i, seg := range segments {
+		if seg.Kind != SegmentKey && seg.Kind != SegmentIndex {
+			return yamlStr, fmt.Errorf("gyaml: SetRaw: %q is not a plain key or index", seg.Text)
+		}
+		if node.Kind == yaml.AliasNode {
+			return yamlStr, fmt.Errorf("gyaml: SetRaw: %q is reached through an alias", joinSegments(segments[:i]))
+		}
+		last := i == len(segments)-1
+		switch node.Kind {
+		case yaml.SequenceNode:
+			idx, _, inRange := indexOf(seg.Text, len(node.Content))
+			if !inRange {
+				return yamlStr, fmt.Errorf("%w: %s in an array of %d", ErrIndexOutOfRange, seg.Text, len(node.Content))
+			}
+			if last {
+				out, err = spliceValue(lines, node, idx, -1, raw, rawNode)
+			}
+			node = node.Content[idx]
+		case yaml.MappingNode:
+			key := unescapeKey(seg.Text)
+			pos := -1
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if k := node.Content[j]; k.Kind == yaml.ScalarNode && k.Value == key && !isMergeKey(k) {
+					pos = j
+				}
+			}
+			switch {
+			case pos >= 0 && last:
+				out, err = spliceValue(lines, node, pos+1, pos, raw, rawNode)
+			case pos >= 0:
+				node = node.Content[pos+1]
+			case last:
+				out, err = appendEntry(lines, node, key, raw, rawNode)
+			default:
+				return yamlStr, fmt.Errorf("gyaml: SetRaw: %q does not exist", joinSegments(segments[:i+1]))
+			}
+		default:
+			return yamlStr, fmt.Errorf("gyaml: SetRaw: the value before %q is not an array or object", seg.Text)
+		}
+		if err != nil {
+			return yamlStr, err
+		}
+	}
+	if err := checkSplice(out, path, raw); err != nil {
+		return yamlStr, err
+	}
+	return out, nil
+}
+
+// Set returns the YAML document with the value at path replaced by value,
+// which can be a Result or any Go value that encodes to YAML. The value
+// is written out as YAML and spliced into the text like a fragment given
+// to SetRaw, so comments, key order, quoting and blank lines outside it
+// are kept as they are:
+//
+//	doc, err = gyaml.Set(doc, "spec.replicas", 3)
+//	doc, err = gyaml.Set(doc, "metadata.labels", map[string]string{"app": "web"})
+//
+// Paths and errors are those of SetRaw. Objects are written with their
+// keys in sorted order, in block style indented by two spaces, or in flow
+// style when they go into a [flow] array or {flow} object.
+func Set(yamlStr, path string, value interface{}) (string, error) {
+	node, err := encodeNode(buildValue(value))
+	if err != nil {
+		return yamlStr, err
+	}
+	raw, err := encodeFragment(node)
+	if err != nil {
+		return yamlStr, err
+	}
+	out, err := SetRaw(yamlStr, path, raw)
+	if !errors.Is(err, errFlowFragment) {
+		return out, err
+	}
+	// Flow collections take a value on a single line
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		node.Style = yaml.FlowStyle
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	}
+	if raw, err = encodeFragment(node); err != nil {
+		return yamlStr, err
+	}
+	return SetRaw(yamlStr, path, raw)
+}
+
+// encodeFragment writes node as YAML indented by two spaces, the
+// indentation most documents use.
+func encodeFragment(node *yaml.Node) (string, error) {
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// joinSegments writes segments back as a path.
+func joinSegments(segments []Segment) string {
+	texts := make([]string, len(segments))
+	for i, seg := range segments {
+		texts[i] = seg.Text
+	}
+	return strings.Join(texts, ".")
+}
+
+// prepareRaw checks that a fragment is a single YAML value, and returns it
+// without the blank lines around it and the indentation common to its
+// lines, along with its parsed node.
+func prepareRaw(rawYAML string) (string, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(rawYAML), &doc); err != nil {
+		return "", nil, fmt.Errorf("gyaml: SetRaw: invalid fragment: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return "", nil, errors.New("gyaml: SetRaw: the fragment is empty")
+	}
+	lines := strings.Split(strings.TrimRight(rawYAML, " \t\r\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " ")); common < 0 || n < common {
+			common = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= common {
+			lines[i] = line[common:]
+		}
+	}
+	return strings.Join(lines, "\n"), doc.Content[0], nil
+}
+
+// isBlockCollection reports whether a node is an array or object written
+// in block style, which cannot follow a key on the same line.
+func isBlockCollection(node *yaml.Node) bool {
+	return (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && node.Style&yaml.FlowStyle == 0
+}
+
+// indentLines prefixes every line of s after the first with indent
+// spaces, leaving empty lines alone.
+func indentLines(s string, indent int) string {
+	prefix := strings.Repeat(" ", indent)
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// valueText returns the text that follows the ":" of a key in a block
+// object, or the "-" of an item in a block array, for a fragment whose
+// continuation lines are indented by indent spaces. A comment found after
+// the indicator is kept at the end of the first line.
+func valueText(raw string, rawNode *yaml.Node, indent int, inArray bool, comment string) string {
+	var text string
+	if isBlockCollection(rawNode) && !inArray {
+		text = "\n" + strings.Repeat(" ", indent) + indentLines(raw, indent)
+	} else {
+		text = " " + indentLines(raw, indent)
+	}
+	if comment == "" {
+		return text
+	}
+	head, tail := text, ""
+	if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+		head, tail = text[:nl], text[nl:]
+	}
+	return head + " " + comment + tail
+}
+
+// skipToIndicator returns the offset just past the indicator c, the ":" of
+// a key or the "-" of an array item, looking from pos over blanks and
+// comments. It returns -1 when something else comes first.
+func skipToIndicator(src string, pos int, c byte) int {
+	for pos < len(src) {
+		switch {
+		case src[pos] == c:
+			return pos + 1
+		case isBlank(src[pos]):
+			pos++
+		case src[pos] == '#':
+			if nl := strings.IndexByte(src[pos:], '\n'); nl >= 0 {
+				pos += nl
+			} else {
+				return -1
+			}
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// commentIn returns the comment in a stretch of text holding only blanks
+// and comments, such as the one between a key and its value.
+func commentIn(text string) string {
+	if i := strings.IndexByte(text, '#'); i >= 0 {
+		if nl := strings.IndexByte(text[i:], '\n'); nl >= 0 {
+			return strings.TrimRight(text[i:i+nl], " \t\r")
+		}
+		return strings.TrimRight(text[i:], " \t\r")
+	}
+	return ""
+}
+
+// lineEnd returns the end of the line holding pos when only blanks and a
+// comment follow pos on it, and pos otherwise.
+func lineEnd(src string, pos int) int {
+	i := pos
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	if i < len(src) && src[i] == '#' {
+		if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+			return i + nl
+		}
+		return len(src)
+	}
+	return pos
+}
+
+// spliceValue replaces the text of the value at position i of the content
+// of parent, an array or object, with raw. keyPos is the position of the
+// key of the value in an object, or -1 in an array.
+func spliceValue(lines lineIndex, parent *yaml.Node, i, keyPos int, raw string, rawNode *yaml.Node) (string, error) {
+	src := lines.src
+	span := lines.span(parent.Content[i])
+	if parent.Style&yaml.FlowStyle != 0 {
+		if strings.Contains(raw, "\n") || isBlockCollection(rawNode) {
+			return "", errFlowFragment
+		}
+		return src[:span.Start] + raw + src[span.End:], nil
+	}
+
+	// Replace everything from the ":" or "-" before the value
+	var start, indent int
+	if keyPos >= 0 {
+		key := lines.span(parent.Content[keyPos])
+		start = skipToIndicator(src, key.End, ':')
+		indent = key.Start - lines.offset(parent.Content[keyPos].Line, 1) + 2
+		if q := explicitKey(src, key.Start); start < 0 && q >= 0 && isEmptyScalar(parent.Content[i]) {
+			return addExplicitValue(src, q, key.End, raw, rawNode), nil
+		}
+	} else {
+		from := lines.span(parent).Start
+		if i > 0 {
+			from = lines.span(parent.Content[i-1]).End
+		}
+		start = skipToIndicator(src, from, '-')
+		indent = start - lineStart(src, start) + 1
+	}
+	if start < 0 || start > span.Start {
+		return "", errors.New("gyaml: SetRaw: cannot find where the value starts")
+	}
+	comment, end := commentIn(src[start:span.Start]), span.End
+	blockScalar := span.End > span.Start && src[span.End-1] == '\n'
+	if e := lineEnd(src, span.End); e > span.End && !blockScalar && strings.Contains(strings.TrimSuffix(raw, "\n"), "\n") {
+		// A comment after a value replaced by several lines stays on the
+		// line of the key rather than moving to the last line
+		comment = strings.TrimSpace(comment + " " + commentIn(src[span.End:e]))
+		end = e
+	}
+	text := valueText(raw, rawNode, indent, keyPos < 0, comment)
+	if blockScalar {
+		// Block scalars end with their line break
+		text += "\n"
+	}
+	return src[:start] + text + src[end:], nil
+}
+
+// explicitKey returns the offset of the "?" that introduces the key
+// starting at pos, or -1 when the key is not explicit.
+func explicitKey(src string, pos int) int {
+	for pos > 0 && (src[pos-1] == ' ' || src[pos-1] == '\t') {
+		pos--
+	}
+	if pos > 0 && src[pos-1] == '?' {
+		return pos - 1
+	}
+	return -1
+}
+
+// addExplicitValue writes the value raw for the explicit key introduced
+// by the "?" at q and ending at keyEnd, which has no ":" of its own, on a
+// line of its own after the key.
+func addExplicitValue(src string, q, keyEnd int, raw string, rawNode *yaml.Node) string {
+	column := q - lineStart(src, q)
+	entry := strings.Repeat(" ", column) + ":" + valueText(raw, rawNode, column+2, false, "")
+	if src[keyEnd-1] == '\n' {
+		// The key is a block scalar, which ends with its line break
+		return src[:keyEnd] + entry + "\n" + src[keyEnd:]
+	}
+	end := lineEnd(src, keyEnd)
+	return src[:end] + "\n" + entry + src[end:]
+}
+
+// errFlowFragment is returned for fragments that cannot be written inside
+// a flow collection.
+var errFlowFragment = errors.New("gyaml: SetRaw: only a fragment on a single line fits into a flow collection")
+
+// appendEntry adds key with the value raw after the last entry of obj.
+func appendEntry(lines lineIndex, obj *yaml.Node, key, raw string, rawNode *yaml.Node) (string, error) {
+	src := lines.src
+	entry := yamlKey(key) + ":"
+	span := lines.span(obj)
+	if obj.Style&yaml.FlowStyle != 0 {
+		if strings.Contains(raw, "\n") || isBlockCollection(rawNode) {
+			return "", errFlowFragment
+		}
+		end := span.End - 1
+		if len(obj.Content) > 0 {
+			entry = ", " + entry
+		}
+		return src[:end] + entry + " " + raw + src[end:], nil
+	}
+
+	first := obj.Content[0]
+	column := lines.offset(first.Line, first.Column) - lines.offset(first.Line, 1)
+	entry = strings.Repeat(" ", column) + entry + valueText(raw, rawNode, column+2, false, "")
+	if src[span.End-1] == '\n' {
+		// The last value is a block scalar, which ends with its line break
+		return src[:span.End] + entry + "\n" + src[span.End:], nil
+	}
+	end := lineEnd(src, span.End)
+	return src[:end] + "\n" + entry + src[end:], nil
+}
+
+// yamlKey returns a mapping key as it is written in YAML, quoted as
+// stringNode quotes values when needed, and double quoted when it spans
+// lines, as a key cannot.
+func yamlKey(key string) string {
+	node := stringNode(key)
+	text, err := yaml.Marshal(node)
+	if s := strings.TrimSuffix(string(text), "\n"); err == nil && !strings.Contains(s, "\n") {
+		return s
+	}
+	node.Style = yaml.DoubleQuotedStyle
+	if text, err = yaml.Marshal(node); err == nil {
+		return strings.TrimSuffix(string(text), "\n")
+	}
+	return strconv.Quote(key)
+}
+
+// checkSplice checks that a spliced document is valid and holds the value
+// of the fragment at path.
+func checkSplice(out, path, raw string) error {
+	var want, root interface{}
+	if err := decodeYAML([]byte(raw+"\n"), &want); err != nil {
+		return err
+	}
+	if err := decodeYAML([]byte(out), &root); err != nil {
+		return fmt.Errorf("gyaml: SetRaw: the fragment does not fit at %q: %w", path, err)
+	}
+	got, ok := root, true
+	for _, part := range splitPath(path) {
+		if got, ok = stepKey(got, part); !ok {
+			break
+		}
+	}
+	if !ok || !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("gyaml: SetRaw: the fragment does not fit at %q", path)
+	}
+	return nil
+}
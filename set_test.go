@@ -0,0 +1,206 @@
+package gyaml
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const setYAML = `# config
+server:
+  host: localhost  # the host
+  port: 8080
+  tags: [a, b]
+  script: |
+    echo hi
+  items:
+    - one
+    - name: two
+      x: 1
+  empty:
+limits: {cpu: 2}
+`
+
+func TestSetRaw(t *testing.T) {
+	tests := []struct {
+		path, raw string
+		old, new  string
+	}{
+		{"server.host", "example.com", "host: localhost  # the host", "host: example.com  # the host"},
+		{"server.port", "\n    a: 1\n    b: [1, 2]\n", "port: 8080\n", "port:\n    a: 1\n    b: [1, 2]\n"},
+		{"server.items.1", "k: v\nz: 2", "- name: two\n      x: 1", "- k: v\n      z: 2"},
+		{"server.items.0", "- x\n- y", "- one", "- - x\n      - y"},
+		{"server.script", "|\n  new\n  text\n", "|\n    echo hi\n", "|\n      new\n      text\n"},
+		{"server.empty", "v", "empty:\n", "empty: v\n"},
+		{"server.tags.1", "c", "[a, b]", "[a, c]"},
+		{"limits.cpu", "'3'", "{cpu: 2}", "{cpu: '3'}"},
+		{"server.new", "q: 1", "empty:\n", "empty:\n  new:\n    q: 1\n"},
+		{"limits.memory", "1Gi", "{cpu: 2}", "{cpu: 2, memory: 1Gi}"},
+		{"new", "top", "{cpu: 2}\n", "{cpu: 2}\nnew: top\n"},
+		{"server.a\\.b", "1", "empty:\n", "empty:\n  a.b: 1\n"},
+	}
+	for _, tt := range tests {
+		out, err := SetRaw(setYAML, tt.path, tt.raw)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if want := strings.Replace(setYAML, tt.old, tt.new, 1); out != want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.path, want, out)
+		}
+		if want := Parse(tt.raw); !Get(out, tt.path).Equal(want) {
+			t.Errorf("%s: expected %s, got %s", tt.path, want.Raw, Get(out, tt.path).Raw)
+		}
+	}
+
+	// A comment between a key and its block value stays on the key's line
+	out, err := SetRaw("a: # note\n  - 1\nb: 2\n", "a", "x: 1")
+	if want := "a: # note\n  x: 1\nb: 2\n"; err != nil || out != want {
+		t.Errorf("Expected %q, got %q, %v", want, out, err)
+	}
+
+	// So does a comment after a scalar replaced by a block value, while
+	// one after a value on a single line stays after it
+	comments := []struct {
+		path  string
+		value interface{}
+		want  string
+	}{
+		{"top.a", map[string]interface{}{"k": []interface{}{1, 2}}, "top:\n  a: # keep\n    k:\n      - 1\n      - 2\n  b: 2 # other\n"},
+		{"top.a", []interface{}{1, 2}, "top:\n  a: # keep\n    - 1\n    - 2\n  b: 2 # other\n"},
+		{"top.b", "x\ny\n", "top:\n  a: 1 # keep\n  b: | # other\n      x\n      y\n"},
+		{"top.a", 5, "top:\n  a: 5 # keep\n  b: 2 # other\n"},
+	}
+	for _, tt := range comments {
+		out, err := Set("top:\n  a: 1 # keep\n  b: 2 # other\n", tt.path, tt.value)
+		if err != nil || out != tt.want {
+			t.Errorf("%s: expected %q, got %q, %v", tt.path, tt.want, out, err)
+		}
+		raw, _ := marshalYAML(tt.value)
+		if want := Parse(string(raw)); !Get(out, tt.path).Equal(want) {
+			t.Errorf("%s: expected %s, got %s", tt.path, want.Raw, Get(out, tt.path).Raw)
+		}
+	}
+
+	if out, err := SetRaw(setYAML, "", "a: 1\n"); err != nil || out != "a: 1\n" {
+		t.Errorf("Expected the whole document to be replaced, got %q, %v", out, err)
+	}
+}
+
+func TestSetRawErrors(t *testing.T) {
+	tests := []struct {
+		doc, path, raw, msg string
+	}{
+		{setYAML, "server.tags.1", "a: 1", "single line"},
+		{setYAML, "limits.memory", "- 1\n- 2", "single line"},
+		{setYAML, "server.missing.key", "1", `"server.missing" does not exist`},
+		{setYAML, "server.host.x", "1", "not an array or object"},
+		{setYAML, "server.items.#", "1", "not a plain key or index"},
+		{setYAML, "server.host", "a: [", "invalid fragment"},
+		{setYAML, "server.host", "", "fragment is empty"},
+		{"base: &b {x: 1}\nuse: *b\n", "use.x", "2", "reached through an alias"},
+		{"a: [", "a", "1", "did not find"},
+	}
+	for _, tt := range tests {
+		out, err := SetRaw(tt.doc, tt.path, tt.raw)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.path, tt.msg, err)
+		}
+		if out != tt.doc {
+			t.Errorf("%s: expected the document to be unchanged, got %q", tt.path, out)
+		}
+	}
+
+	if _, err := SetRaw(setYAML, "server.items.5", "x"); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("Expected ErrIndexOutOfRange, got %v", err)
+	}
+}
+
+func TestSetRawBracketedKeys(t *testing.T) {
+	doc := "labels:\n  app.kubernetes.io/name: web\n"
+	out, err := SetRaw(doc, `labels["app.kubernetes.io/name"]`, "api")
+	if want := "labels:\n  app.kubernetes.io/name: api\n"; err != nil || out != want {
+		t.Errorf("Expected %q, got %q, %v", want, out, err)
+	}
+	out, err = SetRaw(doc, `labels["app.kubernetes.io/part-of"]`, "shop")
+	if want := doc + "  app.kubernetes.io/part-of: shop\n"; err != nil || out != want {
+		t.Errorf("Expected %q, got %q, %v", want, out, err)
+	}
+	if _, err := SetRaw(doc, `missing["......"].x`, "1"); err == nil || !strings.Contains(err.Error(), `"missing" does not exist`) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		path     string
+		value    interface{}
+		old, new string
+	}{
+		{"server.port", 9090, "port: 8080", "port: 9090"},
+		{"server.host", "on", "host: localhost  # the host", `host: "on"  # the host`},
+		{"server.script", "a\nb", "|\n    echo hi\n", "|-\n      a\n      b\n"},
+		{"server.items.0", map[string]interface{}{"b": 2, "a": 1}, "- one", "- a: 1\n      b: 2"},
+		{"server.tags.0", []string{"x", "z"}, "[a, b]", "[[x, z], b]"},
+		{"limits.cpu", "2\n", "{cpu: 2}", `{cpu: "2\n"}`},
+		{"limits.memory", map[string]int{"max": 4}, "{cpu: 2}", "{cpu: 2, memory: {max: 4}}"},
+		{"server.empty", nil, "empty:\n", "empty: null\n"},
+		{"server.port", Get(setYAML, "server.tags").Append("c"), "port: 8080", "port:\n    - a\n    - b\n    - c"},
+	}
+	for _, tt := range tests {
+		out, err := Set(setYAML, tt.path, tt.value)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if want := strings.Replace(setYAML, tt.old, tt.new, 1); out != want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.path, want, out)
+		}
+		if want := ArrayOf(tt.value).Get("0"); !Get(out, tt.path).Equal(want) {
+			t.Errorf("%s: expected %s, got %s", tt.path, want.Raw, Get(out, tt.path).Raw)
+		}
+	}
+
+	if out, err := Set(setYAML, "server.missing.key", 1); err == nil || out != setYAML {
+		t.Errorf("Expected an error and the document unchanged, got %v", err)
+	}
+}
+
+func TestSetNewKeys(t *testing.T) {
+	tests := []struct {
+		doc, path, want string
+	}{
+		{"'%':\n    x: v\n", "%.x\u2028", "'%':\n    x: v\n    \"x\\L\": added\n"},
+		{"a: 1\n", "yes", "a: 1\n\"yes\": added\n"},
+		{"a: 1\n", "x\ny", "a: 1\n\"x\\ny\": added\n"},
+		{"? 'multi\n  line'\n", "multi line", "? 'multi\n  line'\n: added\n"},
+		{"? 'multi\n  line'\nb: 1\n", "multi line", "? 'multi\n  line'\n: added\nb: 1\n"},
+		{"a:\n  ? |\n    text\n  b: 1\n", "a.text\n", "a:\n  ? |\n    text\n  : added\n  b: 1\n"},
+	}
+	for _, tt := range tests {
+		out, err := Set(tt.doc, tt.path, "added")
+		if err != nil || out != tt.want {
+			t.Errorf("%s: expected %q, got %q, %v", tt.path, tt.want, out, err)
+		}
+	}
+}
+
+func TestSetRandomKeysProperty(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		key := randomKey(rng)
+		path := "server." + Escape(key)
+		out, err := Set(setYAML, path, i)
+		if err != nil {
+			t.Errorf("key %q: %v", key, err)
+			continue
+		}
+		if got := Get(out, path); got.Int() != int64(i) {
+			t.Errorf("key %q: Get(%q) = %v, expected %d\n%s", key, path, got, i, out)
+		}
+		if got := Get(out, "server.port").Int(); got != 8080 && key != "port" {
+			t.Errorf("key %q: the other keys changed\n%s", key, out)
+		}
+	}
+}
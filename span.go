@@ -0,0 +1,506 @@
+package gyaml
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Span is a range of bytes in a YAML document, from Start up to but not
+// including End.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Elements returns the spans of the elements of an array, or of the
+// values of an object as they are written, in the document the Result was
+// read from. Slicing the document with a span gives the source text of
+// the element, without surrounding whitespace, comments or indicators.
+//
+// Spans are only known for results resolved through the document's nodes,
+// such as plain key paths and the results of Parse, Array and ForEach. It
+// returns nil for other results, including the values produced by queries
+// and projections, and for scalars.
+func (t Result) Elements() []Span {
+	if t.Type != YAML {
+		return nil
+	}
+	d, ok := t.decode()
+	if !ok || d.node == nil || d.src == "" || d.stream {
+		return nil
+	}
+	node := resolveAlias(d.node)
+	lines := d.lineIndex()
+	var spans []Span
+	switch node.Kind {
+	case yaml.SequenceNode:
+		spans = make([]Span, 0, len(node.Content))
+		for _, child := range node.Content {
+			spans = append(spans, lines.span(child))
+		}
+	case yaml.MappingNode:
+		spans = make([]Span, 0, len(node.Content)/2)
+		for i := 1; i < len(node.Content); i += 2 {
+			spans = append(spans, lines.span(node.Content[i]))
+		}
+	}
+	return spans
+}
+
+// withSpan records in r the position of node, the node of its value, in
+// the source text of d, which the value of r keeps along with its line
+// index.
+func (d *decodedValue) withSpan(r Result, node *yaml.Node) Result {
+	if r.decoded != nil {
+		r.decoded.src = d.src
+	}
+	if node == nil || node.Line == 0 || d.src == "" {
+		// Nodes made up for values missing from the source have no line
+		return r
+	}
+	lines := d.lineIndex()
+	span := lines.span(node)
+	r.Index, r.End = span.Start, span.End
+	if r.decoded != nil {
+		r.decoded.lines.Store(lines)
+	}
+	return r
+}
+
+// withSourceRaw sets the Raw of r, an array or object read from a
+// document, to its text in the document when that text stands on its own,
+// and to the YAML of its value otherwise. Values from GetRef keep an empty
+// Raw.
+func withSourceRaw(r Result) Result {
+	if r.Type != YAML || r.decoded == nil || r.decoded.ref {
+		return r
+	}
+	if raw, ok := r.decoded.sourceText(); ok {
+		r.Raw = raw
+		return r
+	}
+	if r.decoded.normalized() && selfContained(r.decoded.node) {
+		// Written from the node, to keep the order of an !!omap, without
+		// the tag it no longer has
+		node := *r.decoded.node
+		node.Style &^= yaml.TaggedStyle
+		if raw, err := yaml.Marshal(&node); err == nil {
+			r.Raw = string(raw)
+			return r
+		}
+	}
+	if r.Raw == "" {
+		raw, err := marshalYAML(r.decoded.v)
+		if err != nil {
+			return Result{Type: Null}
+		}
+		r.Raw = string(raw)
+	}
+	return r
+}
+
+// sourceText returns the text of an array or object as it is written in
+// the source text of d, comments included, with the indentation of its
+// entries taken off every line. It returns false when the node is not
+// known, is not an array or object, uses an anchor defined outside of it,
+// or is a !!set or !!omap that normalizeNode turned into an array or
+// object, as its text would not parse on its own.
+func (d *decodedValue) sourceText() (string, bool) {
+	node := d.node
+	if node == nil || node.Line == 0 || d.src == "" || d.stream ||
+		node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode || !selfContained(node) {
+		return "", false
+	}
+	if d.normalized() {
+		return "", false
+	}
+	lines := d.lineIndex()
+	span := lines.span(node)
+	text := d.src[span.Start:span.End]
+	// Block entries line up at the indentation of the first one, which
+	// starts the text unless an anchor or tag comes first. The lines of
+	// flow collections keep their indentation relative to the line they
+	// start on.
+	first := span.Start
+	switch {
+	case node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0:
+		first = lineStart(d.src, first)
+		first += len(d.src[first:]) - len(strings.TrimLeft(d.src[first:], " "))
+	case node.Kind == yaml.MappingNode:
+		first = lines.offset(node.Content[0].Line, node.Content[0].Column)
+	default:
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 && strings.TrimSpace(skipProperties(text[:nl])) == "" {
+			first = span.Start + nl + 1
+			first += len(d.src[first:]) - len(strings.TrimLeft(d.src[first:], " "))
+		}
+	}
+	text = dedent(text, first-lineStart(d.src, first))
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text, true
+}
+
+// normalized reports whether the node of d is a !!set or !!omap that
+// normalizeNode turned into an array or object, so that its text reads as
+// another collection than its value.
+func (d *decodedValue) normalized() bool {
+	node := d.node
+	if node == nil || node.Line == 0 || d.src == "" {
+		return false
+	}
+	switch sourceTag(d.src[d.lineIndex().offset(node.Line, node.Column):]) {
+	case "!!set", "!<tag:yaml.org,2002:set>":
+		return node.Kind == yaml.SequenceNode
+	case "!!omap", "!<tag:yaml.org,2002:omap>":
+		return node.Kind == yaml.MappingNode
+	}
+	return false
+}
+
+// sourceTag returns the tag among the properties s starts with, or "" when
+// it has none.
+func sourceTag(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" || s[0] != '&' && s[0] != '!' {
+			return ""
+		}
+		end := tokenEnd(s, 0)
+		if s[0] == '!' {
+			return s[:end]
+		}
+		s = s[end:]
+	}
+}
+
+// skipProperties returns s without the anchor and tag it starts with.
+func skipProperties(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" || s[0] != '&' && s[0] != '!' {
+			return s
+		}
+		s = s[tokenEnd(s, 0):]
+	}
+}
+
+// dedent removes up to indent leading spaces from every line of s after
+// the first.
+func dedent(s string, indent int) string {
+	if indent == 0 || !strings.Contains(s, "\n") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		n := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+		if n > indent {
+			n = indent
+		}
+		lines[i] = lines[i][n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// selfContained reports whether every alias within node refers to an
+// anchor within node.
+func selfContained(node *yaml.Node) bool {
+	aliases := appendAliases(nil, node)
+	if len(aliases) == 0 {
+		return true
+	}
+	inside := make(map[*yaml.Node]bool)
+	var mark func(n *yaml.Node)
+	mark = func(n *yaml.Node) {
+		inside[n] = true
+		for _, child := range n.Content {
+			mark(child)
+		}
+	}
+	mark(node)
+	for _, alias := range aliases {
+		if !inside[alias.Alias] {
+			return false
+		}
+	}
+	return true
+}
+
+// appendAliases appends the alias nodes within node to aliases.
+func appendAliases(aliases []*yaml.Node, node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return append(aliases, node)
+	}
+	for _, child := range node.Content {
+		aliases = appendAliases(aliases, child)
+	}
+	return aliases
+}
+
+// lineIndex returns the line index of the source text of d, building it
+// on first use.
+func (d *decodedValue) lineIndex() *lineIndex {
+	if lines := d.lines.Load(); lines != nil {
+		return lines
+	}
+	lines := newLineIndex(d.src)
+	d.lines.Store(&lines)
+	return &lines
+}
+
+// lineIndex maps the line and column positions of nodes to byte offsets
+// in the source text they were parsed from.
+type lineIndex struct {
+	src    string
+	starts []int
+}
+
+func newLineIndex(src string) lineIndex {
+	starts := []int{lineStart(src, 0)}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return lineIndex{src: src, starts: starts}
+}
+
+// lineStart returns the offset of the line holding pos. The first line
+// starts after the byte order mark, which the parser does not count as a
+// column.
+func lineStart(src string, pos int) int {
+	start := strings.LastIndexByte(src[:pos], '\n') + 1
+	if start == 0 && strings.HasPrefix(src, "\ufeff") {
+		return len("\ufeff")
+	}
+	return start
+}
+
+// offset returns the byte offset of a 1-based line and column. Columns
+// count characters, not bytes.
+func (l lineIndex) offset(line, column int) int {
+	if line < 1 || line > len(l.starts) {
+		return len(l.src)
+	}
+	pos := l.starts[line-1]
+	for c := 1; c < column && pos < len(l.src) && l.src[pos] != '\n'; c++ {
+		_, size := utf8.DecodeRuneInString(l.src[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// span returns the source range of a node, including its anchor and tag.
+func (l lineIndex) span(node *yaml.Node) Span {
+	start := l.offset(node.Line, node.Column)
+	return Span{Start: start, End: l.end(node, start)}
+}
+
+// end returns the offset just past the source text of node, which starts
+// at start.
+func (l lineIndex) end(node *yaml.Node, start int) int {
+	src := l.src
+	pos := start
+	if node.Kind == yaml.AliasNode {
+		return tokenEnd(src, pos+1)
+	}
+	// Skip the anchor and tag
+	tag := ""
+	for pos < len(src) && (src[pos] == '&' || src[pos] == '!') {
+		if src[pos] == '!' {
+			tag = src[pos:tokenEnd(src, pos)]
+		}
+		pos = tokenEnd(src, pos)
+		end := pos
+		for pos < len(src) && strings.IndexByte(" \t\r\n", src[pos]) >= 0 {
+			pos++
+		}
+		if isEmptyScalar(node) {
+			return end
+		}
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		switch {
+		case isEmptyScalar(node):
+			return pos
+		case node.Style&yaml.DoubleQuotedStyle != 0:
+			return quotedEnd(src, pos, '"')
+		case node.Style&yaml.SingleQuotedStyle != 0:
+			return quotedEnd(src, pos, '\'')
+		case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+			return blockScalarEnd(src, pos, l.indent(node.Line))
+		default:
+			return plainEnd(src, pos, node.Value)
+		}
+	case yaml.SequenceNode, yaml.MappingNode:
+		if node.Style&yaml.FlowStyle != 0 {
+			return flowEnd(src, pos)
+		}
+		end := pos
+		// The last value of a block mapping may be empty, so its key is
+		// considered as well
+		for i := len(node.Content) - 1; i >= 0 && i >= len(node.Content)-2; i-- {
+			if s := l.span(node.Content[i]); s.End > end {
+				end = s.End
+			}
+		}
+		if node.Kind == yaml.SequenceNode && (tag == "!!set" || tag == "!<tag:yaml.org,2002:set>") {
+			// The members of a !!set are the keys of a mapping, whose
+			// empty values leave a colon after the last one
+			if i := end + len(src[end:]) - len(strings.TrimLeft(src[end:], " \t")); i < len(src) && src[i] == ':' {
+				end = i + 1
+			}
+		}
+		return end
+	}
+	return pos
+}
+
+// indent returns the indentation of a 1-based line.
+func (l lineIndex) indent(line int) int {
+	pos := l.offset(line, 1)
+	n := 0
+	for pos+n < len(l.src) && l.src[pos+n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// isEmptyScalar reports whether node is a null with no text, as for the
+// value of "key:".
+func isEmptyScalar(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Value == "" && node.Style&^yaml.TaggedStyle == 0 && node.ShortTag() == "!!null"
+}
+
+// tokenEnd returns the end of the anchor, alias or tag starting at pos.
+func tokenEnd(src string, pos int) int {
+	for pos < len(src) && strings.IndexByte(" \t\r\n,[]{}", src[pos]) < 0 {
+		pos++
+	}
+	return pos
+}
+
+// quotedEnd returns the end of the quoted scalar starting at pos.
+func quotedEnd(src string, pos int, quote byte) int {
+	for i := pos + 1; i < len(src); i++ {
+		switch {
+		case quote == '"' && src[i] == '\\':
+			i++
+		case src[i] == quote:
+			if quote == '\'' && i+1 < len(src) && src[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// plainEnd returns the end of the plain scalar starting at pos, by
+// matching its value against the source. Line breaks in the source fold
+// to spaces or newlines in the value, so runs of whitespace are matched
+// as a whole.
+func plainEnd(src string, pos int, value string) int {
+	i, j := 0, pos
+	for i < len(value) && j < len(src) {
+		if isBlank(value[i]) && isBlank(src[j]) {
+			for i < len(value) && isBlank(value[i]) {
+				i++
+			}
+			for j < len(src) && isBlank(src[j]) {
+				j++
+			}
+			continue
+		}
+		if value[i] != src[j] {
+			break
+		}
+		i++
+		j++
+	}
+	return j
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// blockScalarEnd returns the end of the literal or folded scalar whose
+// header starts at pos, on a line indented by parent spaces. The scalar
+// ends with its last content line, including the line break unless the
+// header strips it, and the blank lines after it when the header keeps
+// them.
+func blockScalarEnd(src string, pos, parent int) int {
+	end := pos
+	for end < len(src) && strings.IndexByte("|>+-0123456789", src[end]) >= 0 {
+		end++
+	}
+	chomp := byte(0)
+	if i := strings.IndexAny(src[pos:end], "+-"); i >= 0 {
+		chomp = src[pos+i]
+	}
+	nl := strings.IndexByte(src[end:], '\n')
+	if nl < 0 {
+		return end
+	}
+	indent := -1
+	line := end + nl + 1
+	for line < len(src) {
+		lineEnd, next := len(src), len(src)
+		if k := strings.IndexByte(src[line:], '\n'); k >= 0 {
+			lineEnd, next = line+k, line+k+1
+		}
+		text := strings.TrimRight(src[line:lineEnd], "\r")
+		if content := strings.TrimLeft(text, " "); strings.TrimSpace(content) != "" {
+			n := len(text) - len(content)
+			if indent < 0 {
+				indent = n
+			}
+			if n < indent || n <= parent {
+				break
+			}
+			end = line + len(text)
+			if chomp != '-' {
+				end = next
+			}
+		}
+		line = next
+	}
+	if chomp == '+' && indent >= 0 {
+		end = line
+	}
+	return end
+}
+
+// flowEnd returns the end of the flow collection starting at pos.
+func flowEnd(src string, pos int) int {
+	depth := 0
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"', '\'':
+			i = quotedEnd(src, i, src[i]) - 1
+		case '#':
+			if i > 0 && isBlank(src[i-1]) {
+				// Skip comments inside multi-line collections
+				if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+					i += nl
+				} else {
+					return len(src)
+				}
+			}
+		}
+	}
+	return len(src)
+}
@@ -0,0 +1,218 @@
+package gyaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const spanYAML = `# Deployment settings
+servers:
+  - name: web   # front end
+    port: 8080
+    tags: [a, "b, c", {d: e}]
+  - name: "api"
+    script: |
+      echo start
+
+      echo done
+    # trailing comment
+  - 'it''s'
+  - plain text
+    folded over lines
+  - &ref
+    nested:
+      - 1
+      - 2.5
+  - >-
+    folded
+    block
+  - {inline: [1, 2], "quoted key": x}
+  -
+  - !!str 42
+unicode:
+  ключ: значение
+  emoji: "🙂 ok"
+  empty:
+  last: [1,
+    2]
+`
+
+// reparseSpan parses the text of a span, indented as it is in the source,
+// and returns its value.
+func reparseSpan(t *testing.T, src string, s Span) interface{} {
+	t.Helper()
+	column := s.Start - (strings.LastIndexByte(src[:s.Start], '\n') + 1)
+	text := strings.Repeat(" ", column) + src[s.Start:s.End]
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(text), &v); err != nil {
+		t.Fatalf("Span %q does not parse: %v", src[s.Start:s.End], err)
+	}
+	return v
+}
+
+func checkSpans(t *testing.T, src string, r Result, values []interface{}) {
+	t.Helper()
+	spans := r.Elements()
+	if len(spans) != len(values) {
+		t.Fatalf("Expected %d spans, got %d", len(values), len(spans))
+	}
+	for i, s := range spans {
+		text := src[s.Start:s.End]
+		if strings.TrimLeft(text, " \t\n") != text || strings.TrimRight(text, " \t") != text {
+			t.Errorf("Span %d has surrounding whitespace: %q", i, text)
+		}
+		if got := reparseSpan(t, src, s); !reflect.DeepEqual(got, values[i]) {
+			t.Errorf("Span %d %q parses to %#v, expected %#v", i, text, got, values[i])
+		}
+	}
+}
+
+func TestElementsSequence(t *testing.T) {
+	servers := Get(spanYAML, "servers")
+	var values []interface{}
+	for _, elem := range servers.Array() {
+		values = append(values, elem.Value())
+	}
+	checkSpans(t, spanYAML, servers, values)
+
+	spans := servers.Elements()
+	if got := spanYAML[spans[0].Start:spans[0].End]; !strings.HasSuffix(got, `{d: e}]`) {
+		t.Errorf("Expected the first element to end with its last value, got %q", got)
+	}
+	if got := spanYAML[spans[1].Start:spans[1].End]; !strings.HasSuffix(got, "echo done\n") {
+		t.Errorf("Expected the block scalar to end at its last line, got %q", got)
+	}
+	if got := spanYAML[spans[4].Start:spans[4].End]; !strings.HasPrefix(got, "&ref") {
+		t.Errorf("Expected the span to include the anchor, got %q", got)
+	}
+	if spans[7].Start != spans[7].End {
+		t.Errorf("Expected an empty span for an empty element, got %q", spanYAML[spans[7].Start:spans[7].End])
+	}
+}
+
+func TestElementsMapping(t *testing.T) {
+	unicode := Get(spanYAML, "unicode")
+	values := []interface{}{"значение", "🙂 ok", nil, []interface{}{1, 2}}
+	checkSpans(t, spanYAML, unicode, values)
+
+	spans := unicode.Elements()
+	if got := spanYAML[spans[0].Start:spans[0].End]; got != "значение" {
+		t.Errorf("Expected 'значение', got %q", got)
+	}
+	if got := spanYAML[spans[1].Start:spans[1].End]; got != `"🙂 ok"` {
+		t.Errorf("Expected the quoted value, got %q", got)
+	}
+}
+
+func TestElementsNested(t *testing.T) {
+	doc := Parse(spanYAML)
+	nested := doc.Get("servers").Array()[4].Get("nested")
+	checkSpans(t, spanYAML, nested, []interface{}{1, 2.5})
+
+	var count int
+	doc.ForEach(func(key, value Result) bool {
+		count += len(value.Elements())
+		return true
+	})
+	if count != 13 {
+		t.Errorf("Expected 13 spans across the document, got %d", count)
+	}
+}
+
+func TestElementsSynthesized(t *testing.T) {
+	if Get(spanYAML, "servers.#.name").Elements() != nil {
+		t.Error("Expected no spans for a projection")
+	}
+	if Get(spanYAML, "servers.0.name").Elements() != nil {
+		t.Error("Expected no spans for a scalar")
+	}
+	if makeResult([]interface{}{1, 2}).Elements() != nil {
+		t.Error("Expected no spans for a built value")
+	}
+	if ParseStream(streamYAML).Elements() != nil {
+		t.Error("Expected no spans for a stream")
+	}
+}
+
+func TestElementsBlockScalarChomping(t *testing.T) {
+	src := "a: |+\n  kept\n\nb: |-\n  stripped\nc: >\n  clipped\n\n# done\n"
+	doc := Parse(src)
+	checkSpans(t, src, doc, []interface{}{"kept\n\n", "stripped", "clipped\n"})
+}
+
+func TestResultPositions(t *testing.T) {
+	doc := Parse(spanYAML)
+	for _, path := range []string{"servers", "servers.0.tags", "servers.1.script", "servers.2", "servers.4.nested.1", "unicode.emoji", "unicode.last"} {
+		for _, r := range []Result{Get(spanYAML, path), doc.Get(path), GetRef(spanYAML, path), GetMany(spanYAML, path)[0]} {
+			if r.End <= r.Index {
+				t.Fatalf("%s: expected a position, got %d to %d", path, r.Index, r.End)
+			}
+			if got := reparseSpan(t, spanYAML, Span{r.Index, r.End}); !reflect.DeepEqual(got, Get(spanYAML, path).Value()) {
+				t.Errorf("%s: %q parses to %#v", path, spanYAML[r.Index:r.End], got)
+			}
+		}
+	}
+	if r := Get(spanYAML, "unicode.empty"); !r.IsNull() || r.Index == 0 || r.End != r.Index {
+		t.Errorf("Expected an empty span for an empty value, got %d to %d", r.Index, r.End)
+	}
+
+	// Elements, entries and the document agree with Elements
+	servers := doc.Get("servers")
+	for i, elem := range servers.Array() {
+		if s := servers.Elements()[i]; elem.Index != s.Start || elem.End != s.End {
+			t.Errorf("Element %d at %d to %d, expected %v", i, elem.Index, elem.End, s)
+		}
+	}
+	i := 0
+	doc.Get("unicode").ForEach(func(key, value Result) bool {
+		if key.Index != i || value.Index != doc.Get("unicode").Elements()[i].Start {
+			t.Errorf("%s: unexpected positions %d and %d", key, key.Index, value.Index)
+		}
+		i++
+		return true
+	})
+	if !strings.HasPrefix(spanYAML[doc.Index:doc.End], "servers:") || doc.End != len(spanYAML)-1 {
+		t.Errorf("Expected the document to start at its first key, got %d to %d", doc.Index, doc.End)
+	}
+
+	// Values without source text have no position
+	for _, path := range []string{"servers.#.name", "servers.#(name=web).port", "servers.#", "missing"} {
+		if r := Get(spanYAML, path); r.Index != 0 || r.End != 0 {
+			t.Errorf("%s: expected no position, got %d to %d", path, r.Index, r.End)
+		}
+	}
+}
+
+func TestPositionsAfterByteOrderMark(t *testing.T) {
+	// The byte order mark is not counted as a column by the parser, but
+	// takes 3 bytes of the first line
+	src := "\ufeffa: 1\nb: [x, y]\nc:\n  - é\n  - {k: v}\n"
+	for path, want := range map[string]string{"a": "1", "b": "[x, y]", "b.1": "y", "c.0": "é", "c.1": "{k: v}"} {
+		r := Get(src, path)
+		if got := src[r.Index:r.End]; got != want {
+			t.Errorf("%s: expected %q at %d to %d, got %q", path, want, r.Index, r.End, got)
+		}
+	}
+	if r := Get(src, "a"); r.Index != 6 || r.End != 7 {
+		t.Errorf("Expected a at 6 to 7, got %d to %d", r.Index, r.End)
+	}
+	if spans := Parse("\ufeff- é\n- x\n").Elements(); len(spans) != 2 || spans[0] != (Span{5, 7}) {
+		t.Errorf("Unexpected spans %v", spans)
+	}
+	if doc := Parse(src); doc.Index != 3 {
+		t.Errorf("Expected the document to start after the byte order mark, got %d", doc.Index)
+	}
+
+	// Set writes values in place
+	out, err := Set("\ufeff- 1\n- 2\n", "0", []interface{}{1, 2})
+	if want := "\ufeff- - 1\n  - 2\n- 2\n"; err != nil || out != want {
+		t.Errorf("Expected %q, got %q, %v", want, out, err)
+	}
+	out, err = Set("\ufeffa: 1\nb: 2\n", "a", "x")
+	if want := "\ufeffa: x\nb: 2\n"; err != nil || out != want {
+		t.Errorf("Expected %q, got %q, %v", want, out, err)
+	}
+}
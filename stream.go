@@ -0,0 +1,189 @@
+package gyaml
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeStream decodes every document of a multi-document YAML stream,
+// enforcing the limits set in opts on each before it is decoded. The
+// documents' root nodes are returned as the content of a sequence node;
+// empty documents have a null node.
+func decodeStream(yamlStr string, opts Options) ([]interface{}, *yaml.Node, error) {
+	if err := opts.checkText(yamlStr, true); err != nil {
+		return nil, nil, err
+	}
+	dec := yaml.NewDecoder(strings.NewReader(yamlStr))
+	var docs []interface{}
+	roots := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, roots, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var v interface{}
+		root := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		if len(doc.Content) > 0 {
+			root = doc.Content[0]
+			normalizeNode(root)
+			if err := opts.checkNode(root, len(yamlStr)); err != nil {
+				return nil, nil, err
+			}
+			if opts.KeepMergeKeys {
+				keepMergeKeys(root)
+			}
+			if v, err = decodeNode(root); err != nil {
+				return nil, nil, err
+			}
+		}
+		docs = append(docs, v)
+		roots.Content = append(roots.Content, root)
+	}
+}
+
+// ParseStream parses a stream of "---" separated YAML documents.
+// The returned Result behaves like an array of documents for ForEach,
+// Array and Len, where the key is the document index. Get resolves paths
+// against the first document, just like Parse does.
+//
+// Get also takes a path starting with a document selector, such as
+// "@2.metadata.name", which reads from the third document instead.
+func ParseStream(yamlStr string) Result {
+	stream, _ := parseStream(yamlStr, Options{})
+	return stream
+}
+
+// parseStream parses a stream for ParseStream, and reports why it could
+// not be read.
+func parseStream(yamlStr string, opts Options) (Result, error) {
+	docs, roots, err := decodeStream(yamlStr, opts)
+	if err != nil || len(docs) == 0 {
+		return Result{Type: Null}, err
+	}
+	return Result{Type: YAML, Raw: yamlStr, decoded: &decodedValue{v: docs, node: roots, stream: true, src: yamlStr}}, nil
+}
+
+// Documents returns the documents of a stream of "---" separated YAML
+// documents, the same as ParseStream(yamlStr).Array(). It returns nil when
+// the stream cannot be parsed.
+func Documents(yamlStr string) []Result {
+	stream := ParseStream(yamlStr)
+	if !stream.Exists() {
+		return nil
+	}
+	return stream.Array()
+}
+
+// DocumentCount returns the number of documents in a stream of "---"
+// separated YAML documents, counting empty ones, without decoding their
+// values. It returns 0 when the stream cannot be parsed.
+func DocumentCount(yamlStr string) int {
+	dec := yaml.NewDecoder(strings.NewReader(yamlStr))
+	n := 0
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return n
+		}
+		if err != nil {
+			return 0
+		}
+		n++
+	}
+}
+
+// documentPath splits a path starting with a document selector, such as
+// "@2.metadata.name", into the index of the document and the rest of the
+// path, which follows a "." or a "|".
+func documentPath(path string) (n int, rest string, ok bool) {
+	if len(path) < 2 || path[0] != '@' {
+		return 0, "", false
+	}
+	i := 1
+	for i < len(path) && path[i] >= '0' && path[i] <= '9' {
+		i++
+	}
+	if i == 1 || (i < len(path) && path[i] != '.' && path[i] != '|') {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(path[1:i])
+	if err != nil {
+		return 0, "", false
+	}
+	if i < len(path) {
+		rest = path[i+1:]
+	}
+	return n, rest, true
+}
+
+// selectDocument returns document n of a stream, or Null when the stream
+// holds fewer documents, enforcing the limits set in opts.
+func selectDocument(yamlStr string, n int, opts Options) (Result, error) {
+	docs, err := streamDocuments(yamlStr, opts)
+	if err != nil {
+		return Result{Type: Null}, err
+	}
+	return nthDocument(docs, n), nil
+}
+
+// streamDocuments parses the documents of a stream, enforcing the limits
+// set in opts, for the paths starting with @N to pick from.
+func streamDocuments(yamlStr string, opts Options) ([]Result, error) {
+	stream, err := parseStream(yamlStr, opts)
+	if err != nil || !stream.Exists() {
+		return nil, err
+	}
+	return stream.Array(), nil
+}
+
+// nthDocument returns document n of docs, or Null when there are fewer.
+func nthDocument(docs []Result, n int) Result {
+	if n >= len(docs) {
+		return Result{Type: Null}
+	}
+	doc := docs[n]
+	doc.path = ""
+	return doc
+}
+
+// SelectDocument returns the first document of a "---" separated stream
+// for which every condition holds, or a Null result when there is none.
+// Each key of conditions is a path within the document, and its value is
+// compared with the value found there as the = operator of queries
+// compares them, so that
+//
+//	gyaml.SelectDocument(manifests, map[string]string{
+//		"kind":          "Deployment",
+//		"metadata.name": "web",
+//	}).Get("spec.replicas")
+//
+// reads the replicas of the web Deployment. A path that does not exist in
+// a document fails its condition.
+func SelectDocument(stream string, conditions map[string]string) Result {
+	eq := lookupOperator("=")
+	found := Result{Type: Null}
+	ParseStream(stream).ForEach(func(_, doc Result) bool {
+		for path, value := range conditions {
+			r := doc.Get(path)
+			if !r.Exists() {
+				return true
+			}
+			if ok, _ := (&resolver{}).eval(r.nativeValue(), condition{key: path, op: eq, value: value}); !ok {
+				return true
+			}
+		}
+		found = doc
+		found.path = ""
+		return false
+	})
+	return found
+}
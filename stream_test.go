@@ -0,0 +1,264 @@
+package gyaml
+
+import (
+	"errors"
+	"testing"
+)
+
+const streamYAML = `
+kind: Deployment
+metadata:
+  name: web
+---
+kind: Service
+metadata:
+  name: web
+---
+kind: ConfigMap
+metadata:
+  name: settings
+`
+
+func TestParseStreamForEach(t *testing.T) {
+	stream := ParseStream(streamYAML)
+	if !stream.Exists() {
+		t.Fatal("Expected stream to exist")
+	}
+
+	var kinds []string
+	stream.ForEach(func(key, value Result) bool {
+		if key.Int() != int64(len(kinds)) {
+			t.Errorf("Expected key %d, got %d", len(kinds), key.Int())
+		}
+		kinds = append(kinds, value.Get("kind").String())
+		return true
+	})
+	if len(kinds) != 3 || kinds[0] != "Deployment" || kinds[1] != "Service" || kinds[2] != "ConfigMap" {
+		t.Errorf("Unexpected documents: %v", kinds)
+	}
+
+	visited := 0
+	stream.ForEach(func(key, value Result) bool {
+		visited++
+		return key.Int() < 1
+	})
+	if visited != 2 {
+		t.Errorf("Expected iteration to stop after 2 documents, got %d", visited)
+	}
+}
+
+func TestParseStreamLenAndArray(t *testing.T) {
+	stream := ParseStream(streamYAML)
+	if stream.Len() != 3 {
+		t.Errorf("Expected 3 documents, got %d", stream.Len())
+	}
+	docs := stream.Array()
+	if len(docs) != 3 || docs[2].Get("metadata.name").String() != "settings" {
+		t.Errorf("Unexpected documents: %v", docs)
+	}
+}
+
+func TestParseStreamGetUsesFirstDocument(t *testing.T) {
+	stream := ParseStream(streamYAML)
+	if stream.Get("kind").String() != "Deployment" {
+		t.Errorf("Expected 'Deployment', got '%s'", stream.Get("kind").String())
+	}
+	if stream.Get("kind").String() != Parse(streamYAML).Get("kind").String() {
+		t.Error("Stream Get should match Parse Get")
+	}
+}
+
+func TestParseStreamInvalid(t *testing.T) {
+	if ParseStream("").Exists() {
+		t.Error("Empty stream should not exist")
+	}
+	if ParseStream("a: 1\n---\nb: [1, 2\n").Exists() {
+		t.Error("Stream with a malformed document should not exist")
+	}
+	if ParseStream("a: 1\n").Len() != 1 {
+		t.Error("Single document stream should have length 1")
+	}
+}
+
+func TestLen(t *testing.T) {
+	if Get(testYAML, "children").Len() != 3 {
+		t.Error("Expected 3 children")
+	}
+	if Get(testYAML, "name").Len() != 2 {
+		t.Error("Expected 2 keys in name")
+	}
+	if Get(testYAML, "age").Len() != 0 {
+		t.Error("Expected 0 for a scalar")
+	}
+	if (Result{Type: YAML, Raw: "{1: a, 2: b}"}).Len() != 2 {
+		t.Error("Expected 2 keys in non-string keyed map")
+	}
+}
+
+func TestEmptyProjectionExists(t *testing.T) {
+	empty := Get(testYAML, "friends.#.nickname")
+	if !empty.Exists() {
+		t.Error("Expected an empty projection to exist")
+	}
+	if empty.ExistsNonEmpty() || empty.Count() != 0 {
+		t.Errorf("Expected an empty projection, got %q", empty.Raw)
+	}
+
+	ages := Get(testYAML, "friends.#.age")
+	if !ages.ExistsNonEmpty() || ages.Count() != ages.Len() || ages.Count() != 3 {
+		t.Errorf("Expected 3 ages, got %q", ages.Raw)
+	}
+	if !Get(testYAML, "age").ExistsNonEmpty() {
+		t.Error("Expected a scalar to exist")
+	}
+	if Get(testYAML, "missing").ExistsNonEmpty() {
+		t.Error("Expected a missing value not to exist")
+	}
+	// Documents holding a scalar are YAML results, and not empty
+	for _, doc := range []string{"42", "hello", "true", "''"} {
+		if !Parse(doc).ExistsNonEmpty() {
+			t.Errorf("Expected Parse(%q) to be non-empty", doc)
+		}
+	}
+	if Parse("[]").ExistsNonEmpty() || Parse("{}").ExistsNonEmpty() {
+		t.Error("Expected empty documents to be empty")
+	}
+}
+
+func TestExistsNonEmptyDecodesOnce(t *testing.T) {
+	calls := countUnmarshal(t)
+	if !(Result{Type: YAML, Raw: "[1, 2]"}).ExistsNonEmpty() {
+		t.Error("Expected a non-empty array")
+	}
+	if (Result{Type: YAML, Raw: "{}"}).ExistsNonEmpty() {
+		t.Error("Expected an empty object")
+	}
+	if *calls != 2 {
+		t.Errorf("Expected 2 decodes, got %d", *calls)
+	}
+}
+
+const manifestsYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports: [{port: 80}]
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {tier: frontend}
+spec:
+  replicas: 3
+`
+
+func TestSelectDocument(t *testing.T) {
+	byKind := SelectDocument(manifestsYAML, map[string]string{"kind": "Deployment"})
+	if got := byKind.Get("metadata.name").String(); got != "api" {
+		t.Errorf("Expected the first Deployment, got %q", got)
+	}
+
+	web := SelectDocument(manifestsYAML, map[string]string{"kind": "Deployment", "metadata.name": "web"})
+	if got := web.Get("spec.replicas").Int(); got != 3 {
+		t.Errorf("Expected 3 replicas, got %d", got)
+	}
+	if got := web.Get("metadata.labels.tier").String(); got != "frontend" {
+		t.Errorf("Expected the web Deployment, got %s", web.GoString())
+	}
+	if got := SelectDocument(manifestsYAML, map[string]string{"spec.replicas": "3"}).Get("metadata.name").String(); got != "web" {
+		t.Errorf("Expected numbers to compare as in queries, got %q", got)
+	}
+	if got := SelectDocument(manifestsYAML, map[string]string{"spec.ports.0.port": "80"}).Get("kind").String(); got != "Service" {
+		t.Errorf("Expected a nested path condition to match, got %q", got)
+	}
+
+	for _, conditions := range []map[string]string{
+		{"kind": "StatefulSet"},
+		{"kind": "Service", "metadata.name": "api"},
+		{"metadata.namespace": ""},
+	} {
+		if r := SelectDocument(manifestsYAML, conditions); r.Exists() {
+			t.Errorf("%v: expected Null, got %s", conditions, r.GoString())
+		}
+	}
+	if r := SelectDocument("a: [", map[string]string{}); r.Exists() {
+		t.Errorf("Expected Null for invalid YAML, got %s", r.GoString())
+	}
+	if r := SelectDocument(manifestsYAML, nil); r.Get("metadata.name").String() != "api" {
+		t.Errorf("Expected no conditions to select the first document, got %s", r.GoString())
+	}
+}
+
+func TestDocumentSelector(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"@0.kind", "Deployment"},
+		{"@1.kind", "Service"},
+		{"@2.metadata.name", "settings"},
+		{"@2|metadata.name", "settings"},
+		{"@1", "kind: Service\nmetadata:\n  name: web\n"},
+		{"@3.kind", ""},
+		{"kind", "Deployment"},
+		{`\@1`, ""},
+	}
+	for _, test := range tests {
+		doc, err := ParseDocument(streamYAML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := []Result{
+			Get(streamYAML, test.path), ParseStream(streamYAML).Get(test.path), GetWith(streamYAML, test.path),
+			GetMany(streamYAML, "kind", test.path)[1], doc.Get(test.path),
+		}
+		for i, r := range results {
+			if r.String() != test.expected {
+				t.Errorf("%d: Get(%q) = %q, expected %q", i, test.path, r.String(), test.expected)
+			}
+		}
+		if results[0].path != test.path {
+			t.Errorf("Expected the path %q, got %q", test.path, results[0].path)
+		}
+	}
+
+	// The position is in the stream
+	name := Get(streamYAML, "@2.metadata.name")
+	if streamYAML[name.Index:name.End] != "settings" {
+		t.Errorf("Unexpected span %d-%d", name.Index, name.End)
+	}
+	if _, err := GetE(streamYAML, "@1.kind", WithMaxDepth(2)); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := GetE(streamYAML, "@1.kind", WithMaxScalarSize(4)); !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected the limits to apply to each document, got %v", err)
+	}
+	if _, err := GetE("a: 1\n---\nb: [", "@1.b"); err == nil {
+		t.Error("Expected an error for an invalid stream")
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	docs := Documents(streamYAML)
+	if len(docs) != 3 || docs[2].Get("kind").String() != "ConfigMap" {
+		t.Errorf("Unexpected documents %v", docs)
+	}
+	if n := DocumentCount(streamYAML); n != 3 {
+		t.Errorf("Expected 3 documents, got %d", n)
+	}
+	if n := DocumentCount("a: 1\n---\n---\nb: 2\n"); n != 3 {
+		t.Errorf("Expected empty documents to count, got %d", n)
+	}
+	if docs, n := Documents("a: ["), DocumentCount("a: 1\n---\nb: ["); docs != nil || n != 0 {
+		t.Errorf("Expected nothing for an invalid stream, got %v and %d", docs, n)
+	}
+}
@@ -0,0 +1,939 @@
+package gyaml
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FromTOML reads a TOML document, so that the paths, queries and
+// accessors of gyaml apply to TOML configuration as well:
+//
+//	cfg := gyaml.FromTOML(tomlText)
+//	cfg.Get("servers.#(role=\"primary\").host")
+//
+// The document is converted to YAML, which the Result holds in Raw, with
+// tables as objects in the order they are written. Integers, floats,
+// booleans and strings keep their types; offset date-times, local
+// date-times and local dates are timestamps, which Time reads, and local
+// times are strings. It returns a Null result when the TOML is not valid;
+// FromTOMLE reports why.
+func FromTOML(toml string) Result {
+	r, _ := FromTOMLE(toml)
+	return r
+}
+
+// FromTOMLE reads a TOML document like FromTOML. The error is a
+// *SyntaxError giving the line and column where the TOML is not valid.
+func FromTOMLE(toml string) (Result, error) {
+	p := &tomlParser{src: toml, line: 1, lineStart: 0, root: newTOMLTable()}
+	if err := p.parse(); err != nil {
+		return Result{Type: Null}, err
+	}
+	raw, err := yaml.Marshal(p.root.node())
+	if err != nil {
+		return Result{Type: Null}, err
+	}
+	return parseDocument(string(raw), Options{})
+}
+
+// tomlTable is a TOML table being read.
+type tomlTable struct {
+	keys []string
+	// vals holds a *tomlTable, a *tomlArray or the *yaml.Node of a
+	// scalar for each key
+	vals map[string]interface{}
+	// header is set for a table defined by a [header], dotted for one
+	// made by dotted keys, and inline for an inline table, which is
+	// closed once written
+	header, dotted, inline bool
+}
+
+// tomlArray is an array, or an array of tables written [[name]].
+type tomlArray struct {
+	items  []interface{}
+	tables bool
+}
+
+func newTOMLTable() *tomlTable {
+	return &tomlTable{vals: map[string]interface{}{}}
+}
+
+// set adds a key to t.
+func (t *tomlTable) set(key string, v interface{}) {
+	t.keys = append(t.keys, key)
+	t.vals[key] = v
+}
+
+// node returns the YAML node of t, with its keys in order.
+func (t *tomlTable) node() *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range t.keys {
+		m.Content = append(m.Content, stringNode(k), tomlNode(t.vals[k]))
+	}
+	return m
+}
+
+// tomlNode returns the YAML node of a value read from TOML.
+func tomlNode(v interface{}) *yaml.Node {
+	switch v := v.(type) {
+	case *tomlTable:
+		return v.node()
+	case *tomlArray:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v.items {
+			seq.Content = append(seq.Content, tomlNode(item))
+		}
+		return seq
+	}
+	return v.(*yaml.Node)
+}
+
+// tomlParser reads a TOML document.
+type tomlParser struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+	root      *tomlTable
+	// cur is the table the key/value pairs that follow go into
+	cur *tomlTable
+}
+
+// errorf returns a *SyntaxError at the current position.
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: p.line, Column: p.pos - p.lineStart + 1, Msg: "toml: " + fmt.Sprintf(format, args...)}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skipBlank skips spaces and tabs.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to the end of the line.
+func (p *tomlParser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eof() && p.src[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// newline consumes a line break, and reports whether there was one.
+func (p *tomlParser) newline() bool {
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "\n"):
+		p.pos++
+	case strings.HasPrefix(p.src[p.pos:], "\r\n"):
+		p.pos += 2
+	default:
+		return false
+	}
+	p.line, p.lineStart = p.line+1, p.pos
+	return true
+}
+
+// skipSpace skips blanks, comments and line breaks, as found between the
+// values of an array.
+func (p *tomlParser) skipSpace() {
+	for {
+		p.skipBlank()
+		p.skipComment()
+		if !p.newline() {
+			return
+		}
+	}
+}
+
+// endLine consumes the rest of a line after a header or key/value pair,
+// which may only hold a comment.
+func (p *tomlParser) endLine() error {
+	p.skipBlank()
+	p.skipComment()
+	if !p.eof() && !p.newline() {
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	p.cur = p.root
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+		var err error
+		if p.peek() == '[' {
+			err = p.header()
+		} else {
+			err = p.keyValue(p.cur)
+		}
+		if err == nil {
+			err = p.endLine()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// header reads a [table] or [[array of tables]] header.
+func (p *tomlParser) header() error {
+	start := p.pos
+	p.pos++
+	array := p.peek() == '['
+	if array {
+		p.pos++
+	}
+	p.skipBlank()
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipBlank()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return p.errorf("expected %s after table name", closing)
+	}
+	// Errors in the table name are reported at the header
+	end := p.pos + len(closing)
+	p.pos = start
+	if err := p.openTable(keys, array); err != nil {
+		return err
+	}
+	p.pos = end
+	return nil
+}
+
+// openTable makes the table named keys by a header the current table.
+func (p *tomlParser) openTable(keys []string, array bool) error {
+	parent := p.root
+	for _, k := range keys[:len(keys)-1] {
+		switch v := parent.vals[k].(type) {
+		case nil:
+			t := newTOMLTable()
+			parent.set(k, t)
+			parent = t
+		case *tomlTable:
+			if v.inline {
+				return p.errorf("cannot extend inline table %s", k)
+			}
+			parent = v
+		case *tomlArray:
+			if !v.tables {
+				return p.errorf("cannot extend array %s", k)
+			}
+			parent = v.items[len(v.items)-1].(*tomlTable)
+		default:
+			return p.errorf("key %s is not a table", k)
+		}
+	}
+
+	last := keys[len(keys)-1]
+	existing := parent.vals[last]
+	if array {
+		arr, ok := existing.(*tomlArray)
+		switch {
+		case existing == nil:
+			arr = &tomlArray{tables: true}
+			parent.set(last, arr)
+		case !ok || !arr.tables:
+			return p.errorf("key %s is already defined", last)
+		}
+		t := newTOMLTable()
+		t.header = true
+		arr.items = append(arr.items, t)
+		p.cur = t
+		return nil
+	}
+	switch v := existing.(type) {
+	case nil:
+		t := newTOMLTable()
+		t.header = true
+		parent.set(last, t)
+		p.cur = t
+	case *tomlTable:
+		if v.header || v.dotted || v.inline {
+			return p.errorf("table %s is already defined", last)
+		}
+		v.header = true
+		p.cur = v
+	default:
+		return p.errorf("key %s is already defined", last)
+	}
+	return nil
+}
+
+// keyValue reads a key/value pair into t.
+func (p *tomlParser) keyValue(t *tomlTable) error {
+	start := p.pos
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	end := p.pos
+	// Errors in the key are reported at the key
+	p.pos = start
+	for _, k := range keys[:len(keys)-1] {
+		switch v := t.vals[k].(type) {
+		case nil:
+			next := newTOMLTable()
+			next.dotted, next.inline = true, t.inline
+			t.set(k, next)
+			t = next
+		case *tomlTable:
+			if !v.dotted || v.inline != t.inline {
+				return p.errorf("cannot extend table %s with dotted keys", k)
+			}
+			t = v
+		default:
+			return p.errorf("key %s is not a table", k)
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, exists := t.vals[last]; exists {
+		return p.errorf("key %s is already defined", last)
+	}
+	p.pos = end
+	p.skipBlank()
+	if p.peek() != '=' {
+		return p.errorf("expected = after key")
+	}
+	p.pos++
+	p.skipBlank()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	t.set(last, value)
+	return nil
+}
+
+// key reads a key, dotted or not, and returns its parts.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank()
+		var k string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		case c == '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.src[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a key")
+			}
+			k = p.src[start:p.pos]
+		}
+		keys = append(keys, k)
+		p.skipBlank()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value reads a value and returns a *tomlTable, a *tomlArray or the node
+// of a scalar.
+func (p *tomlParser) value() (interface{}, error) {
+	switch c := p.peek(); {
+	case p.eof():
+		return nil, p.errorf("expected a value")
+	case c == '"' || c == '\'':
+		var s string
+		var err error
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], `"""`):
+			s, err = p.multilineString(`"""`)
+		case strings.HasPrefix(p.src[p.pos:], `'''`):
+			s, err = p.multilineString(`'''`)
+		case c == '"':
+			s, err = p.basicString()
+		default:
+			s, err = p.literalString()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return stringNode(s), nil
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	}
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-_.:0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	if token == "" {
+		return nil, p.errorf("expected a value")
+	}
+	if tomlDate.MatchString(token) && strings.HasPrefix(p.src[p.pos:], " ") && len(p.src) > p.pos+3 && p.src[p.pos+3] == ':' {
+		// A date and a time separated by a space
+		p.pos++
+		for !p.eof() && strings.IndexByte("+-.:0123456789Zz", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		token = token + "T" + p.src[start+len(token)+1:p.pos]
+	}
+	if node, ok := tomlScalar(token); ok {
+		return node, nil
+	}
+	p.pos = start
+	return nil, p.errorf("invalid value %q", token)
+}
+
+var (
+	tomlDate    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlInt     = regexp.MustCompile(`^[+-]?(0|[1-9](_?\d)*)$`)
+	tomlHex     = regexp.MustCompile(`^0x[0-9A-Fa-f](_?[0-9A-Fa-f])*$`)
+	tomlOct     = regexp.MustCompile(`^0o[0-7](_?[0-7])*$`)
+	tomlBin     = regexp.MustCompile(`^0b[01](_?[01])*$`)
+	tomlFloatRe = regexp.MustCompile(`^[+-]?(0|[1-9](_?\d)*)(\.\d(_?\d)*)?([eE][+-]?\d(_?\d)*)?$`)
+	tomlSpecial = map[string]string{
+		"inf": ".inf", "+inf": ".inf", "-inf": "-.inf",
+		"nan": ".nan", "+nan": ".nan", "-nan": ".nan",
+	}
+)
+
+// tomlScalar returns the node of a bare scalar: a boolean, a number or a
+// date or time.
+func tomlScalar(token string) (*yaml.Node, bool) {
+	digits := strings.ReplaceAll(token, "_", "")
+	switch {
+	case token == "true" || token == "false":
+		return plainNode(token), true
+	case tomlSpecial[token] != "":
+		return plainNode(tomlSpecial[token]), true
+	case tomlInt.MatchString(token):
+		if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return plainNode(strconv.FormatInt(n, 10)), true
+		}
+	case tomlHex.MatchString(token) || tomlOct.MatchString(token) || tomlBin.MatchString(token):
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[token[1]]
+		if n, err := strconv.ParseInt(digits[2:], base, 64); err == nil {
+			return plainNode(strconv.FormatInt(n, 10)), true
+		}
+	case tomlFloatRe.MatchString(token):
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return plainNode(formatYAMLFloat(f, 64)), true
+		}
+	case len(token) >= 8 && (token[2] == ':' || token[4] == '-'):
+		return tomlDateTime(token)
+	}
+	return nil, false
+}
+
+// tomlDateTime returns the node of a date, a time or both. Local times
+// are strings, as YAML has no type for them.
+func tomlDateTime(token string) (*yaml.Node, bool) {
+	if len(token) > 10 && (token[10] == 't' || token[10] == 'T') {
+		token = token[:10] + "T" + token[11:]
+	}
+	if strings.HasSuffix(token, "z") {
+		token = token[:len(token)-1] + "Z"
+	}
+	if _, err := time.Parse(time.RFC3339Nano, token); err == nil {
+		return plainNode(token), true
+	}
+	if _, err := time.Parse("2006-01-02T15:04:05", token); err == nil {
+		return plainNode(token[:10] + " " + token[11:]), true
+	}
+	if _, err := time.Parse("2006-01-02", token); err == nil {
+		return plainNode(token), true
+	}
+	if _, err := time.Parse("15:04:05", token); err == nil {
+		return stringNode(token), true
+	}
+	return nil, false
+}
+
+// array reads an array.
+func (p *tomlParser) array() (*tomlArray, error) {
+	p.pos++
+	arr := &tomlArray{items: []interface{}{}}
+	for {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr.items = append(arr.items, v)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable reads an inline table, such as {x = 1, y = 2}.
+func (p *tomlParser) inlineTable() (*tomlTable, error) {
+	p.pos++
+	t := newTOMLTable()
+	t.inline = true
+	p.skipBlank()
+	if p.peek() == '}' {
+		p.pos++
+		return t, nil
+	}
+	for {
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// basicString reads a string in double quotes.
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			if err := p.escape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// literalString reads a string in single quotes, which has no escapes.
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// multilineString reads a string in triple quotes, where delim is """ or
+// ”'. A line break right after the opening quotes is left out, and in
+// basic strings a backslash at the end of a line joins it to the next
+// line, skipping the blanks in between.
+func (p *tomlParser) multilineString(delim string) (string, error) {
+	p.pos += 3
+	p.newline()
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], delim) {
+			// Up to two more quotes belong to the string
+			n := 3
+			for n < 5 && p.pos+n < len(p.src) && p.src[p.pos+n] == delim[0] {
+				n++
+			}
+			sb.WriteString(p.src[p.pos+3 : p.pos+n])
+			p.pos += n
+			return sb.String(), nil
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && delim == `"""`:
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.pos = len(p.src) - len(rest)
+				p.skipSpace()
+				continue
+			}
+			if err := p.escape(&sb); err != nil {
+				return "", err
+			}
+		case p.newline():
+			sb.WriteByte('\n')
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// escape reads an escape sequence in a basic string into sb.
+func (p *tomlParser) escape(sb *strings.Builder) error {
+	if p.pos+1 >= len(p.src) {
+		return p.errorf("unterminated string")
+	}
+	c := p.src[p.pos+1]
+	p.pos += 2
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'e':
+		sb.WriteByte(0x1b)
+	case '"', '\\':
+		sb.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorf("invalid escape \\%c", c)
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid escape \\%c%s", c, p.src[p.pos:p.pos+n])
+		}
+		sb.WriteRune(rune(code))
+		p.pos += n
+	default:
+		p.pos -= 2
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// TOML writes an object as a TOML document: its keys holding objects as
+// [tables], those holding arrays of objects as [[arrays of tables]], and
+// the others as key/value pairs, in the order ForEach visits them.
+// Timestamps are written as offset date-times, or as local dates and
+// date-times when they were written without a zone, binary values and
+// strings that are not valid UTF-8 as their base64 text, and keys that
+// are not strings in their YAML form. TOML has no null: keys holding null
+// are left out, and null in an array is an error, as is a value that is
+// not an object.
+func (t Result) TOML() (string, error) {
+	if !t.IsObject() {
+		return "", fmt.Errorf("gyaml: only an object can be written as TOML, not %s", describe(t))
+	}
+	d, _ := t.decode()
+	var sb strings.Builder
+	if err := writeTOMLTable(&sb, nil, d.v, d.node); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// tomlEntries returns the keys of an object in the order of ForEach,
+// with the values and nodes of each.
+func tomlEntries(obj interface{}, node *yaml.Node) ([]string, []interface{}, []*yaml.Node) {
+	var names []string
+	var values []interface{}
+	var nodes []*yaml.Node
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		keys, n := orderedKeys(obj, node)
+		for _, k := range keys {
+			values = append(values, obj[k])
+		}
+		names, nodes = keys, n
+	case map[interface{}]interface{}:
+		keys, n := orderedAnyKeys(obj, node)
+		for _, k := range keys {
+			names = append(names, canonicalKey(k))
+			values = append(values, obj[k])
+		}
+		nodes = n
+	}
+	return names, values, nodes
+}
+
+// isTOMLTable reports whether v is written as a table, and isTOMLTables
+// whether it is written as an array of tables.
+func isTOMLTable(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	}
+	return false
+}
+
+func isTOMLTables(v interface{}) bool {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) == 0 {
+		return false
+	}
+	for _, elem := range arr {
+		if !isTOMLTable(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyTOMLTables reports whether obj has keys, all of them holding
+// tables or arrays of tables.
+func onlyTOMLTables(obj interface{}) bool {
+	_, values, _ := tomlEntries(obj, nil)
+	for _, v := range values {
+		if !isTOMLTable(v) && !isTOMLTables(v) {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// writeTOMLTable writes the pairs of an object, followed by its tables
+// and arrays of tables under the name given by path.
+func writeTOMLTable(sb *strings.Builder, path []string, obj interface{}, node *yaml.Node) error {
+	names, values, nodes := tomlEntries(obj, node)
+	for i, name := range names {
+		if values[i] == nil || isTOMLTable(values[i]) || isTOMLTables(values[i]) {
+			continue
+		}
+		sb.WriteString(tomlKey(name))
+		sb.WriteString(" = ")
+		if err := writeTOMLValue(sb, values[i], nodes[i]); err != nil {
+			return fmt.Errorf("%w at %s", err, strings.Join(append(path, name), "."))
+		}
+		sb.WriteByte('\n')
+	}
+	for i, name := range names {
+		sub := append(path[:len(path):len(path)], tomlKey(name))
+		switch {
+		case isTOMLTable(values[i]):
+			// A table holding only tables needs no header of its own
+			if !onlyTOMLTables(values[i]) {
+				if sb.Len() > 0 {
+					sb.WriteByte('\n')
+				}
+				sb.WriteString("[" + strings.Join(sub, ".") + "]\n")
+			}
+			if err := writeTOMLTable(sb, sub, values[i], resolveNode(nodes[i])); err != nil {
+				return err
+			}
+		case isTOMLTables(values[i]):
+			arr := values[i].([]interface{})
+			for j, elem := range arr {
+				if sb.Len() > 0 {
+					sb.WriteByte('\n')
+				}
+				sb.WriteString("[[" + strings.Join(sub, ".") + "]]\n")
+				if err := writeTOMLTable(sb, sub, elem, elementNode(resolveNode(nodes[i]), j, len(arr))); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveNode resolves an alias node, and returns nil for nil.
+func resolveNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	return resolveAlias(node)
+}
+
+// writeTOMLValue writes a value that is not a table, or one within an
+// array or inline table.
+func writeTOMLValue(sb *strings.Builder, v interface{}, node *yaml.Node) error {
+	node = resolveNode(node)
+	switch v := v.(type) {
+	case nil:
+		return errors.New("gyaml: TOML has no null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case int:
+		sb.WriteString(strconv.Itoa(v))
+	case int64:
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		sb.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		sb.WriteString(tomlFloat(v, 64))
+	case float32:
+		sb.WriteString(tomlFloat(float64(v), 32))
+	case synthNumber:
+		sb.WriteString(formatNumber(float64(v)))
+	case *big.Int:
+		// TOML integers have 64 bits
+		sb.WriteString(tomlFloat(toFloat(v), 64))
+	case string:
+		if node != nil && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!binary" || !utf8.ValidString(v) {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		sb.WriteString(tomlString(v))
+	case time.Time:
+		// Dates and times written without a zone stay local
+		var text string
+		if node != nil && node.Kind == yaml.ScalarNode {
+			text = node.Value
+		}
+		switch {
+		case tomlDate.MatchString(text):
+			sb.WriteString(v.Format("2006-01-02"))
+		case len(text) > 10 && !strings.ContainsAny(text[10:], "Zz+-"):
+			sb.WriteString(v.Format("2006-01-02T15:04:05.999999999"))
+		default:
+			sb.WriteString(v.Format(time.RFC3339Nano))
+		}
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := writeTOMLValue(sb, elem, elementNode(node, i, len(v))); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case map[string]interface{}, map[interface{}]interface{}:
+		names, values, nodes := tomlEntries(v, node)
+		sb.WriteByte('{')
+		first := true
+		for i, name := range names {
+			if values[i] == nil {
+				continue
+			}
+			if !first {
+				sb.WriteString(", ")
+			}
+			first = false
+			sb.WriteString(tomlKey(name) + " = ")
+			if err := writeTOMLValue(sb, values[i], nodes[i]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		return writeTOMLValue(sb, buildValue(v), nil)
+	}
+	return nil
+}
+
+// tomlFloat formats a float as TOML, which needs a decimal point or an
+// exponent.
+func tomlFloat(f float64, bitSize int) string {
+	switch s := formatYAMLFloat(f, bitSize); s {
+	case ".inf":
+		return "inf"
+	case "-.inf":
+		return "-inf"
+	case ".nan":
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+// tomlKey returns a key as TOML writes it: bare when it can be, and
+// quoted otherwise.
+func tomlKey(k string) string {
+	if k == "" {
+		return `""`
+	}
+	for i := 0; i < len(k); i++ {
+		if !isBareKeyChar(k[i]) {
+			return tomlString(k)
+		}
+	}
+	return k
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
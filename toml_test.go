@@ -0,0 +1,232 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const testTOML = `# Service configuration
+title = "TOML \"Example\""
+version = 2
+"key with.dots" = 'C:\Users'
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+joined = 2024-01-02
+updated = 1979-05-27 07:32:00
+alarm = 07:32:00
+
+[database]
+ports = [ 8000, 8001, 0x1F ]
+data = [ ["gamma", "delta"], [1, 2], ]
+temp = { cpu = 79.5, case.max = 90 }
+enabled = true
+budget = 1_000_000
+ratio = 5e-1
+limit = -inf
+notes = '''
+first
+second'''
+joined = """\
+    one \
+    line"""
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+size.unit = "mm"
+`
+
+func TestFromTOML(t *testing.T) {
+	doc, err := FromTOMLE(testTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"title", `TOML "Example"`},
+		{"version", "2"},
+		{`key with\.dots`, `C:\Users`},
+		{"owner.alarm", "07:32:00"},
+		{"database.ports.2", "31"},
+		{"database.data.0.1", "delta"},
+		{"database.temp.case.max", "90"},
+		{"database.budget", "1000000"},
+		{"database.ratio", "0.5"},
+		{"database.notes", "first\nsecond"},
+		{"database.joined", "one line"},
+		{"servers.alpha.ip", "10.0.0.1"},
+		{"products.#", "2"},
+		{`products.#(name="Nail").size.unit`, "mm"},
+	}
+	for _, tt := range tests {
+		if got := doc.Get(tt.path).String(); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if r := doc.Get("version"); r.Type != Number || r.Int() != 2 {
+		t.Errorf("Expected version to be a number, got %v", r)
+	}
+	if r := doc.Get("database.enabled"); r.Type != True {
+		t.Errorf("Expected database.enabled to be true, got %v", r)
+	}
+	if f := doc.Get("database.limit").Float(); f > -1e308 {
+		t.Errorf("Expected -inf, got %v", f)
+	}
+	if got := doc.Get("owner.dob").Time(); !got.Equal(time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected owner.dob %v", got)
+	}
+	if got := doc.Get("owner.joined").Time(); !got.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected owner.joined %v", got)
+	}
+	if got := doc.Get("owner.updated").Time(); !got.Equal(time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected owner.updated %v", got)
+	}
+
+	// Tables keep the order they are written in
+	if got := doc.Get("database").Keys(); len(got) != 9 || got[0] != "ports" || got[8] != "joined" {
+		t.Errorf("Unexpected keys %q", got)
+	}
+	if got := fmt.Sprint(doc.Keys()); got != "[title version key with.dots owner database servers products]" {
+		t.Errorf("Unexpected keys %s", got)
+	}
+
+	if r := FromTOML("a = "); r.Exists() {
+		t.Errorf("Expected a Null result for invalid TOML, got %v", r)
+	}
+	if r := FromTOML(""); r.Type != YAML || r.Raw != "{}\n" {
+		t.Errorf("Expected an empty object for an empty document, got %v", r)
+	}
+}
+
+func TestFromTOMLErrors(t *testing.T) {
+	tests := []struct {
+		toml         string
+		line, column int
+	}{
+		{"a = 1\na = 2", 2, 1},
+		{"[a]\nx = 1\n[a]", 3, 1},
+		{"a = {b = 1}\n[a]", 2, 1},
+		{"a = {b = 1}\na.c = 2", 2, 1},
+		{"a = [1]\n[[a]]", 2, 1},
+		{"[a.b]\nc = 1\n[a]\nb.d = 1", 4, 1},
+		{"a = 1 b = 2", 1, 7},
+		{`a = "open`, 1, 10},
+		{`a = "\x"`, 1, 6},
+		{"a = 01", 1, 5},
+		{"a = 1__0", 1, 5},
+		{"a = ", 1, 5},
+		{"a = [1, 2", 1, 10},
+		{"[a", 1, 3},
+		{"= 1", 1, 1},
+	}
+	for _, tt := range tests {
+		_, err := FromTOMLE(tt.toml)
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("FromTOMLE(%q): expected a SyntaxError, got %v", tt.toml, err)
+			continue
+		}
+		if serr.Line != tt.line || serr.Column != tt.column {
+			t.Errorf("FromTOMLE(%q): error at %d:%d, want %d:%d (%v)", tt.toml, serr.Line, serr.Column, tt.line, tt.column, err)
+		}
+	}
+}
+
+func TestResultTOML(t *testing.T) {
+	doc := FromTOML(testTOML)
+	got, err := doc.TOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `title = "TOML \"Example\""
+version = 2
+"key with.dots" = "C:\\Users"
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+joined = 2024-01-02
+updated = 1979-05-27T07:32:00
+alarm = "07:32:00"
+
+[database]
+ports = [8000, 8001, 31]
+data = [["gamma", "delta"], [1, 2]]
+enabled = true
+budget = 1000000
+ratio = 0.5
+limit = -inf
+notes = "first\nsecond"
+joined = "one line"
+
+[database.temp]
+cpu = 79.5
+
+[database.temp.case]
+max = 90
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+
+[products.size]
+unit = "mm"
+`
+	if got != want {
+		t.Errorf("TOML() =\n%s\nwant\n%s", got, want)
+	}
+	if back := FromTOML(got); !back.Equal(doc) {
+		t.Errorf("Expected the TOML to read back the same, got %s", back.Raw)
+	}
+
+	// From YAML, with nulls left out and values TOML writes inline
+	yml := `
+name: app
+missing: ~
+"a key": 1.0
+items:
+  - [1, {x: 1}]
+  - {}
+1: one
+`
+	got, err = Parse(yml).TOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `name = "app"
+"a key" = 1.0
+items = [[1, {x = 1}], {}]
+1 = "one"
+`
+	if got != want {
+		t.Errorf("TOML() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got, err := Get(yml, "items.0.1").TOML(); err != nil || got != "x = 1\n" {
+		t.Errorf("TOML() of a nested object = %q, %v", got, err)
+	}
+	for _, bad := range []string{"[1, 2]", "text", "a: [1, ~]", ""} {
+		if _, err := Parse(bad).TOML(); err == nil {
+			t.Errorf("Expected an error writing %q as TOML", bad)
+		}
+	}
+	if _, err := Parse("[1, 2]").TOML(); err == nil || err.Error() != "gyaml: only an object can be written as TOML, not array" {
+		t.Errorf("Expected the error to name the array, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package gyaml
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// TraceOutcome is how a traced operation ended.
+type TraceOutcome int
+
+const (
+	// TraceFound means the value was found, or for Valid, the document
+	// was valid
+	TraceFound TraceOutcome = iota
+	// TraceMiss means the path did not resolve, or the document was empty
+	TraceMiss
+	// TraceError means the document could not be read, or for GetE and
+	// GetWithOptions, that an error was reported
+	TraceError
+)
+
+// String returns the name of the outcome.
+func (o TraceOutcome) String() string {
+	switch o {
+	case TraceFound:
+		return "found"
+	case TraceMiss:
+		return "miss"
+	case TraceError:
+		return "error"
+	}
+	return "TraceOutcome(" + strconv.Itoa(int(o)) + ")"
+}
+
+// TraceEvent describes a call to Get, Parse or Valid, for the function
+// set by SetTraceFunc.
+type TraceEvent struct {
+	// Op is the operation: "Get" for Get, GetBytes, GetE, GetWith,
+	// GetWithOptions and GetRef, "Parse" for Parse, ParseBytes, ParseWith
+	// and ParseWithOptions, and "Valid" for Valid, ValidBytes and
+	// ValidWithError
+	Op string
+	// Path is the path searched for, empty for Parse and Valid
+	Path string
+	// Size is the length of the document in bytes
+	Size int
+	// Duration is the time the call took, not counting the trace function
+	Duration time.Duration
+	// Outcome is how the call ended
+	Outcome TraceOutcome
+	// Err is the reason for a TraceError outcome
+	Err error
+}
+
+// traceFunc holds the function set by SetTraceFunc, or nil.
+var traceFunc atomic.Pointer[func(ev TraceEvent)]
+
+// SetTraceFunc sets a function called after every call to Get, Parse and
+// Valid and their variants, such as GetE and ParseWith, to find slow
+// documents and paths:
+//
+//	gyaml.SetTraceFunc(func(ev gyaml.TraceEvent) {
+//		if ev.Duration > 50*time.Millisecond {
+//			log.Printf("slow gyaml %s %q on %d bytes: %v", ev.Op, ev.Path, ev.Size, ev.Duration)
+//		}
+//	})
+//
+// The function is called on the goroutine that made the call, so it must
+// be safe for concurrent use and should return quickly. Calls made by
+// gyaml itself, such as Result.Get, are not reported. A nil fn removes
+// the function; while none is set, tracing costs a single atomic load
+// per call.
+func SetTraceFunc(fn func(ev TraceEvent)) {
+	if fn == nil {
+		traceFunc.Store(nil)
+		return
+	}
+	traceFunc.Store(&fn)
+}
+
+// tracer is the trace function of a single call, or nil when tracing is
+// off. It holds the start time of the call.
+type tracer struct {
+	fn    func(ev TraceEvent)
+	start time.Time
+}
+
+// startTrace returns the tracer of a call starting now, or nil.
+func startTrace() *tracer {
+	fn := traceFunc.Load()
+	if fn == nil {
+		return nil
+	}
+	return &tracer{fn: *fn, start: time.Now()}
+}
+
+// end reports the call to the trace function.
+func (t *tracer) end(op, path string, size int, found bool, err error) {
+	ev := TraceEvent{
+		Op:       op,
+		Path:     path,
+		Size:     size,
+		Duration: time.Since(t.start),
+		Outcome:  TraceMiss,
+		Err:      err,
+	}
+	switch {
+	case err != nil:
+		ev.Outcome = TraceError
+	case found:
+		ev.Outcome = TraceFound
+	}
+	t.fn(ev)
+}
@@ -0,0 +1,106 @@
+package gyaml
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// recordTrace sets a trace function that records the events, until the
+// test ends.
+func recordTrace(t *testing.T) *[]TraceEvent {
+	t.Helper()
+	var events []TraceEvent
+	SetTraceFunc(func(ev TraceEvent) {
+		events = append(events, ev)
+	})
+	t.Cleanup(func() { SetTraceFunc(nil) })
+	return &events
+}
+
+func TestTraceEvents(t *testing.T) {
+	events := recordTrace(t)
+	doc := "name: web\nports: [80, 443]\n"
+
+	Get(doc, "name")
+	Get(doc, "ports.#(=443)")
+	Get(doc, "missing")
+	Get("a: [", "a")
+	GetBytes([]byte(doc), "ports.1")
+	_, _ = GetE(doc, "ports.5", WithStrictBounds())
+	GetWith(doc, "name")
+	GetRef(doc, "ports")
+	Parse(doc)
+	Parse("")
+	ParseWith(doc, WithMaxDepth(1))
+	Valid(doc)
+	Valid("a: [")
+
+	want := []struct {
+		op, path string
+		size     int
+		outcome  TraceOutcome
+	}{
+		{"Get", "name", len(doc), TraceFound},
+		{"Get", "ports.#(=443)", len(doc), TraceFound},
+		{"Get", "missing", len(doc), TraceMiss},
+		{"Get", "a", 4, TraceError},
+		{"Get", "ports.1", len(doc), TraceFound},
+		{"Get", "ports.5", len(doc), TraceError},
+		{"Get", "name", len(doc), TraceFound},
+		{"Get", "ports", len(doc), TraceFound},
+		{"Parse", "", len(doc), TraceFound},
+		{"Parse", "", 0, TraceMiss},
+		{"Parse", "", len(doc), TraceError},
+		{"Valid", "", len(doc), TraceFound},
+		{"Valid", "", 4, TraceError},
+	}
+	if len(*events) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(want), len(*events), *events)
+	}
+	for i, w := range want {
+		ev := (*events)[i]
+		if ev.Op != w.op || ev.Path != w.path || ev.Size != w.size || ev.Outcome != w.outcome {
+			t.Errorf("Event %d: expected %s %q %d %s, got %s %q %d %s", i, w.op, w.path, w.size, w.outcome, ev.Op, ev.Path, ev.Size, ev.Outcome)
+		}
+		if (ev.Err != nil) != (w.outcome == TraceError) {
+			t.Errorf("Event %d: unexpected error %v", i, ev.Err)
+		}
+		if ev.Duration < 0 || ev.Duration > time.Minute {
+			t.Errorf("Event %d: unexpected duration %v", i, ev.Duration)
+		}
+	}
+	if err := (*events)[5].Err; !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("Expected the error of GetE, got %v", err)
+	}
+	if err := (*events)[10].Err; !errors.Is(err, ErrLimit) {
+		t.Errorf("Expected the limit error of ParseWith, got %v", err)
+	}
+}
+
+func TestTraceInternalCallsNotReported(t *testing.T) {
+	doc := "a: {b: [1, 2]}\nc: x\n"
+	events := recordTrace(t)
+	r := Get(doc, "a")
+	r.Get("b.1")
+	Result{Type: YAML, Raw: "b: 1"}.Get("b")
+	Has(doc, "a.b.#")
+	ForEachPathOf(doc, []string{"a.b", "c"}, func(int, string, Result) bool { return true })
+	if len(*events) != 1 {
+		t.Errorf("Expected only the call to Get to be reported, got %+v", *events)
+	}
+
+	SetTraceFunc(nil)
+	Get(doc, "a")
+	if len(*events) != 1 {
+		t.Errorf("Expected no events once the trace function is removed")
+	}
+}
+
+func TestTraceOutcomeString(t *testing.T) {
+	for o, want := range map[TraceOutcome]string{TraceFound: "found", TraceMiss: "miss", TraceError: "error", 7: "TraceOutcome(7)"} {
+		if got := o.String(); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
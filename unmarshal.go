@@ -0,0 +1,215 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound is returned by Unmarshal when there is no value at the path.
+var ErrNotFound = errors.New("gyaml: value not found")
+
+// Unmarshal decodes the value at path into v, a pointer to a struct, map
+// or any other type yaml.Unmarshal decodes into, without writing the
+// value out as YAML and parsing it again:
+//
+//	var db DatabaseConfig
+//	err := gyaml.Unmarshal(doc, "services.api.database", &db)
+//
+// The value is decoded from its node in the document, the way
+// yaml.Unmarshal decodes it, so yaml tags, UnmarshalYAML methods, tags
+// such as !!binary and timestamps all apply. The error is a *SyntaxError
+// when the document is not valid YAML, and wraps ErrNotFound when the
+// path does not exist. A null leaves v as yaml.Unmarshal does.
+func Unmarshal(yamlStr, path string, v interface{}) error {
+	root, err := parseCached(yamlStr, nil)
+	if err != nil {
+		return newSyntaxError(yamlStr, err)
+	}
+	switch {
+	case root == nil && path == "":
+		// An empty document decodes as null
+		return decodeInto(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}, v)
+	case root == nil:
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	case path == "":
+		return decodeInto(root, v)
+	case isSimplePath(path):
+		if node, ok := lookupNode(root, path); ok {
+			if node == nil {
+				return fmt.Errorf("%w: %s", ErrNotFound, path)
+			}
+			return decodeInto(node, v)
+		}
+	}
+	r := Get(yamlStr, path)
+	if !r.Exists() && !r.IsNull() {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return r.Unmarshal(v)
+}
+
+// Unmarshal decodes the value into v like the Unmarshal function. Arrays
+// and objects read from a document are decoded from their nodes; other
+// values are decoded from the YAML form of their value. It returns an
+// error wrapping ErrNotFound for a Result that does not exist, and leaves
+// v as yaml.Unmarshal does for a null.
+func (t Result) Unmarshal(v interface{}) error {
+	node, err := t.valueNode()
+	if err != nil {
+		return err
+	}
+	return decodeInto(node, v)
+}
+
+// decodeInto decodes node into v, which must be a non-nil pointer.
+func decodeInto(node *yaml.Node, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("gyaml: Unmarshal: v must be a non-nil pointer")
+	}
+	return node.Decode(v)
+}
+
+// valueNode returns a node that decodes to the value of t.
+func (t Result) valueNode() (*yaml.Node, error) {
+	switch t.Type {
+	case YAML:
+		d, ok := t.decode()
+		if !ok {
+			return nil, errors.New("gyaml: Unmarshal: the value is not valid YAML")
+		}
+		if d.node != nil {
+			return d.node, nil
+		}
+		return encodeNode(d.v)
+	case String:
+		switch {
+		case t.Raw != "":
+			// A !!binary scalar keeps its base64 text in Raw
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: t.Raw}, nil
+		case !t.Time().IsZero():
+			// Timestamps decode into time.Time, and into strings as written
+			return plainNode(t.Str), nil
+		}
+		return stringNode(t.Str), nil
+	case Number:
+		if t.Raw != "" {
+			return plainNode(t.Raw), nil
+		}
+		return plainNode(formatNumber(t.Num)), nil
+	case True, False:
+		return plainNode(t.String()), nil
+	}
+	if t.IsNull() {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Extract fills the fields of v, a pointer to a struct, from the paths in
+// their gyaml tags, so that a flat struct can gather values from all over
+// a document in one call:
+//
+//	var cfg struct {
+//		Name    string   `gyaml:"app.name"`
+//		Port    int      `gyaml:"app.server.port,required"`
+//		Primary string   `gyaml:"databases.#(role=\"primary\").host"`
+//		Hosts   []string `gyaml:"servers.#.host"`
+//	}
+//	err := gyaml.Extract(doc, &cfg)
+//
+// A path has the full syntax of Get, and each value is decoded into its
+// field like Result.Unmarshal does. Fields whose path does not exist, or
+// holds null, are left as they are, unless the tag ends with ",required",
+// in which case the error wraps ErrNotFound. A struct field whose own
+// fields have gyaml tags is filled the same way, with their paths starting
+// from the value of its path, or from the same value when it has no tag.
+// Fields without a tag, and those tagged "-", are skipped. The error is a
+// *SyntaxError when the document is not valid YAML, and names the field
+// that could not be filled otherwise.
+func Extract(yamlStr string, v interface{}) error {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil {
+		return newSyntaxError(yamlStr, err)
+	}
+	return doc.Extract(v)
+}
+
+// Extract fills the fields of v from the paths in their gyaml tags like
+// the Extract function, with the paths starting from t.
+func (t Result) Extract(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("gyaml: Extract: v must be a non-nil pointer to a struct")
+	}
+	return t.extractFields(rv.Elem(), "")
+}
+
+// extractFields fills the fields of sv, a struct, from the paths in their
+// tags. prefix leads to sv from the struct given to Extract, for errors.
+func (t Result) extractFields(sv reflect.Value, prefix string) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := sv.Field(i)
+		tag, tagged := field.Tag.Lookup("gyaml")
+		switch {
+		case tag == "-":
+			continue
+		case !tagged:
+			// Untagged structs group fields with paths from the same value
+			if hasPathTags(field.Type) {
+				if err := t.extractFields(fv, prefix+field.Name+"."); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		path, required := tag, false
+		if i := strings.LastIndexByte(tag, ','); i >= 0 && tag[i+1:] == "required" {
+			path, required = tag[:i], true
+		}
+		r := t.Get(path)
+		if !r.Exists() {
+			if required {
+				return fmt.Errorf("gyaml: Extract: field %s%s: %w: %s", prefix, field.Name, ErrNotFound, path)
+			}
+			continue
+		}
+		if hasPathTags(field.Type) {
+			if err := r.extractFields(fv, prefix+field.Name+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.Unmarshal(fv.Addr().Interface()); err != nil {
+			return fmt.Errorf("gyaml: Extract: field %s%s: %w", prefix, field.Name, err)
+		}
+	}
+	return nil
+}
+
+// hasPathTags reports whether typ is a struct with gyaml tags on its
+// fields, or on those of its untagged struct fields.
+func hasPathTags(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("gyaml"); ok || hasPathTags(field.Type) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package gyaml
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const unmarshalYAML = `
+defaults: &defaults
+  timeout: 30
+services:
+  api:
+    host: api.local
+    port: 8080
+    started: 2024-01-02T03:04:05Z
+    tags: [a, b]
+    icon: !!binary R0lG
+  web:
+    <<: *defaults
+    host: web.local
+    port: 0x50
+  empty: ~
+`
+
+type unmarshalService struct {
+	Host    string    `yaml:"host"`
+	Port    int       `yaml:"port"`
+	Timeout int       `yaml:"timeout"`
+	Started time.Time `yaml:"started"`
+	Tags    []string  `yaml:"tags"`
+	Icon    string    `yaml:"icon"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	var api unmarshalService
+	if err := Unmarshal(unmarshalYAML, "services.api", &api); err != nil {
+		t.Fatal(err)
+	}
+	want := unmarshalService{Host: "api.local", Port: 8080, Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Tags: []string{"a", "b"}, Icon: "GIF"}
+	if !reflect.DeepEqual(api, want) {
+		t.Errorf("Unexpected %+v", api)
+	}
+
+	// Merge keys and queries go through the general engine
+	var web unmarshalService
+	if err := Unmarshal(unmarshalYAML, "services.web", &web); err != nil || web.Timeout != 30 || web.Port != 80 {
+		t.Errorf("Unexpected %+v, %v", web, err)
+	}
+	var hosts []string
+	if err := Unmarshal(unmarshalYAML, "services.@values.#.host", &hosts); err != nil || !reflect.DeepEqual(hosts, []string{"api.local", "web.local"}) {
+		t.Errorf("Unexpected hosts %q, %v", hosts, err)
+	}
+	var all map[string]interface{}
+	if err := Unmarshal(unmarshalYAML, "", &all); err != nil || len(all) != 2 {
+		t.Errorf("Unexpected document %v, %v", all, err)
+	}
+
+	var started time.Time
+	if err := Unmarshal(unmarshalYAML, "services.api.started", &started); err != nil || started.Hour() != 3 {
+		t.Errorf("Unexpected time %v, %v", started, err)
+	}
+	port := 1
+	if err := Unmarshal(unmarshalYAML, "services.empty", &port); err != nil || port != 1 {
+		t.Errorf("Expected null to leave the value, got %d, %v", port, err)
+	}
+
+	if err := Unmarshal(unmarshalYAML, "services.missing", &port); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := Unmarshal(unmarshalYAML, `services.api.tags.#(="x")`, &port); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a query, got %v", err)
+	}
+	var tag string
+	if err := Unmarshal(unmarshalYAML, `services.api.tags.#(="b")`, &tag); err != nil || tag != "b" {
+		t.Errorf("Expected the query to match, got %q, %v", tag, err)
+	}
+	if err := Unmarshal(unmarshalYAML, "services.api.host", &port); err == nil {
+		t.Errorf("Expected an error decoding a string into an int")
+	}
+	if err := Unmarshal(unmarshalYAML, "services.api", port); err == nil {
+		t.Errorf("Expected an error for a value that is not a pointer")
+	}
+	var serr *SyntaxError
+	if err := Unmarshal("a: [1", "a", &port); !errors.As(err, &serr) {
+		t.Errorf("Expected a SyntaxError, got %v", err)
+	}
+}
+
+func TestResultUnmarshal(t *testing.T) {
+	doc := Parse(unmarshalYAML)
+	var api unmarshalService
+	if err := doc.Get("services.api").Unmarshal(&api); err != nil || api.Port != 8080 || api.Icon != "GIF" {
+		t.Errorf("Unexpected %+v, %v", api, err)
+	}
+
+	tests := []struct {
+		path string
+		into interface{}
+		want interface{}
+	}{
+		{"services.api.port", new(int), 8080},
+		{"services.web.port", new(uint8), uint8(80)},
+		{"services.api.host", new(string), "api.local"},
+		{"services.api.started", new(time.Time), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"services.api.started", new(string), "2024-01-02T03:04:05Z"},
+		{"services.api.tags.#", new(int), 2},
+		{"services.api.tags|@reverse", new([]string), []string{"b", "a"}},
+		{"services.web.timeout", new(float64), 30.0},
+		{"{services.api.port,services.web.host}", new(map[string]interface{}), map[string]interface{}{"port": 8080, "host": "web.local"}},
+	}
+	for _, tt := range tests {
+		if err := doc.Get(tt.path).Unmarshal(tt.into); err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.into).Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	var b bool
+	if err := ArrayOf(true).Get("0").Unmarshal(&b); err != nil || !b {
+		t.Errorf("Unexpected %v, %v", b, err)
+	}
+	if err := doc.Get("missing").Unmarshal(&b); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	type endpoint struct {
+		Host string `gyaml:"host"`
+		Port int    `gyaml:"port"`
+	}
+	var cfg struct {
+		API      unmarshalService `gyaml:"services.api"`
+		WebHost  string           `gyaml:"services.web.host,required"`
+		Timeout  int              `gyaml:"services.web.timeout"`
+		Ports    []int            `gyaml:"services.@values.#.port"`
+		Web      endpoint         `gyaml:"services.web"`
+		Started  time.Time        `gyaml:"services.api.started"`
+		Count    int              `gyaml:"services.@keys.#"`
+		Tag      string           `gyaml:"services.api.tags.#(=\"b\")"`
+		Kept     string           `gyaml:"services.missing"`
+		Empty    string           `gyaml:"services.empty"`
+		Skipped  string           `gyaml:"-"`
+		Untagged string
+		Defaults struct {
+			Timeout int `gyaml:"defaults.timeout"`
+		}
+		unexposed string `gyaml:"services.api.host"`
+	}
+	cfg.Kept, cfg.Empty, cfg.Untagged = "kept", "empty", "untagged"
+	if err := Extract(unmarshalYAML, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.API.Host != "api.local" || cfg.API.Port != 8080 || len(cfg.API.Tags) != 2 || cfg.Tag != "b" {
+		t.Errorf("Unexpected API %+v, tag %q", cfg.API, cfg.Tag)
+	}
+	if cfg.WebHost != "web.local" || cfg.Timeout != 30 || !reflect.DeepEqual(cfg.Ports, []int{8080, 80}) {
+		t.Errorf("Unexpected %q, %d, %v", cfg.WebHost, cfg.Timeout, cfg.Ports)
+	}
+	if cfg.Web != (endpoint{"web.local", 80}) {
+		t.Errorf("Unexpected nested struct %+v", cfg.Web)
+	}
+	if cfg.Started.Year() != 2024 || cfg.Count != 3 || cfg.Defaults.Timeout != 30 {
+		t.Errorf("Unexpected %v, %d, %d", cfg.Started, cfg.Count, cfg.Defaults.Timeout)
+	}
+	if cfg.Kept != "kept" || cfg.Empty != "empty" || cfg.Untagged != "untagged" || cfg.Skipped != "" || cfg.unexposed != "" {
+		t.Errorf("Expected fields without values to be left alone, got %+v", cfg)
+	}
+
+	// Paths start from the Result
+	var ep endpoint
+	if err := Get(unmarshalYAML, "services.api").Extract(&ep); err != nil || ep != (endpoint{"api.local", 8080}) {
+		t.Errorf("Unexpected %+v, %v", ep, err)
+	}
+
+	var required struct {
+		Inner struct {
+			Name string `gyaml:"name,required"`
+		} `gyaml:"services.api"`
+	}
+	err := Extract(unmarshalYAML, &required)
+	if !errors.Is(err, ErrNotFound) || err.Error() != "gyaml: Extract: field Inner.Name: gyaml: value not found: name" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	var wrong struct {
+		Port bool `gyaml:"services.api.host"`
+	}
+	if err := Extract(unmarshalYAML, &wrong); err == nil || !strings.Contains(err.Error(), "field Port") {
+		t.Errorf("Expected a decoding error naming the field, got %v", err)
+	}
+	if err := Extract(unmarshalYAML, ep); err == nil {
+		t.Errorf("Expected an error for a value that is not a pointer")
+	}
+	var serr *SyntaxError
+	if err := Extract("a: [1", &ep); !errors.As(err, &serr) {
+		t.Errorf("Expected a SyntaxError, got %v", err)
+	}
+}
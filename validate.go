@@ -0,0 +1,247 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a key of a document that does not match the struct passed
+// to ValidateStruct, or a field of the struct the document leaves out.
+type FieldError struct {
+	// Path leads to the key, or to the missing field, from the top of the
+	// document, in the syntax of Get
+	Path string
+	// Missing is true for a field the document leaves out, and false for a
+	// key the struct has no field for
+	Missing bool
+	// Line and Column locate the key, or for a missing field, the object
+	// that leaves it out, counting from 1
+	Line   int
+	Column int
+}
+
+func (e *FieldError) Error() string {
+	what := "unknown field"
+	if e.Missing {
+		what = "missing field"
+	}
+	return fmt.Sprintf("gyaml: line %d: %s %s", e.Line, what, e.Path)
+}
+
+// FieldErrors holds the mismatches found by ValidateStruct, in the order
+// they were found.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ValidateStruct decodes the YAML into v, a pointer to a struct, and
+// checks that the keys of the document match its fields, to catch typos
+// such as databse: in a configuration file:
+//
+//	var cfg Config
+//	if err := gyaml.ValidateStruct(doc, &cfg); err != nil {
+//		log.Fatal(err) // gyaml: line 3: unknown field databse
+//	}
+//
+// Fields are named as yaml.v3 names them: by their yaml tag, or by their
+// name in lower case. Every key of an object decoded into a struct must
+// name a field, and every field must have a key unless its tag has the
+// omitempty flag. The check follows nested structs, pointers, slices and
+// maps, and keys merged with <<; it skips values decoded by an
+// UnmarshalYAML method and by interface fields.
+//
+// The error is a *SyntaxError when the document is not valid YAML, and a
+// FieldErrors listing every mismatch when the keys do not match, in which
+// case v is still decoded. Otherwise it is the error of decoding, such as
+// a string found where the struct has an integer, or nil.
+func ValidateStruct(yamlStr string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("gyaml: ValidateStruct: v must be a non-nil pointer")
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlStr), &doc); err != nil {
+		return newSyntaxError(yamlStr, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	var errs FieldErrors
+	checkFields(doc.Content[0], rv.Type().Elem(), "", &errs)
+	decodeErr := doc.Content[0].Decode(v)
+	if len(errs) > 0 {
+		return errs
+	}
+	return decodeErr
+}
+
+// unmarshalerType is the interface of types that decode themselves.
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkFields appends to errs the mismatches between node, found at path,
+// and the type t it is decoded into.
+func checkFields(node *yaml.Node, t reflect.Type, path string, errs *FieldErrors) {
+	node = resolveAlias(node)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if node.Kind == yaml.SequenceNode {
+			for i, item := range node.Content {
+				checkFields(item, t.Elem(), joinPath(path, strconv.Itoa(i)), errs)
+			}
+		}
+	case reflect.Map:
+		if node.Kind == yaml.MappingNode {
+			keys, values := mappingEntries(node)
+			for i, key := range keys {
+				checkFields(values[i], t.Elem(), joinPath(path, Escape(key.Value)), errs)
+			}
+		}
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields, open := structFields(t)
+		byName := make(map[string]structField, len(fields))
+		for _, f := range fields {
+			byName[f.name] = f
+		}
+		found := map[string]bool{}
+		keys, values := mappingEntries(node)
+		for i, key := range keys {
+			keyPath := joinPath(path, Escape(key.Value))
+			f, ok := byName[key.Value]
+			switch {
+			case ok:
+				found[key.Value] = true
+				checkFields(values[i], f.typ, keyPath, errs)
+			case !open:
+				*errs = append(*errs, &FieldError{Path: keyPath, Line: key.Line, Column: key.Column})
+			}
+		}
+		for _, f := range fields {
+			if !f.optional && !found[f.name] {
+				*errs = append(*errs, &FieldError{Path: joinPath(path, Escape(f.name)), Missing: true, Line: node.Line, Column: node.Column})
+			}
+		}
+	}
+}
+
+// mappingEntries returns the scalar keys of a mapping node and the nodes
+// of their values, with the keys brought in by merge keys in place of the
+// "<<" entry. A key written again replaces the merged one.
+func mappingEntries(node *yaml.Node) ([]*yaml.Node, []*yaml.Node) {
+	var keys, values []*yaml.Node
+	at := map[string]int{}
+	var visit func(m *yaml.Node, merged bool)
+	visit = func(m *yaml.Node, merged bool) {
+		m = resolveAlias(m)
+		switch m.Kind {
+		case yaml.SequenceNode:
+			for _, item := range m.Content {
+				visit(item, true)
+			}
+			return
+		case yaml.MappingNode:
+		default:
+			return
+		}
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			key := resolveAlias(m.Content[i])
+			if isMergeKey(key) {
+				visit(m.Content[i+1], true)
+				continue
+			}
+			if key.Kind != yaml.ScalarNode {
+				continue
+			}
+			if j, ok := at[key.Value]; ok {
+				if !merged {
+					keys[j], values[j] = key, m.Content[i+1]
+				}
+				continue
+			}
+			at[key.Value] = len(keys)
+			keys = append(keys, key)
+			values = append(values, m.Content[i+1])
+		}
+	}
+	visit(node, false)
+	return keys, values
+}
+
+// structField is a field of a struct as yaml.v3 decodes it.
+type structField struct {
+	name     string
+	typ      reflect.Type
+	optional bool
+}
+
+// structFields returns the fields of a struct type in the order they are
+// declared, with those of ",inline" structs in place. open is true when an
+// inline map takes any other key.
+func structFields(t reflect.Type) (fields []structField, open bool) {
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			tag := f.Tag.Get("yaml")
+			if tag == "" && !strings.Contains(string(f.Tag), ":") {
+				tag = string(f.Tag)
+			}
+			if tag == "-" {
+				continue
+			}
+			name, flags, _ := strings.Cut(tag, ",")
+			if hasFlag(flags, "inline") {
+				switch ft := f.Type; {
+				case ft.Kind() == reflect.Map:
+					open = true
+				case ft.Kind() == reflect.Struct:
+					add(ft)
+				case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+					add(ft.Elem())
+				}
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			fields = append(fields, structField{name: name, typ: f.Type, optional: hasFlag(flags, "omitempty")})
+		}
+	}
+	add(t)
+	return fields, open
+}
+
+// hasFlag reports whether the comma separated flags of a tag hold flag.
+func hasFlag(flags, flag string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
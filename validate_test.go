@@ -0,0 +1,87 @@
+package gyaml
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type validateServer struct {
+	Host string
+	Port int `yaml:"port,omitempty"`
+}
+
+type validateConfig struct {
+	Name     string                    `yaml:"name"`
+	Database validateServer            `yaml:"database"`
+	Replicas []validateServer          `yaml:"replicas,omitempty"`
+	Labels   map[string]validateServer `yaml:"labels,omitempty"`
+	Extra    interface{}               `yaml:"extra,omitempty"`
+	Skipped  string                    `yaml:"-"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	var cfg validateConfig
+	err := ValidateStruct("name: shop\ndatabase:\n  host: db\n  port: 5432\nextra: {any: thing}\n", &cfg)
+	if err != nil || cfg.Database.Port != 5432 || cfg.Name != "shop" {
+		t.Errorf("Expected the document to match, got %v and %+v", err, cfg)
+	}
+
+	doc := `name: shop
+databse:
+  host: db
+replicas:
+  - host: a
+  - hots: b
+labels:
+  "x.y": {host: c, extra: 1}
+`
+	cfg = validateConfig{}
+	err = ValidateStruct(doc, &cfg)
+	var errs FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected FieldErrors, got %v", err)
+	}
+	want := []string{
+		"gyaml: line 2: unknown field databse",
+		"gyaml: line 6: unknown field replicas.1.hots",
+		"gyaml: line 6: missing field replicas.1.host",
+		`gyaml: line 8: unknown field labels.x\.y.extra`,
+		"gyaml: line 1: missing field database",
+	}
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if errs[0].Missing || errs[0].Column != 1 || !errs[2].Missing {
+		t.Errorf("Unexpected details %+v %+v", errs[0], errs[2])
+	}
+	if cfg.Name != "shop" || len(cfg.Replicas) != 2 {
+		t.Errorf("Expected the document to be decoded anyway, got %+v", cfg)
+	}
+
+	// Merged keys count, and count once
+	merged := "base: &b {host: db}\nname: x\ndatabase:\n  <<: *b\n  port: 1\n"
+	var withBase struct {
+		Base     validateServer `yaml:"base"`
+		Name     string         `yaml:"name"`
+		Database validateServer `yaml:"database"`
+	}
+	if err := ValidateStruct(merged, &withBase); err != nil || withBase.Database.Host != "db" {
+		t.Errorf("Expected merged keys to match, got %v", err)
+	}
+
+	var serr *SyntaxError
+	if err := ValidateStruct("name: [", &cfg); !errors.As(err, &serr) {
+		t.Errorf("Expected a SyntaxError, got %v", err)
+	}
+	if err := ValidateStruct("name: shop\ndatabase: {host: [1]}\n", &cfg); err == nil || errors.As(err, &errs) {
+		t.Errorf("Expected a decoding error, got %v", err)
+	}
+	if err := ValidateStruct("name: shop", cfg); err == nil {
+		t.Error("Expected an error for a value that is not a pointer")
+	}
+}
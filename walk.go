@@ -0,0 +1,261 @@
+package gyaml
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// walkEntry is a value reached by walkTree.
+type walkEntry struct {
+	// parts are the path components leading to the value, escaped. The
+	// slice is reused for the following entries.
+	parts []string
+	// key is the mapping key of the value as written, or "" for an
+	// array element
+	key string
+	// index is the position of an array element, or -1 for a mapping
+	// value
+	index int
+	value interface{}
+	// node is the node of the value, or nil when unknown
+	node *yaml.Node
+	// skip is set by the visitor to leave out the values within this one
+	skip bool
+}
+
+// path returns the path of the entry.
+func (e *walkEntry) path() string {
+	return strings.Join(e.parts, ".")
+}
+
+// walkTree calls fn for every value below a decoded value, depth-first in
+// document order: a value is visited before the values it contains, and
+// mapping keys brought in by a merge key are visited in place of the
+// "<<" entry. Values are only decoded; no Result is built unless fn asks
+// for one. It returns false when fn stopped the walk by returning false.
+func walkTree(parts []string, value interface{}, node *yaml.Node, fn func(e *walkEntry) bool) bool {
+	e := walkEntry{parts: parts}
+	visit := func() bool {
+		e.skip = false
+		if !fn(&e) {
+			return false
+		}
+		return e.skip || walkTree(e.parts, e.value, e.node, fn)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys, nodes := orderedKeys(v, node)
+		for i, k := range keys {
+			e.parts, e.key, e.index, e.value, e.node = append(parts, Escape(k)), k, -1, v[k], nodes[i]
+			if !visit() {
+				return false
+			}
+		}
+	case map[interface{}]interface{}:
+		keys, nodes := orderedAnyKeys(v, node)
+		for i, k := range keys {
+			key := canonicalKey(k)
+			e.parts, e.key, e.index, e.value, e.node = append(parts, Escape(key)), key, -1, v[k], nodes[i]
+			if !visit() {
+				return false
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			e.parts, e.key, e.index, e.value, e.node = append(parts, strconv.Itoa(i)), "", i, elem, elementNode(node, i, len(v))
+			if !visit() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Walk calls fn for every value in the document, depth-first in document
+// order, with its path from the top of the document, escaped so that it
+// can be passed to Get:
+//
+//	gyaml.Walk(config, func(path string, value gyaml.Result) bool {
+//		if value.Type == gyaml.String && strings.HasPrefix(value.Str, "http://") {
+//			log.Printf("%s: insecure URL %s", path, value.Str)
+//		}
+//		return true
+//	})
+//
+// Arrays and objects are visited before the values they contain, and the
+// keys brought in by a merge key in place of the "<<" entry. Null values
+// are visited too, with a Null Result. Returning false stops the walk.
+// Nothing is visited when the document cannot be parsed; only the first
+// document of a stream is walked.
+func Walk(yamlStr string, fn func(path string, value Result) bool) {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil || doc.decoded == nil {
+		return
+	}
+	d := doc.decoded
+	walkTree(nil, d.v, d.node, func(e *walkEntry) bool {
+		r := d.at(e.value, e.node)
+		r.path = e.path()
+		return fn(r.path, r)
+	})
+}
+
+// Flatten returns the values of the document that hold no other value,
+// keyed by their path, to bridge a document to flat configuration such as
+// environment variables:
+//
+//	database:
+//	  primary: {host: db1, port: 5432}
+//	  replicas: []
+//
+// flattens to database.primary.host, database.primary.port and
+// database.replicas, the last one an empty array. Paths are escaped so
+// that they can be passed to Get, with a key such as "a.b" written
+// a\.b. Null values, which do not exist, are left out. Flatten returns
+// nil when the document cannot be parsed, and an empty map when it holds
+// a single scalar.
+func Flatten(yamlStr string) map[string]Result {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil || doc.decoded == nil {
+		return nil
+	}
+	d := doc.decoded
+	flat := map[string]Result{}
+	walkTree(nil, d.v, d.node, func(e *walkEntry) bool {
+		switch v := e.value.(type) {
+		case nil:
+			return true
+		case []interface{}:
+			if len(v) > 0 {
+				return true
+			}
+		case map[string]interface{}:
+			if len(v) > 0 {
+				return true
+			}
+		case map[interface{}]interface{}:
+			if len(v) > 0 {
+				return true
+			}
+		}
+		r := d.at(e.value, e.node)
+		r.path = e.path()
+		flat[r.path] = r
+		return true
+	})
+	return flat
+}
+
+// Match is a value found by FindAll.
+type Match struct {
+	// Path leads to the value from the top of the document, escaped so
+	// that it can be passed to Get
+	Path string
+	// Result is the value
+	Result Result
+}
+
+// FindAll returns every value of the document for which fn returns true,
+// in the order Walk visits them, to find values without knowing where
+// they are:
+//
+//	for _, m := range gyaml.FindAll(config, func(v gyaml.Result) bool {
+//		return v.Type == gyaml.String && v.Str == "changeme"
+//	}) {
+//		log.Printf("%s still holds the default password", m.Path)
+//	}
+//
+// fn sees arrays and objects as well as the values within them, and null
+// values as a Null Result. FindAll returns nil when nothing matches or
+// the document cannot be parsed.
+func FindAll(yamlStr string, fn func(value Result) bool) []Match {
+	var matches []Match
+	Walk(yamlStr, func(path string, value Result) bool {
+		if fn(value) {
+			matches = append(matches, Match{Path: path, Result: value})
+		}
+		return true
+	})
+	return matches
+}
+
+// GetFirstKey searches the whole document for a mapping key and returns
+// the value of its first occurrence, depth-first in document order. It
+// returns a Null result when the key does not occur with a value.
+//
+//	gyaml.GetFirstKey(helmValues, "image")
+//
+// Unlike a path, the key is matched as written, without escapes.
+func GetFirstKey(yamlStr, key string) Result {
+	found := Result{Type: Null}
+	findKey(yamlStr, key, func(r Result) bool {
+		found = r
+		return false
+	})
+	return found
+}
+
+// GetAllKeys searches the whole document for a mapping key and returns
+// the values of all its occurrences, depth-first in document order. The
+// values nested within a match are searched too. Occurrences whose value
+// is null are left out, as such values do not exist.
+func GetAllKeys(yamlStr, key string) []Result {
+	var found []Result
+	findKey(yamlStr, key, func(r Result) bool {
+		found = append(found, r)
+		return true
+	})
+	return found
+}
+
+// WalkPaths returns the path of every value under the result, relative
+// to it and escaped so that it can be passed to Get. Arrays and objects
+// are listed before the values they contain, in document order where the
+// order is known. Null values, which do not exist, are left out. Paths
+// with more than maxDepth components are not listed; a maxDepth below 1
+// lists every path.
+//
+//	gyaml.Get(yaml, "services").WalkPaths(2)
+//	// ["web", "web.image", "web.ports", "db", "db.image"]
+func (t Result) WalkPaths(maxDepth int) []string {
+	if t.Type != YAML {
+		return nil
+	}
+	d, ok := t.decode()
+	if !ok {
+		return nil
+	}
+	if d.stream {
+		// Streams resolve paths against their first document
+		return t.Array()[0].WalkPaths(maxDepth)
+	}
+	var paths []string
+	walkTree(nil, d.v, d.node, func(e *walkEntry) bool {
+		if e.value != nil {
+			paths = append(paths, e.path())
+		}
+		e.skip = maxDepth > 0 && len(e.parts) >= maxDepth
+		return true
+	})
+	return paths
+}
+
+// findKey calls fn with the value of every occurrence of key, until fn
+// returns false.
+func findKey(yamlStr, key string, fn func(r Result) bool) {
+	doc, err := parseDocument(yamlStr, Options{})
+	if err != nil || doc.decoded == nil {
+		return
+	}
+	d := doc.decoded
+	walkTree(nil, d.v, d.node, func(e *walkEntry) bool {
+		if e.index >= 0 || e.key != key || e.value == nil {
+			return true
+		}
+		r := d.at(e.value, e.node)
+		r.path = e.path()
+		return fn(r)
+	})
+}
@@ -0,0 +1,289 @@
+package gyaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+const helmValuesYAML = `
+image:
+  repository: nginx
+  tag: "1.25"
+sidecars:
+  - name: proxy
+    image: envoy:1.28
+  - name: logs
+    config:
+      image: fluentd:1.16
+      image.pullPolicy: Always
+base: &base
+  image: busybox
+job:
+  <<: *base
+  schedule: daily
+empty:
+  image:
+ports: {80: {image: redirect}}
+`
+
+func TestGetFirstKey(t *testing.T) {
+	r := GetFirstKey(helmValuesYAML, "image")
+	if r.Get("repository").String() != "nginx" {
+		t.Errorf("Expected the top-level image first, got %q", r.Raw)
+	}
+	if r.path != "image" {
+		t.Errorf("Expected path image, got %q", r.path)
+	}
+	if got := GetFirstKey(helmValuesYAML, "repository").String(); got != "nginx" {
+		t.Errorf("Expected nginx, got %q", got)
+	}
+	if got := GetFirstKey(helmValuesYAML, "image.pullPolicy").String(); got != "Always" {
+		t.Errorf("Expected keys to match as written, got %q", got)
+	}
+	if r := GetFirstKey(helmValuesYAML, "missing"); r.Exists() {
+		t.Errorf("Expected Null for a missing key, got %q", r.Raw)
+	}
+	if r := GetFirstKey("a: [", "a"); r.Exists() {
+		t.Errorf("Expected Null for invalid YAML, got %q", r.Raw)
+	}
+}
+
+func TestGetAllKeys(t *testing.T) {
+	var values, paths []string
+	for _, r := range GetAllKeys(helmValuesYAML, "image") {
+		values = append(values, r.String())
+		paths = append(paths, r.path)
+		if !Get(helmValuesYAML, r.path).Equal(r) {
+			t.Errorf("Path %q does not lead to %q", r.path, r.Raw)
+		}
+	}
+	wantValues := []string{
+		"repository: nginx\ntag: \"1.25\"\n",
+		"envoy:1.28",
+		"fluentd:1.16",
+		"busybox",
+		"busybox",
+		"redirect",
+	}
+	wantPaths := []string{
+		"image",
+		"sidecars.0.image",
+		"sidecars.1.config.image",
+		"base.image",
+		"job.image",
+		`ports.\80.image`,
+	}
+	if len(values) != len(wantValues) {
+		t.Fatalf("Expected %d values, got %q at %q", len(wantValues), values, paths)
+	}
+	for i := range values {
+		if values[i] != wantValues[i] || paths[i] != wantPaths[i] {
+			t.Errorf("Occurrence %d: got %q at %q, want %q at %q", i, values[i], paths[i], wantValues[i], wantPaths[i])
+		}
+	}
+
+	nested := GetAllKeys("a: {a: {a: 1}}", "a")
+	if len(nested) != 3 || nested[2].Int() != 1 {
+		t.Errorf("Expected values within a match to be searched, got %d", len(nested))
+	}
+	if got := GetAllKeys(helmValuesYAML, "missing"); got != nil {
+		t.Errorf("Expected nil for a missing key, got %v", got)
+	}
+}
+
+func TestWalkTreeSpans(t *testing.T) {
+	// Values found by the walk keep their source text
+	r := GetFirstKey(helmValuesYAML, "sidecars")
+	spans := r.Elements()
+	if len(spans) != 2 || helmValuesYAML[spans[0].Start:spans[0].End] != "name: proxy\n    image: envoy:1.28" {
+		t.Errorf("Unexpected spans %v", spans)
+	}
+}
+
+func TestWalkPaths(t *testing.T) {
+	services := Get(complexYAML, "application.services")
+	want := []string{
+		"user_service",
+		"user_service.endpoints",
+		"user_service.endpoints.0",
+		"user_service.endpoints.1",
+		"user_service.dependencies",
+		"user_service.dependencies.0",
+		"user_service.dependencies.1",
+		"auth_service",
+		"auth_service.jwt",
+		"auth_service.jwt.secret",
+		"auth_service.jwt.expiry",
+		"auth_service.jwt.refresh_expiry",
+		"auth_service.jwt.algorithms",
+		"auth_service.oauth",
+		"auth_service.oauth.providers",
+	}
+	if got := services.WalkPaths(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected paths\n%q\ngot\n%q", want, got)
+	}
+	if got := services.WalkPaths(1); !reflect.DeepEqual(got, []string{"user_service", "auth_service"}) {
+		t.Errorf("Unexpected top level paths %q", got)
+	}
+
+	// Without a limit every path resolves against the result
+	all := services.WalkPaths(0)
+	if len(all) <= len(want) {
+		t.Fatalf("Expected more than %d paths, got %d", len(want), len(all))
+	}
+	for _, path := range all {
+		if !services.Get(path).Exists() {
+			t.Errorf("Path %q does not resolve", path)
+		}
+	}
+
+	escaped := Get("a: {b.c: 1, d: null, e: [x]}", "a").WalkPaths(0)
+	if !reflect.DeepEqual(escaped, []string{`b\.c`, "e", "e.0"}) {
+		t.Errorf("Unexpected paths %q", escaped)
+	}
+	if got := Get("a: 1", "a").WalkPaths(0); got != nil {
+		t.Errorf("Expected no paths under a scalar, got %q", got)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	var visited []string
+	Walk(helmValuesYAML, func(path string, value Result) bool {
+		if value.path != path {
+			t.Errorf("Path %q: Result has path %q", path, value.path)
+		}
+		if got := Get(helmValuesYAML, path); !reflect.DeepEqual(got.Value(), value.Value()) {
+			t.Errorf("Path %q: expected %v, got %v", path, got.Value(), value.Value())
+		}
+		visited = append(visited, path+"="+value.String())
+		return true
+	})
+	want := []string{
+		"image=repository: nginx\ntag: \"1.25\"\n",
+		"image.repository=nginx",
+		"image.tag=1.25",
+		"sidecars=- name: proxy\n  image: envoy:1.28\n- name: logs\n  config:\n    image: fluentd:1.16\n    image.pullPolicy: Always\n",
+		"sidecars.0=name: proxy\nimage: envoy:1.28\n",
+		"sidecars.0.name=proxy",
+		"sidecars.0.image=envoy:1.28",
+		"sidecars.1=name: logs\nconfig:\n  image: fluentd:1.16\n  image.pullPolicy: Always\n",
+		"sidecars.1.name=logs",
+		"sidecars.1.config=image: fluentd:1.16\nimage.pullPolicy: Always\n",
+		"sidecars.1.config.image=fluentd:1.16",
+		`sidecars.1.config.image\.pullPolicy=Always`,
+		"base=&base\nimage: busybox\n",
+		"base.image=busybox",
+		"job=image: busybox\nschedule: daily\n",
+		"job.image=busybox",
+		"job.schedule=daily",
+		"empty=image:\n",
+		"empty.image=",
+		"ports={80: {image: redirect}}\n",
+		`ports.\80={image: redirect}` + "\n",
+		`ports.\80.image=redirect`,
+	}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("Expected\n%q\ngot\n%q", want, visited)
+	}
+
+	// Returning false stops the walk
+	var n int
+	Walk(helmValuesYAML, func(path string, value Result) bool {
+		n++
+		return path != "image.repository"
+	})
+	if n != 2 {
+		t.Errorf("Expected the walk to stop after 2 values, got %d", n)
+	}
+
+	Walk("a: [1", func(string, Result) bool {
+		t.Error("Expected nothing visited for an invalid document")
+		return false
+	})
+}
+
+func TestFindAll(t *testing.T) {
+	doc := `
+db:
+  password: changeme
+  port: 5432
+services:
+  - name: web
+    port: 80
+    admin: {password: changeme}
+  - name: api
+    port: 70000
+token: "changeme"
+`
+	var paths []string
+	for _, m := range FindAll(doc, func(v Result) bool { return v.Type == String && v.Str == "changeme" }) {
+		if got := Get(doc, m.Path).String(); got != m.Result.String() {
+			t.Errorf("Path %q: expected %q, got %q", m.Path, got, m.Result.String())
+		}
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"db.password", "services.0.admin.password", "token"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %q, got %q", want, paths)
+	}
+
+	ports := FindAll(doc, func(v Result) bool {
+		return v.Type == Number && (v.Int() < 1024 || v.Int() > 65535)
+	})
+	if len(ports) != 2 || ports[0].Path != "services.0.port" || ports[1].Result.Int() != 70000 {
+		t.Errorf("Unexpected ports %v", ports)
+	}
+
+	if m := FindAll(doc, func(Result) bool { return false }); m != nil {
+		t.Errorf("Expected no matches, got %v", m)
+	}
+	if m := FindAll("a: [1", func(Result) bool { return true }); m != nil {
+		t.Errorf("Expected no matches for an invalid document, got %v", m)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	doc := `
+database:
+  primary:
+    connection: {host: db1, port: 5432}
+  replicas: []
+  options: {}
+features:
+  - name: search
+    enabled: true
+  - beta
+"log.level": debug
+unset:
+`
+	flat := Flatten(doc)
+	got := map[string]string{}
+	for path, r := range flat {
+		got[path] = r.String()
+		if Get(doc, path).String() != r.String() {
+			t.Errorf("Path %q does not resolve to %q", path, r.String())
+		}
+	}
+	want := map[string]string{
+		"database.primary.connection.host": "db1",
+		"database.primary.connection.port": "5432",
+		"database.replicas":                "[]\n",
+		"database.options":                 "{}\n",
+		"features.0.name":                  "search",
+		"features.0.enabled":               "true",
+		"features.1":                       "beta",
+		`log\.level`:                       "debug",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if flat["database.primary.connection.port"].Int() != 5432 {
+		t.Errorf("Expected the port as a number")
+	}
+
+	if flat := Flatten("just a scalar"); flat == nil || len(flat) != 0 {
+		t.Errorf("Expected an empty map for a scalar document, got %v", flat)
+	}
+	if flat := Flatten("a: [1"); flat != nil {
+		t.Errorf("Expected nil for an invalid document, got %v", flat)
+	}
+}